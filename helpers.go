@@ -1,6 +1,8 @@
 package main
 
 import (
+	"crypto/tls"
+	"encoding/json"
 	"log"
 	"net/http"
 	"os"
@@ -11,10 +13,17 @@ import (
 	"github.com/Slimo300/MicroservicesChatApp/backend/lib/events"
 	"github.com/Slimo300/MicroservicesChatApp/backend/lib/msgqueue"
 	"github.com/Slimo300/MicroservicesChatApp/backend/lib/msgqueue/kafka"
+	"github.com/Slimo300/chat-groupservice/internal/config"
+	"github.com/Slimo300/chat-groupservice/internal/eventemitter"
+	"github.com/Slimo300/chat-groupservice/internal/eventlistener"
+	"github.com/Slimo300/chat-groupservice/internal/eventprocessor"
 )
 
+// consumedTopics are kafka topics service consumes events from
+var consumedTopics = []string{"users", "wsmessages"}
+
 // startHTTPSServer starts HTTPS server if SSL certificate is provided
-func startHTTPSServer(httpsServer *http.Server, certDir string, errChan chan<- error) {
+func startHTTPSServer(httpsServer *http.Server, certDir string, tlsConfig *tls.Config, errChan chan<- error) {
 	cert := filepath.Join(certDir, "cert.pem")
 	if _, err := os.Stat(cert); err != nil {
 		log.Printf("Couldn't start https server. No cert.pem or key.pem in %s\n", certDir)
@@ -26,38 +35,84 @@ func startHTTPSServer(httpsServer *http.Server, certDir string, errChan chan<- e
 		return
 	}
 
+	httpsServer.TLSConfig = tlsConfig
 	log.Printf("HTTPS Server starting on: %s", httpsServer.Addr)
 	errChan <- httpsServer.ListenAndServeTLS(cert, key)
 }
 
-// kafkaSetup starts Kafka EventEmiter and EventListener
-func kafkaSetup(brokerAddresses []string) (msgqueue.EventEmiter, msgqueue.EventListener, error) {
+// kafkaSetup starts Kafka EventEmiter and EventListener, listener starts consuming from startOffset ("earliest" or "latest")
+// and emiter waits for requiredAcks ("all", "local" or "none")
+func kafkaSetup(brokerAddresses []string, startOffset, requiredAcks string, withEmitter bool) (msgqueue.EventEmiter, *eventlistener.KafkaListener, error) {
+
+	offset, err := eventlistener.StartOffset(startOffset)
+	if err != nil {
+		return nil, nil, err
+	}
+	acks, err := eventemitter.RequiredAcks(requiredAcks)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	brokerConf := sarama.NewConfig()
 	brokerConf.ClientID = "groupsService"
 	brokerConf.Version = sarama.V2_3_0_0
 	brokerConf.Producer.Return.Successes = true
+	brokerConf.Producer.RequiredAcks = acks
+	brokerConf.Consumer.Offsets.Initial = offset
 	client, err := sarama.NewClient(brokerAddresses, brokerConf)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	emiter, err := kafka.NewKafkaEventEmiter(client)
-	if err != nil {
-		return nil, nil, err
+	// no producer is needed when events are emitted elsewhere
+	var emiter msgqueue.EventEmiter
+	if withEmitter {
+		if emiter, err = kafka.NewKafkaEventEmiter(client); err != nil {
+			return nil, nil, err
+		}
 	}
-	mapper := msgqueue.NewDynamicEventMapper()
-	if err := mapper.RegisterTypes(
+	consumed := []reflect.Type{
 		reflect.TypeOf(events.UserRegisteredEvent{}),
 		reflect.TypeOf(events.UserPictureModifiedEvent{}),
-	); err != nil {
+		reflect.TypeOf(events.MessageSentEvent{}),
+	}
+	if err := eventprocessor.CheckEventTypes(consumed...); err != nil {
+		return nil, nil, err
+	}
+	mapper := msgqueue.NewDynamicEventMapper()
+	if err := mapper.RegisterTypes(consumed...); err != nil {
 		return nil, nil, err
 	}
-	listener, err := kafka.NewKafkaEventListener(client, mapper, kafka.KafkaTopic{Name: "users"})
+	consumer, err := sarama.NewConsumerFromClient(client)
 	if err != nil {
 		return nil, nil, err
 	}
+	topics := make([]eventlistener.KafkaTopic, 0, len(consumedTopics))
+	for _, topic := range consumedTopics {
+		topics = append(topics, eventlistener.KafkaTopic{Name: topic})
+	}
+	listener := eventlistener.NewKafkaListener(consumer, mapper, brokerConf.Consumer.Offsets.Initial, topics...)
+	listener.EnableReplay(client, func() (sarama.Consumer, error) {
+		return sarama.NewConsumerFromClient(client)
+	})
 
 	return emiter, listener, nil
 
 }
+
+// logConfig logs effective configuration as a single JSON object with secrets redacted. Credentials of S3
+// aren't part of config as AWS SDK reads them on its own, only whether they're set is logged
+func logConfig(conf config.Config) {
+	fields := conf.LogFields()
+	fields["brokers"] = []string{conf.BrokerAddress}
+	fields["consumedTopics"] = consumedTopics
+	fields["awsAccessKeyID"] = config.Redact(os.Getenv("AWS_ACCESS_KEY_ID"))
+	fields["awsSecretAccessKey"] = config.Redact(os.Getenv("AWS_SECRET_ACCESS_KEY"))
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		log.Printf("Couldn't log config: %v", err)
+		return
+	}
+	log.Printf("Effective config: %s", data)
+}
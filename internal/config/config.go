@@ -1,10 +1,15 @@
 package config
 
 import (
+	"crypto/tls"
 	"errors"
+	"fmt"
+	"net"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -15,14 +20,57 @@ type Config struct {
 	HTTPPort  string `mapstructure:"httpPort"`
 	HTTPSPort string `mapstructure:"httpsPort"`
 
-	CertDir string `mapstructure:"certDir"`
+	CertDir         string   `mapstructure:"certDir"`
+	TLSMinVersion   uint16   `mapstructure:"tlsMinVersion"`
+	TLSCipherSuites []uint16 `mapstructure:"tlsCipherSuites"`
 
 	TokenServiceAddress string `mapstructure:"tokenServiceAddress"`
 
 	Origin string `mapstructure:"origin"`
 
-	BrokerAddress string `mapstructure:"brokerAddress"`
-	S3Bucket      string `mapstructure:"bucketname"`
+	BrokerAddress       string `mapstructure:"brokerAddress"`
+	ConsumerStartOffset string `mapstructure:"consumerStartOffset"`
+	ProducerAcks        string `mapstructure:"producerRequiredAcks"`
+	EmitterType         string `mapstructure:"emitterType"`
+	WebhookURL          string `mapstructure:"webhookURL"`
+	WebhookSecret       string `mapstructure:"webhookSecret"`
+	S3Bucket            string `mapstructure:"bucketname"`
+	S3KeyPrefix         string `mapstructure:"s3KeyPrefix"`
+	S3SSE               string `mapstructure:"s3SSE"`
+	S3SSEKMSKeyID       string `mapstructure:"s3SSEKMSKeyID"`
+
+	ModerationEndpoint string        `mapstructure:"moderationEndpoint"`
+	ModerationTimeout  time.Duration `mapstructure:"moderationTimeout"`
+	ModerationFailOpen bool          `mapstructure:"moderationFailOpen"`
+
+	MaxImageDimension     int `mapstructure:"maxImageDimension"`
+	MaxConcurrentImageOps int `mapstructure:"maxConcurrentImageOps"`
+
+	InternalAPIKey string `mapstructure:"internalAPIKey"`
+
+	ActivityDebounce time.Duration `mapstructure:"activityDebounce"`
+	RequestTimeout   time.Duration `mapstructure:"requestTimeout"`
+
+	UserBatchSize     int           `mapstructure:"userBatchSize"`
+	UserFlushInterval time.Duration `mapstructure:"userFlushInterval"`
+	LogUnknownGroups  bool          `mapstructure:"logUnknownGroups"`
+
+	AvatarMaxAge time.Duration `mapstructure:"avatarMaxAge"`
+	// PlatformMetricsTTL is how long aggregate metrics of all groups are cached
+	PlatformMetricsTTL time.Duration `mapstructure:"platformMetricsTTL"`
+	// DefaultSorts maps names of lists to sorts used when client doesn't choose any
+	DefaultSorts map[string]string `mapstructure:"defaultSorts"`
+
+	UniqueGroupNamePerOwner bool `mapstructure:"uniqueGroupNamePerOwner"`
+	MaxGroupsPerUser        int  `mapstructure:"maxGroupsPerUser"`
+
+	ReadOnly bool `mapstructure:"readOnly"`
+
+	RateLimitEnabled   bool         `mapstructure:"rateLimitEnabled"`
+	RateLimitPerSecond int          `mapstructure:"rateLimitPerSecond"`
+	RateLimitBurst     int          `mapstructure:"rateLimitBurst"`
+	RateLimitExempt    []*net.IPNet `mapstructure:"rateLimitExempt"`
+	TrustedProxies     []*net.IPNet `mapstructure:"trustedProxies"`
 }
 
 // LoadConfigFromEnvironment loads user service configuration from environment variables and returns an error
@@ -54,8 +102,43 @@ func LoadConfigFromEnvironment() (conf Config, err error) {
 		return Config{}, errors.New("Environment variable ORIGIN not set")
 	}
 
+	conf.ConsumerStartOffset = os.Getenv("CONSUMER_START_OFFSET")
+	switch conf.ConsumerStartOffset {
+	case "":
+		conf.ConsumerStartOffset = "earliest"
+	case "earliest", "latest":
+	default:
+		return Config{}, errors.New("Environment variable CONSUMER_START_OFFSET must be either earliest or latest")
+	}
+
+	conf.ProducerAcks = os.Getenv("PRODUCER_REQUIRED_ACKS")
+	switch conf.ProducerAcks {
+	case "":
+		conf.ProducerAcks = "all"
+	case "all", "local", "none":
+	default:
+		return Config{}, errors.New("Environment variable PRODUCER_REQUIRED_ACKS must be one of all, local or none")
+	}
+
+	conf.EmitterType = os.Getenv("EMITTER_TYPE")
+	switch conf.EmitterType {
+	case "":
+		conf.EmitterType = "kafka"
+	case "kafka":
+	case "webhook":
+		conf.WebhookURL = os.Getenv("WEBHOOK_URL")
+		if conf.WebhookURL == "" {
+			return Config{}, errors.New("Environment variable WEBHOOK_URL not set")
+		}
+		// optional, requests aren't signed when not set
+		conf.WebhookSecret = os.Getenv("WEBHOOK_SECRET")
+	default:
+		return Config{}, errors.New("Environment variable EMITTER_TYPE must be either kafka or webhook")
+	}
+
+	// optional with webhook emitter, events of other services aren't consumed when not set
 	conf.BrokerAddress = os.Getenv("BROKER_ADDRESS")
-	if conf.BrokerAddress == "" {
+	if conf.BrokerAddress == "" && conf.EmitterType != "webhook" {
 		return Config{}, errors.New("Environment variable BROKER_ADDRESS not set")
 	}
 
@@ -63,12 +146,141 @@ func LoadConfigFromEnvironment() (conf Config, err error) {
 	if conf.S3Bucket == "" {
 		return Config{}, errors.New("Environment variable S3_BUCKET not set")
 	}
+	// optional, whole bucket is used when not set
+	conf.S3KeyPrefix = os.Getenv("S3_KEY_PREFIX")
+
+	conf.S3SSE = os.Getenv("S3_SSE")
+	switch conf.S3SSE {
+	case "", "AES256":
+	case "aws:kms":
+		// optional, bucket's default KMS key is used when not set
+		conf.S3SSEKMSKeyID = os.Getenv("S3_SSE_KMS_KEY_ID")
+	default:
+		return Config{}, errors.New("Environment variable S3_SSE must be either AES256 or aws:kms")
+	}
+
+	// optional, uploaded pictures aren't moderated when not set
+	conf.ModerationEndpoint = os.Getenv("MODERATION_ENDPOINT")
+
+	conf.ModerationTimeout, err = getDurationEnv("MODERATION_TIMEOUT", 5*time.Second)
+	if err != nil {
+		return Config{}, err
+	}
+
+	conf.ModerationFailOpen, err = getBoolEnv("MODERATION_FAIL_OPEN", false)
+	if err != nil {
+		return Config{}, err
+	}
 
 	conf.CertDir = os.Getenv("CERT_DIR")
 	if conf.CertDir == "" {
 		return Config{}, errors.New("Environment variable CERT_DIR not set")
 	}
 
+	conf.TLSMinVersion, err = getTLSVersionEnv("TLS_MIN_VERSION", tls.VersionTLS12)
+	if err != nil {
+		return Config{}, err
+	}
+
+	conf.TLSCipherSuites, err = getCipherSuitesEnv("TLS_CIPHER_SUITES")
+	if err != nil {
+		return Config{}, err
+	}
+
+	conf.MaxImageDimension, err = getPositiveIntEnv("MAX_IMAGE_DIMENSION", 4096)
+	if err != nil {
+		return Config{}, err
+	}
+
+	conf.MaxConcurrentImageOps, err = getPositiveIntEnv("MAX_CONCURRENT_IMAGE_OPS", 4)
+	if err != nil {
+		return Config{}, err
+	}
+
+	conf.ActivityDebounce, err = getDurationEnv("ACTIVITY_DEBOUNCE", 5*time.Minute)
+	if err != nil {
+		return Config{}, err
+	}
+
+	conf.UserBatchSize, err = getPositiveIntEnv("USER_BATCH_SIZE", 100)
+	if err != nil {
+		return Config{}, err
+	}
+
+	conf.UserFlushInterval, err = getDurationEnv("USER_FLUSH_INTERVAL", time.Second)
+	if err != nil {
+		return Config{}, err
+	}
+
+	conf.LogUnknownGroups, err = getBoolEnv("LOG_UNKNOWN_GROUP_EVENTS", false)
+	if err != nil {
+		return Config{}, err
+	}
+
+	conf.RequestTimeout, err = getDurationEnv("REQUEST_TIMEOUT", 30*time.Second)
+	if err != nil {
+		return Config{}, err
+	}
+
+	conf.AvatarMaxAge, err = getDurationEnv("AVATAR_MAX_AGE", 24*time.Hour)
+	if err != nil {
+		return Config{}, err
+	}
+
+	conf.PlatformMetricsTTL, err = getDurationEnv("PLATFORM_METRICS_CACHE_TTL", 5*time.Minute)
+	if err != nil {
+		return Config{}, err
+	}
+
+	conf.DefaultSorts, err = getKeyValuesEnv("DEFAULT_SORTS")
+	if err != nil {
+		return Config{}, err
+	}
+
+	conf.UniqueGroupNamePerOwner, err = getBoolEnv("UNIQUE_GROUP_NAME_PER_OWNER", false)
+	if err != nil {
+		return Config{}, err
+	}
+
+	conf.MaxGroupsPerUser, err = getPositiveIntEnv("MAX_GROUPS_PER_USER", 200)
+	if err != nil {
+		return Config{}, err
+	}
+
+	conf.ReadOnly, err = getBoolEnv("READ_ONLY", false)
+	if err != nil {
+		return Config{}, err
+	}
+
+	conf.RateLimitEnabled, err = getBoolEnv("RATE_LIMIT_ENABLED", false)
+	if err != nil {
+		return Config{}, err
+	}
+
+	conf.RateLimitPerSecond, err = getPositiveIntEnv("RATE_LIMIT_PER_SECOND", 20)
+	if err != nil {
+		return Config{}, err
+	}
+
+	conf.RateLimitBurst, err = getPositiveIntEnv("RATE_LIMIT_BURST", 40)
+	if err != nil {
+		return Config{}, err
+	}
+
+	conf.RateLimitExempt, err = getCIDRsEnv("RATE_LIMIT_EXEMPT_CIDRS")
+	if err != nil {
+		return Config{}, err
+	}
+
+	// optional, X-Forwarded-For is ignored and clients are identified by their address when not set
+	conf.TrustedProxies, err = getCIDRsEnv("TRUSTED_PROXIES")
+	if err != nil {
+		return Config{}, err
+	}
+
+	// optional, internal endpoints reject all requests when not set
+	conf.InternalAPIKey = os.Getenv("INTERNAL_API_KEY")
+
 	return
 }
 
@@ -89,3 +301,140 @@ func LoadConfigFromFile(path string) (config Config, err error) {
 	err = vp.Unmarshal(&config)
 	return
 }
+
+// getPositiveIntEnv reads environment variable as a positive integer, returning def when variable is not set
+func getPositiveIntEnv(name string, def int) (int, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return def, nil
+	}
+	num, err := strconv.Atoi(value)
+	if err != nil || num <= 0 {
+		return 0, fmt.Errorf("Environment variable %s is not a valid positive number", name)
+	}
+	return num, nil
+}
+
+// getDurationEnv reads environment variable as a positive duration (e.g. "30s", "5m"), returning def when variable is not set
+func getDurationEnv(name string, def time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return def, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("Environment variable %s is not a valid positive duration", name)
+	}
+	return duration, nil
+}
+
+// getTLSVersionEnv reads environment variable as TLS version ("1.2" or "1.3"), returning def when variable is not set
+func getTLSVersionEnv(name string, def uint16) (uint16, error) {
+	switch os.Getenv(name) {
+	case "":
+		return def, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("Environment variable %s must be either 1.2 or 1.3", name)
+	}
+}
+
+// getCipherSuitesEnv reads environment variable as comma separated list of cipher suite names
+// (e.g. "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"), returning nil when variable is not set. Only suites
+// considered secure by crypto/tls are accepted
+func getCipherSuitesEnv(name string) ([]uint16, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	known := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+
+	var suites []uint16
+	for _, suiteName := range strings.Split(value, ",") {
+		id, ok := known[strings.TrimSpace(suiteName)]
+		if !ok {
+			return nil, fmt.Errorf("Environment variable %s contains unknown cipher suite %s", name, strings.TrimSpace(suiteName))
+		}
+		suites = append(suites, id)
+	}
+	return suites, nil
+}
+
+// getKeyValuesEnv reads environment variable as comma separated list of key=value pairs
+// (e.g. "groups=name_asc,members=activity_desc"), returning nil when variable is not set
+func getKeyValuesEnv(name string) (map[string]string, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	pairs := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		key, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || key == "" || val == "" {
+			return nil, fmt.Errorf("Environment variable %s must be a comma separated list of key=value pairs", name)
+		}
+		pairs[key] = val
+	}
+	return pairs, nil
+}
+
+// getCIDRsEnv reads environment variable as comma separated list of networks (e.g. "10.0.0.0/8,fd00::/8"),
+// single addresses are accepted as networks containing only them. It returns nil when variable is not set
+func getCIDRsEnv(name string) ([]*net.IPNet, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	var networks []*net.IPNet
+	for _, cidr := range strings.Split(value, ",") {
+		cidr = strings.TrimSpace(cidr)
+		if !strings.Contains(cidr, "/") {
+			if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("Environment variable %s contains invalid network %s", name, cidr)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// TLSConfig builds TLS configuration of HTTPS server, minimal version defaults to TLS 1.2. Cipher suites
+// don't apply to TLS 1.3 connections as Go doesn't allow configuring them
+func (c Config) TLSConfig() *tls.Config {
+	minVersion := c.TLSMinVersion
+	if minVersion == 0 {
+		minVersion = tls.VersionTLS12
+	}
+	return &tls.Config{
+		MinVersion:   minVersion,
+		CipherSuites: c.TLSCipherSuites,
+	}
+}
+
+// getBoolEnv reads environment variable as a boolean, returning def when variable is not set
+func getBoolEnv(name string, def bool) (bool, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return def, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("Environment variable %s is not a valid boolean", name)
+	}
+	return b, nil
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// REDACTED replaces secrets in logged configuration
+const REDACTED = "****"
+
+// Redact hides secret value, empty value is kept so that unset secrets can be told apart from set ones
+func Redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return REDACTED
+}
+
+// redactDSN hides password in MySQL DSN ("user:password@tcp(host:port)/db"), password may contain '@'
+// so credentials end at the last one
+func redactDSN(dsn string) string {
+	at := strings.LastIndex(dsn, "@")
+	if at < 0 {
+		return dsn
+	}
+	user, _, hasPassword := strings.Cut(dsn[:at], ":")
+	if !hasPassword {
+		return dsn
+	}
+	return user + ":" + REDACTED + dsn[at:]
+}
+
+// redactURL keeps only scheme and host of URL, credentials and tokens are often passed in its userinfo, path
+// or query. URL that can't be parsed is hidden entirely
+func redactURL(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" {
+		return REDACTED
+	}
+	return parsed.Scheme + "://" + parsed.Host
+}
+
+func tlsVersionName(version uint16) string {
+	switch version {
+	case 0:
+		return ""
+	case tls.VersionTLS12:
+		return "1.2"
+	case tls.VersionTLS13:
+		return "1.3"
+	default:
+		return fmt.Sprintf("0x%04x", version)
+	}
+}
+
+func networkNames(networks []*net.IPNet) []string {
+	names := make([]string, 0, len(networks))
+	for _, network := range networks {
+		names = append(names, network.String())
+	}
+	return names
+}
+
+// LogFields returns effective configuration keyed by names of its fields in config file, ready to be logged.
+// Secrets are redacted, URLs are reduced to their hosts and durations, TLS settings and networks are given in their readable form
+func (c Config) LogFields() map[string]interface{} {
+	cipherSuites := make([]string, 0, len(c.TLSCipherSuites))
+	for _, suite := range c.TLSCipherSuites {
+		cipherSuites = append(cipherSuites, tls.CipherSuiteName(suite))
+	}
+
+	return map[string]interface{}{
+		"dbAddress": redactDSN(c.DBAddress),
+		"httpPort":  c.HTTPPort,
+		"httpsPort": c.HTTPSPort,
+
+		"certDir":         c.CertDir,
+		"tlsMinVersion":   tlsVersionName(c.TLSMinVersion),
+		"tlsCipherSuites": cipherSuites,
+
+		"tokenServiceAddress": c.TokenServiceAddress,
+
+		"origin": c.Origin,
+
+		"brokerAddress":        c.BrokerAddress,
+		"consumerStartOffset":  c.ConsumerStartOffset,
+		"producerRequiredAcks": c.ProducerAcks,
+		"emitterType":          c.EmitterType,
+		"webhookURL":           redactURL(c.WebhookURL),
+		"webhookSecret":        Redact(c.WebhookSecret),
+		"bucketname":           c.S3Bucket,
+		"s3KeyPrefix":          c.S3KeyPrefix,
+		"s3SSE":                c.S3SSE,
+		"s3SSEKMSKeyID":        c.S3SSEKMSKeyID,
+
+		"moderationEndpoint": redactURL(c.ModerationEndpoint),
+		"moderationTimeout":  c.ModerationTimeout.String(),
+		"moderationFailOpen": c.ModerationFailOpen,
+
+		"maxImageDimension":     c.MaxImageDimension,
+		"maxConcurrentImageOps": c.MaxConcurrentImageOps,
+
+		"internalAPIKey": Redact(c.InternalAPIKey),
+
+		"activityDebounce": c.ActivityDebounce.String(),
+		"requestTimeout":   c.RequestTimeout.String(),
+
+		"userBatchSize":     c.UserBatchSize,
+		"userFlushInterval": c.UserFlushInterval.String(),
+		"logUnknownGroups":  c.LogUnknownGroups,
+
+		"avatarMaxAge":       c.AvatarMaxAge.String(),
+		"platformMetricsTTL": c.PlatformMetricsTTL.String(),
+		"defaultSorts":       c.DefaultSorts,
+
+		"uniqueGroupNamePerOwner": c.UniqueGroupNamePerOwner,
+		"maxGroupsPerUser":        c.MaxGroupsPerUser,
+
+		"readOnly": c.ReadOnly,
+
+		"rateLimitEnabled":   c.RateLimitEnabled,
+		"rateLimitPerSecond": c.RateLimitPerSecond,
+		"rateLimitBurst":     c.RateLimitBurst,
+		"rateLimitExempt":    networkNames(c.RateLimitExempt),
+		"trustedProxies":     networkNames(c.TrustedProxies),
+	}
+}
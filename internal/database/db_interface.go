@@ -1,26 +1,73 @@
 package database
 
 import (
+	"context"
+	"time"
+
 	"github.com/Slimo300/MicroservicesChatApp/backend/lib/events"
 	"github.com/Slimo300/chat-groupservice/internal/models"
 	"github.com/google/uuid"
 )
 
 type DBLayer interface {
-	GetUserGroups(id uuid.UUID) ([]models.Group, error)
+	GetUserGroups(id uuid.UUID, sort models.Sort) ([]models.Group, error)
 
+	GetSharedGroups(userID, targetID uuid.UUID, sort models.Sort, after *models.Cursor, num, offset int) ([]models.Group, error)
+	GetGroupsModifiedSince(since time.Time, afterID uuid.UUID, num int) ([]models.Group, error)
 	CreateGroup(userID uuid.UUID, name string) (models.Group, error)
+	CreateGroups(definitions []models.GroupDefinition, allOrNothing bool) ([]models.GroupCreationResult, error)
+	GetMembership(userID, groupID, targetID uuid.UUID) (*models.Member, error)
+	GetGroupWithMembership(userID, groupID uuid.UUID) (*models.Member, error)
+	FindMember(groupID, userID uuid.UUID) (*models.Member, error)
+	StreamGroupMembers(groupID uuid.UUID, fn func(models.Member) error) error
+	GetUserMemberships(userID uuid.UUID, groupIDs []uuid.UUID) ([]models.Member, error)
+	GetGroupMemberships(userID, groupID uuid.UUID, userIDs []uuid.UUID) ([]models.Member, error)
+	GetRecentMembers(userID, groupID uuid.UUID, since time.Time, sort models.Sort, after *models.Cursor, num, offset int) ([]models.Member, error)
 	DeleteMember(userID, groupID, memberID uuid.UUID) (*models.Member, error)
 	GrantRights(userID, groupID, memberID uuid.UUID, rights models.MemberRights) (*models.Member, error)
+	ChangeMemberRoles(userID, groupID uuid.UUID, changes []models.RoleChange) ([]models.RoleChangeResult, error)
+	MergeUserMemberships(fromID, toID uuid.UUID) (models.MembershipMerge, error)
+	HandoverMember(userID, groupID, sourceID, targetID uuid.UUID, handover models.MemberHandover) (*models.Member, *models.Member, error)
+	StepDown(userID, groupID uuid.UUID) (*models.Member, error)
+	SetNickname(userID, groupID, memberID uuid.UUID, nickname string) (*models.Member, error)
+	CountUserGroups(userID uuid.UUID) (int64, error)
+	GetGroupsExistence(groupIDs []uuid.UUID) ([]models.Group, error)
+	GetGroupSettings(userID, groupID uuid.UUID) (models.EffectiveSettings, error)
+	UpdateGroupSettings(userID, groupID uuid.UUID, settings models.GroupSettings) (models.Group, error)
+	ValidateGroupSettings(userID, groupID uuid.UUID, settings models.GroupSettings) (models.SettingsReport, error)
 	DeleteGroup(userID, groupID uuid.UUID) (models.Group, error)
+	GetGroupStats(groupID uuid.UUID) (models.GroupStats, error)
+	GetPlatformGroupMetrics(includeDeleted bool) (models.PlatformGroupMetrics, error)
+	GetGroupCard(userID, groupID uuid.UUID) (models.GroupCard, error)
+	GetGroupRoleCounts(userID, groupID uuid.UUID) (map[string]int64, error)
+	TouchMemberActivity(groupID, userID uuid.UUID, at time.Time) error
+	ExportGroup(userID, groupID uuid.UUID, exporter GroupExporter) error
 
-	GetGroupProfilePictureURL(userID, groupID uuid.UUID) (string, error)
+	GetGroupPicture(userID, groupID uuid.UUID) (string, error)
+	SetGroupProfilePicture(userID, groupID uuid.UUID, key string, upload func() error) (string, error)
 	DeleteGroupProfilePicture(userID, groupID uuid.UUID) (string, error)
+	ReleasePicture(key string, remove func() error) error
 
 	GetUserInvites(userID uuid.UUID, num, offset int) ([]models.Invite, error)
-	AddInvite(issID, targetID, groupID uuid.UUID) (*models.Invite, error)
+	GetInvite(userID, inviteID uuid.UUID) (*models.Invite, error)
+	GetSentInvites(userID uuid.UUID, status models.InviteStatus, num, offset int) ([]models.Invite, error)
+	AddInvite(issID, targetID, groupID uuid.UUID, rights models.InviteRights) (*models.Invite, error)
 	AnswerInvite(userID, inviteID uuid.UUID, answer bool) (*models.Invite, *models.Group, *models.Member, error)
 
 	NewUser(event events.UserRegisteredEvent) error
+	NewUsers(evts []events.UserRegisteredEvent) error
 	UpdateUserProfilePictureURL(event events.UserPictureModifiedEvent) error
 }
+
+// ContextDBLayer is implemented by database layers able to bind their queries to a context
+type ContextDBLayer interface {
+	WithContext(ctx context.Context) DBLayer
+}
+
+// GroupExporter receives group's data during export. Group is always written first,
+// members and invites are written in batches afterwards
+type GroupExporter interface {
+	WriteGroup(group models.Group) error
+	WriteMembers(members []models.Member) error
+	WriteInvites(invites []models.Invite) error
+}
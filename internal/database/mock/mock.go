@@ -3,9 +3,12 @@
 package mock
 
 import (
+	time "time"
+
 	events "github.com/Slimo300/MicroservicesChatApp/backend/lib/events"
 	mock "github.com/stretchr/testify/mock"
 
+	database "github.com/Slimo300/chat-groupservice/internal/database"
 	models "github.com/Slimo300/chat-groupservice/internal/models"
 
 	uuid "github.com/google/uuid"
@@ -16,13 +19,13 @@ type MockGroupsDB struct {
 	mock.Mock
 }
 
-// AddInvite provides a mock function with given fields: issID, targetID, groupID
-func (_m *MockGroupsDB) AddInvite(issID uuid.UUID, targetID uuid.UUID, groupID uuid.UUID) (*models.Invite, error) {
-	ret := _m.Called(issID, targetID, groupID)
+// AddInvite provides a mock function with given fields: issID, targetID, groupID, rights
+func (_m *MockGroupsDB) AddInvite(issID uuid.UUID, targetID uuid.UUID, groupID uuid.UUID, rights models.InviteRights) (*models.Invite, error) {
+	ret := _m.Called(issID, targetID, groupID, rights)
 
 	var r0 *models.Invite
-	if rf, ok := ret.Get(0).(func(uuid.UUID, uuid.UUID, uuid.UUID) *models.Invite); ok {
-		r0 = rf(issID, targetID, groupID)
+	if rf, ok := ret.Get(0).(func(uuid.UUID, uuid.UUID, uuid.UUID, models.InviteRights) *models.Invite); ok {
+		r0 = rf(issID, targetID, groupID, rights)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*models.Invite)
@@ -30,8 +33,8 @@ func (_m *MockGroupsDB) AddInvite(issID uuid.UUID, targetID uuid.UUID, groupID u
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(uuid.UUID, uuid.UUID, uuid.UUID) error); ok {
-		r1 = rf(issID, targetID, groupID)
+	if rf, ok := ret.Get(1).(func(uuid.UUID, uuid.UUID, uuid.UUID, models.InviteRights) error); ok {
+		r1 = rf(issID, targetID, groupID, rights)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -80,6 +83,50 @@ func (_m *MockGroupsDB) AnswerInvite(userID uuid.UUID, inviteID uuid.UUID, answe
 	return r0, r1, r2, r3
 }
 
+// ChangeMemberRoles provides a mock function with given fields: userID, groupID, changes
+func (_m *MockGroupsDB) ChangeMemberRoles(userID uuid.UUID, groupID uuid.UUID, changes []models.RoleChange) ([]models.RoleChangeResult, error) {
+	ret := _m.Called(userID, groupID, changes)
+
+	var r0 []models.RoleChangeResult
+	if rf, ok := ret.Get(0).(func(uuid.UUID, uuid.UUID, []models.RoleChange) []models.RoleChangeResult); ok {
+		r0 = rf(userID, groupID, changes)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.RoleChangeResult)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uuid.UUID, uuid.UUID, []models.RoleChange) error); ok {
+		r1 = rf(userID, groupID, changes)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CountUserGroups provides a mock function with given fields: userID
+func (_m *MockGroupsDB) CountUserGroups(userID uuid.UUID) (int64, error) {
+	ret := _m.Called(userID)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(uuid.UUID) int64); ok {
+		r0 = rf(userID)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uuid.UUID) error); ok {
+		r1 = rf(userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // CreateGroup provides a mock function with given fields: userID, name
 func (_m *MockGroupsDB) CreateGroup(userID uuid.UUID, name string) (models.Group, error) {
 	ret := _m.Called(userID, name)
@@ -101,6 +148,29 @@ func (_m *MockGroupsDB) CreateGroup(userID uuid.UUID, name string) (models.Group
 	return r0, r1
 }
 
+// CreateGroups provides a mock function with given fields: definitions, allOrNothing
+func (_m *MockGroupsDB) CreateGroups(definitions []models.GroupDefinition, allOrNothing bool) ([]models.GroupCreationResult, error) {
+	ret := _m.Called(definitions, allOrNothing)
+
+	var r0 []models.GroupCreationResult
+	if rf, ok := ret.Get(0).(func([]models.GroupDefinition, bool) []models.GroupCreationResult); ok {
+		r0 = rf(definitions, allOrNothing)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.GroupCreationResult)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func([]models.GroupDefinition, bool) error); ok {
+		r1 = rf(definitions, allOrNothing)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // DeleteGroup provides a mock function with given fields: userID, groupID
 func (_m *MockGroupsDB) DeleteGroup(userID uuid.UUID, groupID uuid.UUID) (models.Group, error) {
 	ret := _m.Called(userID, groupID)
@@ -166,8 +236,89 @@ func (_m *MockGroupsDB) DeleteMember(userID uuid.UUID, groupID uuid.UUID, member
 	return r0, r1
 }
 
-// GetGroupProfilePictureURL provides a mock function with given fields: userID, groupID
-func (_m *MockGroupsDB) GetGroupProfilePictureURL(userID uuid.UUID, groupID uuid.UUID) (string, error) {
+// ExportGroup provides a mock function with given fields: userID, groupID, exporter
+func (_m *MockGroupsDB) ExportGroup(userID uuid.UUID, groupID uuid.UUID, exporter database.GroupExporter) error {
+	ret := _m.Called(userID, groupID, exporter)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(uuid.UUID, uuid.UUID, database.GroupExporter) error); ok {
+		r0 = rf(userID, groupID, exporter)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FindMember provides a mock function with given fields: groupID, userID
+func (_m *MockGroupsDB) FindMember(groupID uuid.UUID, userID uuid.UUID) (*models.Member, error) {
+	ret := _m.Called(groupID, userID)
+
+	var r0 *models.Member
+	if rf, ok := ret.Get(0).(func(uuid.UUID, uuid.UUID) *models.Member); ok {
+		r0 = rf(groupID, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Member)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uuid.UUID, uuid.UUID) error); ok {
+		r1 = rf(groupID, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetGroupCard provides a mock function with given fields: userID, groupID
+func (_m *MockGroupsDB) GetGroupCard(userID uuid.UUID, groupID uuid.UUID) (models.GroupCard, error) {
+	ret := _m.Called(userID, groupID)
+
+	var r0 models.GroupCard
+	if rf, ok := ret.Get(0).(func(uuid.UUID, uuid.UUID) models.GroupCard); ok {
+		r0 = rf(userID, groupID)
+	} else {
+		r0 = ret.Get(0).(models.GroupCard)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uuid.UUID, uuid.UUID) error); ok {
+		r1 = rf(userID, groupID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetGroupMemberships provides a mock function with given fields: userID, groupID, userIDs
+func (_m *MockGroupsDB) GetGroupMemberships(userID uuid.UUID, groupID uuid.UUID, userIDs []uuid.UUID) ([]models.Member, error) {
+	ret := _m.Called(userID, groupID, userIDs)
+
+	var r0 []models.Member
+	if rf, ok := ret.Get(0).(func(uuid.UUID, uuid.UUID, []uuid.UUID) []models.Member); ok {
+		r0 = rf(userID, groupID, userIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Member)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uuid.UUID, uuid.UUID, []uuid.UUID) error); ok {
+		r1 = rf(userID, groupID, userIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetGroupPicture provides a mock function with given fields: userID, groupID
+func (_m *MockGroupsDB) GetGroupPicture(userID uuid.UUID, groupID uuid.UUID) (string, error) {
 	ret := _m.Called(userID, groupID)
 
 	var r0 string
@@ -187,13 +338,101 @@ func (_m *MockGroupsDB) GetGroupProfilePictureURL(userID uuid.UUID, groupID uuid
 	return r0, r1
 }
 
-// GetUserGroups provides a mock function with given fields: id
-func (_m *MockGroupsDB) GetUserGroups(id uuid.UUID) ([]models.Group, error) {
-	ret := _m.Called(id)
+// GetGroupRoleCounts provides a mock function with given fields: userID, groupID
+func (_m *MockGroupsDB) GetGroupRoleCounts(userID uuid.UUID, groupID uuid.UUID) (map[string]int64, error) {
+	ret := _m.Called(userID, groupID)
+
+	var r0 map[string]int64
+	if rf, ok := ret.Get(0).(func(uuid.UUID, uuid.UUID) map[string]int64); ok {
+		r0 = rf(userID, groupID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]int64)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uuid.UUID, uuid.UUID) error); ok {
+		r1 = rf(userID, groupID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetGroupSettings provides a mock function with given fields: userID, groupID
+func (_m *MockGroupsDB) GetGroupSettings(userID uuid.UUID, groupID uuid.UUID) (models.EffectiveSettings, error) {
+	ret := _m.Called(userID, groupID)
+
+	var r0 models.EffectiveSettings
+	if rf, ok := ret.Get(0).(func(uuid.UUID, uuid.UUID) models.EffectiveSettings); ok {
+		r0 = rf(userID, groupID)
+	} else {
+		r0 = ret.Get(0).(models.EffectiveSettings)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uuid.UUID, uuid.UUID) error); ok {
+		r1 = rf(userID, groupID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetGroupStats provides a mock function with given fields: groupID
+func (_m *MockGroupsDB) GetGroupStats(groupID uuid.UUID) (models.GroupStats, error) {
+	ret := _m.Called(groupID)
+
+	var r0 models.GroupStats
+	if rf, ok := ret.Get(0).(func(uuid.UUID) models.GroupStats); ok {
+		r0 = rf(groupID)
+	} else {
+		r0 = ret.Get(0).(models.GroupStats)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uuid.UUID) error); ok {
+		r1 = rf(groupID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetGroupWithMembership provides a mock function with given fields: userID, groupID
+func (_m *MockGroupsDB) GetGroupWithMembership(userID uuid.UUID, groupID uuid.UUID) (*models.Member, error) {
+	ret := _m.Called(userID, groupID)
+
+	var r0 *models.Member
+	if rf, ok := ret.Get(0).(func(uuid.UUID, uuid.UUID) *models.Member); ok {
+		r0 = rf(userID, groupID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Member)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uuid.UUID, uuid.UUID) error); ok {
+		r1 = rf(userID, groupID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetGroupsExistence provides a mock function with given fields: groupIDs
+func (_m *MockGroupsDB) GetGroupsExistence(groupIDs []uuid.UUID) ([]models.Group, error) {
+	ret := _m.Called(groupIDs)
 
 	var r0 []models.Group
-	if rf, ok := ret.Get(0).(func(uuid.UUID) []models.Group); ok {
-		r0 = rf(id)
+	if rf, ok := ret.Get(0).(func([]uuid.UUID) []models.Group); ok {
+		r0 = rf(groupIDs)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]models.Group)
@@ -201,8 +440,190 @@ func (_m *MockGroupsDB) GetUserGroups(id uuid.UUID) ([]models.Group, error) {
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(uuid.UUID) error); ok {
-		r1 = rf(id)
+	if rf, ok := ret.Get(1).(func([]uuid.UUID) error); ok {
+		r1 = rf(groupIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetGroupsModifiedSince provides a mock function with given fields: since, afterID, num
+func (_m *MockGroupsDB) GetGroupsModifiedSince(since time.Time, afterID uuid.UUID, num int) ([]models.Group, error) {
+	ret := _m.Called(since, afterID, num)
+
+	var r0 []models.Group
+	if rf, ok := ret.Get(0).(func(time.Time, uuid.UUID, int) []models.Group); ok {
+		r0 = rf(since, afterID, num)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Group)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(time.Time, uuid.UUID, int) error); ok {
+		r1 = rf(since, afterID, num)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetInvite provides a mock function with given fields: userID, inviteID
+func (_m *MockGroupsDB) GetInvite(userID uuid.UUID, inviteID uuid.UUID) (*models.Invite, error) {
+	ret := _m.Called(userID, inviteID)
+
+	var r0 *models.Invite
+	if rf, ok := ret.Get(0).(func(uuid.UUID, uuid.UUID) *models.Invite); ok {
+		r0 = rf(userID, inviteID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Invite)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uuid.UUID, uuid.UUID) error); ok {
+		r1 = rf(userID, inviteID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetMembership provides a mock function with given fields: userID, groupID, targetID
+func (_m *MockGroupsDB) GetMembership(userID uuid.UUID, groupID uuid.UUID, targetID uuid.UUID) (*models.Member, error) {
+	ret := _m.Called(userID, groupID, targetID)
+
+	var r0 *models.Member
+	if rf, ok := ret.Get(0).(func(uuid.UUID, uuid.UUID, uuid.UUID) *models.Member); ok {
+		r0 = rf(userID, groupID, targetID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Member)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uuid.UUID, uuid.UUID, uuid.UUID) error); ok {
+		r1 = rf(userID, groupID, targetID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPlatformGroupMetrics provides a mock function with given fields: includeDeleted
+func (_m *MockGroupsDB) GetPlatformGroupMetrics(includeDeleted bool) (models.PlatformGroupMetrics, error) {
+	ret := _m.Called(includeDeleted)
+
+	var r0 models.PlatformGroupMetrics
+	if rf, ok := ret.Get(0).(func(bool) models.PlatformGroupMetrics); ok {
+		r0 = rf(includeDeleted)
+	} else {
+		r0 = ret.Get(0).(models.PlatformGroupMetrics)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(bool) error); ok {
+		r1 = rf(includeDeleted)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetRecentMembers provides a mock function with given fields: userID, groupID, since, sort, after, num, offset
+func (_m *MockGroupsDB) GetRecentMembers(userID uuid.UUID, groupID uuid.UUID, since time.Time, sort models.Sort, after *models.Cursor, num int, offset int) ([]models.Member, error) {
+	ret := _m.Called(userID, groupID, since, sort, after, num, offset)
+
+	var r0 []models.Member
+	if rf, ok := ret.Get(0).(func(uuid.UUID, uuid.UUID, time.Time, models.Sort, *models.Cursor, int, int) []models.Member); ok {
+		r0 = rf(userID, groupID, since, sort, after, num, offset)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Member)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uuid.UUID, uuid.UUID, time.Time, models.Sort, *models.Cursor, int, int) error); ok {
+		r1 = rf(userID, groupID, since, sort, after, num, offset)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetSentInvites provides a mock function with given fields: userID, status, num, offset
+func (_m *MockGroupsDB) GetSentInvites(userID uuid.UUID, status models.InviteStatus, num int, offset int) ([]models.Invite, error) {
+	ret := _m.Called(userID, status, num, offset)
+
+	var r0 []models.Invite
+	if rf, ok := ret.Get(0).(func(uuid.UUID, models.InviteStatus, int, int) []models.Invite); ok {
+		r0 = rf(userID, status, num, offset)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Invite)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uuid.UUID, models.InviteStatus, int, int) error); ok {
+		r1 = rf(userID, status, num, offset)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetSharedGroups provides a mock function with given fields: userID, targetID, sort, after, num, offset
+func (_m *MockGroupsDB) GetSharedGroups(userID uuid.UUID, targetID uuid.UUID, sort models.Sort, after *models.Cursor, num int, offset int) ([]models.Group, error) {
+	ret := _m.Called(userID, targetID, sort, after, num, offset)
+
+	var r0 []models.Group
+	if rf, ok := ret.Get(0).(func(uuid.UUID, uuid.UUID, models.Sort, *models.Cursor, int, int) []models.Group); ok {
+		r0 = rf(userID, targetID, sort, after, num, offset)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Group)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uuid.UUID, uuid.UUID, models.Sort, *models.Cursor, int, int) error); ok {
+		r1 = rf(userID, targetID, sort, after, num, offset)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUserGroups provides a mock function with given fields: id, sort
+func (_m *MockGroupsDB) GetUserGroups(id uuid.UUID, sort models.Sort) ([]models.Group, error) {
+	ret := _m.Called(id, sort)
+
+	var r0 []models.Group
+	if rf, ok := ret.Get(0).(func(uuid.UUID, models.Sort) []models.Group); ok {
+		r0 = rf(id, sort)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Group)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uuid.UUID, models.Sort) error); ok {
+		r1 = rf(id, sort)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -233,6 +654,29 @@ func (_m *MockGroupsDB) GetUserInvites(userID uuid.UUID, num int, offset int) ([
 	return r0, r1
 }
 
+// GetUserMemberships provides a mock function with given fields: userID, groupIDs
+func (_m *MockGroupsDB) GetUserMemberships(userID uuid.UUID, groupIDs []uuid.UUID) ([]models.Member, error) {
+	ret := _m.Called(userID, groupIDs)
+
+	var r0 []models.Member
+	if rf, ok := ret.Get(0).(func(uuid.UUID, []uuid.UUID) []models.Member); ok {
+		r0 = rf(userID, groupIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Member)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uuid.UUID, []uuid.UUID) error); ok {
+		r1 = rf(userID, groupIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GrantRights provides a mock function with given fields: userID, groupID, memberID, rights
 func (_m *MockGroupsDB) GrantRights(userID uuid.UUID, groupID uuid.UUID, memberID uuid.UUID, rights models.MemberRights) (*models.Member, error) {
 	ret := _m.Called(userID, groupID, memberID, rights)
@@ -256,6 +700,59 @@ func (_m *MockGroupsDB) GrantRights(userID uuid.UUID, groupID uuid.UUID, memberI
 	return r0, r1
 }
 
+// HandoverMember provides a mock function with given fields: userID, groupID, sourceID, targetID, handover
+func (_m *MockGroupsDB) HandoverMember(userID uuid.UUID, groupID uuid.UUID, sourceID uuid.UUID, targetID uuid.UUID, handover models.MemberHandover) (*models.Member, *models.Member, error) {
+	ret := _m.Called(userID, groupID, sourceID, targetID, handover)
+
+	var r0 *models.Member
+	if rf, ok := ret.Get(0).(func(uuid.UUID, uuid.UUID, uuid.UUID, uuid.UUID, models.MemberHandover) *models.Member); ok {
+		r0 = rf(userID, groupID, sourceID, targetID, handover)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Member)
+		}
+	}
+
+	var r1 *models.Member
+	if rf, ok := ret.Get(1).(func(uuid.UUID, uuid.UUID, uuid.UUID, uuid.UUID, models.MemberHandover) *models.Member); ok {
+		r1 = rf(userID, groupID, sourceID, targetID, handover)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*models.Member)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(uuid.UUID, uuid.UUID, uuid.UUID, uuid.UUID, models.MemberHandover) error); ok {
+		r2 = rf(userID, groupID, sourceID, targetID, handover)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// MergeUserMemberships provides a mock function with given fields: fromID, toID
+func (_m *MockGroupsDB) MergeUserMemberships(fromID uuid.UUID, toID uuid.UUID) (models.MembershipMerge, error) {
+	ret := _m.Called(fromID, toID)
+
+	var r0 models.MembershipMerge
+	if rf, ok := ret.Get(0).(func(uuid.UUID, uuid.UUID) models.MembershipMerge); ok {
+		r0 = rf(fromID, toID)
+	} else {
+		r0 = ret.Get(0).(models.MembershipMerge)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uuid.UUID, uuid.UUID) error); ok {
+		r1 = rf(fromID, toID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewUser provides a mock function with given fields: event
 func (_m *MockGroupsDB) NewUser(event events.UserRegisteredEvent) error {
 	ret := _m.Called(event)
@@ -270,6 +767,150 @@ func (_m *MockGroupsDB) NewUser(event events.UserRegisteredEvent) error {
 	return r0
 }
 
+// NewUsers provides a mock function with given fields: evts
+func (_m *MockGroupsDB) NewUsers(evts []events.UserRegisteredEvent) error {
+	ret := _m.Called(evts)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]events.UserRegisteredEvent) error); ok {
+		r0 = rf(evts)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ReleasePicture provides a mock function with given fields: key, remove
+func (_m *MockGroupsDB) ReleasePicture(key string, remove func() error) error {
+	ret := _m.Called(key, remove)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, func() error) error); ok {
+		r0 = rf(key, remove)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetGroupProfilePicture provides a mock function with given fields: userID, groupID, key, upload
+func (_m *MockGroupsDB) SetGroupProfilePicture(userID uuid.UUID, groupID uuid.UUID, key string, upload func() error) (string, error) {
+	ret := _m.Called(userID, groupID, key, upload)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(uuid.UUID, uuid.UUID, string, func() error) string); ok {
+		r0 = rf(userID, groupID, key, upload)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uuid.UUID, uuid.UUID, string, func() error) error); ok {
+		r1 = rf(userID, groupID, key, upload)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetNickname provides a mock function with given fields: userID, groupID, memberID, nickname
+func (_m *MockGroupsDB) SetNickname(userID uuid.UUID, groupID uuid.UUID, memberID uuid.UUID, nickname string) (*models.Member, error) {
+	ret := _m.Called(userID, groupID, memberID, nickname)
+
+	var r0 *models.Member
+	if rf, ok := ret.Get(0).(func(uuid.UUID, uuid.UUID, uuid.UUID, string) *models.Member); ok {
+		r0 = rf(userID, groupID, memberID, nickname)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Member)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uuid.UUID, uuid.UUID, uuid.UUID, string) error); ok {
+		r1 = rf(userID, groupID, memberID, nickname)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// StepDown provides a mock function with given fields: userID, groupID
+func (_m *MockGroupsDB) StepDown(userID uuid.UUID, groupID uuid.UUID) (*models.Member, error) {
+	ret := _m.Called(userID, groupID)
+
+	var r0 *models.Member
+	if rf, ok := ret.Get(0).(func(uuid.UUID, uuid.UUID) *models.Member); ok {
+		r0 = rf(userID, groupID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Member)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uuid.UUID, uuid.UUID) error); ok {
+		r1 = rf(userID, groupID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// StreamGroupMembers provides a mock function with given fields: groupID, fn
+func (_m *MockGroupsDB) StreamGroupMembers(groupID uuid.UUID, fn func(models.Member) error) error {
+	ret := _m.Called(groupID, fn)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(uuid.UUID, func(models.Member) error) error); ok {
+		r0 = rf(groupID, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// TouchMemberActivity provides a mock function with given fields: groupID, userID, at
+func (_m *MockGroupsDB) TouchMemberActivity(groupID uuid.UUID, userID uuid.UUID, at time.Time) error {
+	ret := _m.Called(groupID, userID, at)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(uuid.UUID, uuid.UUID, time.Time) error); ok {
+		r0 = rf(groupID, userID, at)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateGroupSettings provides a mock function with given fields: userID, groupID, settings
+func (_m *MockGroupsDB) UpdateGroupSettings(userID uuid.UUID, groupID uuid.UUID, settings models.GroupSettings) (models.Group, error) {
+	ret := _m.Called(userID, groupID, settings)
+
+	var r0 models.Group
+	if rf, ok := ret.Get(0).(func(uuid.UUID, uuid.UUID, models.GroupSettings) models.Group); ok {
+		r0 = rf(userID, groupID, settings)
+	} else {
+		r0 = ret.Get(0).(models.Group)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uuid.UUID, uuid.UUID, models.GroupSettings) error); ok {
+		r1 = rf(userID, groupID, settings)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // UpdateUserProfilePictureURL provides a mock function with given fields: event
 func (_m *MockGroupsDB) UpdateUserProfilePictureURL(event events.UserPictureModifiedEvent) error {
 	ret := _m.Called(event)
@@ -284,6 +925,27 @@ func (_m *MockGroupsDB) UpdateUserProfilePictureURL(event events.UserPictureModi
 	return r0
 }
 
+// ValidateGroupSettings provides a mock function with given fields: userID, groupID, settings
+func (_m *MockGroupsDB) ValidateGroupSettings(userID uuid.UUID, groupID uuid.UUID, settings models.GroupSettings) (models.SettingsReport, error) {
+	ret := _m.Called(userID, groupID, settings)
+
+	var r0 models.SettingsReport
+	if rf, ok := ret.Get(0).(func(uuid.UUID, uuid.UUID, models.GroupSettings) models.SettingsReport); ok {
+		r0 = rf(userID, groupID, settings)
+	} else {
+		r0 = ret.Get(0).(models.SettingsReport)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uuid.UUID, uuid.UUID, models.GroupSettings) error); ok {
+		r1 = rf(userID, groupID, settings)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 type mockConstructorTestingTNewMockGroupsDB interface {
 	mock.TestingT
 	Cleanup(func())
@@ -0,0 +1,47 @@
+package orm
+
+import (
+	"github.com/Slimo300/MicroservicesChatApp/backend/lib/apperrors"
+	"github.com/Slimo300/chat-groupservice/internal/database"
+	"github.com/Slimo300/chat-groupservice/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const EXPORT_BATCH_SIZE = 500
+
+// ExportGroup passes all data of a group to exporter, members and invites are read in batches so big groups
+// are never loaded into memory at once. Only group creator can export a group
+func (db *Database) ExportGroup(userID, groupID uuid.UUID, exporter database.GroupExporter) error {
+
+	var member models.Member
+	if err := db.Where(models.Member{UserID: userID, GroupID: groupID}).First(&member).Error; err != nil || !member.CanExportGroup() {
+		return apperrors.NewForbidden("User has no right to export group")
+	}
+
+	var group models.Group
+	if err := db.Where(models.Group{ID: groupID}).First(&group).Error; err != nil {
+		return apperrors.NewNotFound("group", groupID.String())
+	}
+	if err := exporter.WriteGroup(group); err != nil {
+		return err
+	}
+
+	var members []models.Member
+	if err := db.Where(models.Member{GroupID: groupID}).Preload("User").
+		FindInBatches(&members, EXPORT_BATCH_SIZE, func(tx *gorm.DB, batch int) error {
+			return exporter.WriteMembers(members)
+		}).Error; err != nil {
+		return err
+	}
+
+	var invites []models.Invite
+	if err := db.Where(models.Invite{GroupID: groupID}).Preload("Iss").Preload("Target").
+		FindInBatches(&invites, EXPORT_BATCH_SIZE, func(tx *gorm.DB, batch int) error {
+			return exporter.WriteInvites(invites)
+		}).Error; err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -1,20 +1,50 @@
 package orm
 
 import (
+	"crypto/sha1"
+	"errors"
 	"fmt"
 
 	"github.com/Slimo300/MicroservicesChatApp/backend/lib/apperrors"
 	"github.com/Slimo300/chat-groupservice/internal/models"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
-func (db *Database) GetGroupProfilePictureURL(userID, groupID uuid.UUID) (string, error) {
+// PICTURE_LOCK_TIMEOUT is how many seconds operations on a picture wait for other operations on it to finish
+const PICTURE_LOCK_TIMEOUT = 10
+
+// withPictureLock runs fn on a single connection holding MySQL named lock of picture key. Writes of fn are
+// committed before lock is released, so groups starting to use a picture and deletion of unused picture are
+// serialized even though its object is stored outside of database
+func (db *Database) withPictureLock(key string, fn func(conn *gorm.DB) error) error {
+	return db.Connection(func(conn *gorm.DB) error {
+		// names of locks are limited to 64 characters
+		name := fmt.Sprintf("picture:%x", sha1.Sum([]byte(key)))
+
+		var acquired *int64
+		if err := conn.Raw("SELECT GET_LOCK(?, ?)", name, PICTURE_LOCK_TIMEOUT).Row().Scan(&acquired); err != nil {
+			return err
+		}
+		if acquired == nil || *acquired != 1 {
+			return errors.New("picture is locked by another operation")
+		}
+		defer conn.Exec("SELECT RELEASE_LOCK(?)", name)
+
+		return fn(conn)
+	})
+}
+
+// SetGroupProfilePicture sets picture with given key as group's profile picture, upload stores its object right
+// before while key is locked. Keys are derived from content so one picture can be used by many groups. Previous
+// key is returned when it has changed, it should be passed to ReleasePicture
+func (db *Database) SetGroupProfilePicture(userID, groupID uuid.UUID, key string, upload func() error) (string, error) {
 	var member models.Member
 	if err := db.Where(models.Member{UserID: userID, GroupID: groupID}).First(&member).Error; err != nil {
 		return "", apperrors.NewForbidden(fmt.Sprintf("User %v has no rights to set in group %v", userID, groupID))
 	}
 
-	if !member.Admin {
+	if !member.CanEditGroup() {
 		return "", apperrors.NewForbidden(fmt.Sprintf("User %v has no rights to set in group %v", userID, groupID))
 	}
 
@@ -24,17 +54,52 @@ func (db *Database) GetGroupProfilePictureURL(userID, groupID uuid.UUID) (string
 		return "", apperrors.NewForbidden(fmt.Sprintf("User %v has no rights to set in group %v", userID, groupID))
 	}
 
-	if group.Picture == "" {
-		newPictureURL := uuid.NewString()
-		if err := db.Model(&group).Update("picture_url", newPictureURL).Error; err != nil {
-			return "", apperrors.NewInternal()
+	previous := group.Picture
+	var uploadErr error
+	if err := db.withPictureLock(key, func(conn *gorm.DB) error {
+		if uploadErr = upload(); uploadErr != nil {
+			return uploadErr
+		}
+		return conn.Model(&group).Update("picture_url", key).Error
+	}); err != nil {
+		if uploadErr != nil {
+			return "", uploadErr
 		}
-		return newPictureURL, nil
+		return "", apperrors.NewInternal()
+	}
+	if previous == key {
+		return "", nil
 	}
+	return previous, nil
+}
 
-	return group.Picture, nil
+// ReleasePicture calls remove when no existing group uses picture with given key anymore. Key is locked until
+// remove returns, so no group can start using the picture while its object is being deleted
+func (db *Database) ReleasePicture(key string, remove func() error) error {
+	if key == "" {
+		return nil
+	}
+	var removeErr error
+	if err := db.withPictureLock(key, func(conn *gorm.DB) error {
+		var count int64
+		if err := conn.Model(&models.Group{}).Where("picture_url = ?", key).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+		removeErr = remove()
+		return removeErr
+	}); err != nil {
+		if removeErr != nil {
+			return removeErr
+		}
+		return apperrors.NewInternal()
+	}
+	return nil
 }
 
+// DeleteGroupProfilePicture removes group's profile picture, its key is returned to be passed to ReleasePicture
 func (db *Database) DeleteGroupProfilePicture(userID, groupID uuid.UUID) (string, error) {
 
 	var member models.Member
@@ -42,7 +107,7 @@ func (db *Database) DeleteGroupProfilePicture(userID, groupID uuid.UUID) (string
 		return "", apperrors.NewForbidden(fmt.Sprintf("User %v has no rights to set in group %v", userID, groupID))
 	}
 
-	if !member.Admin {
+	if !member.CanEditGroup() {
 		return "", apperrors.NewForbidden(fmt.Sprintf("User %v has no rights to set in group %v", userID, groupID))
 	}
 
@@ -56,9 +121,24 @@ func (db *Database) DeleteGroupProfilePicture(userID, groupID uuid.UUID) (string
 		return "", apperrors.NewForbidden(fmt.Sprintf("group %v has no profile picture", groupID))
 	}
 
+	picture := group.Picture
 	if err := db.Model(&group).Update("picture_url", "").Error; err != nil {
 		return "", apperrors.NewInternal()
 	}
-	return group.Picture, nil
+	return picture, nil
+
+}
+
+// GetGroupPicture returns key of group's profile picture, it's available to all members of a group
+func (db *Database) GetGroupPicture(userID, groupID uuid.UUID) (string, error) {
+	var member models.Member
+	if err := db.Where(models.Member{UserID: userID, GroupID: groupID}).Preload("Group").First(&member).Error; err != nil {
+		return "", apperrors.NewForbidden(fmt.Sprintf("User %v is not a member of group %v", userID, groupID))
+	}
+
+	if member.Group.Picture == "" {
+		return "", apperrors.NewNotFound("group picture", groupID.String())
+	}
 
+	return member.Group.Picture, nil
 }
@@ -1,15 +1,19 @@
 package orm
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/Slimo300/MicroservicesChatApp/backend/lib/apperrors"
 	"github.com/Slimo300/chat-groupservice/internal/models"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-func (db *Database) GetUserGroups(id uuid.UUID) (groups []models.Group, err error) {
+// GetUserGroups returns groups user is a member of in given order
+func (db *Database) GetUserGroups(id uuid.UUID, sort models.Sort) (groups []models.Group, err error) {
 
 	var userGroupsIDs []uuid.UUID
 	if err := db.Table("`groups`").Select("`groups`.id").
@@ -19,12 +23,30 @@ func (db *Database) GetUserGroups(id uuid.UUID) (groups []models.Group, err erro
 		return groups, err
 	}
 
-	if err := db.Where("id in (?)", userGroupsIDs).Preload("Members").Preload("Members.User").Find(&groups).Error; err != nil {
+	if err := db.Where("id in (?)", userGroupsIDs).Order(orderOf(groupOrders, sort, models.SORT_NAME_ASC)).
+		Preload("Members").Preload("Members.User").Find(&groups).Error; err != nil {
 		return groups, err
 	}
 	return groups, nil
 }
 
+// CountUserGroups returns number of groups user is a member of, deleted groups aren't counted
+func (db *Database) CountUserGroups(userID uuid.UUID) (count int64, err error) {
+	return count, db.Model(&models.Member{}).
+		Joins("inner join `groups` on `groups`.id = `members`.group_id").
+		Where("`members`.user_id = ? AND `groups`.deleted_at IS NULL", userID).Count(&count).Error
+}
+
+// GetSharedGroups returns groups both users are members of in given order, newest first by default. When after
+// isn't nil page starts after its position
+func (db *Database) GetSharedGroups(userID, targetID uuid.UUID, sort models.Sort, after *models.Cursor, num, offset int) (groups []models.Group, err error) {
+	query := db.Select("`groups`.*").
+		Joins("inner join `members` caller on caller.group_id = `groups`.id and caller.user_id = ?", userID).
+		Joins("inner join `members` target on target.group_id = `groups`.id and target.user_id = ?", targetID)
+	query = keyOf(groupOrders, sort, models.SORT_CREATED_DESC).after(query, after)
+	return groups, query.Order(orderOf(groupOrders, sort, models.SORT_CREATED_DESC)).Limit(num).Offset(offset).Find(&groups).Error
+}
+
 func (db *Database) CreateGroup(userID uuid.UUID, name string) (models.Group, error) {
 	group := models.Group{ID: uuid.New(), Name: name, Created: time.Now(), Picture: ""}
 
@@ -34,23 +56,151 @@ func (db *Database) CreateGroup(userID uuid.UUID, name string) (models.Group, er
 	}
 
 	if err := db.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Create(&group).Error; err != nil {
-			return err
+		return db.createGroup(tx, &group, userID, nil)
+	}); err != nil {
+		return models.Group{}, err
+	}
+
+	if err := db.Where(models.Group{ID: group.ID}).Preload("Members").Preload("Members.User").First(&group).Error; err != nil {
+		return models.Group{}, err
+	}
+	return group, nil
+}
+
+// errBatchRolledBack is reported for groups of an all or nothing batch which were rolled back because another one failed
+var errBatchRolledBack = &apperrors.Error{Type: apperrors.Conflict, Message: "group not created as another group of the batch failed"}
+
+// CreateGroups creates groups of an import together with their initial members, each group is created in its own
+// transaction so failure of one doesn't affect others. When allOrNothing is set all groups are created in a single
+// transaction and none of them is kept when any fails. Groups are subject to the same limits as in CreateGroup
+func (db *Database) CreateGroups(definitions []models.GroupDefinition, allOrNothing bool) ([]models.GroupCreationResult, error) {
+	results := make([]models.GroupCreationResult, len(definitions))
+
+	create := func(tx *gorm.DB, i int) error {
+		if err := tx.First(&models.User{}, definitions[i].OwnerID).Error; err != nil {
+			return apperrors.NewNotFound("user", definitions[i].OwnerID.String())
 		}
-		member := models.Member{ID: uuid.New(), UserID: userID, GroupID: group.ID, Adding: true, DeletingMembers: true, Admin: true, Creator: true}
-		if err := tx.Create(&member).Error; err != nil {
+		group := models.Group{ID: uuid.New(), Name: definitions[i].Name, Created: time.Now()}
+		if err := db.createGroup(tx, &group, definitions[i].OwnerID, definitions[i].Members); err != nil {
 			return err
 		}
+		results[i].Group = &group
+		return nil
+	}
+
+	if !allOrNothing {
+		for i := range definitions {
+			if err := db.Transaction(func(tx *gorm.DB) error { return create(tx, i) }); err != nil {
+				results[i].Err = groupCreationError(err)
+			}
+		}
+		return results, nil
+	}
 
+	failed := -1
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		for i := range definitions {
+			if err := create(tx, i); err != nil {
+				failed = i
+				return err
+			}
+		}
 		return nil
 	}); err != nil {
-		return models.Group{}, err
+		if failed < 0 {
+			return nil, apperrors.NewInternal()
+		}
+		for i := range results {
+			results[i] = models.GroupCreationResult{Err: errBatchRolledBack}
+		}
+		results[failed].Err = groupCreationError(err)
 	}
+	return results, nil
+}
 
-	if err := db.Where(models.Group{ID: group.ID}).Preload("Members").Preload("Members.User").First(&group).Error; err != nil {
-		return models.Group{}, err
+// groupCreationError passes errors meant for callers (limits, unknown users) and hides the rest
+func groupCreationError(err error) error {
+	var appErr *apperrors.Error
+	if errors.As(err, &appErr) {
+		return appErr
 	}
-	return group, nil
+	return apperrors.NewInternal()
+}
+
+// createGroup inserts group with creator and initial basic members in transaction tx, checking creator's limits
+func (db *Database) createGroup(tx *gorm.DB, group *models.Group, userID uuid.UUID, memberIDs []uuid.UUID) error {
+	if db.UniqueGroupNames || db.MaxGroupsPerUser > 0 {
+		// creator's row is locked until the end of transaction so concurrent requests
+		// of the same user can't all pass the checks below
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.User{}, userID).Error; err != nil {
+			return err
+		}
+	}
+	if db.UniqueGroupNames {
+		if err := checkGroupNameUnique(tx, userID, group.Name); err != nil {
+			return err
+		}
+	}
+	if db.MaxGroupsPerUser > 0 {
+		if err := checkGroupLimit(tx, userID, db.MaxGroupsPerUser); err != nil {
+			return err
+		}
+	}
+	if err := tx.Create(group).Error; err != nil {
+		return err
+	}
+	creator := models.Member{ID: uuid.New(), UserID: userID, GroupID: group.ID, Adding: true, DeletingMembers: true, Admin: true, Creator: true, Joined: group.Created}
+	if err := tx.Create(&creator).Error; err != nil {
+		return err
+	}
+	group.Members = []models.Member{creator}
+
+	seen := map[uuid.UUID]bool{userID: true}
+	for _, memberID := range memberIDs {
+		if seen[memberID] {
+			continue
+		}
+		seen[memberID] = true
+
+		if err := tx.First(&models.User{}, memberID).Error; err != nil {
+			return apperrors.NewNotFound("user", memberID.String())
+		}
+		member := models.Member{ID: uuid.New(), UserID: memberID, GroupID: group.ID, Joined: group.Created}
+		if err := tx.Create(&member).Error; err != nil {
+			return err
+		}
+		group.Members = append(group.Members, member)
+	}
+	return nil
+}
+
+// checkGroupNameUnique returns conflict error when user already created a group with given name. It isn't backed
+// by a unique index: groups don't store their creator (it's a member flag) and the policy can be switched off,
+// so it only holds for groups inserted by createGroup, which is the only path creating groups
+func checkGroupNameUnique(tx *gorm.DB, userID uuid.UUID, name string) error {
+	var count int64
+	if err := tx.Model(&models.Group{}).
+		Joins("JOIN members ON members.group_id = groups.id").
+		Where("members.user_id = ? AND members.creator = ? AND LOWER(groups.name) = LOWER(?)", userID, true, name).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return apperrors.NewConflict("group name", name)
+	}
+	return nil
+}
+
+// checkGroupLimit returns conflict error when user already created max groups
+func checkGroupLimit(tx *gorm.DB, userID uuid.UUID, max int) error {
+	var count int64
+	if err := tx.Model(&models.Member{}).Where(models.Member{UserID: userID, Creator: true}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count >= int64(max) {
+		return &apperrors.Error{Type: apperrors.Conflict, Message: fmt.Sprintf("user cannot create more than %d groups", max)}
+	}
+	return nil
 }
 
 func (db *Database) DeleteGroup(userID, groupID uuid.UUID) (models.Group, error) {
@@ -59,7 +209,7 @@ func (db *Database) DeleteGroup(userID, groupID uuid.UUID) (models.Group, error)
 	if err := db.Where(models.Member{UserID: userID, GroupID: groupID}).First(&member).Error; err != nil {
 		return models.Group{}, apperrors.NewForbidden("User has no right to delete group")
 	}
-	if !member.Creator {
+	if !member.CanDeleteGroup() {
 		return models.Group{}, apperrors.NewForbidden("User has no right to delete group")
 	}
 
@@ -71,14 +221,196 @@ func (db *Database) DeleteGroup(userID, groupID uuid.UUID) (models.Group, error)
 		if err := tx.Where(models.Invite{GroupID: groupID}).Delete(&models.Invite{}).Error; err != nil {
 			return err
 		}
-		group = models.Group{ID: groupID}
-		if err := tx.Delete(&group).Error; err != nil {
+		if err := tx.First(&group, groupID).Error; err != nil {
+			return err
+		}
+		// soft delete doesn't bump updated_at by itself and changes feed relies on it
+		now := time.Now()
+		if err := tx.Model(&group).UpdateColumns(map[string]interface{}{"updated_at": now, "deleted_at": now}).Error; err != nil {
 			return err
 		}
 		return nil
 	}); err != nil {
 		return models.Group{}, apperrors.NewInternal()
 	}
+	return group, nil
+}
+
+// GetGroupsModifiedSince returns groups, including deleted ones, changed after (since, afterID) position ordered
+// by update time and ID so results can be paged through with last returned group as next position
+func (db *Database) GetGroupsModifiedSince(since time.Time, afterID uuid.UUID, num int) (groups []models.Group, err error) {
+	return groups, db.Unscoped().
+		Where("updated_at > ? OR (updated_at = ? AND id > ?)", since, since, afterID).
+		Order("updated_at, id").Limit(num).Find(&groups).Error
+}
+
+// GetGroupsExistence returns IDs and deletion times of given groups including deleted ones, unknown groups
+// are omitted
+func (db *Database) GetGroupsExistence(groupIDs []uuid.UUID) (groups []models.Group, err error) {
+	return groups, db.Unscoped().Select("id", "deleted_at").Where("id IN ?", groupIDs).Find(&groups).Error
+}
+
+// UpdateGroupSettings changes settings of a group. Admins can change slow mode, announcement flag is reserved
+// to group's creator
+func (db *Database) UpdateGroupSettings(userID, groupID uuid.UUID, settings models.GroupSettings) (models.Group, error) {
+	var member models.Member
+	if err := db.Where(models.Member{UserID: userID, GroupID: groupID}).Preload("Group").First(&member).Error; err != nil {
+		return models.Group{}, apperrors.NewForbidden("User has no right to change group settings")
+	}
+	if !member.CanEditGroup() || (settings.IsAnnouncement != nil && !member.CanSetAnnouncement()) {
+		return models.Group{}, apperrors.NewForbidden("User has no right to change group settings")
+	}
+
+	group := member.Group
+	updates := make(map[string]interface{})
+	if settings.IsAnnouncement != nil {
+		updates["is_announcement"] = *settings.IsAnnouncement
+		updates["is_announcement_set"] = true
+		group.Announcement = *settings.IsAnnouncement
+		group.AnnouncementSet = true
+	}
+	if settings.SlowModeSeconds != nil {
+		updates["slow_mode_seconds"] = *settings.SlowModeSeconds
+		updates["slow_mode_seconds_set"] = true
+		group.SlowModeSeconds = *settings.SlowModeSeconds
+		group.SlowModeSet = true
+	}
+	if err := db.Model(&group).Updates(updates).Error; err != nil {
+		return models.Group{}, apperrors.NewInternal()
+	}
 
 	return group, nil
 }
+
+// GetGroupSettings returns effective settings of a group, they can be read by every member
+func (db *Database) GetGroupSettings(userID, groupID uuid.UUID) (models.EffectiveSettings, error) {
+	var member models.Member
+	if err := db.Where(models.Member{UserID: userID, GroupID: groupID}).Preload("Group").First(&member).Error; err != nil {
+		return models.EffectiveSettings{}, apperrors.NewForbidden(fmt.Sprintf("User %v is not a member of group %v", userID, groupID))
+	}
+	return member.Group.EffectiveSettings(), nil
+}
+
+// ValidateGroupSettings checks settings change the same way UpdateGroupSettings does but instead of applying it
+// reports every problem found and settings that would change. Non-members get an error so that they cannot
+// read group's settings this way
+func (db *Database) ValidateGroupSettings(userID, groupID uuid.UUID, settings models.GroupSettings) (models.SettingsReport, error) {
+	var member models.Member
+	if err := db.Where(models.Member{UserID: userID, GroupID: groupID}).Preload("Group").First(&member).Error; err != nil {
+		return models.SettingsReport{}, apperrors.NewForbidden("User has no right to change group settings")
+	}
+
+	report := models.SettingsReport{Problems: []string{}, Changes: []string{}}
+	if !member.CanEditGroup() {
+		report.Problems = append(report.Problems, "User has no right to change group settings")
+	} else if settings.IsAnnouncement != nil && !member.CanSetAnnouncement() {
+		report.Problems = append(report.Problems, "User has no right to change announcement flag")
+	}
+	report.Problems = append(report.Problems, settings.Problems()...)
+
+	group := member.Group
+	if settings.IsAnnouncement != nil && *settings.IsAnnouncement != group.Announcement {
+		report.Changes = append(report.Changes, "isAnnouncement")
+	}
+	if settings.SlowModeSeconds != nil && *settings.SlowModeSeconds != group.SlowModeSeconds {
+		report.Changes = append(report.Changes, "slowModeSeconds")
+	}
+	report.Valid = len(report.Problems) == 0
+
+	return report, nil
+}
+
+// GetGroupRoleCounts returns number of members of a group having each role, roles nobody has are counted as 0.
+// Roles are resolved by the database the same way Member.RoleName does so that members aren't loaded one by one
+func (db *Database) GetGroupRoleCounts(userID, groupID uuid.UUID) (map[string]int64, error) {
+	if err := db.Where(models.Member{UserID: userID, GroupID: groupID}).First(&models.Member{}).Error; err != nil {
+		return nil, apperrors.NewForbidden(fmt.Sprintf("User %v is not a member of group %v", userID, groupID))
+	}
+
+	var rows []struct {
+		Role  string
+		Count int64
+	}
+	if err := db.Model(&models.Member{}).Where(models.Member{GroupID: groupID}).
+		Select("CASE WHEN creator THEN 'creator' WHEN co_owner THEN 'co-owner' WHEN setting THEN 'admin' " +
+			"WHEN deleting_members THEN 'deleter' ELSE 'basic' END AS role, COUNT(*) AS count").
+		Group("role").Scan(&rows).Error; err != nil {
+		return nil, apperrors.NewInternal()
+	}
+
+	counts := make(map[string]int64, len(models.RoleNames))
+	for _, role := range models.RoleNames {
+		counts[role] = 0
+	}
+	for _, row := range rows {
+		counts[row.Role] = row.Count
+	}
+	return counts, nil
+}
+
+// GetGroupCard returns preview of a group, groups aren't public so only their members can see it
+func (db *Database) GetGroupCard(userID, groupID uuid.UUID) (models.GroupCard, error) {
+	if err := db.Where(models.Member{UserID: userID, GroupID: groupID}).First(&models.Member{}).Error; err != nil {
+		return models.GroupCard{}, apperrors.NewForbidden(fmt.Sprintf("User %v is not a member of group %v", userID, groupID))
+	}
+
+	var group models.Group
+	if err := db.Select("id", "name", "picture_url").Where(models.Group{ID: groupID}).First(&group).Error; err != nil {
+		return models.GroupCard{}, apperrors.NewNotFound("group", groupID.String())
+	}
+	card := models.GroupCard{ID: group.ID, Name: group.Name, Picture: group.Picture}
+	if err := db.Model(&models.Member{}).Where(models.Member{GroupID: groupID}).Count(&card.MemberCount).Error; err != nil {
+		return models.GroupCard{}, apperrors.NewInternal()
+	}
+	return card, nil
+}
+
+// GetGroupStats computes statistics of a group, it doesn't check user's rights
+func (db *Database) GetGroupStats(groupID uuid.UUID) (models.GroupStats, error) {
+	var stats models.GroupStats
+
+	now := time.Now()
+	if err := db.Model(&models.Member{}).Where(models.Member{GroupID: groupID}).
+		Select("COUNT(*) AS members, "+
+			"COALESCE(SUM(CASE WHEN joined_at >= ? THEN 1 ELSE 0 END), 0) AS joined_last_7_days, "+
+			"COALESCE(SUM(CASE WHEN joined_at >= ? THEN 1 ELSE 0 END), 0) AS joined_last_30_days",
+			now.AddDate(0, 0, -7), now.AddDate(0, 0, -30)).
+		Scan(&stats).Error; err != nil {
+		return models.GroupStats{}, apperrors.NewInternal()
+	}
+
+	if err := db.Model(&models.Invite{}).Where(models.Invite{GroupID: groupID, Status: models.INVITE_AWAITING}).
+		Count(&stats.PendingInvites).Error; err != nil {
+		return models.GroupStats{}, apperrors.NewInternal()
+	}
+
+	return stats, nil
+}
+
+// GetPlatformGroupMetrics counts groups and memberships of the whole platform with single aggregate queries,
+// number of deleted groups is counted only when includeDeleted is set
+func (db *Database) GetPlatformGroupMetrics(includeDeleted bool) (models.PlatformGroupMetrics, error) {
+	metrics := models.PlatformGroupMetrics{ComputedAt: time.Now()}
+
+	if err := db.Model(&models.Group{}).Count(&metrics.Groups).Error; err != nil {
+		return models.PlatformGroupMetrics{}, apperrors.NewInternal()
+	}
+	if err := db.Model(&models.Member{}).
+		Joins("inner join `groups` on `groups`.id = `members`.group_id").
+		Where("`groups`.deleted_at IS NULL").Count(&metrics.Memberships).Error; err != nil {
+		return models.PlatformGroupMetrics{}, apperrors.NewInternal()
+	}
+	if metrics.Groups > 0 {
+		metrics.AverageGroupSize = float64(metrics.Memberships) / float64(metrics.Groups)
+	}
+
+	if includeDeleted {
+		var deleted int64
+		if err := db.Unscoped().Model(&models.Group{}).Where("deleted_at IS NOT NULL").Count(&deleted).Error; err != nil {
+			return models.PlatformGroupMetrics{}, apperrors.NewInternal()
+		}
+		metrics.DeletedGroups = &deleted
+	}
+
+	return metrics, nil
+}
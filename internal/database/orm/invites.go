@@ -17,15 +17,43 @@ func (db *Database) GetUserInvites(userID uuid.UUID, num, offset int) (invites [
 		Preload("Iss").Preload("Group").Preload("Target").Find(&invites).Error
 }
 
-func (db *Database) AddInvite(issID, targetID, groupID uuid.UUID) (*models.Invite, error) {
+// GetSentInvites returns invites issued by user, newest first. When status is 0 invites with any status are returned
+func (db *Database) GetSentInvites(userID uuid.UUID, status models.InviteStatus, num, offset int) (invites []models.Invite, err error) {
+	return invites, db.Order("created DESC").Limit(num).Offset(offset).
+		Where(models.Invite{IssId: userID, Status: status}).
+		Preload("Group").Preload("Target").Find(&invites).Error
+}
+
+// GetInvite returns invite visible to user. Invites are visible to their issuer, target and admins of a group,
+// for other users they don't exist
+func (db *Database) GetInvite(userID, inviteID uuid.UUID) (*models.Invite, error) {
+	var invite models.Invite
+	if err := db.Where(models.Invite{ID: inviteID}).First(&invite).Error; err != nil {
+		return nil, apperrors.NewNotFound("invite", inviteID.String())
+	}
+	if invite.IssId == userID || invite.TargetID == userID {
+		return &invite, nil
+	}
+
+	var member models.Member
+	if err := db.Where(models.Member{UserID: userID, GroupID: invite.GroupID}).First(&member).Error; err != nil || !member.CanEditGroup() {
+		return nil, apperrors.NewNotFound("invite", inviteID.String())
+	}
+	return &invite, nil
+}
+
+func (db *Database) AddInvite(issID, targetID, groupID uuid.UUID, rights models.InviteRights) (*models.Invite, error) {
 
 	var member models.Member
 	if err := db.Where(models.Member{UserID: issID, GroupID: groupID}).First(&member).Error; err != nil {
 		return nil, apperrors.NewForbidden(fmt.Sprintf("User %v has no rights to add new members to group %v", issID, groupID))
 	}
-	if !member.Adding && !member.Admin && !member.Creator {
+	if !member.CanInvite(models.InviteRights{}) {
 		return nil, apperrors.NewForbidden(fmt.Sprintf("User %v has no rights to add new members to group %v", issID, groupID))
 	}
+	if !member.CanInvite(rights) {
+		return nil, apperrors.NewForbidden(fmt.Sprintf("User %v cannot grant rights to new members of group %v", issID, groupID))
+	}
 	if err := db.First(&models.User{}, targetID).Error; err != nil {
 		return nil, apperrors.NewNotFound("user", targetID.String())
 	}
@@ -35,7 +63,7 @@ func (db *Database) AddInvite(issID, targetID, groupID uuid.UUID) (*models.Invit
 	if err := db.Where(models.Invite{GroupID: groupID, TargetID: targetID, Status: models.INVITE_AWAITING}).First(&models.Invite{}).Error; err != gorm.ErrRecordNotFound {
 		return nil, apperrors.NewForbidden(fmt.Sprintf("User %v already invited to group %v", targetID, groupID))
 	}
-	invite := models.Invite{ID: uuid.New(), IssId: issID, TargetID: targetID, GroupID: groupID, Status: models.INVITE_AWAITING, Created: time.Now(), Modified: time.Now()}
+	invite := models.Invite{ID: uuid.New(), IssId: issID, TargetID: targetID, GroupID: groupID, Status: models.INVITE_AWAITING, Rights: rights, Created: time.Now(), Modified: time.Now()}
 	if err := db.Create(&invite).Error; err != nil {
 		return nil, apperrors.NewInternal()
 	}
@@ -77,11 +105,24 @@ func (db *Database) AnswerInvite(userID, inviteID uuid.UUID, answer bool) (*mode
 		if err := tx.First(&models.Invite{}, inviteID).Updates(models.Invite{Status: models.INVITE_ACCEPT, Modified: time.Now()}).Error; err != nil {
 			return err
 		}
-		if err := tx.Create(&models.Member{ID: memberID, UserID: userID, GroupID: invite.GroupID}).Error; err != nil {
+		if err := tx.Create(&models.Member{
+			ID:               memberID,
+			UserID:           userID,
+			GroupID:          invite.GroupID,
+			Adding:           invite.Rights.Adding,
+			DeletingMembers:  invite.Rights.DeletingMembers,
+			DeletingMessages: invite.Rights.DeletingMessages,
+			Admin:            invite.Rights.Admin,
+			Joined:           time.Now(),
+		}).Error; err != nil {
 			return err
 		}
 		return nil
 	}); err != nil {
+		// unique (group_id, user_id) index stops concurrent joins from creating second membership
+		if isDuplicateKey(err) {
+			return nil, nil, nil, &apperrors.Error{Type: apperrors.Conflict, Message: "user is already a member of this group"}
+		}
 		return nil, nil, nil, apperrors.NewInternal()
 	}
 
@@ -1,13 +1,155 @@
 package orm
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/Slimo300/MicroservicesChatApp/backend/lib/apperrors"
 	"github.com/Slimo300/chat-groupservice/internal/models"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
+// GetMembership returns membership of target user in a group. Users can always see their own membership,
+// memberships of others are visible only to group admins and creator
+func (db *Database) GetMembership(userID, groupID, targetID uuid.UUID) (*models.Member, error) {
+	if userID != targetID {
+		var issuer models.Member
+		if err := db.Where(models.Member{UserID: userID, GroupID: groupID}).First(&issuer).Error; err != nil {
+			return nil, apperrors.NewForbidden(fmt.Sprintf("User %v has no right to view members of group %v", userID, groupID))
+		}
+		if !issuer.CanEditGroup() {
+			return nil, apperrors.NewForbidden(fmt.Sprintf("User %v has no right to view members of group %v", userID, groupID))
+		}
+	}
+
+	var member models.Member
+	if err := db.Where(models.Member{UserID: targetID, GroupID: groupID}).Preload("User").First(&member).Error; err != nil {
+		return nil, apperrors.NewNotFound("member", targetID.String())
+	}
+	return &member, nil
+}
+
+// FindMember returns rights of user in a group or nil when user is not a member. It's a single lookup
+// on (group_id, user_id) unique index meant for frequent checks done by other services
+func (db *Database) FindMember(groupID, userID uuid.UUID) (*models.Member, error) {
+	var members []models.Member
+	if err := db.Select("id", "adding", "deleting_members", "deleting_messages", "setting", "co_owner", "creator").
+		Where(models.Member{GroupID: groupID, UserID: userID}).Limit(1).Find(&members).Error; err != nil {
+		return nil, apperrors.NewInternal()
+	}
+	if len(members) == 0 {
+		return nil, nil
+	}
+	return &members[0], nil
+}
+
+// GetGroupWithMembership returns user's membership together with its group fetched in a single joined query,
+// nil is returned when user isn't a member of a group
+func (db *Database) GetGroupWithMembership(userID, groupID uuid.UUID) (*models.Member, error) {
+	var members []models.Member
+	if err := db.Joins("Group").Where("`members`.user_id = ? AND `members`.group_id = ?", userID, groupID).
+		Limit(1).Find(&members).Error; err != nil {
+		return nil, apperrors.NewInternal()
+	}
+	if len(members) == 0 {
+		return nil, nil
+	}
+	return &members[0], nil
+}
+
+// StreamGroupMembers passes every member of a group to fn one by one, members are read through a database
+// cursor so only a single row is held in memory at a time. Returning an error from fn stops the stream
+func (db *Database) StreamGroupMembers(groupID uuid.UUID, fn func(models.Member) error) error {
+	if err := db.Where(models.Group{ID: groupID}).First(&models.Group{}).Error; err != nil {
+		return apperrors.NewNotFound("group", groupID.String())
+	}
+
+	// joined user's columns are prefixed with "User__" so that ScanRows fills member's User
+	rows, err := db.Model(&models.Member{}).Joins("User").Where(models.Member{GroupID: groupID}).Order("members.id").Rows()
+	if err != nil {
+		return apperrors.NewInternal()
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var member models.Member
+		if err := db.ScanRows(rows, &member); err != nil {
+			return apperrors.NewInternal()
+		}
+		// ScanRows doesn't run hooks, display name is filled like for members found by queries
+		if err := member.AfterFind(db.DB); err != nil {
+			return apperrors.NewInternal()
+		}
+		if err := fn(member); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return apperrors.NewInternal()
+	}
+	return nil
+}
+
+// GetUserMemberships returns rights of user in those of given groups user is a member of
+func (db *Database) GetUserMemberships(userID uuid.UUID, groupIDs []uuid.UUID) (members []models.Member, err error) {
+	if err := db.Select("id", "group_id", "adding", "deleting_members", "deleting_messages", "setting", "co_owner", "creator").
+		Where("user_id = ? AND group_id IN ?", userID, groupIDs).Find(&members).Error; err != nil {
+		return nil, apperrors.NewInternal()
+	}
+	return members, nil
+}
+
+// GetGroupMemberships returns rights of those of given users who are members of a group. Only members of a group
+// can check them, caller's own membership is looked up in the same query
+func (db *Database) GetGroupMemberships(userID, groupID uuid.UUID, userIDs []uuid.UUID) ([]models.Member, error) {
+	var found []models.Member
+	if err := db.Select("id", "user_id", "adding", "deleting_members", "deleting_messages", "setting", "co_owner", "creator").
+		Where("group_id = ? AND user_id IN ?", groupID, append([]uuid.UUID{userID}, userIDs...)).Find(&found).Error; err != nil {
+		return nil, apperrors.NewInternal()
+	}
+
+	requested := make(map[uuid.UUID]bool, len(userIDs))
+	for _, id := range userIDs {
+		requested[id] = true
+	}
+	isMember := false
+	members := make([]models.Member, 0, len(found))
+	for _, member := range found {
+		if member.UserID == userID {
+			isMember = true
+		}
+		if requested[member.UserID] {
+			members = append(members, member)
+		}
+	}
+	if !isMember {
+		return nil, apperrors.NewForbidden(fmt.Sprintf("User %v is not a member of group %v", userID, groupID))
+	}
+	return members, nil
+}
+
+// GetRecentMembers returns members of a group who joined after since (all of them when since is zero) in given
+// order, newest first by default. When after isn't nil page starts after its position. Only members of a group can
+// list them
+func (db *Database) GetRecentMembers(userID, groupID uuid.UUID, since time.Time, sort models.Sort, after *models.Cursor, num, offset int) (members []models.Member, err error) {
+	if err := db.Where(models.Member{UserID: userID, GroupID: groupID}).First(&models.Member{}).Error; err != nil {
+		return nil, apperrors.NewForbidden(fmt.Sprintf("User %v is not a member of group %v", userID, groupID))
+	}
+
+	query := db.Select("`members`.*").Joins("inner join `users` on `users`.id = `members`.user_id").
+		Where(models.Member{GroupID: groupID})
+	if !since.IsZero() {
+		query = query.Where("`members`.joined_at > ?", since)
+	}
+	query = keyOf(memberOrders, sort, models.SORT_JOINED_DESC).after(query, after)
+	if err := query.Order(orderOf(memberOrders, sort, models.SORT_JOINED_DESC)).Limit(num).Offset(offset).Preload("User").Find(&members).Error; err != nil {
+		return nil, apperrors.NewInternal()
+	}
+	return members, nil
+}
+
 func (db *Database) DeleteMember(userID, groupID, memberID uuid.UUID) (*models.Member, error) {
 	var issuer models.Member
 	if err := db.Where(models.Member{UserID: userID, GroupID: groupID}).First(&issuer).Error; err != nil {
@@ -17,6 +159,11 @@ func (db *Database) DeleteMember(userID, groupID, memberID uuid.UUID) (*models.M
 	if err := db.Where(models.Member{ID: memberID, GroupID: groupID}).First(&target).Error; err != nil {
 		return nil, apperrors.NewNotFound("member", memberID.String())
 	}
+	if issuer.ID == target.ID {
+		if err := target.CheckOwnerRemains(); err != nil {
+			return nil, err
+		}
+	}
 	if !issuer.CanDelete(target) {
 		return nil, apperrors.NewForbidden(fmt.Sprintf("User %v cannot delete member %v", userID, memberID))
 	}
@@ -45,9 +192,155 @@ func (db *Database) GrantRights(userID, groupID, memberID uuid.UUID, rights mode
 	if err := target.ApplyRights(rights); err != nil {
 		return nil, apperrors.NewBadRequest(err.Error())
 	}
+	if !issuer.CanPromote(target) {
+		return nil, apperrors.NewForbidden(fmt.Sprintf("User %v cannot grant member %v role %v", userID, memberID, target.RoleName()))
+	}
 
 	if err := db.Save(&target).Error; err != nil {
 		return nil, apperrors.NewInternal()
 	}
 	return &target, nil
 }
+
+// ChangeMemberRoles assigns roles to members of a group in a single transaction. Changes of members issuer
+// can't alter or that aren't in a group are rejected one by one without affecting the rest
+func (db *Database) ChangeMemberRoles(userID, groupID uuid.UUID, changes []models.RoleChange) ([]models.RoleChangeResult, error) {
+	var issuer models.Member
+	if err := db.Where(models.Member{UserID: userID, GroupID: groupID}).First(&issuer).Error; err != nil {
+		return nil, apperrors.NewForbidden(fmt.Sprintf("User %v has no right to alter members in group %v", userID, groupID))
+	}
+	if !issuer.CanAlter(models.Member{}) {
+		return nil, apperrors.NewForbidden(fmt.Sprintf("User %v has no right to alter members in group %v", userID, groupID))
+	}
+
+	var results []models.RoleChangeResult
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		results = make([]models.RoleChangeResult, 0, len(changes))
+		for _, change := range changes {
+			result := models.RoleChangeResult{UserID: change.UserID}
+
+			var target models.Member
+			if err := tx.Where(models.Member{UserID: change.UserID, GroupID: groupID}).Preload("User").First(&target).Error; err != nil {
+				result.Err = apperrors.NewNotFound("member", change.UserID.String())
+			} else if !issuer.CanAlter(target) {
+				result.Err = apperrors.NewForbidden(fmt.Sprintf("User %v cannot alter member %v", userID, target.ID))
+			} else if err := target.SetRole(change.Role); err != nil {
+				result.Err = apperrors.NewBadRequest(err.Error())
+			} else if !issuer.CanPromote(target) {
+				result.Err = apperrors.NewForbidden(fmt.Sprintf("User %v cannot grant member %v role %v", userID, target.ID, change.Role))
+			} else {
+				if err := tx.Model(&target).Select("setting", "co_owner", "deleting_members").Updates(&target).Error; err != nil {
+					return err
+				}
+				result.Member = &target
+			}
+			results = append(results, result)
+		}
+		return nil
+	}); err != nil {
+		return nil, apperrors.NewInternal()
+	}
+
+	return results, nil
+}
+
+// HandoverMember hands role and rights of source member over to target member, both members are saved
+// in a single transaction
+func (db *Database) HandoverMember(userID, groupID, sourceID, targetID uuid.UUID, handover models.MemberHandover) (*models.Member, *models.Member, error) {
+	var issuer models.Member
+	if err := db.Where(models.Member{UserID: userID, GroupID: groupID}).First(&issuer).Error; err != nil {
+		return nil, nil, apperrors.NewForbidden(fmt.Sprintf("User %v has no right to alter members in group %v", userID, groupID))
+	}
+	var source models.Member
+	if err := db.Where(models.Member{ID: sourceID, GroupID: groupID}).Preload("User").First(&source).Error; err != nil {
+		return nil, nil, apperrors.NewNotFound("member", sourceID.String())
+	}
+	var target models.Member
+	if err := db.Where(models.Member{ID: targetID, GroupID: groupID}).Preload("User").First(&target).Error; err != nil {
+		return nil, nil, apperrors.NewNotFound("member", targetID.String())
+	}
+
+	if err := issuer.HandOver(&source, &target, handover); err != nil {
+		return nil, nil, err
+	}
+
+	columns := []string{"adding", "deleting_messages", "deleting_members", "setting", "co_owner"}
+	if handover.Nickname {
+		columns = append(columns, "nickname")
+	}
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&target).Select(columns).Updates(&target).Error; err != nil {
+			return err
+		}
+		if handover.ClearSource {
+			return tx.Model(&source).Select(columns).Updates(&source).Error
+		}
+		return nil
+	}); err != nil {
+		return nil, nil, apperrors.NewInternal()
+	}
+	return &source, &target, nil
+}
+
+// StepDown lowers user's own role in a group by one level
+func (db *Database) StepDown(userID, groupID uuid.UUID) (*models.Member, error) {
+	var member models.Member
+	if err := db.Where(models.Member{UserID: userID, GroupID: groupID}).Preload("User").First(&member).Error; err != nil {
+		return nil, apperrors.NewForbidden(fmt.Sprintf("User %v is not a member of group %v", userID, groupID))
+	}
+
+	if err := member.CheckOwnerRemains(); err != nil {
+		return nil, err
+	}
+	if err := member.StepDown(); err != nil {
+		return nil, apperrors.NewBadRequest(err.Error())
+	}
+
+	if err := db.Model(&member).Select("setting", "co_owner", "deleting_members").Updates(&member).Error; err != nil {
+		return nil, apperrors.NewInternal()
+	}
+	return &member, nil
+}
+
+// SetNickname sets member's nickname in a group. Members can change their own nickname,
+// nicknames of others can only be reset by members who can alter them
+func (db *Database) SetNickname(userID, groupID, memberID uuid.UUID, nickname string) (*models.Member, error) {
+
+	var issuer models.Member
+	if err := db.Where(models.Member{UserID: userID, GroupID: groupID}).First(&issuer).Error; err != nil {
+		return nil, apperrors.NewForbidden(fmt.Sprintf("User %v has no right to alter members in group %v", userID, groupID))
+	}
+	var target models.Member
+	if err := db.Where(models.Member{ID: memberID, GroupID: groupID}).Preload("User").First(&target).Error; err != nil {
+		return nil, apperrors.NewNotFound("member", memberID.String())
+	}
+
+	if target.ID != issuer.ID && (nickname != "" || !issuer.CanAlter(target)) {
+		return nil, apperrors.NewForbidden(fmt.Sprintf("User %v cannot change nickname of member %v", userID, memberID))
+	}
+
+	if err := db.Model(&target).Update("nickname", nickname).Error; err != nil {
+		return nil, apperrors.NewInternal()
+	}
+	return &target, nil
+}
+
+// TouchMemberActivity sets time of member's last activity, older times than already stored are ignored
+// so replayed messages don't move it back. NotFound error is returned when group doesn't exist (or was deleted)
+func (db *Database) TouchMemberActivity(groupID, userID uuid.UUID, at time.Time) error {
+	result := db.Model(&models.Member{}).
+		Where(models.Member{GroupID: groupID, UserID: userID}).
+		Where("last_active_at IS NULL OR last_active_at < ?", at).
+		Update("last_active_at", at)
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.Error
+	}
+
+	if err := db.Select("id").Where(models.Group{ID: groupID}).First(&models.Group{}).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperrors.NewNotFound("group", groupID.String())
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package orm
+
+import (
+	"github.com/Slimo300/MicroservicesChatApp/backend/lib/apperrors"
+	"github.com/Slimo300/chat-groupservice/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MergeUserMemberships moves memberships and invites of user fromID to user toID. When both users are members
+// of the same group toID's membership is kept with rights of both and fromID's one is deleted. Awaiting invites
+// of fromID to groups toID is already in or invited to are dropped
+func (db *Database) MergeUserMemberships(fromID, toID uuid.UUID) (models.MembershipMerge, error) {
+	var merge models.MembershipMerge
+	if fromID == toID {
+		return merge, apperrors.NewBadRequest("cannot merge user with itself")
+	}
+
+	var target models.User
+	if err := db.First(&target, toID).Error; err != nil {
+		return merge, apperrors.NewNotFound("user", toID.String())
+	}
+
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		var members []models.Member
+		if err := tx.Where(models.Member{UserID: fromID}).Find(&members).Error; err != nil {
+			return err
+		}
+
+		for _, member := range members {
+			var existing []models.Member
+			if err := tx.Where(models.Member{UserID: toID, GroupID: member.GroupID}).Limit(1).Find(&existing).Error; err != nil {
+				return err
+			}
+
+			if len(existing) == 0 {
+				if err := tx.Model(&member).Update("user_id", toID).Error; err != nil {
+					return err
+				}
+				member.UserID, member.User = toID, target
+				merge.Updated = append(merge.Updated, member)
+				continue
+			}
+
+			kept := existing[0]
+			kept.Adding = kept.Adding || member.Adding
+			kept.DeletingMembers = kept.DeletingMembers || member.DeletingMembers
+			kept.DeletingMessages = kept.DeletingMessages || member.DeletingMessages
+			kept.Admin = kept.Admin || member.Admin
+			kept.CoOwner = kept.CoOwner || member.CoOwner
+			kept.Creator = kept.Creator || member.Creator
+			if err := tx.Model(&kept).Select("adding", "deleting_members", "deleting_messages", "setting", "co_owner", "creator").Updates(&kept).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&member).Error; err != nil {
+				return err
+			}
+			kept.User = target
+			merge.Updated = append(merge.Updated, kept)
+			merge.Deleted = append(merge.Deleted, member)
+		}
+
+		var invites []models.Invite
+		if err := tx.Where(models.Invite{TargetID: fromID, Status: models.INVITE_AWAITING}).Find(&invites).Error; err != nil {
+			return err
+		}
+		for _, invite := range invites {
+			var duplicates int64
+			if err := tx.Model(&models.Member{}).Where(models.Member{UserID: toID, GroupID: invite.GroupID}).Count(&duplicates).Error; err != nil {
+				return err
+			}
+			if duplicates == 0 {
+				if err := tx.Model(&models.Invite{}).Where(models.Invite{TargetID: toID, GroupID: invite.GroupID, Status: models.INVITE_AWAITING}).
+					Count(&duplicates).Error; err != nil {
+					return err
+				}
+			}
+			if duplicates > 0 {
+				if err := tx.Delete(&invite).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := tx.Model(&models.Invite{}).Where(models.Invite{TargetID: fromID}).Update("target_id", toID).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Invite{}).Where(models.Invite{IssId: fromID}).Update("iss_id", toID).Error
+	}); err != nil {
+		// target user joined one of the groups while memberships were being moved
+		if isDuplicateKey(err) {
+			return models.MembershipMerge{}, &apperrors.Error{Type: apperrors.Conflict, Message: "user is already a member of this group"}
+		}
+		return models.MembershipMerge{}, apperrors.NewInternal()
+	}
+
+	return merge, nil
+}
@@ -1,15 +1,37 @@
 package orm
 
 import (
+	"context"
+	"errors"
 	"fmt"
 
+	"github.com/Slimo300/chat-groupservice/internal/database"
 	"github.com/Slimo300/chat-groupservice/internal/models"
+	mysqlerr "github.com/go-sql-driver/mysql"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
 
 type Database struct {
 	*gorm.DB
+	// UniqueGroupNames makes CreateGroup reject names (case insensitive) already used by one of creator's groups
+	UniqueGroupNames bool
+	// MaxGroupsPerUser limits how many groups a single user can create, 0 means no limit
+	MaxGroupsPerUser int
+}
+
+// WithContext returns Database running its queries with ctx
+func (db *Database) WithContext(ctx context.Context) database.DBLayer {
+	return &Database{DB: db.DB.WithContext(ctx), UniqueGroupNames: db.UniqueGroupNames, MaxGroupsPerUser: db.MaxGroupsPerUser}
+}
+
+// MySQL error number of unique constraint violation
+const ER_DUP_ENTRY = 1062
+
+// isDuplicateKey checks whether err was caused by violating unique index
+func isDuplicateKey(err error) bool {
+	var mysqlErr *mysqlerr.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == ER_DUP_ENTRY
 }
 
 // Setup creates Database object and initializes connection between MySQL database
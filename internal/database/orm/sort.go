@@ -0,0 +1,84 @@
+package orm
+
+import (
+	"fmt"
+
+	"github.com/Slimo300/chat-groupservice/internal/models"
+	"gorm.io/gorm"
+)
+
+// sortKey is a value list is sorted by, rows with equal values are ordered by primary key in the same direction
+// so that pages don't overlap
+type sortKey struct {
+	column string
+	id     string
+	desc   bool
+	// nullsLast puts rows without a value after all others
+	nullsLast bool
+}
+
+// Sorts clients can choose
+var groupOrders = map[models.Sort]sortKey{
+	models.SORT_NAME_ASC:     {column: "`groups`.name", id: "`groups`.id"},
+	models.SORT_NAME_DESC:    {column: "`groups`.name", id: "`groups`.id", desc: true},
+	models.SORT_CREATED_ASC:  {column: "`groups`.created", id: "`groups`.id"},
+	models.SORT_CREATED_DESC: {column: "`groups`.created", id: "`groups`.id", desc: true},
+	models.SORT_UPDATED_DESC: {column: "`groups`.updated_at", id: "`groups`.id", desc: true},
+}
+
+var memberOrders = map[models.Sort]sortKey{
+	models.SORT_JOINED_ASC:  {column: "`members`.joined_at", id: "`members`.id"},
+	models.SORT_JOINED_DESC: {column: "`members`.joined_at", id: "`members`.id", desc: true},
+	// members who were never active go last
+	models.SORT_ACTIVITY_DESC: {column: "`members`.last_active_at", id: "`members`.id", desc: true, nullsLast: true},
+	// members are sorted by the name displayed to others, which is nickname or username when it isn't set
+	models.SORT_NAME_ASC:  {column: "COALESCE(NULLIF(`members`.nickname, ''), `users`.username)", id: "`members`.id"},
+	models.SORT_NAME_DESC: {column: "COALESCE(NULLIF(`members`.nickname, ''), `users`.username)", id: "`members`.id", desc: true},
+}
+
+// keyOf returns key of sort, falling back to def for sorts the list doesn't support
+func keyOf(orders map[models.Sort]sortKey, sort models.Sort, def models.Sort) sortKey {
+	if key, ok := orders[sort]; ok {
+		return key
+	}
+	return orders[def]
+}
+
+// orderOf returns order clause of sort, falling back to def for sorts the list doesn't support
+func orderOf(orders map[models.Sort]sortKey, sort models.Sort, def models.Sort) string {
+	key := keyOf(orders, sort, def)
+	direction := "ASC"
+	if key.desc {
+		direction = "DESC"
+	}
+	order := fmt.Sprintf("%s %s, %s %s", key.column, direction, key.id, direction)
+	if key.nullsLast {
+		order = fmt.Sprintf("%s IS NULL, %s", key.column, order)
+	}
+	return order
+}
+
+// after limits query sorted with key to rows placed after cursor, nil cursor leaves query unchanged
+func (key sortKey) after(query *gorm.DB, cursor *models.Cursor) *gorm.DB {
+	if cursor == nil {
+		return query
+	}
+	op := ">"
+	if key.desc {
+		op = "<"
+	}
+
+	var value interface{} = cursor.Name
+	if cursor.Time != nil {
+		value = *cursor.Time
+	} else if key.nullsLast {
+		// all rows left have no value either, so only primary key orders them
+		return query.Where(fmt.Sprintf("%s IS NULL AND %s %s ?", key.column, key.id, op), cursor.ID)
+	}
+
+	condition := fmt.Sprintf("%[1]s %[2]s ? OR (%[1]s = ? AND %[3]s %[2]s ?)", key.column, op, key.id)
+	if key.nullsLast {
+		condition = fmt.Sprintf("%[1]s %[2]s ? OR %[1]s IS NULL OR (%[1]s = ? AND %[3]s %[2]s ?)", key.column, op, key.id)
+	}
+	return query.Where(condition, value, value, cursor.ID)
+}
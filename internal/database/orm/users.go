@@ -3,16 +3,34 @@ package orm
 import (
 	"github.com/Slimo300/MicroservicesChatApp/backend/lib/events"
 	"github.com/Slimo300/chat-groupservice/internal/models"
+	"gorm.io/gorm/clause"
 )
 
+// NewUser adds user to database, users that already exist are skipped so replaying users topic is harmless
 func (db *Database) NewUser(event events.UserRegisteredEvent) error {
-	return db.Create(&models.User{
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.User{
 		ID:       event.ID,
 		UserName: event.Username,
 		Picture:  event.PictureURL,
 	}).Error
 }
 
+// NewUsers adds users to database with a single multi-row insert, already existing users are skipped
+func (db *Database) NewUsers(evts []events.UserRegisteredEvent) error {
+	if len(evts) == 0 {
+		return nil
+	}
+	users := make([]models.User, len(evts))
+	for i, event := range evts {
+		users[i] = models.User{
+			ID:       event.ID,
+			UserName: event.Username,
+			Picture:  event.PictureURL,
+		}
+	}
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&users).Error
+}
+
 func (db *Database) UpdateUserProfilePictureURL(event events.UserPictureModifiedEvent) error {
 	return db.Model(&models.User{ID: event.ID}).Update("picture", event.PictureURL).Error
 }
@@ -0,0 +1,24 @@
+package eventemitter
+
+import (
+	"fmt"
+
+	"github.com/Shopify/sarama"
+)
+
+// RequiredAcks maps name of producer acknowledgement level to sarama's setting. With "all" event is confirmed
+// only after all in-sync replicas stored it, so it survives leader's failure at the cost of latency. With "local"
+// only the leader has to store it and with "none" nothing is awaited, these are faster but events confirmed
+// this way may be lost if broker fails
+func RequiredAcks(name string) (sarama.RequiredAcks, error) {
+	switch name {
+	case "all":
+		return sarama.WaitForAll, nil
+	case "local":
+		return sarama.WaitForLocal, nil
+	case "none":
+		return sarama.NoResponse, nil
+	default:
+		return 0, fmt.Errorf("Unsupported required acks: %s", name)
+	}
+}
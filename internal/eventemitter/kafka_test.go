@@ -0,0 +1,39 @@
+package eventemitter_test
+
+import (
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/Slimo300/chat-groupservice/internal/eventemitter"
+	"github.com/stretchr/testify/suite"
+)
+
+type KafkaEmitterTestSuite struct {
+	suite.Suite
+}
+
+func (s *KafkaEmitterTestSuite) TestRequiredAcks() {
+	testCases := []struct {
+		name     string
+		expected sarama.RequiredAcks
+	}{
+		{name: "all", expected: sarama.WaitForAll},
+		{name: "local", expected: sarama.WaitForLocal},
+		{name: "none", expected: sarama.NoResponse},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			acks, err := eventemitter.RequiredAcks(tC.name)
+			s.NoError(err)
+			s.Equal(tC.expected, acks)
+		})
+	}
+
+	_, err := eventemitter.RequiredAcks("some")
+	s.Error(err)
+}
+
+func TestKafkaEmitter(t *testing.T) {
+	suite.Run(t, new(KafkaEmitterTestSuite))
+}
@@ -0,0 +1,104 @@
+package eventemitter
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Slimo300/MicroservicesChatApp/backend/lib/msgqueue"
+)
+
+const (
+	WEBHOOK_TIMEOUT     = 5 * time.Second
+	WEBHOOK_MAX_RETRIES = 3
+	WEBHOOK_BACKOFF     = 500 * time.Millisecond
+)
+
+// WebhookEmitter sends events as HTTP POST requests to a single URL. Body is the same envelope kafka emitter
+// publishes and when secret is set it's signed with HMAC-SHA256 in X-Signature header
+type WebhookEmitter struct {
+	URL    string
+	Secret string
+	Client *http.Client
+	// MaxRetries is the number of additional attempts made after network errors, 5xx and 429 responses
+	MaxRetries int
+	// Backoff is the delay before the first retry, it doubles with every next one
+	Backoff time.Duration
+}
+
+type webhookMessage struct {
+	EventName string      `json:"eventName"`
+	Payload   interface{} `json:"payload"`
+}
+
+// NewWebhookEmitter creates emitter posting events to url
+func NewWebhookEmitter(url, secret string) *WebhookEmitter {
+	return &WebhookEmitter{
+		URL:        url,
+		Secret:     secret,
+		Client:     &http.Client{Timeout: WEBHOOK_TIMEOUT},
+		MaxRetries: WEBHOOK_MAX_RETRIES,
+		Backoff:    WEBHOOK_BACKOFF,
+	}
+}
+
+// Emit posts event to webhook URL retrying failed deliveries
+func (w *WebhookEmitter) Emit(event msgqueue.Event) error {
+	body, err := json.Marshal(webhookMessage{
+		EventName: event.EventName(),
+		Payload:   event,
+	})
+	if err != nil {
+		return err
+	}
+
+	backoff := w.Backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := w.send(event.EventName(), body)
+		if err == nil || !retry || attempt >= w.MaxRetries {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// send makes a single delivery attempt and reports whether failed one is worth retrying
+func (w *WebhookEmitter) send(eventName string, body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-Name", eventName)
+	if w.Secret != "" {
+		req.Header.Set("X-Signature", "sha256="+Sign(w.Secret, body))
+	}
+
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+		return true, fmt.Errorf("webhook responded with %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("webhook responded with %d", resp.StatusCode)
+	}
+}
+
+// Sign returns hex encoded HMAC-SHA256 of body, receivers compute it the same way to verify requests
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,111 @@
+package eventemitter_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Slimo300/MicroservicesChatApp/backend/lib/events"
+	"github.com/Slimo300/chat-groupservice/internal/eventemitter"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+type WebhookTestSuite struct {
+	suite.Suite
+	event events.GroupDeletedEvent
+}
+
+func (s *WebhookTestSuite) SetupSuite() {
+	s.event = events.GroupDeletedEvent{ID: uuid.MustParse("6b2f8c1e-4d3a-4f5b-9e7c-8a1d2b3c4e5f")}
+}
+
+func (s *WebhookTestSuite) TestEmit() {
+	testCases := []struct {
+		desc             string
+		statuses         []int
+		secret           string
+		expectedAttempts int32
+		expectError      bool
+	}{
+		{
+			desc:             "WebhookDelivered",
+			statuses:         []int{http.StatusOK},
+			secret:           "secret",
+			expectedAttempts: 1,
+		},
+		{
+			desc:             "WebhookUnsigned",
+			statuses:         []int{http.StatusNoContent},
+			expectedAttempts: 1,
+		},
+		{
+			desc:             "WebhookRetriedAfterServerError",
+			statuses:         []int{http.StatusInternalServerError, http.StatusTooManyRequests, http.StatusOK},
+			secret:           "secret",
+			expectedAttempts: 3,
+		},
+		{
+			desc:             "WebhookClientErrorNotRetried",
+			statuses:         []int{http.StatusBadRequest},
+			secret:           "secret",
+			expectedAttempts: 1,
+			expectError:      true,
+		},
+		{
+			desc:             "WebhookRetriesExhausted",
+			statuses:         []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway},
+			secret:           "secret",
+			expectedAttempts: 3,
+			expectError:      true,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+			var attempts int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempt := atomic.AddInt32(&attempts, 1)
+
+				body, _ := io.ReadAll(r.Body)
+				s.Equal("application/json", r.Header.Get("Content-Type"))
+				s.Equal("groups.deleted", r.Header.Get("X-Event-Name"))
+				if tC.secret != "" {
+					s.Equal("sha256="+eventemitter.Sign(tC.secret, body), r.Header.Get("X-Signature"))
+				} else {
+					s.Empty(r.Header.Get("X-Signature"))
+				}
+
+				var msg struct {
+					EventName string                   `json:"eventName"`
+					Payload   events.GroupDeletedEvent `json:"payload"`
+				}
+				s.NoError(json.Unmarshal(body, &msg))
+				s.Equal("groups.deleted", msg.EventName)
+				s.Equal(s.event, msg.Payload)
+
+				w.WriteHeader(tC.statuses[attempt-1])
+			}))
+			defer server.Close()
+
+			emitter := eventemitter.NewWebhookEmitter(server.URL, tC.secret)
+			emitter.MaxRetries = 2
+			emitter.Backoff = 0
+
+			err := emitter.Emit(s.event)
+			if tC.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+			s.Equal(tC.expectedAttempts, atomic.LoadInt32(&attempts))
+		})
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	suite.Run(t, new(WebhookTestSuite))
+}
@@ -0,0 +1,359 @@
+package eventlistener
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/Slimo300/MicroservicesChatApp/backend/lib/apperrors"
+	"github.com/Slimo300/MicroservicesChatApp/backend/lib/msgqueue"
+	"github.com/Slimo300/chat-groupservice/internal/metrics"
+)
+
+var pausedTopicsGauge = metrics.NewGaugeVec("groupservice_consumer_paused",
+	"Whether consumption of a topic is paused (1) or running (0)", "topic")
+
+// StartOffset maps name of a starting position ("earliest" or "latest") to sarama offset.
+//
+// Listener consumes partitions without a consumer group so no offsets are committed and every start
+// of the service begins at this position. With "earliest" the whole users topic is replayed on startup,
+// which rebuilds the local users table from scratch (already existing users are skipped). With "latest"
+// only users registered after startup are received and users created while the service was down are missing
+// from the table. Messages topic is consumed from the same position, it only restores members' last activity.
+func StartOffset(name string) (int64, error) {
+	switch name {
+	case "earliest":
+		return sarama.OffsetOldest, nil
+	case "latest":
+		return sarama.OffsetNewest, nil
+	default:
+		return 0, fmt.Errorf("Unsupported start offset: %s", name)
+	}
+}
+
+// KafkaTopic holds kafka topic and partitions it covers. When Partitions is empty all partitions are consumed
+type KafkaTopic struct {
+	Name       string
+	Partitions []int32
+}
+
+type kafkaMessage struct {
+	EventName string      `json:"eventName"`
+	Payload   interface{} `json:"payload"`
+}
+
+// OffsetFinder looks up offsets of partitions by time, it is implemented by sarama.Client
+type OffsetFinder interface {
+	GetOffset(topic string, partitionID int32, time int64) (int64, error)
+}
+
+// ReplayRequest describes a range of already consumed events to process again.
+// Replay starts at Since when it is set, at Offset (in every partition) when it isn't nil
+// and at the beginning of the topic otherwise. It ends at the newest offset at the time of request.
+// Offset at or before the oldest offset of a partition replays whole topic, it's refused unless Confirmed
+type ReplayRequest struct {
+	Topic     string
+	EventName string
+	Since     time.Time
+	Offset    *int64
+	Confirmed bool
+}
+
+// Replayer reprocesses already consumed events, returning number of messages that will be read again
+type Replayer interface {
+	Replay(req ReplayRequest) (int64, error)
+}
+
+// Pauser stops and restarts consumption of a topic without closing its partitions
+type Pauser interface {
+	Pause(topic string) error
+	Resume(topic string) error
+	PausedTopics() []string
+}
+
+var errNotListening = errors.New("listener is not running")
+var errReplayDisabled = errors.New("replay is not enabled")
+var errReplayNotConfirmed = apperrors.NewBadRequest("replaying whole topic requires confirm flag")
+
+// KafkaListener forwards events from kafka topics starting from configured offset
+type KafkaListener struct {
+	consumer sarama.Consumer
+	mapper   msgqueue.EventMapper
+	decoder  msgqueue.Decoder
+	topics   []KafkaTopic
+	offset   int64
+
+	offsets     OffsetFinder
+	newConsumer func() (sarama.Consumer, error)
+
+	mu       sync.Mutex
+	results  chan msgqueue.Event
+	errors   chan error
+	accepted map[string]bool
+	// consumed holds partitions of every topic consumed by Listen, paused holds topics which are paused
+	consumed map[string][]int32
+	paused   map[string]bool
+}
+
+// NewKafkaListener creates KafkaListener consuming given topics from offset
+func NewKafkaListener(consumer sarama.Consumer, mapper msgqueue.EventMapper, offset int64, topics ...KafkaTopic) *KafkaListener {
+	return &KafkaListener{
+		consumer: consumer,
+		mapper:   mapper,
+		decoder:  msgqueue.NewJSONDecoder(),
+		topics:   topics,
+		offset:   offset,
+	}
+}
+
+// Listen starts consuming listener's topics and forwards events with given names through returned channel.
+// When no event names are given all events are forwarded
+func (k *KafkaListener) Listen(eventNames ...string) (<-chan msgqueue.Event, <-chan error, error) {
+	results := make(chan msgqueue.Event)
+	errors := make(chan error)
+
+	accepted := make(map[string]bool)
+	for _, name := range eventNames {
+		accepted[name] = true
+	}
+
+	consumed := make(map[string][]int32)
+	for _, topic := range k.topics {
+
+		partitions, err := k.partitions(k.consumer, topic)
+		if err != nil {
+			return nil, nil, err
+		}
+		consumed[topic.Name] = partitions
+
+		for _, partition := range partitions {
+			con, err := k.consumer.ConsumePartition(topic.Name, partition, k.offset)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			go k.forward(con, results, errors, accepted, -1)
+			go k.forwardErrors(con, errors)
+		}
+	}
+
+	k.mu.Lock()
+	k.results, k.errors, k.accepted = results, errors, accepted
+	k.consumed, k.paused = consumed, make(map[string]bool)
+	k.mu.Unlock()
+	for topic := range consumed {
+		pausedTopicsGauge.WithLabelValues(topic).Set(0)
+	}
+
+	return results, errors, nil
+}
+
+// EnableReplay allows listener to replay events. Partitions are already being consumed by listener's consumer
+// so replays are read by new consumers created with newConsumer
+func (k *KafkaListener) EnableReplay(offsets OffsetFinder, newConsumer func() (sarama.Consumer, error)) {
+	k.offsets = offsets
+	k.newConsumer = newConsumer
+}
+
+// Replay reads events described by req once again and forwards them through channel returned by Listen.
+// Events not accepted by Listen are skipped. Reading happens in background, Replay returns right after
+// replay has started
+func (k *KafkaListener) Replay(req ReplayRequest) (int64, error) {
+	k.mu.Lock()
+	results, errs, accepted := k.results, k.errors, k.accepted
+	k.mu.Unlock()
+
+	if results == nil {
+		return 0, errNotListening
+	}
+	if k.offsets == nil || k.newConsumer == nil {
+		return 0, errReplayDisabled
+	}
+
+	var topic *KafkaTopic
+	for i := range k.topics {
+		if k.topics[i].Name == req.Topic {
+			topic = &k.topics[i]
+		}
+	}
+	if topic == nil {
+		return 0, apperrors.NewBadRequest(fmt.Sprintf("topic %s is not consumed by this service", req.Topic))
+	}
+
+	if req.EventName != "" {
+		if len(accepted) > 0 && !accepted[req.EventName] {
+			return 0, apperrors.NewBadRequest(fmt.Sprintf("event %s is not processed by this service", req.EventName))
+		}
+		accepted = map[string]bool{req.EventName: true}
+	}
+
+	consumer, err := k.newConsumer()
+	if err != nil {
+		return 0, err
+	}
+
+	partitions, err := k.partitions(consumer, *topic)
+	if err != nil {
+		consumer.Close()
+		return 0, err
+	}
+
+	type partitionRange struct {
+		partition  int32
+		start, end int64
+	}
+	var ranges []partitionRange
+	var total int64
+	for _, partition := range partitions {
+		end, err := k.offsets.GetOffset(topic.Name, partition, sarama.OffsetNewest)
+		if err != nil {
+			consumer.Close()
+			return 0, err
+		}
+
+		var start int64
+		switch {
+		case !req.Since.IsZero():
+			start, err = k.offsets.GetOffset(topic.Name, partition, req.Since.UnixMilli())
+		case req.Offset != nil:
+			start = *req.Offset
+			var oldest int64
+			oldest, err = k.offsets.GetOffset(topic.Name, partition, sarama.OffsetOldest)
+			if err == nil && start <= oldest && !req.Confirmed {
+				err = errReplayNotConfirmed
+			}
+		default:
+			start, err = k.offsets.GetOffset(topic.Name, partition, sarama.OffsetOldest)
+		}
+		if err != nil {
+			consumer.Close()
+			return 0, err
+		}
+
+		// no messages newer than requested time
+		if start == sarama.OffsetNewest || start >= end {
+			continue
+		}
+		ranges = append(ranges, partitionRange{partition: partition, start: start, end: end})
+		total += end - start
+	}
+
+	var wg sync.WaitGroup
+	for _, r := range ranges {
+		con, err := consumer.ConsumePartition(topic.Name, r.partition, r.start)
+		if err != nil {
+			consumer.Close()
+			return 0, err
+		}
+
+		wg.Add(1)
+		go func(end int64) {
+			defer wg.Done()
+			k.forward(con, results, errs, accepted, end)
+			con.AsyncClose()
+		}(r.end)
+		go k.forwardErrors(con, errs)
+	}
+
+	go func() {
+		wg.Wait()
+		consumer.Close()
+	}()
+
+	return total, nil
+}
+
+// Pause stops fetching messages of topic while keeping its partitions open. Partition consumers remember
+// their position so Resume continues right after the last message received before the pause. Messages that
+// were already fetched are still processed and replays started with Replay aren't paused
+func (k *KafkaListener) Pause(topic string) error {
+	return k.setPaused(topic, true)
+}
+
+// Resume restarts fetching messages of a topic paused with Pause
+func (k *KafkaListener) Resume(topic string) error {
+	return k.setPaused(topic, false)
+}
+
+func (k *KafkaListener) setPaused(topic string, paused bool) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.consumed == nil {
+		return errNotListening
+	}
+	partitions, ok := k.consumed[topic]
+	if !ok {
+		return apperrors.NewBadRequest(fmt.Sprintf("topic %s is not consumed by this service", topic))
+	}
+
+	if paused {
+		k.consumer.Pause(map[string][]int32{topic: partitions})
+		pausedTopicsGauge.WithLabelValues(topic).Set(1)
+	} else {
+		k.consumer.Resume(map[string][]int32{topic: partitions})
+		pausedTopicsGauge.WithLabelValues(topic).Set(0)
+	}
+	k.paused[topic] = paused
+	return nil
+}
+
+// PausedTopics returns sorted names of topics which are currently paused
+func (k *KafkaListener) PausedTopics() []string {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	topics := []string{}
+	for topic, paused := range k.paused {
+		if paused {
+			topics = append(topics, topic)
+		}
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// partitions returns partitions of topic, listing them with consumer when topic doesn't specify any
+func (k *KafkaListener) partitions(consumer sarama.Consumer, topic KafkaTopic) ([]int32, error) {
+	if len(topic.Partitions) > 0 {
+		return topic.Partitions, nil
+	}
+	return consumer.Partitions(topic.Name)
+}
+
+// forward decodes messages and sends accepted events to results. When end is not negative it returns
+// after message preceding end offset
+func (k *KafkaListener) forward(con sarama.PartitionConsumer, results chan<- msgqueue.Event, errors chan<- error, accepted map[string]bool, end int64) {
+	for msg := range con.Messages() {
+		k.handleMessage(msg, results, errors, accepted)
+		if end >= 0 && msg.Offset+1 >= end {
+			return
+		}
+	}
+}
+
+func (k *KafkaListener) handleMessage(msg *sarama.ConsumerMessage, results chan<- msgqueue.Event, errors chan<- error, accepted map[string]bool) {
+	var body kafkaMessage
+	if err := k.decoder.Decode(msg.Value, &body); err != nil {
+		errors <- fmt.Errorf("Could not unmarshal message: %s", err.Error())
+		return
+	}
+	if len(accepted) > 0 && !accepted[body.EventName] {
+		return
+	}
+	evt, err := k.mapper.MapEvent(body.EventName, body.Payload)
+	if err != nil {
+		errors <- fmt.Errorf("Error when mapping events: %s", err.Error())
+		return
+	}
+	results <- evt
+}
+
+func (k *KafkaListener) forwardErrors(con sarama.PartitionConsumer, errors chan<- error) {
+	for err := range con.Errors() {
+		errors <- err
+	}
+}
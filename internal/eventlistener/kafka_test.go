@@ -0,0 +1,200 @@
+package eventlistener_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+	"github.com/Slimo300/MicroservicesChatApp/backend/lib/events"
+	"github.com/Slimo300/MicroservicesChatApp/backend/lib/msgqueue"
+	"github.com/Slimo300/chat-groupservice/internal/eventlistener"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+type KafkaListenerTestSuite struct {
+	suite.Suite
+	mapper *msgqueue.DynamicEventMapper
+}
+
+func (s *KafkaListenerTestSuite) SetupSuite() {
+	s.mapper = msgqueue.NewDynamicEventMapper()
+	if err := s.mapper.RegisterTypes(reflect.TypeOf(events.UserRegisteredEvent{})); err != nil {
+		s.Fail(err.Error())
+	}
+}
+
+func (s *KafkaListenerTestSuite) message(event msgqueue.Event) *sarama.ConsumerMessage {
+	body, err := json.Marshal(map[string]interface{}{"eventName": event.EventName(), "payload": event})
+	if err != nil {
+		s.Fail(err.Error())
+	}
+	return &sarama.ConsumerMessage{Topic: "users", Value: body}
+}
+
+func (s *KafkaListenerTestSuite) TestStartOffset() {
+	offset, err := eventlistener.StartOffset("earliest")
+	s.NoError(err)
+	s.Equal(sarama.OffsetOldest, offset)
+
+	offset, err = eventlistener.StartOffset("latest")
+	s.NoError(err)
+	s.Equal(sarama.OffsetNewest, offset)
+
+	_, err = eventlistener.StartOffset("middle")
+	s.Error(err)
+}
+
+func (s *KafkaListenerTestSuite) TestListenAppliesOffset() {
+	for _, name := range []string{"earliest", "latest"} {
+		s.Run(name, func() {
+			offset, err := eventlistener.StartOffset(name)
+			s.NoError(err)
+
+			event := events.UserRegisteredEvent{ID: uuid.New(), Username: "user"}
+
+			consumer := mocks.NewConsumer(s.T(), nil)
+			consumer.SetTopicMetadata(map[string][]int32{"users": {0}})
+			consumer.ExpectConsumePartition("users", 0, offset).YieldMessage(s.message(event))
+
+			listener := eventlistener.NewKafkaListener(consumer, s.mapper, offset, eventlistener.KafkaTopic{Name: "users"})
+			received, _, err := listener.Listen()
+			s.NoError(err)
+
+			select {
+			case evt := <-received:
+				s.Equal(&event, evt)
+			case <-time.After(time.Second):
+				s.Fail("event not received")
+			}
+			s.NoError(consumer.Close())
+		})
+	}
+}
+
+func (s *KafkaListenerTestSuite) TestListenFiltersEventNames() {
+	event := events.UserRegisteredEvent{ID: uuid.New(), Username: "user"}
+
+	consumer := mocks.NewConsumer(s.T(), nil)
+	consumer.ExpectConsumePartition("users", 0, sarama.OffsetOldest).
+		YieldMessage(s.message(events.UserPictureModifiedEvent{ID: uuid.New()})).
+		YieldMessage(s.message(event))
+
+	listener := eventlistener.NewKafkaListener(consumer, s.mapper, sarama.OffsetOldest, eventlistener.KafkaTopic{Name: "users", Partitions: []int32{0}})
+	received, _, err := listener.Listen(event.EventName())
+	s.NoError(err)
+
+	select {
+	case evt := <-received:
+		s.Equal(&event, evt)
+	case <-time.After(time.Second):
+		s.Fail("event not received")
+	}
+	s.NoError(consumer.Close())
+}
+
+type fakeOffsets map[int64]int64
+
+// GetOffset behaves like kafka for times without later messages and returns OffsetNewest
+func (f fakeOffsets) GetOffset(topic string, partition int32, time int64) (int64, error) {
+	if offset, ok := f[time]; ok {
+		return offset, nil
+	}
+	return sarama.OffsetNewest, nil
+}
+
+func (s *KafkaListenerTestSuite) TestReplay() {
+	event := events.UserRegisteredEvent{ID: uuid.New(), Username: "user"}
+	since := time.Now().Add(-time.Hour)
+
+	consumer := mocks.NewConsumer(s.T(), nil)
+	consumer.ExpectConsumePartition("users", 0, sarama.OffsetOldest)
+
+	replayConsumer := mocks.NewConsumer(s.T(), nil)
+	replayConsumer.ExpectConsumePartition("users", 0, 3).
+		YieldMessage(s.message(events.UserPictureModifiedEvent{ID: uuid.New()})).
+		YieldMessage(s.message(event))
+
+	listener := eventlistener.NewKafkaListener(consumer, s.mapper, sarama.OffsetOldest, eventlistener.KafkaTopic{Name: "users", Partitions: []int32{0}})
+	listener.EnableReplay(fakeOffsets{sarama.OffsetNewest: 5, sarama.OffsetOldest: 2, since.UnixMilli(): 3}, func() (sarama.Consumer, error) {
+		return replayConsumer, nil
+	})
+
+	_, err := listener.Replay(eventlistener.ReplayRequest{Topic: "users", Since: since})
+	s.Error(err)
+
+	received, _, err := listener.Listen()
+	s.NoError(err)
+
+	_, err = listener.Replay(eventlistener.ReplayRequest{Topic: "messages", Since: since})
+	s.Error(err)
+
+	messages, err := listener.Replay(eventlistener.ReplayRequest{Topic: "users", EventName: event.EventName(), Since: since})
+	s.NoError(err)
+	s.Equal(int64(2), messages)
+
+	select {
+	case evt := <-received:
+		s.Equal(&event, evt)
+	case <-time.After(time.Second):
+		s.Fail("event not received")
+	}
+
+	// offsets that aren't retained anymore replay whole topic
+	retained := int64(1)
+	_, err = listener.Replay(eventlistener.ReplayRequest{Topic: "users", Offset: &retained})
+	s.EqualError(err, "Bad request. Reason: replaying whole topic requires confirm flag")
+
+	// nothing was published after requested time
+	messages, err = listener.Replay(eventlistener.ReplayRequest{Topic: "users", Since: time.Now()})
+	s.NoError(err)
+	s.Equal(int64(0), messages)
+
+	s.NoError(consumer.Close())
+}
+
+func (s *KafkaListenerTestSuite) TestPauseResume() {
+	first := events.UserRegisteredEvent{ID: uuid.New(), Username: "first"}
+	second := events.UserRegisteredEvent{ID: uuid.New(), Username: "second"}
+
+	consumer := mocks.NewConsumer(s.T(), nil)
+	partition := consumer.ExpectConsumePartition("users", 0, sarama.OffsetOldest)
+
+	listener := eventlistener.NewKafkaListener(consumer, s.mapper, sarama.OffsetOldest, eventlistener.KafkaTopic{Name: "users", Partitions: []int32{0}})
+	s.Error(listener.Pause("users"))
+
+	received, _, err := listener.Listen()
+	s.NoError(err)
+	s.Error(listener.Pause("messages"))
+
+	s.NoError(listener.Pause("users"))
+	s.Equal([]string{"users"}, listener.PausedTopics())
+
+	partition.YieldMessage(s.message(first)).YieldMessage(s.message(second))
+	select {
+	case evt := <-received:
+		s.Fail("event received while paused", "%v", evt)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// messages published during pause are received in order once consumption is resumed
+	s.NoError(listener.Resume("users"))
+	s.Equal([]string{}, listener.PausedTopics())
+	for _, expected := range []events.UserRegisteredEvent{first, second} {
+		select {
+		case evt := <-received:
+			s.Equal(&expected, evt)
+		case <-time.After(time.Second):
+			s.Fail("event not received")
+		}
+	}
+
+	s.NoError(consumer.Close())
+}
+
+func TestKafkaListenerSuite(t *testing.T) {
+	suite.Run(t, &KafkaListenerTestSuite{})
+}
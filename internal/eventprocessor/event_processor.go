@@ -1,27 +1,132 @@
 package eventprocessor
 
 import (
+	"errors"
+	"fmt"
 	"log"
+	"reflect"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/Slimo300/MicroservicesChatApp/backend/lib/apperrors"
 	"github.com/Slimo300/MicroservicesChatApp/backend/lib/events"
 	"github.com/Slimo300/MicroservicesChatApp/backend/lib/msgqueue"
 	"github.com/Slimo300/chat-groupservice/internal/database"
+	"github.com/Slimo300/chat-groupservice/internal/metrics"
+	"github.com/google/uuid"
 )
 
+const ACTIVITY_DEBOUNCE = 5 * time.Minute
+const USER_BATCH_SIZE = 100
+const USER_FLUSH_INTERVAL = time.Second
+
+// USER_FLUSH_ATTEMPTS is a number of failed batch inserts after which queued users are saved one by one
+const USER_FLUSH_ATTEMPTS = 3
+
+// MAX_USER_FLUSH_BACKOFF caps the delay between retries of saving users while database is failing
+const MAX_USER_FLUSH_BACKOFF = 30 * time.Second
+
+// MAX_PENDING_USER_BATCHES limits queued users to that many batches, no more events are consumed beyond it
+// until queued users are saved
+const MAX_PENDING_USER_BATCHES = 10
+
+var unknownGroupEvents = metrics.NewCounterVec("groupservice_unknown_group_events_total",
+	"Number of consumed events skipped because they reference a group that doesn't exist", "event")
+
+// ConsumedEvents are events handled by ProcessEvents
+var ConsumedEvents = []msgqueue.Event{
+	events.UserRegisteredEvent{},
+	events.UserPictureModifiedEvent{},
+	events.MessageSentEvent{},
+}
+
+// CheckEventTypes compares event types registered in listener's mapper with ConsumedEvents. Event missing
+// from mapper would be dropped by listener and unexpected one would reach processor just to be ignored
+func CheckEventTypes(registered ...reflect.Type) error {
+	names := make(map[string]bool)
+	for _, typ := range registered {
+		event, ok := reflect.New(typ).Elem().Interface().(msgqueue.Event)
+		if !ok {
+			return fmt.Errorf("type %s is not an event", typ.Name())
+		}
+		names[event.EventName()] = true
+	}
+
+	var missing []string
+	for _, event := range ConsumedEvents {
+		if !names[event.EventName()] {
+			missing = append(missing, event.EventName())
+		}
+		delete(names, event.EventName())
+	}
+	var unexpected []string
+	for name := range names {
+		unexpected = append(unexpected, name)
+	}
+	sort.Strings(unexpected)
+
+	var problems []string
+	if len(missing) > 0 {
+		problems = append(problems, "not registered: "+strings.Join(missing, ", "))
+	}
+	if len(unexpected) > 0 {
+		problems = append(problems, "not handled: "+strings.Join(unexpected, ", "))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("event types mismatch, %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
 // EventProcessor processes events from listener and updates state of application
 type EventProcessor struct {
 	DB       database.DBLayer
 	Listener msgqueue.EventListener
+	// ActivityDebounce is a minimal time between two updates of member's last activity
+	ActivityDebounce time.Duration
+	// registered users are saved in batches of UserBatchSize, smaller batches are saved every UserFlushInterval
+	UserBatchSize     int
+	UserFlushInterval time.Duration
+	// LogUnknownGroups logs every skipped event referencing a group that doesn't exist, they're only counted otherwise
+	LogUnknownGroups bool
+
+	lastActivity map[activityKey]time.Time
+	lastPrune    time.Time
+	pendingUsers []events.UserRegisteredEvent
+	// flushFailures counts failed flushes since users were last saved
+	flushFailures int
+
+	stop, stopped chan struct{}
+}
+
+type activityKey struct {
+	groupID, userID uuid.UUID
 }
 
 // NewEventProcessor is a constructor for EventProcessor type
 func NewEventProcessor(db database.DBLayer, listener msgqueue.EventListener) *EventProcessor {
 	return &EventProcessor{
-		DB:       db,
-		Listener: listener,
+		DB:                db,
+		Listener:          listener,
+		ActivityDebounce:  ACTIVITY_DEBOUNCE,
+		UserBatchSize:     USER_BATCH_SIZE,
+		UserFlushInterval: USER_FLUSH_INTERVAL,
+		lastActivity:      make(map[activityKey]time.Time),
+		stop:              make(chan struct{}),
+		stopped:           make(chan struct{}),
 	}
 }
 
+// Stop makes ProcessEvents save queued users and return, it waits until they're saved. Offsets of
+// consumed events aren't committed anywhere, so users still queued when the service crashes are lost
+// unless users topic is consumed from the beginning on startup
+func (p *EventProcessor) Stop() {
+	close(p.stop)
+	<-p.stopped
+}
+
 // Process events listens to listener and updates state of application
 func (p *EventProcessor) ProcessEvents(eventNames ...string) {
 	received, errors, err := p.Listener.Listen(eventNames...)
@@ -29,23 +134,155 @@ func (p *EventProcessor) ProcessEvents(eventNames ...string) {
 		log.Println(err)
 	}
 
+	flush := time.NewTimer(p.UserFlushInterval)
+	defer flush.Stop()
+
 	for {
+		// listener isn't read while queue is full, so users wait in the topic instead of being dropped
+		// when database is unavailable
+		in := received
+		if len(p.pendingUsers) >= MAX_PENDING_USER_BATCHES*p.UserBatchSize {
+			in = nil
+		}
+
 		select {
-		case evt := <-received:
-			switch e := evt.(type) {
-			case *events.UserRegisteredEvent:
-				if err := p.DB.NewUser(*e); err != nil {
-					log.Printf("Listener NewUser error: %s", err.Error())
-				}
-			case *events.UserPictureModifiedEvent:
-				if err := p.DB.UpdateUserProfilePictureURL(*e); err != nil {
-					log.Printf("Listener UpdatePicture error: %s", err.Error())
-				}
-			default:
-				log.Println("Unsupported event type")
-			}
+		case evt := <-in:
+			p.handleEvent(evt)
+		case <-flush.C:
+			p.flushUsers()
+			flush.Reset(p.flushDelay())
 		case err = <-errors:
 			log.Printf("Listener error: %s", err.Error())
+		case <-p.stop:
+			p.flushUsers()
+			close(p.stopped)
+			return
 		}
 	}
 }
+
+// handleEvent applies a single event, panic caused by it is logged and the event is dropped so that
+// one malformed event doesn't stop the consumer
+func (p *EventProcessor) handleEvent(evt msgqueue.Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Listener panic while processing %T: %v\n%s", evt, r, debug.Stack())
+		}
+	}()
+
+	switch e := evt.(type) {
+	case *events.UserRegisteredEvent:
+		p.addUser(*e)
+	case *events.UserPictureModifiedEvent:
+		// modified user may still be waiting in a batch
+		p.flushUsers()
+		if err := p.DB.UpdateUserProfilePictureURL(*e); err != nil {
+			log.Printf("Listener UpdatePicture error: %s", err.Error())
+		}
+	case *events.MessageSentEvent:
+		err := p.touchMemberActivity(e.GroupID, e.UserID, e.Posted)
+		if isUnknownGroup(err) {
+			p.skipUnknownGroup(e, e.GroupID)
+		} else if err != nil {
+			log.Printf("Listener TouchMemberActivity error: %s", err.Error())
+		}
+	default:
+		log.Println("Unsupported event type")
+	}
+}
+
+// isUnknownGroup tells whether database rejected an update because group it references doesn't exist. Memberships
+// are removed when a group is deleted so such events can't ever be applied and there is no point retrying them
+func isUnknownGroup(err error) bool {
+	var appErr *apperrors.Error
+	return errors.As(err, &appErr) && appErr.Type == apperrors.NotFound
+}
+
+func (p *EventProcessor) skipUnknownGroup(evt msgqueue.Event, groupID uuid.UUID) {
+	unknownGroupEvents.WithLabelValues(evt.EventName()).Inc()
+	if p.LogUnknownGroups {
+		log.Printf("Listener warning: skipping %s referencing unknown group %v", evt.EventName(), groupID)
+	}
+}
+
+// addUser queues user to be saved, batch is saved right away when it's full. While database is failing
+// saving is only retried by the timer so that every new user doesn't hit it again
+func (p *EventProcessor) addUser(event events.UserRegisteredEvent) {
+	p.pendingUsers = append(p.pendingUsers, event)
+	if len(p.pendingUsers) >= p.UserBatchSize && p.flushFailures == 0 {
+		p.flushUsers()
+	}
+}
+
+// flushDelay returns time until the next flush, it doubles with every failed flush up to MAX_USER_FLUSH_BACKOFF
+func (p *EventProcessor) flushDelay() time.Duration {
+	delay := p.UserFlushInterval
+	for i := 0; i < p.flushFailures && 2*delay <= MAX_USER_FLUSH_BACKOFF; i++ {
+		delay *= 2
+	}
+	return delay
+}
+
+// flushUsers saves queued users, when it fails users stay queued and are saved with the next flush.
+// After every USER_FLUSH_ATTEMPTS failures users are saved one by one so that a single row rejected by
+// database doesn't block all the others, rejected users are then logged and dropped
+func (p *EventProcessor) flushUsers() {
+	if len(p.pendingUsers) == 0 {
+		return
+	}
+	err := p.DB.NewUsers(p.pendingUsers)
+	if err == nil {
+		p.pendingUsers = nil
+		p.flushFailures = 0
+		return
+	}
+	log.Printf("Listener NewUsers error: %s", err.Error())
+
+	p.flushFailures++
+	if p.flushFailures%USER_FLUSH_ATTEMPTS != 0 {
+		return
+	}
+
+	var rejected []events.UserRegisteredEvent
+	var errs []error
+	for _, user := range p.pendingUsers {
+		if err := p.DB.NewUser(user); err != nil {
+			rejected = append(rejected, user)
+			errs = append(errs, err)
+		}
+	}
+	// nothing could be saved so database is most likely unavailable rather than rejecting some rows
+	if len(rejected) == len(p.pendingUsers) {
+		return
+	}
+	for i, user := range rejected {
+		log.Printf("Listener NewUser error, dropping user %v: %s", user.ID, errs[i].Error())
+	}
+	p.pendingUsers = nil
+	p.flushFailures = 0
+}
+
+// touchMemberActivity updates member's last activity unless it was already updated within debounce window
+func (p *EventProcessor) touchMemberActivity(groupID, userID uuid.UUID, at time.Time) error {
+	key := activityKey{groupID: groupID, userID: userID}
+	if last, ok := p.lastActivity[key]; ok && at.Sub(last) < p.ActivityDebounce {
+		return nil
+	}
+	if err := p.DB.TouchMemberActivity(groupID, userID, at); err != nil {
+		return err
+	}
+	p.lastActivity[key] = at
+
+	// entries older than debounce window won't suppress any update so they can be dropped,
+	// message time is used instead of current time so replayed messages are debounced as well
+	if at.Sub(p.lastPrune) > p.ActivityDebounce {
+		for k, last := range p.lastActivity {
+			if at.Sub(last) > p.ActivityDebounce {
+				delete(p.lastActivity, k)
+			}
+		}
+		p.lastPrune = at
+	}
+
+	return nil
+}
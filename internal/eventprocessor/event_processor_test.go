@@ -0,0 +1,300 @@
+package eventprocessor
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Slimo300/MicroservicesChatApp/backend/lib/apperrors"
+	"github.com/Slimo300/MicroservicesChatApp/backend/lib/events"
+	"github.com/Slimo300/MicroservicesChatApp/backend/lib/msgqueue"
+	mockdb "github.com/Slimo300/chat-groupservice/internal/database/mock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type EventProcessorTestSuite struct {
+	suite.Suite
+}
+
+func (s *EventProcessorTestSuite) TestTouchMemberActivityDebounce() {
+	groupID, userID, otherUserID := uuid.New(), uuid.New(), uuid.New()
+	start := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	db := new(mockdb.MockGroupsDB)
+	db.On("TouchMemberActivity", groupID, userID, start).Return(nil).Once()
+	db.On("TouchMemberActivity", groupID, otherUserID, start.Add(time.Minute)).Return(nil).Once()
+	db.On("TouchMemberActivity", groupID, userID, start.Add(6*time.Minute)).Return(nil).Once()
+
+	processor := NewEventProcessor(db, nil)
+
+	s.NoError(processor.touchMemberActivity(groupID, userID, start))
+	// within debounce window
+	s.NoError(processor.touchMemberActivity(groupID, userID, start.Add(time.Minute)))
+	s.NoError(processor.touchMemberActivity(groupID, userID, start.Add(4*time.Minute)))
+	// other members are not affected
+	s.NoError(processor.touchMemberActivity(groupID, otherUserID, start.Add(time.Minute)))
+	// window has passed
+	s.NoError(processor.touchMemberActivity(groupID, userID, start.Add(6*time.Minute)))
+
+	db.AssertExpectations(s.T())
+}
+
+func (s *EventProcessorTestSuite) TestUsersSavedInBatches() {
+	users := []events.UserRegisteredEvent{
+		{ID: uuid.New(), Username: "user1"},
+		{ID: uuid.New(), Username: "user2"},
+		{ID: uuid.New(), Username: "user3"},
+	}
+
+	db := new(mockdb.MockGroupsDB)
+	db.On("NewUsers", users).Return(nil).Once()
+
+	processor := NewEventProcessor(db, nil)
+	processor.UserBatchSize = 3
+
+	processor.addUser(users[0])
+	processor.addUser(users[1])
+	db.AssertNotCalled(s.T(), "NewUsers", mock.Anything)
+
+	processor.addUser(users[2])
+	db.AssertExpectations(s.T())
+
+	// nothing left to flush
+	processor.flushUsers()
+	db.AssertNumberOfCalls(s.T(), "NewUsers", 1)
+}
+
+func (s *EventProcessorTestSuite) TestUsersKeptOnFlushFailure() {
+	users := []events.UserRegisteredEvent{
+		{ID: uuid.New(), Username: "user1"},
+		{ID: uuid.New(), Username: "user2"},
+		{ID: uuid.New(), Username: "user3"},
+	}
+
+	db := new(mockdb.MockGroupsDB)
+	db.On("NewUsers", users[:2]).Return(errors.New("database unavailable")).Once()
+	db.On("NewUsers", users).Return(nil).Once()
+
+	processor := NewEventProcessor(db, nil)
+	processor.UserBatchSize = 2
+
+	processor.addUser(users[0])
+	processor.addUser(users[1])
+	// saving is retried by the timer only
+	processor.addUser(users[2])
+	db.AssertNumberOfCalls(s.T(), "NewUsers", 1)
+
+	processor.flushUsers()
+	db.AssertExpectations(s.T())
+}
+
+func (s *EventProcessorTestSuite) TestRejectedUserDropped() {
+	users := []events.UserRegisteredEvent{
+		{ID: uuid.New(), Username: "user1"},
+		{ID: uuid.New(), Username: strings.Repeat("x", 300)},
+		{ID: uuid.New(), Username: "user3"},
+	}
+
+	db := new(mockdb.MockGroupsDB)
+	db.On("NewUsers", users).Return(errors.New("data too long for column username"))
+	db.On("NewUser", users[0]).Return(nil).Once()
+	db.On("NewUser", users[1]).Return(errors.New("data too long for column username")).Once()
+	db.On("NewUser", users[2]).Return(nil).Once()
+
+	processor := NewEventProcessor(db, nil)
+	processor.UserBatchSize = 3
+
+	for _, user := range users {
+		processor.addUser(user)
+	}
+	for i := 1; i < USER_FLUSH_ATTEMPTS; i++ {
+		processor.flushUsers()
+	}
+
+	db.AssertExpectations(s.T())
+	db.AssertNumberOfCalls(s.T(), "NewUsers", USER_FLUSH_ATTEMPTS)
+	s.Empty(processor.pendingUsers)
+}
+
+func (s *EventProcessorTestSuite) TestFlushBackoff() {
+	db := new(mockdb.MockGroupsDB)
+	db.On("NewUsers", mock.Anything).Return(errors.New("database unavailable"))
+	db.On("NewUser", mock.Anything).Return(errors.New("database unavailable"))
+
+	processor := NewEventProcessor(db, nil)
+	processor.UserFlushInterval = time.Second
+	processor.addUser(events.UserRegisteredEvent{ID: uuid.New(), Username: "user"})
+
+	s.Equal(time.Second, processor.flushDelay())
+	for _, expected := range []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 16 * time.Second} {
+		processor.flushUsers()
+		s.Equal(expected, processor.flushDelay())
+	}
+	// users are kept however long database is unavailable
+	s.Len(processor.pendingUsers, 1)
+}
+
+type fakeListener struct {
+	events chan msgqueue.Event
+}
+
+func (l fakeListener) Listen(...string) (<-chan msgqueue.Event, <-chan error, error) {
+	return l.events, nil, nil
+}
+
+func (s *EventProcessorTestSuite) TestStopSavesQueuedUsers() {
+	user := events.UserRegisteredEvent{ID: uuid.New(), Username: "user"}
+
+	db := new(mockdb.MockGroupsDB)
+	db.On("NewUsers", []events.UserRegisteredEvent{user}).Return(nil).Once()
+
+	listener := fakeListener{events: make(chan msgqueue.Event)}
+	processor := NewEventProcessor(db, listener)
+	processor.UserFlushInterval = time.Hour
+
+	go processor.ProcessEvents()
+	listener.events <- &user
+	processor.Stop()
+
+	db.AssertExpectations(s.T())
+}
+
+// Once queue is full no more events are consumed, so no user is lost while database is unavailable
+func (s *EventProcessorTestSuite) TestFullQueueStopsConsuming() {
+	var (
+		mu        sync.Mutex
+		recovered bool
+		saved     = make(map[uuid.UUID]bool)
+	)
+	save := func(users ...events.UserRegisteredEvent) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if !recovered {
+			return errors.New("database unavailable")
+		}
+		for _, user := range users {
+			saved[user.ID] = true
+		}
+		return nil
+	}
+	db := new(mockdb.MockGroupsDB)
+	db.On("NewUsers", mock.Anything).Return(func(users []events.UserRegisteredEvent) error { return save(users...) })
+	db.On("NewUser", mock.Anything).Return(func(user events.UserRegisteredEvent) error { return save(user) })
+
+	listener := fakeListener{events: make(chan msgqueue.Event)}
+	processor := NewEventProcessor(db, listener)
+	processor.UserBatchSize = 2
+	processor.UserFlushInterval = time.Millisecond
+	limit := MAX_PENDING_USER_BATCHES * processor.UserBatchSize
+
+	users := make([]events.UserRegisteredEvent, 3*limit)
+	for i := range users {
+		users[i] = events.UserRegisteredEvent{ID: uuid.New(), Username: "user"}
+	}
+
+	go processor.ProcessEvents()
+	var sent int32
+	done := make(chan struct{})
+	go func() {
+		for i := range users {
+			listener.events <- &users[i]
+			atomic.AddInt32(&sent, 1)
+		}
+		close(done)
+	}()
+
+	s.Eventually(func() bool { return atomic.LoadInt32(&sent) == int32(limit) }, time.Second, time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	s.Equal(int32(limit), atomic.LoadInt32(&sent))
+
+	mu.Lock()
+	recovered = true
+	mu.Unlock()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		s.FailNow("listener is still blocked after database recovered")
+	}
+	processor.Stop()
+
+	s.Len(saved, len(users))
+	for _, user := range users {
+		s.True(saved[user.ID])
+	}
+}
+
+func (s *EventProcessorTestSuite) TestPanickingEventRecovered() {
+	broken := events.UserPictureModifiedEvent{ID: uuid.New(), PictureURL: "broken"}
+	fine := events.UserPictureModifiedEvent{ID: uuid.New(), PictureURL: "fine"}
+
+	db := new(mockdb.MockGroupsDB)
+	db.On("UpdateUserProfilePictureURL", broken).Run(func(mock.Arguments) {
+		panic("nil pointer dereference")
+	}).Once()
+	db.On("UpdateUserProfilePictureURL", fine).Return(nil).Once()
+
+	processor := NewEventProcessor(db, nil)
+
+	s.NotPanics(func() { processor.handleEvent(&broken) })
+	// processor keeps handling events after a panic
+	s.NotPanics(func() { processor.handleEvent(&fine) })
+
+	db.AssertExpectations(s.T())
+}
+
+func (s *EventProcessorTestSuite) TestUnknownGroupSkipped() {
+	unknownGroup, groupID, userID := uuid.New(), uuid.New(), uuid.New()
+	posted := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	orphaned := events.MessageSentEvent{GroupID: unknownGroup, UserID: userID, Posted: posted}
+	fine := events.MessageSentEvent{GroupID: groupID, UserID: userID, Posted: posted}
+
+	db := new(mockdb.MockGroupsDB)
+	db.On("TouchMemberActivity", unknownGroup, userID, posted).Return(apperrors.NewNotFound("group", unknownGroup.String())).Once()
+	db.On("TouchMemberActivity", groupID, userID, posted).Return(nil).Once()
+
+	processor := NewEventProcessor(db, nil)
+	processor.LogUnknownGroups = true
+
+	skipped := unknownGroupEvents.WithLabelValues(orphaned.EventName()).Value()
+	s.NotPanics(func() { processor.handleEvent(&orphaned) })
+	s.Equal(skipped+1, unknownGroupEvents.WithLabelValues(orphaned.EventName()).Value())
+
+	// processor proceeds with the next event
+	s.NotPanics(func() { processor.handleEvent(&fine) })
+	s.Equal(skipped+1, unknownGroupEvents.WithLabelValues(orphaned.EventName()).Value())
+
+	db.AssertExpectations(s.T())
+}
+
+func (s *EventProcessorTestSuite) TestCheckEventTypes() {
+	s.NoError(CheckEventTypes(
+		reflect.TypeOf(events.MessageSentEvent{}),
+		reflect.TypeOf(events.UserRegisteredEvent{}),
+		reflect.TypeOf(events.UserPictureModifiedEvent{}),
+	))
+
+	s.EqualError(CheckEventTypes(
+		reflect.TypeOf(events.UserRegisteredEvent{}),
+		reflect.TypeOf(events.MessageSentEvent{}),
+	), "event types mismatch, not registered: users.picturemodified")
+
+	s.EqualError(CheckEventTypes(
+		reflect.TypeOf(events.UserRegisteredEvent{}),
+		reflect.TypeOf(events.UserPictureModifiedEvent{}),
+		reflect.TypeOf(events.MessageSentEvent{}),
+		reflect.TypeOf(events.GroupDeletedEvent{}),
+	), "event types mismatch, not handled: groups.deleted")
+
+	s.Error(CheckEventTypes(reflect.TypeOf(uuid.UUID{})))
+}
+
+func TestEventProcessorSuite(t *testing.T) {
+	suite.Run(t, &EventProcessorTestSuite{})
+}
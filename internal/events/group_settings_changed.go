@@ -0,0 +1,17 @@
+package events
+
+import (
+	"github.com/google/uuid"
+)
+
+// GroupSettingsChangedEvent holds current settings of a group after one of them was changed
+type GroupSettingsChangedEvent struct {
+	GroupID         uuid.UUID `json:"groupID" mapstructure:"groupID"`
+	IsAnnouncement  bool      `json:"isAnnouncement" mapstructure:"isAnnouncement"`
+	SlowModeSeconds int       `json:"slowModeSeconds" mapstructure:"slowModeSeconds"`
+}
+
+// EventName method from Event interface
+func (GroupSettingsChangedEvent) EventName() string {
+	return "groups.settingschanged"
+}
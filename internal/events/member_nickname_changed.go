@@ -0,0 +1,19 @@
+package events
+
+import (
+	"github.com/google/uuid"
+)
+
+// MemberNicknameChangedEvent holds information about member's nickname in a group being changed,
+// empty Nickname means that nickname was reset and username should be displayed instead
+type MemberNicknameChangedEvent struct {
+	ID       uuid.UUID `json:"ID" mapstructure:"ID"`
+	GroupID  uuid.UUID `json:"groupID" mapstructure:"groupID"`
+	UserID   uuid.UUID `json:"userID" mapstructure:"userID"`
+	Nickname string    `json:"nickname" mapstructure:"nickname"`
+}
+
+// EventName method from Event interface
+func (MemberNicknameChangedEvent) EventName() string {
+	return "groups.membernicknamechanged"
+}
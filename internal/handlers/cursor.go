@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Slimo300/chat-groupservice/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// NEXT_CURSOR_HEADER carries cursor of the next page of a sorted list, it's set only when page was full
+const NEXT_CURSOR_HEADER = "X-Next-Cursor"
+
+var (
+	errInvalidCursor    = errors.New("invalid cursor")
+	errCursorSort       = errors.New("cursor doesn't match sort")
+	errCursorWithOffset = errors.New("cursor can't be combined with offset")
+)
+
+// parseCursor reads "cursor" query parameter holding position after which page of a list sorted with sort starts.
+// Cursors are opaque to clients, they're taken from NEXT_CURSOR_HEADER of previous page. Nil is returned when
+// cursor isn't set
+func parseCursor(r *http.Request, sort models.Sort, page pagination) (*models.Cursor, error) {
+	value := r.URL.Query().Get("cursor")
+	if value == "" {
+		return nil, nil
+	}
+	if page.Offset != 0 {
+		return nil, errCursorWithOffset
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	var cursor models.Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == uuid.Nil {
+		return nil, errInvalidCursor
+	}
+	if cursor.Sort != sort {
+		return nil, errCursorSort
+	}
+	// only activity may be missing, other times are always set
+	if !sort.ByName() && sort != models.SORT_ACTIVITY_DESC && cursor.Time == nil {
+		return nil, errInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// setNextCursor tells client where next page of a list starts
+func setNextCursor(c *gin.Context, cursor models.Cursor) {
+	data, _ := json.Marshal(cursor)
+	c.Header(NEXT_CURSOR_HEADER, base64.RawURLEncoding.EncodeToString(data))
+}
+
+// groupCursor returns position of group in a list sorted with sort
+func groupCursor(sort models.Sort, group models.Group) models.Cursor {
+	cursor := models.Cursor{Sort: sort, ID: group.ID}
+	switch sort {
+	case models.SORT_NAME_ASC, models.SORT_NAME_DESC:
+		cursor.Name = group.Name
+	case models.SORT_UPDATED_DESC:
+		cursor.Time = &group.UpdatedAt
+	default:
+		cursor.Time = &group.Created
+	}
+	return cursor
+}
+
+// memberCursor returns position of member in a list sorted with sort
+func memberCursor(sort models.Sort, member models.Member) models.Cursor {
+	cursor := models.Cursor{Sort: sort, ID: member.ID}
+	switch sort {
+	case models.SORT_NAME_ASC, models.SORT_NAME_DESC:
+		cursor.Name = member.DisplayName
+	case models.SORT_ACTIVITY_DESC:
+		cursor.Time = member.LastActive
+	default:
+		cursor.Time = &member.Joined
+	}
+	return cursor
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"log"
+
+	"github.com/Slimo300/MicroservicesChatApp/backend/lib/msgqueue"
+	"github.com/Slimo300/chat-groupservice/internal/metrics"
+)
+
+var (
+	emittedEvents = metrics.NewCounterVec("groupservice_emitted_events_total",
+		"Number of emitted events by name, mode (sync or async) and result", "event", "mode", "result")
+	pendingEmitsGauge = metrics.NewGauge("groupservice_pending_emits",
+		"Number of events still being emitted in background")
+)
+
+// emitSync emits event and waits for the result. It's used for lifecycle events (members joining and leaving,
+// rights being granted or revoked, groups being deleted) other services must not miss, so request fails when
+// emitting them fails
+func (s *Server) emitSync(event msgqueue.Event) error {
+	err := s.Emitter.Emit(event)
+	observeEmit(event, "sync", err)
+	return err
+}
+
+// emitAsync emits event in background without delaying response, failure is only logged. It's used for events
+// describing changes clients can live without until next refresh
+func (s *Server) emitAsync(event msgqueue.Event) {
+	s.pendingEmits.Add(1)
+	pendingEmitsGauge.Add(1)
+	go func() {
+		defer s.pendingEmits.Done()
+		defer pendingEmitsGauge.Add(-1)
+		err := s.Emitter.Emit(event)
+		observeEmit(event, "async", err)
+		if err != nil {
+			log.Printf("Couldn't emit %s event: %v", event.EventName(), err)
+		}
+	}()
+}
+
+func observeEmit(event msgqueue.Event, mode string, err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	emittedEvents.WithLabelValues(event.EventName(), mode, result).Inc()
+}
+
+// WaitForEmits blocks until all events emitted in background are sent, it should be called on shutdown after
+// server stopped handling requests
+func (s *Server) WaitForEmits() {
+	s.pendingEmits.Wait()
+}
@@ -0,0 +1,153 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Slimo300/MicroservicesChatApp/backend/lib/events"
+	mockqueue "github.com/Slimo300/MicroservicesChatApp/backend/lib/msgqueue/mock"
+	mockdb "github.com/Slimo300/chat-groupservice/internal/database/mock"
+	"github.com/Slimo300/chat-groupservice/internal/handlers"
+	"github.com/Slimo300/chat-groupservice/internal/metrics"
+	"github.com/Slimo300/chat-groupservice/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+// logWriter passes every log line to a channel so test can wait for it
+type logWriter chan string
+
+func (w logWriter) Write(p []byte) (int, error) {
+	w <- string(p)
+	return len(p), nil
+}
+
+type EmitTestSuite struct {
+	suite.Suite
+	userID   uuid.UUID
+	groupID  uuid.UUID
+	memberID uuid.UUID
+	server   *handlers.Server
+}
+
+func (s *EmitTestSuite) SetupSuite() {
+	s.userID = uuid.MustParse("2c4e6a8c-0e2a-4c6e-8a0c-2e4a6c8e0a2c")
+	s.groupID = uuid.MustParse("6a8c0e2a-4c6e-4a0c-9e4a-6c8e0a2c4e6a")
+	s.memberID = uuid.MustParse("8c0e2a4c-6e8a-4c2e-8a6c-0e2a4c6e8a0c")
+
+	db := new(mockdb.MockGroupsDB)
+	db.On("DeleteGroup", s.userID, s.groupID).Return(models.Group{ID: s.groupID}, nil)
+	db.On("UpdateGroupSettings", s.userID, s.groupID, mock.Anything).Return(models.Group{ID: s.groupID, Announcement: true}, nil)
+	db.On("StepDown", s.userID, s.groupID).Return(&models.Member{GroupID: s.groupID, UserID: s.userID}, nil)
+	db.On("GrantRights", s.userID, s.groupID, s.memberID, mock.Anything).Return(&models.Member{ID: s.memberID, GroupID: s.groupID}, nil)
+
+	// emitter is down, every emit fails
+	emiter := new(mockqueue.MockEmitter)
+	emiter.On("Emit", mock.Anything).Return(errors.New("broker unavailable"))
+
+	s.server = handlers.NewServer(db, nil, nil, emiter)
+}
+
+func (s *EmitTestSuite) serve(method, path, route string, handler gin.HandlerFunc, body []byte) *http.Response {
+	req, _ := http.NewRequest(method, path, bytes.NewBuffer(body))
+
+	w := httptest.NewRecorder()
+	_, engine := gin.CreateTestContext(w)
+	engine.Use(func(c *gin.Context) {
+		c.Set("userID", s.userID.String())
+	})
+	engine.Handle(method, route, handler)
+	engine.ServeHTTP(w, req)
+
+	return w.Result()
+}
+
+// Failure of emitting lifecycle event fails the request
+func (s *EmitTestSuite) TestSyncEmitFailure() {
+	gin.SetMode(gin.TestMode)
+
+	response := s.serve(http.MethodDelete, "/api/group/"+s.groupID.String(), "/api/group/:groupID", s.server.DeleteGroup, nil)
+	defer response.Body.Close()
+
+	s.Equal(http.StatusInternalServerError, response.StatusCode)
+
+	var msg gin.H
+	if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+		s.Fail(err.Error())
+	}
+	s.Equal(gin.H{"err": "broker unavailable"}, msg)
+}
+
+// Other services must learn about revoked rights, so failing to emit rights change fails the request too
+func (s *EmitTestSuite) TestRightsChangeEmitFailure() {
+	gin.SetMode(gin.TestMode)
+
+	body, _ := json.Marshal(map[string]interface{}{"admin": -1})
+	responses := []*http.Response{
+		s.serve(http.MethodPost, "/api/group/"+s.groupID.String()+"/stepdown", "/api/group/:groupID/stepdown", s.server.StepDown, nil),
+		s.serve(http.MethodPatch, "/api/group/"+s.groupID.String()+"/member/"+s.memberID.String(), "/api/group/:groupID/member/:memberID", s.server.GrantPriv, body),
+	}
+	for _, response := range responses {
+		defer response.Body.Close()
+		s.Equal(http.StatusInternalServerError, response.StatusCode)
+
+		var msg gin.H
+		if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+			s.Fail(err.Error())
+		}
+		s.Equal(gin.H{"err": "broker unavailable"}, msg)
+	}
+}
+
+// Failure of emitting event in background is only logged
+func (s *EmitTestSuite) TestAsyncEmitFailure() {
+	gin.SetMode(gin.TestMode)
+
+	logs := make(logWriter, 1)
+	log.SetOutput(logs)
+	defer log.SetOutput(os.Stderr)
+
+	body, _ := json.Marshal(map[string]interface{}{"isAnnouncement": true})
+	response := s.serve(http.MethodPatch, "/api/group/"+s.groupID.String()+"/settings", "/api/group/:groupID/settings", s.server.UpdateGroupSettings, body)
+	defer response.Body.Close()
+
+	s.Equal(http.StatusOK, response.StatusCode)
+
+	select {
+	case line := <-logs:
+		s.True(strings.Contains(line, "broker unavailable"), line)
+	case <-time.After(time.Second):
+		s.Fail("emit failure wasn't logged")
+	}
+	s.server.WaitForEmits()
+}
+
+// Results of emits are exported as metrics
+func (s *EmitTestSuite) TestEmitMetrics() {
+	gin.SetMode(gin.TestMode)
+
+	response := s.serve(http.MethodDelete, "/api/group/"+s.groupID.String(), "/api/group/:groupID", s.server.DeleteGroup, nil)
+	response.Body.Close()
+
+	w := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	expected := fmt.Sprintf(`groupservice_emitted_events_total{event="%s",mode="sync",result="failure"}`, events.GroupDeletedEvent{}.EventName())
+	s.Contains(w.Body.String(), expected)
+	s.Contains(w.Body.String(), "groupservice_pending_emits")
+}
+
+func TestEmitSuite(t *testing.T) {
+	suite.Run(t, new(EmitTestSuite))
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Slimo300/MicroservicesChatApp/backend/lib/apperrors"
+	"github.com/Slimo300/chat-groupservice/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const EXPORT_SCHEMA_VERSION = 1
+
+func (s *Server) ExportGroup(c *gin.Context) {
+	userID := c.GetString("userID")
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid ID"})
+		return
+	}
+	groupID := c.Param("groupID")
+	groupUUID, err := uuid.Parse(groupID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid group ID"})
+		return
+	}
+
+	encoder := &groupExportEncoder{
+		w:           c.Writer,
+		generatedAt: time.Now(),
+		section:     -1,
+		onStart: func() {
+			c.Header("Content-Type", "application/json")
+			c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="group-%s.json"`, groupUUID))
+		},
+	}
+
+	if err := s.requestDB(c).ExportGroup(userUUID, groupUUID, encoder); err != nil {
+		// when document has already been partially sent the only thing left is to cut it short
+		if !encoder.started {
+			c.JSON(apperrors.Status(err), gin.H{"err": err.Error()})
+		}
+		return
+	}
+	_ = encoder.finish()
+}
+
+var exportSections = []string{"members", "invites"}
+
+// groupExportEncoder writes exported group as a single JSON document directly to w,
+// only a single batch of members or invites is held in memory at a time
+type groupExportEncoder struct {
+	w           io.Writer
+	generatedAt time.Time
+	onStart     func()
+
+	started bool
+	section int
+	first   bool
+}
+
+func (e *groupExportEncoder) WriteGroup(group models.Group) error {
+	e.onStart()
+	e.started = true
+
+	generatedAt, err := json.Marshal(e.generatedAt)
+	if err != nil {
+		return err
+	}
+	groupJSON, err := json.Marshal(group)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(e.w, `{"schemaVersion":%d,"generatedAt":%s,"group":%s`, EXPORT_SCHEMA_VERSION, generatedAt, groupJSON)
+	return err
+}
+
+func (e *groupExportEncoder) WriteMembers(members []models.Member) error {
+	items := make([]interface{}, len(members))
+	for i := range members {
+		items[i] = members[i]
+	}
+	return e.writeItems(0, items)
+}
+
+func (e *groupExportEncoder) WriteInvites(invites []models.Invite) error {
+	items := make([]interface{}, len(invites))
+	for i := range invites {
+		items[i] = invites[i]
+	}
+	return e.writeItems(1, items)
+}
+
+// enter closes current section and opens following ones up to section, sections without any items
+// are written as empty arrays
+func (e *groupExportEncoder) enter(section int) error {
+	for e.section < section {
+		if e.section >= 0 {
+			if _, err := io.WriteString(e.w, "]"); err != nil {
+				return err
+			}
+		}
+		e.section++
+		if _, err := fmt.Fprintf(e.w, `,"%s":[`, exportSections[e.section]); err != nil {
+			return err
+		}
+		e.first = true
+	}
+	return nil
+}
+
+func (e *groupExportEncoder) writeItems(section int, items []interface{}) error {
+	if err := e.enter(section); err != nil {
+		return err
+	}
+	for _, item := range items {
+		if !e.first {
+			if _, err := io.WriteString(e.w, ","); err != nil {
+				return err
+			}
+		}
+		e.first = false
+
+		data, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		if _, err := e.w.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *groupExportEncoder) finish() error {
+	if err := e.enter(len(exportSections) - 1); err != nil {
+		return err
+	}
+	_, err := io.WriteString(e.w, "]}")
+	return err
+}
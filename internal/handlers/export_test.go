@@ -0,0 +1,135 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Slimo300/MicroservicesChatApp/backend/lib/apperrors"
+	"github.com/Slimo300/chat-groupservice/internal/database"
+	mockdb "github.com/Slimo300/chat-groupservice/internal/database/mock"
+	"github.com/Slimo300/chat-groupservice/internal/handlers"
+	"github.com/Slimo300/chat-groupservice/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type ExportTestSuite struct {
+	suite.Suite
+	IDs    map[string]uuid.UUID
+	server *handlers.Server
+}
+
+func (s *ExportTestSuite) SetupSuite() {
+	s.IDs = make(map[string]uuid.UUID)
+	s.IDs["userOK"] = uuid.MustParse("1c4dccaf-a341-4920-9003-f24e0412f8e0")
+	s.IDs["userNoRights"] = uuid.MustParse("634240cf-1219-4be2-adfa-90ab6b47899b")
+	s.IDs["group"] = uuid.MustParse("61fbd273-b941-471c-983a-0a3cd2c74747")
+
+	db := new(mockdb.MockGroupsDB)
+	db.On("ExportGroup", s.IDs["userOK"], s.IDs["group"], mock.Anything).Run(func(args mock.Arguments) {
+		exporter := args.Get(2).(database.GroupExporter)
+		_ = exporter.WriteGroup(models.Group{ID: s.IDs["group"], Name: "group"})
+		_ = exporter.WriteMembers([]models.Member{{UserID: s.IDs["userOK"], Creator: true}, {Admin: true}})
+		_ = exporter.WriteMembers([]models.Member{{Adding: true}})
+	}).Return(nil)
+	db.On("ExportGroup", s.IDs["userNoRights"], s.IDs["group"], mock.Anything).Return(apperrors.NewForbidden("User has no right to export group"))
+
+	s.server = handlers.NewServer(db, nil, nil, nil)
+}
+
+func (s *ExportTestSuite) TestExportGroup() {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		desc               string
+		userID             string
+		groupID            string
+		expectedStatusCode int
+		expectedResponse   gin.H
+	}{
+		{
+			desc:               "ExportGroupInvalidGroupID",
+			userID:             s.IDs["userOK"].String(),
+			groupID:            "1",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "invalid group ID"},
+		},
+		{
+			desc:               "ExportGroupNoRights",
+			userID:             s.IDs["userNoRights"].String(),
+			groupID:            s.IDs["group"].String(),
+			expectedStatusCode: http.StatusForbidden,
+			expectedResponse:   gin.H{"err": "Forbidden action. Reason: User has no right to export group"},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+			req, _ := http.NewRequest("GET", "/api/group/"+tC.groupID+"/export", nil)
+
+			w := httptest.NewRecorder()
+			_, engine := gin.CreateTestContext(w)
+
+			engine.Use(func(c *gin.Context) {
+				c.Set("userID", tC.userID)
+			})
+			engine.Handle(http.MethodGet, "/api/group/:groupID/export", s.server.ExportGroup)
+			engine.ServeHTTP(w, req)
+			response := w.Result()
+			defer response.Body.Close()
+
+			s.Equal(tC.expectedStatusCode, response.StatusCode)
+
+			var msg gin.H
+			if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+				s.Fail(err.Error())
+			}
+			s.Equal(tC.expectedResponse, msg)
+		})
+	}
+}
+
+func (s *ExportTestSuite) TestExportGroupDocument() {
+	gin.SetMode(gin.TestMode)
+
+	req, _ := http.NewRequest("GET", "/api/group/"+s.IDs["group"].String()+"/export", nil)
+
+	w := httptest.NewRecorder()
+	_, engine := gin.CreateTestContext(w)
+
+	engine.Use(func(c *gin.Context) {
+		c.Set("userID", s.IDs["userOK"].String())
+	})
+	engine.Handle(http.MethodGet, "/api/group/:groupID/export", s.server.ExportGroup)
+	engine.ServeHTTP(w, req)
+	response := w.Result()
+	defer response.Body.Close()
+
+	s.Equal(http.StatusOK, response.StatusCode)
+	s.Equal(`attachment; filename="group-`+s.IDs["group"].String()+`.json"`, response.Header.Get("Content-Disposition"))
+
+	var document struct {
+		SchemaVersion int             `json:"schemaVersion"`
+		GeneratedAt   string          `json:"generatedAt"`
+		Group         models.Group    `json:"group"`
+		Members       []models.Member `json:"members"`
+		Invites       []models.Invite `json:"invites"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&document); err != nil {
+		s.Fail(err.Error())
+	}
+
+	s.Equal(handlers.EXPORT_SCHEMA_VERSION, document.SchemaVersion)
+	s.NotEmpty(document.GeneratedAt)
+	s.Equal("group", document.Group.Name)
+	s.Equal([]models.Member{{UserID: s.IDs["userOK"], Creator: true}, {Admin: true}, {Adding: true}}, document.Members)
+	s.Equal([]models.Invite{}, document.Invites)
+}
+
+func TestExportSuite(t *testing.T) {
+	suite.Run(t, &ExportTestSuite{})
+}
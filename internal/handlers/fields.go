@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// fields of groups and members clients can select with "fields" query parameter, nothing outside of these lists
+// is ever returned in a projection
+var (
+	groupFields  = []string{"ID", "name", "pictureUrl", "created", "isAnnouncement", "slowModeSeconds", "updatedAt"}
+	memberFields = []string{"ID", "groupID", "userID", "User", "adding", "deletingMembers", "deletingMessages", "admin",
+		"coOwner", "creator", "joined", "lastActive", "nickname", "displayName"}
+)
+
+// parseFields reads comma separated "fields" query parameter and returns those of selected fields that are allowed,
+// unknown ones are ignored. Nil is returned when parameter isn't given which means that whole object is wanted
+func parseFields(r *http.Request, allowed []string) []string {
+	query := r.URL.Query()
+	if !query.Has("fields") {
+		return nil
+	}
+
+	known := make(map[string]bool, len(allowed))
+	for _, field := range allowed {
+		known[field] = true
+	}
+	selected := []string{}
+	for _, field := range strings.Split(query.Get("fields"), ",") {
+		field = strings.TrimSpace(field)
+		if known[field] {
+			known[field] = false
+			selected = append(selected, field)
+		}
+	}
+	return selected
+}
+
+// project returns JSON representation of v limited to given fields, v is returned unchanged when fields are nil
+func project(v interface{}, fields []string) (interface{}, error) {
+	if fields == nil {
+		return v, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var full map[string]json.RawMessage
+	if err := json.Unmarshal(data, &full); err != nil {
+		return nil, err
+	}
+
+	projection := make(map[string]json.RawMessage, len(fields))
+	for _, field := range fields {
+		if value, ok := full[field]; ok {
+			projection[field] = value
+		}
+	}
+	return projection, nil
+}
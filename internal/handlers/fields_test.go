@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type FieldsTestSuite struct {
+	suite.Suite
+}
+
+func (s *FieldsTestSuite) TestParseFields() {
+	testCases := []struct {
+		desc     string
+		query    string
+		expected []string
+	}{
+		{
+			desc:     "FieldsOmitted",
+			query:    "",
+			expected: nil,
+		},
+		{
+			desc:     "FieldsValid",
+			query:    "?fields=ID,name",
+			expected: []string{"ID", "name"},
+		},
+		{
+			desc:     "FieldsPartiallyUnknown",
+			query:    "?fields=name,%20deleted_at,ID,name",
+			expected: []string{"name", "ID"},
+		},
+		{
+			desc:     "FieldsUnknown",
+			query:    "?fields=Members,password",
+			expected: []string{},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+			req := httptest.NewRequest("GET", "/"+tC.query, nil)
+			s.Equal(tC.expected, parseFields(req, groupFields))
+		})
+	}
+}
+
+func (s *FieldsTestSuite) TestProject() {
+	value := struct {
+		ID   int    `json:"ID"`
+		Name string `json:"name"`
+	}{ID: 1, Name: "group"}
+
+	full, err := project(value, nil)
+	s.NoError(err)
+	s.Equal(value, full)
+
+	partial, err := project(value, []string{"name"})
+	s.NoError(err)
+	data, _ := json.Marshal(partial)
+	s.JSONEq(`{"name":"group"}`, string(data))
+}
+
+func TestFields(t *testing.T) {
+	suite.Run(t, &FieldsTestSuite{})
+}
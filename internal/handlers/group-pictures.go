@@ -1,9 +1,17 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"mime/multipart"
 	"net/http"
 
 	"github.com/Slimo300/MicroservicesChatApp/backend/lib/apperrors"
+	"github.com/Slimo300/chat-groupservice/internal/storage"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
@@ -22,8 +30,19 @@ func (s *Server) SetGroupProfilePicture(c *gin.Context) {
 		return
 	}
 
+	if !s.canSetPicture(c, userUID, groupUID) {
+		return
+	}
+
+	// whole form is read before anything is uploaded, so streamed bodies cut at the size limit never reach storage
 	imageFileHeader, err := c.FormFile("avatarFile")
 	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.Header("Connection", "close")
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"err": fmt.Sprintf("Max payload size of %v exceeded", maxBytesErr.Limit)})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
 		return
 	}
@@ -34,25 +53,161 @@ func (s *Server) SetGroupProfilePicture(c *gin.Context) {
 		return
 	}
 
+	crop, err := parseCropRect(c.PostForm)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+		return
+	}
+
 	file, err := imageFileHeader.Open()
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"err": "bad image"})
 		return
 	}
+	defer file.Close()
+
+	s.setGroupPicture(c, file, imageFileHeader.Size, mimeType, crop, userUID, groupUID)
+}
 
-	pictureURL, err := s.DB.GetGroupProfilePictureURL(userUID, groupUID)
+// canSetPicture checks rights before image is processed and uploaded, they are checked again when picture is set.
+// It responds with an error itself when user can't set group's picture
+func (s *Server) canSetPicture(c *gin.Context, userUID, groupUID uuid.UUID) bool {
+	member, err := s.requestDB(c).FindMember(groupUID, userUID)
 	if err != nil {
 		c.JSON(apperrors.Status(err), gin.H{"err": err.Error()})
+		return false
+	}
+	if member == nil || !member.CanEditGroup() {
+		err := apperrors.NewForbidden(fmt.Sprintf("User %v has no rights to set in group %v", userUID, groupUID))
+		c.JSON(apperrors.Status(err), gin.H{"err": err.Error()})
+		return false
 	}
+	return true
+}
 
-	if err = s.Storage.UploadFile(file, pictureURL); err != nil {
+// setGroupPicture validates, moderates and stores image which then becomes group's picture
+func (s *Server) setGroupPicture(c *gin.Context, file multipart.File, size int64, mimeType string, crop *image.Rectangle, userUID, groupUID uuid.UUID) {
+	// requests wait for a free slot until request timeout passes
+	if err := s.imageOps.acquire(c.Request.Context()); err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"err": "too many images being processed, try again later"})
+		return
+	}
+	upload, size, err := processImage(file, size, s.MaxImageDimension, crop)
+	s.imageOps.release()
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, errImageTooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		c.JSON(status, gin.H{"err": err.Error()})
+		return
+	}
+
+	if status, err := s.moderateImage(c.Request.Context(), upload, mimeType, userUID, groupUID); err != nil {
+		c.JSON(status, gin.H{"err": err.Error()})
+		return
+	}
+
+	// key changes together with content so clients can cache pictures for a long time, identical pictures of
+	// different groups share one object
+	pictureURL, err := storage.ContentKey(s.KeyPrefix, upload)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"err": err.Error()})
+		return
+	}
+	if _, err := upload.Seek(0, io.SeekStart); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"err": err.Error()})
 		return
 	}
 
+	// picture is uploaded while its key is locked so that it can't be deleted by group that stops using it
+	// before this group starts to
+	previousURL, err := s.requestDB(c).SetGroupProfilePicture(userUID, groupUID, pictureURL, func() error {
+		return s.uploadFile(c.Request.Context(), upload, pictureURL, mimeType, size)
+	})
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{"err": err.Error()})
+		return
+	}
+
+	// failing to delete previous picture only leaves an orphaned object
+	_ = s.releasePicture(c, previousURL)
+
 	c.JSON(http.StatusOK, gin.H{"newUrl": pictureURL})
 }
 
+// moderateImage screens picture when moderation is configured and rewinds it afterwards. Flagged pictures are
+// rejected, pictures that couldn't be screened are rejected only when moderation fails closed
+func (s *Server) moderateImage(ctx context.Context, upload io.ReadSeeker, contentType string, userID, groupID uuid.UUID) (int, error) {
+	if s.Moderator == nil {
+		return 0, nil
+	}
+
+	verdict, err := s.Moderator.Check(ctx, upload, contentType)
+	if _, seekErr := upload.Seek(0, io.SeekStart); seekErr != nil {
+		return http.StatusInternalServerError, seekErr
+	}
+	if err != nil {
+		if !s.ModerationFailOpen {
+			log.Printf("Couldn't moderate picture of group %v, rejecting it: %v", groupID, err)
+			return http.StatusServiceUnavailable, errors.New("image moderation unavailable, try again later")
+		}
+		log.Printf("Couldn't moderate picture of group %v, accepting it: %v", groupID, err)
+		return 0, nil
+	}
+	if verdict.Flagged {
+		log.Printf("Picture uploaded by user %v to group %v rejected by moderation: %s", userID, groupID, verdict.Reason)
+		return http.StatusUnprocessableEntity, errors.New("image rejected by moderation")
+	}
+	return 0, nil
+}
+
+// GetGroupAvatar streams group's profile picture from storage for clients that can't reach storage directly
+func (s *Server) GetGroupAvatar(c *gin.Context) {
+	userID := c.GetString("userID")
+	userUID, err := uuid.Parse(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid ID"})
+		return
+	}
+	groupID := c.Param("groupID")
+	groupUID, err := uuid.Parse(groupID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid group ID"})
+		return
+	}
+
+	pictureKey, err := s.requestDB(c).GetGroupPicture(userUID, groupUID)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{"err": err.Error()})
+		return
+	}
+
+	// malformed header is ignored just like in net/http
+	modifiedSince, _ := http.ParseTime(c.GetHeader("If-Modified-Since"))
+
+	file, err := s.getFile(c.Request.Context(), pictureKey, modifiedSince)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotModified):
+			c.Status(http.StatusNotModified)
+		case errors.Is(err, storage.ErrFileNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"err": "group picture not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"err": err.Error()})
+		}
+		return
+	}
+	defer file.Body.Close()
+
+	// picture key changes with every upload so response under given key never changes
+	headers := map[string]string{"Cache-Control": fmt.Sprintf("private, max-age=%d, immutable", int(s.AvatarMaxAge.Seconds()))}
+	if !file.LastModified.IsZero() {
+		headers["Last-Modified"] = file.LastModified.UTC().Format(http.TimeFormat)
+	}
+	c.DataFromReader(http.StatusOK, file.ContentLength, file.ContentType, file.Body, headers)
+}
+
 func (s *Server) DeleteGroupProfilePicture(c *gin.Context) {
 	userID := c.GetString("userID")
 	userUID, err := uuid.Parse(userID)
@@ -67,16 +222,26 @@ func (s *Server) DeleteGroupProfilePicture(c *gin.Context) {
 		return
 	}
 
-	pictureURL, err := s.DB.DeleteGroupProfilePicture(userUID, groupUID)
+	pictureURL, err := s.requestDB(c).DeleteGroupProfilePicture(userUID, groupUID)
 	if err != nil {
 		c.JSON(apperrors.Status(err), gin.H{"err": err.Error()})
 		return
 	}
 
-	if err = s.Storage.DeleteFile(pictureURL); err != nil {
+	if err = s.releasePicture(c, pictureURL); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"err": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "success"})
 }
+
+// releasePicture deletes picture with given key from storage unless some group still uses it
+func (s *Server) releasePicture(c *gin.Context, key string) error {
+	if key == "" {
+		return nil
+	}
+	return s.requestDB(c).ReleasePicture(key, func() error {
+		return s.deleteFile(c.Request.Context(), key)
+	})
+}
@@ -2,16 +2,26 @@ package handlers_test
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/Slimo300/MicroservicesChatApp/backend/lib/apperrors"
 	dbmock "github.com/Slimo300/chat-groupservice/internal/database/mock"
 	"github.com/Slimo300/chat-groupservice/internal/handlers"
+	"github.com/Slimo300/chat-groupservice/internal/models"
+	"github.com/Slimo300/chat-groupservice/internal/moderation"
+	"github.com/Slimo300/chat-groupservice/internal/routes"
 	"github.com/Slimo300/chat-groupservice/internal/storage"
-	limits "github.com/gin-contrib/size"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/mock"
@@ -41,16 +51,158 @@ func (s *GroupPicturesTestSuite) SetupSuite() {
 	db.On("DeleteGroupProfilePicture", s.IDs["userOK"], s.IDs["groupWithoutPicture"]).
 		Return("", apperrors.NewForbidden(fmt.Sprintf("group %v has no profile picture", s.IDs["groupWithoutPicture"])))
 
-	db.On("GetGroupProfilePictureURL", s.IDs["userOK"], s.IDs["groupOK"]).Return("picture_url", nil)
-	db.On("GetGroupProfilePictureURL", s.IDs["userWithoutRights"], s.IDs["groupOK"]).
-		Return("", apperrors.NewForbidden(fmt.Sprintf("User %v has no rights to set in group %v", s.IDs["userWithoutRights"], s.IDs["groupOK"])))
+	s.IDs["groupSharedPicture"] = uuid.MustParse("b7d9f1a3-5c7e-4a9b-8d1f-3a5c7e9b1d3f")
+	db.On("FindMember", s.IDs["groupOK"], s.IDs["userOK"]).Return(&models.Member{Admin: true}, nil)
+	db.On("FindMember", s.IDs["groupSharedPicture"], s.IDs["userOK"]).Return(&models.Member{Admin: true}, nil)
+	db.On("FindMember", s.IDs["groupOK"], s.IDs["userWithoutRights"]).Return(&models.Member{}, nil)
+	db.On("SetGroupProfilePicture", s.IDs["userOK"], s.IDs["groupOK"], mock.Anything, mock.Anything).
+		Return("old_picture_url", uploadPicture)
+	db.On("SetGroupProfilePicture", s.IDs["userOK"], s.IDs["groupSharedPicture"], mock.Anything, mock.Anything).
+		Return("shared_picture_url", uploadPicture)
+	// this picture is still used by another group
+	db.On("ReleasePicture", "shared_picture_url", mock.Anything).Return(nil)
+	db.On("ReleasePicture", mock.Anything, mock.Anything).Return(func(_ string, remove func() error) error {
+		return remove()
+	})
+
+	s.IDs["groupSharedDeleted"] = uuid.MustParse("d3f5b7d9-1b3d-4f5b-9d9f-1b3d5f7b9d1f")
+	db.On("DeleteGroupProfilePicture", s.IDs["userOK"], s.IDs["groupSharedDeleted"]).Return("shared_picture_url", nil)
+
+	s.IDs["groupMissingFile"] = uuid.MustParse("e1bd5a0c-1b09-4bde-8a35-3e7e1ac2a0a4")
+	db.On("GetGroupPicture", s.IDs["userOK"], s.IDs["groupOK"]).Return("picture_url", nil)
+	db.On("GetGroupPicture", s.IDs["userOK"], s.IDs["groupMissingFile"]).Return("missing_picture_url", nil)
+	db.On("GetGroupPicture", s.IDs["userOK"], s.IDs["groupWithoutPicture"]).
+		Return("", apperrors.NewNotFound("group picture", s.IDs["groupWithoutPicture"].String()))
+	db.On("GetGroupPicture", s.IDs["userWithoutRights"], s.IDs["groupOK"]).
+		Return("", apperrors.NewForbidden(fmt.Sprintf("User %v is not a member of group %v", s.IDs["userWithoutRights"], s.IDs["groupOK"])))
+
+	mockStorage := new(storage.MockStorage)
+
+	mockStorage.On("GetFile", "picture_url", mock.MatchedBy(func(t time.Time) bool { return !t.Before(pictureModified) })).
+		Return(nil, storage.ErrNotModified)
+	mockStorage.On("GetFile", "picture_url", mock.Anything).Return(func(string, time.Time) (*storage.File, error) {
+		return &storage.File{
+			Body:          io.NopCloser(strings.NewReader("image")),
+			ContentType:   "image/png",
+			ContentLength: 5,
+			LastModified:  pictureModified,
+		}, nil
+	})
+	mockStorage.On("GetFile", "missing_picture_url", mock.Anything).Return(nil, storage.ErrFileNotFound)
+
+	mockStorage.On("DeleteFile", mock.Anything).Return(nil)
+	mockStorage.On("UploadFile", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	s.server = handlers.NewServer(db, mockStorage, nil, nil)
+}
+
+var pictureModified = time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// uploadPicture makes mocked SetGroupProfilePicture upload picture like database does
+func uploadPicture(_, _ uuid.UUID, _ string, upload func() error) error {
+	return upload()
+}
+
+func (s *GroupPicturesTestSuite) TestGetGroupAvatar() {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		desc               string
+		userID             string
+		groupID            string
+		modifiedSince      string
+		expectedStatusCode int
+		expectedBody       string
+		expectedResponse   interface{}
+	}{
+		{
+			desc:               "GetAvatarInvalidGroupID",
+			userID:             s.IDs["userOK"].String(),
+			groupID:            s.IDs["groupOK"].String()[:2],
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "invalid group ID"},
+		},
+		{
+			desc:               "GetAvatarNotMember",
+			userID:             s.IDs["userWithoutRights"].String(),
+			groupID:            s.IDs["groupOK"].String(),
+			expectedStatusCode: http.StatusForbidden,
+			expectedResponse:   gin.H{"err": "Forbidden action. Reason: User ee2c6112-1114-4d9f-8869-716068ff7159 is not a member of group 4552667f-ea03-4ad3-8757-ea4645c8b4a0"},
+		},
+		{
+			desc:               "GetAvatarNoPicture",
+			userID:             s.IDs["userOK"].String(),
+			groupID:            s.IDs["groupWithoutPicture"].String(),
+			expectedStatusCode: http.StatusNotFound,
+			expectedResponse:   gin.H{"err": "resource: group picture with value: 4399b92e-d68a-42be-9a01-a9e098df98d8 not found"},
+		},
+		{
+			desc:               "GetAvatarMissingFile",
+			userID:             s.IDs["userOK"].String(),
+			groupID:            s.IDs["groupMissingFile"].String(),
+			expectedStatusCode: http.StatusNotFound,
+			expectedResponse:   gin.H{"err": "group picture not found"},
+		},
+		{
+			desc:               "GetAvatarNotModified",
+			userID:             s.IDs["userOK"].String(),
+			groupID:            s.IDs["groupOK"].String(),
+			modifiedSince:      pictureModified.Format(http.TimeFormat),
+			expectedStatusCode: http.StatusNotModified,
+		},
+		{
+			desc:               "GetAvatarModified",
+			userID:             s.IDs["userOK"].String(),
+			groupID:            s.IDs["groupOK"].String(),
+			modifiedSince:      pictureModified.Add(-time.Hour).Format(http.TimeFormat),
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       "image",
+		},
+		{
+			desc:               "GetAvatarSuccess",
+			userID:             s.IDs["userOK"].String(),
+			groupID:            s.IDs["groupOK"].String(),
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       "image",
+		},
+	}
 
-	storage := new(storage.MockStorage)
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
 
-	storage.On("DeleteFile", mock.Anything).Return(nil)
-	storage.On("UploadFile", mock.Anything, mock.Anything).Return(nil)
+			req, _ := http.NewRequest(http.MethodGet, "/api/group/"+tC.groupID+"/image", nil)
+			if tC.modifiedSince != "" {
+				req.Header.Set("If-Modified-Since", tC.modifiedSince)
+			}
+			w := httptest.NewRecorder()
+			_, engine := gin.CreateTestContext(w)
+			engine.Use(func(c *gin.Context) {
+				c.Set("userID", tC.userID)
+			})
 
-	s.server = handlers.NewServer(db, storage, nil, nil)
+			engine.Handle(http.MethodGet, "/api/group/:groupID/image", s.server.GetGroupAvatar)
+			engine.ServeHTTP(w, req)
+			response := w.Result()
+			defer response.Body.Close()
+
+			s.Equal(tC.expectedStatusCode, response.StatusCode)
+
+			switch {
+			case tC.expectedBody != "":
+				body, _ := io.ReadAll(response.Body)
+				s.Equal(tC.expectedBody, string(body))
+				s.Equal("image/png", response.Header.Get("Content-Type"))
+				s.Equal("private, max-age=86400, immutable", response.Header.Get("Cache-Control"))
+				s.Equal(pictureModified.Format(http.TimeFormat), response.Header.Get("Last-Modified"))
+			case tC.expectedResponse != nil:
+				var msg gin.H
+				if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+					s.Fail(err.Error())
+				}
+				s.Equal(tC.expectedResponse, msg)
+			}
+		})
+	}
 }
 
 func (s *GroupPicturesTestSuite) TestDeleteGroupProfilePicture() {
@@ -199,7 +351,7 @@ func (s *GroupPicturesTestSuite) TestSetGroupProfilePicture() {
 			imageData:          map[string]string{"Key": "avatarFile", "CType": "image/png"},
 			setBodyLimiter:     false,
 			expectedStatusCode: http.StatusOK,
-			expectedResponse:   gin.H{"newUrl": "picture_url"},
+			expectedResponse:   gin.H{"newUrl": imageKey(createImage(200, 100))},
 		},
 	}
 
@@ -221,7 +373,7 @@ func (s *GroupPicturesTestSuite) TestSetGroupProfilePicture() {
 			})
 
 			if tC.setBodyLimiter {
-				engine.Use(limits.RequestSizeLimiter(10))
+				engine.Use(routes.RequestSizeLimiter(10))
 			}
 			engine.Handle(http.MethodPut, "/api/group/:groupID/image", s.server.SetGroupProfilePicture)
 			engine.ServeHTTP(w, req)
@@ -230,22 +382,536 @@ func (s *GroupPicturesTestSuite) TestSetGroupProfilePicture() {
 
 			s.Equal(tC.expectedStatusCode, response.StatusCode)
 
-			var respBody interface{}
+			var msg gin.H
+			if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+				s.Fail(err.Error())
+			}
+
 			if tC.setBodyLimiter {
-				if n, err := response.Body.Read([]byte{}); err != nil || n != 0 {
-					s.Fail("Response should be empty whe 413 status is returned")
-				}
+				s.Contains(msg["err"], "Max payload size of 10 exceeded")
 			} else {
-				var msg gin.H
-				if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
-					s.Fail(err.Error())
-				}
-				respBody = msg
+				s.Equal(tC.expectedResponse, msg)
+			}
+		})
+	}
+
+	s.server.Storage.(*storage.MockStorage).AssertCalled(s.T(), "DeleteFile", "old_picture_url")
+}
+
+func (s *GroupPicturesTestSuite) TestSetGroupProfilePictureDeduplicated() {
+	gin.SetMode(gin.TestMode)
+
+	mockStorage := new(storage.MockStorage)
+	mockStorage.On("UploadFile", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockStorage.On("DeleteFile", mock.Anything).Return(nil)
+
+	server := *s.server
+	server.Storage = mockStorage
+	server.KeyPrefix = "groups/"
+
+	expectedKey := "groups/" + imageKey(createImage(200, 100))
+
+	// the same picture is set in two groups
+	for _, groupID := range []uuid.UUID{s.IDs["groupOK"], s.IDs["groupSharedPicture"]} {
+		body, writer, err := createTestFormFile("avatarFile", "image/png")
+		if err != nil {
+			s.Fail("error when creating form file: %v", err)
+		}
+
+		req, _ := http.NewRequest(http.MethodPut, "/api/group/"+groupID.String()+"/image", body)
+		req.Header.Add("Content-Type", writer.FormDataContentType())
+
+		w := httptest.NewRecorder()
+		_, engine := gin.CreateTestContext(w)
+		engine.Use(func(c *gin.Context) {
+			c.Set("userID", s.IDs["userOK"].String())
+		})
+		engine.Handle(http.MethodPut, "/api/group/:groupID/image", server.SetGroupProfilePicture)
+		engine.ServeHTTP(w, req)
+		response := w.Result()
+		defer response.Body.Close()
+
+		s.Equal(http.StatusOK, response.StatusCode)
+
+		var msg gin.H
+		if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+			s.Fail(err.Error())
+		}
+		s.Equal(gin.H{"newUrl": expectedKey}, msg)
+	}
+
+	mockStorage.AssertCalled(s.T(), "UploadFile", mock.Anything, expectedKey, "image/png")
+	mockStorage.AssertNumberOfCalls(s.T(), "UploadFile", 2)
+	// only previous picture of the first group was orphaned
+	mockStorage.AssertCalled(s.T(), "DeleteFile", "old_picture_url")
+	mockStorage.AssertNumberOfCalls(s.T(), "DeleteFile", 1)
+}
+
+func (s *GroupPicturesTestSuite) TestSetGroupProfilePictureModeration() {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		desc               string
+		verdict            moderation.Verdict
+		moderationErr      error
+		failOpen           bool
+		expectedStatusCode int
+		expectedResponse   gin.H
+		expectUpload       bool
+	}{
+		{
+			desc:               "ModerationApproved",
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   gin.H{"newUrl": imageKey(createImage(200, 100))},
+			expectUpload:       true,
+		},
+		{
+			desc:               "ModerationFlagged",
+			verdict:            moderation.Verdict{Flagged: true, Reason: "violence"},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			expectedResponse:   gin.H{"err": "image rejected by moderation"},
+		},
+		{
+			desc:               "ModerationDownFailClosed",
+			moderationErr:      errors.New("connection refused"),
+			expectedStatusCode: http.StatusServiceUnavailable,
+			expectedResponse:   gin.H{"err": "image moderation unavailable, try again later"},
+		},
+		{
+			desc:               "ModerationDownFailOpen",
+			moderationErr:      errors.New("connection refused"),
+			failOpen:           true,
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   gin.H{"newUrl": imageKey(createImage(200, 100))},
+			expectUpload:       true,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+			var screened []byte
+			moderator := new(moderation.MockModerator)
+			moderator.On("Check", mock.Anything, mock.Anything, "image/png").Run(func(args mock.Arguments) {
+				screened, _ = io.ReadAll(args.Get(1).(io.Reader))
+			}).Return(tC.verdict, tC.moderationErr)
+
+			var uploaded []byte
+			mockStorage := new(storage.MockStorage)
+			mockStorage.On("UploadFile", mock.Anything, mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+				uploaded, _ = io.ReadAll(args.Get(0).(io.Reader))
+			})
+			mockStorage.On("DeleteFile", mock.Anything).Return(nil)
+
+			server := *s.server
+			server.Storage = mockStorage
+			server.Moderator = moderator
+			server.ModerationFailOpen = tC.failOpen
+
+			body, writer, err := createTestFormFile("avatarFile", "image/png")
+			if err != nil {
+				s.Fail("error when creating form file: %v", err)
+			}
+			req, _ := http.NewRequest(http.MethodPut, "/api/group/"+s.IDs["groupOK"].String()+"/image", body)
+			req.Header.Add("Content-Type", writer.FormDataContentType())
+
+			w := httptest.NewRecorder()
+			_, engine := gin.CreateTestContext(w)
+			engine.Use(func(c *gin.Context) {
+				c.Set("userID", s.IDs["userOK"].String())
+			})
+			engine.Handle(http.MethodPut, "/api/group/:groupID/image", server.SetGroupProfilePicture)
+			engine.ServeHTTP(w, req)
+			response := w.Result()
+			defer response.Body.Close()
+
+			s.Equal(tC.expectedStatusCode, response.StatusCode)
+
+			var msg gin.H
+			if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+				s.Fail(err.Error())
+			}
+			s.Equal(tC.expectedResponse, msg)
+
+			s.NotEmpty(screened)
+			if tC.expectUpload {
+				// picture is rewound after screening so the whole of it gets uploaded
+				s.Equal(screened, uploaded)
+			} else {
+				mockStorage.AssertNotCalled(s.T(), "UploadFile", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+// Removing picture used by other groups keeps its object in storage
+func (s *GroupPicturesTestSuite) TestDeleteGroupProfilePictureShared() {
+	gin.SetMode(gin.TestMode)
+
+	mockStorage := new(storage.MockStorage)
+	server := *s.server
+	server.Storage = mockStorage
+
+	req, _ := http.NewRequest(http.MethodDelete, "/api/group/"+s.IDs["groupSharedDeleted"].String()+"/image", nil)
+	w := httptest.NewRecorder()
+	_, engine := gin.CreateTestContext(w)
+	engine.Use(func(c *gin.Context) {
+		c.Set("userID", s.IDs["userOK"].String())
+	})
+	engine.Handle(http.MethodDelete, "/api/group/:groupID/image", server.DeleteGroupProfilePicture)
+	engine.ServeHTTP(w, req)
+	response := w.Result()
+	defer response.Body.Close()
+
+	s.Equal(http.StatusOK, response.StatusCode)
+	mockStorage.AssertNotCalled(s.T(), "DeleteFile", mock.Anything)
+}
+
+func (s *GroupPicturesTestSuite) TestSetGroupProfilePictureStreamedTooLarge() {
+	gin.SetMode(gin.TestMode)
+
+	mockStorage := new(storage.MockStorage)
+	server := *s.server
+	server.Storage = mockStorage
+
+	body, writer, err := createTestFormFile("avatarFile", "image/png")
+	if err != nil {
+		s.Fail("error when creating form file: %v", err)
+	}
+
+	// hiding buffer's type makes body length unknown so it is streamed like a chunked upload
+	req, _ := http.NewRequest(http.MethodPut, "/api/group/"+s.IDs["groupOK"].String()+"/image", io.MultiReader(body))
+	req.ContentLength = -1
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+
+	w := httptest.NewRecorder()
+	_, engine := gin.CreateTestContext(w)
+	engine.Use(func(c *gin.Context) {
+		c.Set("userID", s.IDs["userOK"].String())
+	})
+	engine.Use(routes.RequestSizeLimiter(100))
+	engine.Handle(http.MethodPut, "/api/group/:groupID/image", server.SetGroupProfilePicture)
+	engine.ServeHTTP(w, req)
+	response := w.Result()
+	defer response.Body.Close()
+
+	s.Equal(http.StatusRequestEntityTooLarge, response.StatusCode)
+
+	var msg gin.H
+	if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+		s.Fail(err.Error())
+	}
+	s.Equal(gin.H{"err": "Max payload size of 100 exceeded"}, msg)
+
+	mockStorage.AssertNotCalled(s.T(), "UploadFile", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (s *GroupPicturesTestSuite) TestSetGroupProfilePictureImageDimensions() {
+	gin.SetMode(gin.TestMode)
+
+	server := *s.server
+	server.MaxImageDimension = 150
+
+	testCases := []struct {
+		desc               string
+		width              int
+		height             int
+		expectedStatusCode int
+		expectedResponse   interface{}
+	}{
+		{
+			desc:               "UpdateProfilePictureTooWide",
+			width:              200,
+			height:             100,
+			expectedStatusCode: http.StatusRequestEntityTooLarge,
+			expectedResponse:   gin.H{"err": "image dimensions exceed 150px"},
+		},
+		{
+			desc:               "UpdateProfilePictureTooHigh",
+			width:              100,
+			height:             151,
+			expectedStatusCode: http.StatusRequestEntityTooLarge,
+			expectedResponse:   gin.H{"err": "image dimensions exceed 150px"},
+		},
+		{
+			desc:               "UpdateProfilePictureDimensionsOnLimit",
+			width:              150,
+			height:             150,
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   gin.H{"newUrl": imageKey(createImage(150, 150))},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+
+			body, writer, err := createTestFormFileWithImage("avatarFile", "image/png", createImage(tC.width, tC.height))
+			if err != nil {
+				s.Fail("error when creating form file: %v", err)
+			}
+
+			req, _ := http.NewRequest(http.MethodPut, "/api/group/"+s.IDs["groupOK"].String()+"/image", body)
+			req.Header.Add("Content-Type", writer.FormDataContentType())
+
+			w := httptest.NewRecorder()
+			_, engine := gin.CreateTestContext(w)
+			engine.Use(func(c *gin.Context) {
+				c.Set("userID", s.IDs["userOK"].String())
+			})
+
+			engine.Handle(http.MethodPut, "/api/group/:groupID/image", server.SetGroupProfilePicture)
+			engine.ServeHTTP(w, req)
+			response := w.Result()
+			defer response.Body.Close()
+
+			s.Equal(tC.expectedStatusCode, response.StatusCode)
+
+			var msg gin.H
+			if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+				s.Fail(err.Error())
+			}
+
+			s.Equal(tC.expectedResponse, msg)
+		})
+	}
+}
+
+func (s *GroupPicturesTestSuite) TestSetGroupProfilePictureCrop() {
+	gin.SetMode(gin.TestMode)
+
+	var uploaded image.Config
+	var uploadedKey string
+	mockStorage := new(storage.MockStorage)
+	mockStorage.On("UploadFile", mock.Anything, mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		uploaded, _, _ = image.DecodeConfig(args.Get(0).(io.Reader))
+		uploadedKey = args.String(1)
+	})
+	mockStorage.On("DeleteFile", mock.Anything).Return(nil)
+
+	server := *s.server
+	server.Storage = mockStorage
+
+	testCases := []struct {
+		desc               string
+		crop               map[string]string
+		expectedStatusCode int
+		expectedResponse   gin.H
+		expectedSize       image.Point
+	}{
+		{
+			desc:               "CropIncomplete",
+			crop:               map[string]string{"x": "0", "y": "0", "width": "50"},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "crop requires x, y, width and height"},
+		},
+		{
+			desc:               "CropNotNumber",
+			crop:               map[string]string{"x": "a", "y": "0", "width": "50", "height": "50"},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "invalid crop x"},
+		},
+		{
+			desc:               "CropEmpty",
+			crop:               map[string]string{"x": "0", "y": "0", "width": "0", "height": "50"},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "crop width and height must be positive"},
+		},
+		{
+			desc:               "CropOutOfBounds",
+			crop:               map[string]string{"x": "150", "y": "50", "width": "100", "height": "100"},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "crop rectangle doesn't fit within image"},
+		},
+		{
+			desc:               "CropSuccess",
+			crop:               map[string]string{"x": "100", "y": "0", "width": "100", "height": "100"},
+			expectedStatusCode: http.StatusOK,
+			expectedSize:       image.Pt(100, 100),
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+			uploaded = image.Config{}
+
+			body, writer, err := createTestFormFileWithFields("avatarFile", "image/png", createImage(200, 100), tC.crop)
+			if err != nil {
+				s.Fail("error when creating form file: %v", err)
 			}
 
-			if !tC.setBodyLimiter {
-				s.Equal(tC.expectedResponse, respBody)
+			req, _ := http.NewRequest(http.MethodPut, "/api/group/"+s.IDs["groupOK"].String()+"/image", body)
+			req.Header.Add("Content-Type", writer.FormDataContentType())
+
+			w := httptest.NewRecorder()
+			_, engine := gin.CreateTestContext(w)
+			engine.Use(func(c *gin.Context) {
+				c.Set("userID", s.IDs["userOK"].String())
+			})
+
+			engine.Handle(http.MethodPut, "/api/group/:groupID/image", server.SetGroupProfilePicture)
+			engine.ServeHTTP(w, req)
+			response := w.Result()
+			defer response.Body.Close()
+
+			s.Equal(tC.expectedStatusCode, response.StatusCode)
+
+			var msg gin.H
+			if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+				s.Fail(err.Error())
+			}
+
+			expected := tC.expectedResponse
+			if expected == nil {
+				// cropped image is encoded again so its key is known only after upload
+				expected = gin.H{"newUrl": uploadedKey}
 			}
+			s.Equal(expected, msg)
+			s.Equal(tC.expectedSize, image.Pt(uploaded.Width, uploaded.Height))
+		})
+	}
+}
+
+func (s *GroupPicturesTestSuite) TestSetGroupPictureFromURL() {
+	gin.SetMode(gin.TestMode)
+
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/image":
+			_ = png.Encode(w, createImage(200, 100))
+		case "/text":
+			_, _ = w.Write([]byte("not an image"))
+		case "/large":
+			_, _ = w.Write(make([]byte, handlers.MAX_BODY_BYTES+1))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer imageServer.Close()
+
+	// test server listens on loopback which default client refuses to connect to
+	trusting := *s.server
+	trusting.RemoteImages = imageServer.Client()
+
+	testCases := []struct {
+		desc               string
+		userID             string
+		server             *handlers.Server
+		body               string
+		expectedStatusCode int
+		expectedResponse   gin.H
+	}{
+		{
+			desc:               "FromURLNoURL",
+			userID:             s.IDs["userOK"].String(),
+			server:             s.server,
+			body:               `{}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "image URL not specified"},
+		},
+		{
+			desc:               "FromURLNoRights",
+			userID:             s.IDs["userWithoutRights"].String(),
+			server:             &trusting,
+			body:               `{"url": "` + imageServer.URL + `/image"}`,
+			expectedStatusCode: http.StatusForbidden,
+			expectedResponse:   gin.H{"err": "Forbidden action. Reason: User ee2c6112-1114-4d9f-8869-716068ff7159 has no rights to set in group 4552667f-ea03-4ad3-8757-ea4645c8b4a0"},
+		},
+		{
+			desc:               "FromURLWrongScheme",
+			userID:             s.IDs["userOK"].String(),
+			server:             s.server,
+			body:               `{"url": "file:///etc/passwd"}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "image URL must use http or https"},
+		},
+		{
+			desc:               "FromURLLoopback",
+			userID:             s.IDs["userOK"].String(),
+			server:             s.server,
+			body:               `{"url": "` + imageServer.URL + `/image"}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "image URL points to a disallowed address"},
+		},
+		{
+			desc:               "FromURLLocalhost",
+			userID:             s.IDs["userOK"].String(),
+			server:             s.server,
+			body:               `{"url": "http://localhost/image"}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "image URL points to a disallowed address"},
+		},
+		{
+			desc:               "FromURLPrivate",
+			userID:             s.IDs["userOK"].String(),
+			server:             s.server,
+			body:               `{"url": "http://10.0.0.1/image"}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "image URL points to a disallowed address"},
+		},
+		{
+			desc:               "FromURLMetadata",
+			userID:             s.IDs["userOK"].String(),
+			server:             s.server,
+			body:               `{"url": "http://169.254.169.254/latest/meta-data"}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "image URL points to a disallowed address"},
+		},
+		{
+			desc:               "FromURLNotFound",
+			userID:             s.IDs["userOK"].String(),
+			server:             &trusting,
+			body:               `{"url": "` + imageServer.URL + `/missing"}`,
+			expectedStatusCode: http.StatusBadGateway,
+			expectedResponse:   gin.H{"err": "couldn't fetch image: remote server responded with 404"},
+		},
+		{
+			desc:               "FromURLNotImage",
+			userID:             s.IDs["userOK"].String(),
+			server:             &trusting,
+			body:               `{"url": "` + imageServer.URL + `/text"}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "image extention not allowed"},
+		},
+		{
+			desc:               "FromURLTooLarge",
+			userID:             s.IDs["userOK"].String(),
+			server:             &trusting,
+			body:               `{"url": "` + imageServer.URL + `/large"}`,
+			expectedStatusCode: http.StatusRequestEntityTooLarge,
+			expectedResponse:   gin.H{"err": fmt.Sprintf("remote image exceeds %d bytes", handlers.MAX_BODY_BYTES)},
+		},
+		{
+			desc:               "FromURLSuccess",
+			userID:             s.IDs["userOK"].String(),
+			server:             &trusting,
+			body:               `{"url": "` + imageServer.URL + `/image"}`,
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   gin.H{"newUrl": imageKey(createImage(200, 100))},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+
+			req, _ := http.NewRequest(http.MethodPost, "/api/group/"+s.IDs["groupOK"].String()+"/image/url", strings.NewReader(tC.body))
+
+			w := httptest.NewRecorder()
+			_, engine := gin.CreateTestContext(w)
+			engine.Use(func(c *gin.Context) {
+				c.Set("userID", tC.userID)
+			})
+
+			engine.Handle(http.MethodPost, "/api/group/:groupID/image/url", tC.server.SetGroupPictureFromURL)
+			engine.ServeHTTP(w, req)
+			response := w.Result()
+			defer response.Body.Close()
+
+			s.Equal(tC.expectedStatusCode, response.StatusCode)
+
+			var msg gin.H
+			if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+				s.Fail(err.Error())
+			}
+			s.Equal(tC.expectedResponse, msg)
 		})
 	}
 }
@@ -253,3 +919,139 @@ func (s *GroupPicturesTestSuite) TestSetGroupProfilePicture() {
 func TestGroupPicturesSuite(t *testing.T) {
 	suite.Run(t, &GroupPicturesTestSuite{})
 }
+
+// pictureLockDB keeps group pictures in memory and serializes operations on each key like database does
+type pictureLockDB struct {
+	*dbmock.MockGroupsDB
+
+	mu       sync.Mutex
+	keyLocks map[string]*sync.Mutex
+	pictures map[uuid.UUID]string
+}
+
+func (db *pictureLockDB) lock(key string) func() {
+	db.mu.Lock()
+	l, ok := db.keyLocks[key]
+	if !ok {
+		l = new(sync.Mutex)
+		db.keyLocks[key] = l
+	}
+	db.mu.Unlock()
+
+	l.Lock()
+	return l.Unlock
+}
+
+func (db *pictureLockDB) FindMember(groupID, userID uuid.UUID) (*models.Member, error) {
+	return &models.Member{GroupID: groupID, UserID: userID, Admin: true}, nil
+}
+
+func (db *pictureLockDB) SetGroupProfilePicture(userID, groupID uuid.UUID, key string, upload func() error) (string, error) {
+	defer db.lock(key)()
+	if err := upload(); err != nil {
+		return "", err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	previous := db.pictures[groupID]
+	db.pictures[groupID] = key
+	if previous == key {
+		return "", nil
+	}
+	return previous, nil
+}
+
+func (db *pictureLockDB) ReleasePicture(key string, remove func() error) error {
+	defer db.lock(key)()
+
+	db.mu.Lock()
+	for _, picture := range db.pictures {
+		if picture == key {
+			db.mu.Unlock()
+			return nil
+		}
+	}
+	db.mu.Unlock()
+
+	return remove()
+}
+
+// memoryStorage keeps uploaded objects in memory
+type memoryStorage struct {
+	mu      sync.Mutex
+	objects map[string]bool
+}
+
+func (st *memoryStorage) UploadFile(_ multipart.File, key, _ string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.objects[key] = true
+	return nil
+}
+
+func (st *memoryStorage) GetFile(key string, _ time.Time) (*storage.File, error) {
+	return nil, storage.ErrFileNotFound
+}
+
+func (st *memoryStorage) DeleteFile(key string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	delete(st.objects, key)
+	return nil
+}
+
+// Group starting to use a picture while another group replaces the same picture must not lose its object
+func (s *GroupPicturesTestSuite) TestSetGroupProfilePictureConcurrentIdentical() {
+	gin.SetMode(gin.TestMode)
+
+	shared, replacement := createImage(200, 100), createImage(100, 100)
+	groupA := uuid.MustParse("0b7f5e2c-8a4d-4c1e-9f3b-6d2a8c4e0f1a")
+	groupB := uuid.MustParse("1c8a6f3d-9b5e-4d2f-8a4c-7e3b9d5f1a2b")
+
+	for i := 0; i < 50; i++ {
+		db := &pictureLockDB{keyLocks: make(map[string]*sync.Mutex), pictures: make(map[uuid.UUID]string)}
+		st := &memoryStorage{objects: make(map[string]bool)}
+		server := *s.server
+		server.DB = db
+		server.Storage = st
+
+		engine := gin.New()
+		engine.Use(func(c *gin.Context) {
+			c.Set("userID", s.IDs["userOK"].String())
+		})
+		engine.Handle(http.MethodPut, "/api/group/:groupID/image", server.SetGroupProfilePicture)
+
+		setPicture := func(groupID uuid.UUID, img image.Image) int {
+			body, writer, err := createTestFormFileWithImage("avatarFile", "image/png", img)
+			if err != nil {
+				s.Fail("error when creating form file: %v", err)
+			}
+			req, _ := http.NewRequest(http.MethodPut, "/api/group/"+groupID.String()+"/image", body)
+			req.Header.Add("Content-Type", writer.FormDataContentType())
+
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+			return w.Code
+		}
+
+		s.Require().Equal(http.StatusOK, setPicture(groupA, shared))
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			s.Equal(http.StatusOK, setPicture(groupB, shared))
+		}()
+		go func() {
+			defer wg.Done()
+			s.Equal(http.StatusOK, setPicture(groupA, replacement))
+		}()
+		wg.Wait()
+
+		for groupID, key := range db.pictures {
+			s.True(st.objects[key], "picture %s of group %v was deleted", key, groupID)
+		}
+		s.Len(st.objects, 2)
+	}
+}
@@ -1,10 +1,18 @@
 package handlers
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/Slimo300/MicroservicesChatApp/backend/lib/apperrors"
 	"github.com/Slimo300/MicroservicesChatApp/backend/lib/events"
+	groupevents "github.com/Slimo300/chat-groupservice/internal/events"
+	"github.com/Slimo300/chat-groupservice/internal/models"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
@@ -14,9 +22,15 @@ func (s *Server) GetUserGroups(c *gin.Context) {
 	userUID, err := uuid.Parse(userID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid ID"})
+		return
+	}
+	sort, err := s.parseSort(c.Request, SORTED_USER_GROUPS)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+		return
 	}
 
-	groups, err := s.DB.GetUserGroups(userUID)
+	groups, err := s.requestDB(c).GetUserGroups(userUID, sort)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
 		return
@@ -26,8 +40,181 @@ func (s *Server) GetUserGroups(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, groups)
+	c.JSON(http.StatusOK, formatTimes(c, groups))
+
+}
+
+// GetActiveGroupCount returns number of groups caller is a member of, it's meant to be polled by clients
+// showing it as a badge
+func (s *Server) GetActiveGroupCount(c *gin.Context) {
+	userID := c.GetString("userID")
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid ID"})
+		return
+	}
+
+	count, err := s.requestDB(c).CountUserGroups(userUUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"err": err.Error()})
+		return
+	}
 
+	c.JSON(http.StatusOK, gin.H{"count": count})
+}
+
+// GetSharedGroups returns groups caller shares with another user, full pages carry cursor of the next one in
+// NEXT_CURSOR_HEADER
+func (s *Server) GetSharedGroups(c *gin.Context) {
+	userID := c.GetString("userID")
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid ID"})
+		return
+	}
+	targetID := c.Param("userID")
+	targetUUID, err := uuid.Parse(targetID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid user ID"})
+		return
+	}
+	page, err := parsePagination(c.Request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+		return
+	}
+
+	sort, err := s.parseSort(c.Request, SORTED_SHARED_GROUPS)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+		return
+	}
+
+	after, err := parseCursor(c.Request, sort, page)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+		return
+	}
+
+	groups, err := s.requestDB(c).GetSharedGroups(userUUID, targetUUID, sort, after, page.Limit, page.Offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"err": err.Error()})
+		return
+	}
+
+	if len(groups) == 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	if len(groups) == page.Limit {
+		setNextCursor(c, groupCursor(sort, groups[len(groups)-1]))
+	}
+
+	c.JSON(http.StatusOK, formatTimes(c, groups))
+}
+
+const MAX_GROUP_EXISTENCE_BATCH = 500
+
+// CheckGroupsExist tells which of given groups still exist, which were deleted and which are unknown. It's meant
+// for cleanup jobs of other services purging references to removed groups
+func (s *Server) CheckGroupsExist(c *gin.Context) {
+	payload := struct {
+		GroupIDs []string `json:"groupIDs"`
+	}{}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+		return
+	}
+
+	seen := make(map[uuid.UUID]bool)
+	var groupIDs []uuid.UUID
+	for _, groupID := range payload.GroupIDs {
+		groupUUID, err := uuid.Parse(groupID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": "invalid group ID"})
+			return
+		}
+		if seen[groupUUID] {
+			continue
+		}
+		seen[groupUUID] = true
+		groupIDs = append(groupIDs, groupUUID)
+	}
+	if len(groupIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "groups not specified"})
+		return
+	}
+	if len(groupIDs) > MAX_GROUP_EXISTENCE_BATCH {
+		c.JSON(http.StatusBadRequest, gin.H{"err": fmt.Sprintf("at most %d groups can be checked at once", MAX_GROUP_EXISTENCE_BATCH)})
+		return
+	}
+
+	groups, err := s.requestDB(c).GetGroupsExistence(groupIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"err": err.Error()})
+		return
+	}
+
+	deleted := make(map[uuid.UUID]bool, len(groups))
+	for _, group := range groups {
+		deleted[group.ID] = group.DeletedAt.Valid
+	}
+	existing, removed, missing := []uuid.UUID{}, []uuid.UUID{}, []uuid.UUID{}
+	for _, groupID := range groupIDs {
+		isDeleted, found := deleted[groupID]
+		switch {
+		case !found:
+			missing = append(missing, groupID)
+		case isDeleted:
+			removed = append(removed, groupID)
+		default:
+			existing = append(existing, groupID)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"existing": existing, "deleted": removed, "missing": missing})
+}
+
+// GetGroupsModifiedSince returns changes feed of groups for services keeping their own copy of groups (e.g. search
+// index). Groups are ordered by update time and ID, next page is requested with "since" and "afterID" of last
+// returned group
+func (s *Server) GetGroupsModifiedSince(c *gin.Context) {
+	since, err := time.Parse(time.RFC3339Nano, c.Query("since"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid since timestamp"})
+		return
+	}
+	var afterID uuid.UUID
+	if id := c.Query("afterID"); id != "" {
+		afterID, err = uuid.Parse(id)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": "invalid group ID"})
+			return
+		}
+	}
+	page, err := parsePagination(c.Request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+		return
+	}
+
+	groups, err := s.requestDB(c).GetGroupsModifiedSince(since, afterID, page.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"err": err.Error()})
+		return
+	}
+
+	if len(groups) == 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	changes := make([]models.GroupChange, 0, len(groups))
+	for _, group := range groups {
+		changes = append(changes, models.GroupChange{Group: group, Deleted: group.DeletedAt.Valid})
+	}
+
+	c.JSON(http.StatusOK, formatTimes(c, changes))
 }
 
 func (s *Server) CreateGroup(c *gin.Context) {
@@ -51,21 +238,102 @@ func (s *Server) CreateGroup(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"err": "name not specified"})
 		return
 	}
+	name, err := models.NormalizeGroupName(payload.Name)
+	if err != nil {
+		respondInvalidText(c, err)
+		return
+	}
 
-	group, err := s.DB.CreateGroup(userUID, payload.Name)
+	group, err := s.requestDB(c).CreateGroup(userUID, name)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"err": err.Error()})
+		c.JSON(apperrors.Status(err), gin.H{"err": err.Error()})
 		return
 	}
 
-	_ = s.Emitter.Emit(events.MemberCreatedEvent{
+	if err := s.emitSync(events.MemberCreatedEvent{
 		ID:      group.Members[0].ID,
 		GroupID: group.ID,
 		UserID:  userUID,
 		Creator: true,
-	})
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"err": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, formatTimes(c, group))
+}
+
+const MAX_BULK_GROUPS = 100
+
+// BulkCreateGroups creates groups of an import (e.g. from an org chart) with their owners and initial members.
+// By default every group succeeds or fails on its own, with "allOrNothing" set no group is kept when any fails
+func (s *Server) BulkCreateGroups(c *gin.Context) {
+	payload := struct {
+		Groups       []models.GroupDefinition `json:"groups"`
+		AllOrNothing bool                     `json:"allOrNothing"`
+	}{}
+	if err := c.ShouldBindJSON(&payload); err != nil || len(payload.Groups) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "groups not specified"})
+		return
+	}
+	if len(payload.Groups) > MAX_BULK_GROUPS {
+		c.JSON(http.StatusBadRequest, gin.H{"err": fmt.Sprintf("at most %d groups can be created at once", MAX_BULK_GROUPS)})
+		return
+	}
 
-	c.JSON(http.StatusCreated, group)
+	type groupResult struct {
+		Index   int        `json:"index"`
+		GroupID *uuid.UUID `json:"groupID,omitempty"`
+		Created bool       `json:"created"`
+		Err     string     `json:"err,omitempty"`
+	}
+	results := make([]groupResult, len(payload.Groups))
+
+	// definitions with invalid names never reach the database, in all or nothing mode they reject whole batch
+	var valid []models.GroupDefinition
+	var indexes []int
+	for i, definition := range payload.Groups {
+		results[i].Index = i
+		name, err := models.NormalizeGroupName(definition.Name)
+		if err != nil {
+			if payload.AllOrNothing {
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"err": fmt.Sprintf("group %d: %v", i, err)})
+				return
+			}
+			results[i].Err = err.Error()
+			continue
+		}
+		definition.Name = name
+		valid = append(valid, definition)
+		indexes = append(indexes, i)
+	}
+
+	if len(valid) > 0 {
+		created, err := s.requestDB(c).CreateGroups(valid, payload.AllOrNothing)
+		if err != nil {
+			c.JSON(apperrors.Status(err), gin.H{"err": err.Error()})
+			return
+		}
+		for j, result := range created {
+			i := indexes[j]
+			if result.Err != nil {
+				results[i].Err = result.Err.Error()
+				continue
+			}
+			results[i].GroupID = &result.Group.ID
+			results[i].Created = true
+
+			// group is created already, error only tells that other services weren't notified
+			for _, member := range result.Group.Members {
+				if err := s.emitSync(memberCreatedEvent(member)); err != nil {
+					results[i].Err = err.Error()
+					break
+				}
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": results})
 }
 
 func (s *Server) DeleteGroup(c *gin.Context) {
@@ -82,24 +350,242 @@ func (s *Server) DeleteGroup(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid group ID"})
 		return
 	}
-	group, err := s.DB.DeleteGroup(userUUID, groupUUID)
+	group, err := s.requestDB(c).DeleteGroup(userUUID, groupUUID)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{"err": err.Error()})
+		return
+	}
+	if err := s.releasePicture(c, group.Picture); err != nil {
+		// TODO: this err should be handled by logging it for investigation or handled
+		// at a later time
+		c.JSON(http.StatusInternalServerError, gin.H{"err": err.Error()})
+		return
+	}
+
+	if err := s.emitSync(events.GroupDeletedEvent{
+		ID: group.ID,
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"err": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "group deleted"})
+
+}
+
+// GetGroupSettings returns every setting of a group with server defaults for settings that were never set
+func (s *Server) GetGroupSettings(c *gin.Context) {
+	userID := c.GetString("userID")
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid ID"})
+		return
+	}
+	groupID := c.Param("groupID")
+	groupUUID, err := uuid.Parse(groupID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid group ID"})
+		return
+	}
+
+	settings, err := s.requestDB(c).GetGroupSettings(userUUID, groupUUID)
 	if err != nil {
 		c.JSON(apperrors.Status(err), gin.H{"err": err.Error()})
 		return
 	}
-	if group.Picture != "" {
-		if err := s.Storage.DeleteFile(group.Picture); err != nil {
-			// TODO: this err should be handled by logging it for investigation or handled
-			// at a later time
-			c.JSON(http.StatusInternalServerError, gin.H{"err": err.Error()})
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateGroupSettings changes group's settings and returns the updated group. With "validate_only" query parameter
+// set nothing is changed and a report of problems and changes that applying settings would cause is returned
+func (s *Server) UpdateGroupSettings(c *gin.Context) {
+	userID := c.GetString("userID")
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid ID"})
+		return
+	}
+	groupID := c.Param("groupID")
+	groupUUID, err := uuid.Parse(groupID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid group ID"})
+		return
+	}
+
+	var settings models.GroupSettings
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+		return
+	}
+	if settings == (models.GroupSettings{}) {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "no settings specified"})
+		return
+	}
+
+	if validate := c.Query("validate_only"); validate != "" {
+		validateOnly, err := strconv.ParseBool(validate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": "invalid validate_only value"})
+			return
+		}
+		if validateOnly {
+			report, err := s.requestDB(c).ValidateGroupSettings(userUUID, groupUUID, settings)
+			if err != nil {
+				c.JSON(apperrors.Status(err), gin.H{"err": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, report)
 			return
 		}
 	}
 
-	_ = s.Emitter.Emit(events.GroupDeletedEvent{
-		ID: group.ID,
+	if err := settings.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+		return
+	}
+
+	group, err := s.requestDB(c).UpdateGroupSettings(userUUID, groupUUID, settings)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{"err": err.Error()})
+		return
+	}
+
+	s.emitAsync(groupevents.GroupSettingsChangedEvent{
+		GroupID:         group.ID,
+		IsAnnouncement:  group.Announcement,
+		SlowModeSeconds: group.SlowModeSeconds,
 	})
 
-	c.JSON(http.StatusOK, gin.H{"message": "group deleted"})
+	c.JSON(http.StatusOK, formatTimes(c, group))
+}
+
+// group cards change rarely, clients revalidate them with ETag afterwards
+const GROUP_CARD_CACHE_CONTROL = "private, max-age=60"
+
+// GetGroupCard returns compact preview of a group for rendering shared group links. Response carries an ETag
+// so clients and proxies can revalidate cached cards cheaply
+func (s *Server) GetGroupCard(c *gin.Context) {
+	userID := c.GetString("userID")
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid ID"})
+		return
+	}
+	groupID := c.Param("groupID")
+	groupUUID, err := uuid.Parse(groupID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid group ID"})
+		return
+	}
+
+	card, err := s.requestDB(c).GetGroupCard(userUUID, groupUUID)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{"err": err.Error()})
+		return
+	}
+
+	body, err := json.Marshal(card)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"err": err.Error()})
+		return
+	}
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	c.Header("Cache-Control", GROUP_CARD_CACHE_CONTROL)
+	c.Header("ETag", etag)
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+// GetGroupRoleCounts returns how many members of a group have each role so clients can tell which roles
+// are present without fetching whole roster
+func (s *Server) GetGroupRoleCounts(c *gin.Context) {
+	userID := c.GetString("userID")
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid ID"})
+		return
+	}
+	groupID := c.Param("groupID")
+	groupUUID, err := uuid.Parse(groupID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid group ID"})
+		return
+	}
+
+	counts, err := s.requestDB(c).GetGroupRoleCounts(userUUID, groupUUID)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{"err": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"roles": counts})
+}
+
+func (s *Server) GetGroupStats(c *gin.Context) {
+	userID := c.GetString("userID")
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid ID"})
+		return
+	}
+	groupID := c.Param("groupID")
+	groupUUID, err := uuid.Parse(groupID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid group ID"})
+		return
+	}
+
+	member, err := s.requestDB(c).GetMembership(userUUID, groupUUID, userUUID)
+	if err != nil || !member.CanEditGroup() {
+		c.JSON(http.StatusForbidden, gin.H{"err": fmt.Sprintf("user %v has no right to view stats of group %v", userUUID, groupUUID)})
+		return
+	}
+
+	// aggregation is much heavier than other reads so results are cached for a short time
+	if stats, ok := s.statsCache.Get(groupUUID); ok {
+		c.JSON(http.StatusOK, stats)
+		return
+	}
+
+	stats, err := s.requestDB(c).GetGroupStats(groupUUID)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{"err": err.Error()})
+		return
+	}
+	s.statsCache.Set(groupUUID, stats)
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// AdminGroupMetrics reports totals of all groups of the platform for admins, deleted groups are counted
+// separately when includeDeleted is set. Counting scans whole tables so results are cached
+func (s *Server) AdminGroupMetrics(c *gin.Context) {
+	includeDeleted := false
+	if value := c.Query("includeDeleted"); value != "" {
+		var err error
+		if includeDeleted, err = strconv.ParseBool(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": "invalid includeDeleted value"})
+			return
+		}
+	}
+
+	if metrics, ok := s.platformMetrics.Get(includeDeleted); ok {
+		c.JSON(http.StatusOK, formatTimes(c, metrics))
+		return
+	}
+
+	metrics, err := s.requestDB(c).GetPlatformGroupMetrics(includeDeleted)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{"err": err.Error()})
+		return
+	}
+	s.platformMetrics.Set(includeDeleted, metrics)
 
+	c.JSON(http.StatusOK, formatTimes(c, metrics))
 }
@@ -3,10 +3,14 @@ package handlers_test
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/Slimo300/MicroservicesChatApp/backend/lib/apperrors"
 	mockqueue "github.com/Slimo300/MicroservicesChatApp/backend/lib/msgqueue/mock"
 	mockdb "github.com/Slimo300/chat-groupservice/internal/database/mock"
 	"github.com/Slimo300/chat-groupservice/internal/handlers"
@@ -16,11 +20,13 @@ import (
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
 )
 
 type GroupTestSuite struct {
 	suite.Suite
 	IDs    map[string]uuid.UUID
+	since  time.Time
 	server *handlers.Server
 }
 
@@ -34,16 +40,125 @@ func (s *GroupTestSuite) SetupSuite() {
 	s.IDs["group2"] = uuid.MustParse("be0accb1-50db-4698-b048-fb0128e35684")
 
 	s.IDs["member"] = uuid.MustParse("6c564875-cd55-4e20-a035-44f1750d25b9")
+	s.IDs["groupDeleted"] = uuid.MustParse("9a8b7c6d-5e4f-4a3b-9c2d-1e0f9a8b7c6d")
 
 	db := new(mockdb.MockGroupsDB)
-	db.On("GetUserGroups", s.IDs["user1"]).Return([]models.Group{
+	db.On("GetUserGroups", s.IDs["user1"], models.SORT_NAME_ASC).Return([]models.Group{
 		{ID: s.IDs["group1"]},
 		{ID: s.IDs["group2"]},
 	}, nil)
-	db.On("GetUserGroups", s.IDs["user2"]).Return([]models.Group{}, nil)
+	db.On("GetUserGroups", s.IDs["user2"], models.SORT_NAME_ASC).Return([]models.Group{}, nil)
+	db.On("CountUserGroups", s.IDs["user1"]).Return(int64(2), nil)
+	db.On("CountUserGroups", s.IDs["user2"]).Return(int64(0), nil)
+
+	s.IDs["groupUnknown"] = uuid.MustParse("5f4e3d2c-1b0a-4f9e-8d7c-6b5a4f3e2d1c")
+	db.On("GetGroupCard", s.IDs["user1"], s.IDs["group1"]).Return(models.GroupCard{
+		ID: s.IDs["group1"], Name: "group1", Picture: "picture", MemberCount: 5,
+	}, nil)
+	db.On("GetGroupCard", s.IDs["user2"], s.IDs["group1"]).
+		Return(models.GroupCard{}, apperrors.NewForbidden(fmt.Sprintf("User %v is not a member of group %v", s.IDs["user2"], s.IDs["group1"])))
+	db.On("GetGroupsExistence", []uuid.UUID{s.IDs["group1"], s.IDs["groupDeleted"], s.IDs["groupUnknown"]}).Return([]models.Group{
+		{ID: s.IDs["group1"]},
+		{ID: s.IDs["groupDeleted"], DeletedAt: gorm.DeletedAt{Time: time.Now(), Valid: true}},
+	}, nil)
+
+	// users share only the first of user1's groups
+	db.On("GetSharedGroups", s.IDs["user1"], s.IDs["user2"], models.SORT_CREATED_DESC, (*models.Cursor)(nil), 50, 0).Return([]models.Group{{ID: s.IDs["group1"]}}, nil)
+	db.On("GetSharedGroups", s.IDs["user1"], s.IDs["user2"], models.SORT_CREATED_DESC, (*models.Cursor)(nil), 1, 1).Return([]models.Group{}, nil)
+	db.On("GetSharedGroups", s.IDs["user1"], s.IDs["user2"], models.SORT_NAME_DESC, (*models.Cursor)(nil), 1, 1).Return([]models.Group{{ID: s.IDs["group1"]}}, nil)
+	// next page of shared groups sorted by name starts after group1
+	db.On("GetSharedGroups", s.IDs["user1"], s.IDs["user2"], models.SORT_NAME_ASC, (*models.Cursor)(nil), 1, 0).
+		Return([]models.Group{{ID: s.IDs["group1"], Name: "group1"}}, nil)
+	db.On("GetSharedGroups", s.IDs["user1"], s.IDs["user2"], models.SORT_NAME_ASC, &models.Cursor{Sort: models.SORT_NAME_ASC, Name: "group1", ID: s.IDs["group1"]}, 1, 0).
+		Return([]models.Group{}, nil)
+
+	// group1 was created, group2 had its settings changed and group3 was deleted after feed position
+	s.IDs["group3"] = uuid.MustParse("0c1d7a8e-2f43-4f0b-9d8e-5a6b7c8d9e0f")
+	s.since = time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	db.On("GetGroupsModifiedSince", s.since, uuid.Nil, 50).Return([]models.Group{
+		{ID: s.IDs["group1"], Name: "Created", UpdatedAt: s.since.Add(time.Minute)},
+		{ID: s.IDs["group2"], Name: "Updated", Announcement: true, UpdatedAt: s.since.Add(2 * time.Minute)},
+		{ID: s.IDs["group3"], Name: "Deleted", UpdatedAt: s.since.Add(3 * time.Minute), DeletedAt: gorm.DeletedAt{Time: s.since.Add(3 * time.Minute), Valid: true}},
+	}, nil)
+	db.On("GetGroupsModifiedSince", s.since.Add(3*time.Minute), s.IDs["group3"], 50).Return([]models.Group{}, nil)
+
+	db.On("GetMembership", s.IDs["user1"], s.IDs["group1"], s.IDs["user1"]).Return(&models.Member{Admin: true}, nil)
+	db.On("GetMembership", s.IDs["user2"], s.IDs["group1"], s.IDs["user2"]).Return(&models.Member{}, nil)
+	db.On("GetMembership", s.IDs["user2"], s.IDs["group2"], s.IDs["user2"]).Return(nil, apperrors.NewNotFound("member", s.IDs["user2"].String()))
+	// platform of three live groups with nine memberships and one deleted group, mocked once so
+	// repeated requests have to be served from cache
+	deleted := int64(1)
+	db.On("GetPlatformGroupMetrics", false).Return(models.PlatformGroupMetrics{
+		Groups: 3, Memberships: 9, AverageGroupSize: 3, ComputedAt: time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC),
+	}, nil).Once()
+	db.On("GetPlatformGroupMetrics", true).Return(models.PlatformGroupMetrics{
+		Groups: 3, Memberships: 9, AverageGroupSize: 3, DeletedGroups: &deleted, ComputedAt: time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC),
+	}, nil).Once()
+
+	db.On("GetGroupStats", s.IDs["group1"]).Return(models.GroupStats{Members: 5, PendingInvites: 2, JoinedLast7Days: 1, JoinedLast30Days: 3}, nil).Once()
+
+	db.On("CreateGroup", s.IDs["user1"], "Existing Group").Return(models.Group{}, apperrors.NewConflict("group name", "Existing Group"))
+	db.On("CreateGroup", s.IDs["user2"], "Existing Group").Return(models.Group{Name: "Existing Group", Members: []models.Member{{ID: s.IDs["member"]}}}, nil)
+
+	db.On("CreateGroup", s.IDs["user2"], "One Too Many").
+		Return(models.Group{}, &apperrors.Error{Type: apperrors.Conflict, Message: "user cannot create more than 200 groups"})
 
 	db.On("CreateGroup", s.IDs["user1"], "New Group").Return(models.Group{Name: "New Group", Members: []models.Member{{ID: s.IDs["member"]}}}, nil)
 
+	// bulk import of two groups where second one takes name user1 already uses
+	s.IDs["bulkGroup"] = uuid.MustParse("3b1f6a2e-7c4d-4e8a-9f0b-2d5c6e7f8a9b")
+	marketing := models.GroupDefinition{Name: "Marketing", OwnerID: s.IDs["user1"], Members: []uuid.UUID{s.IDs["user2"]}}
+	existing := models.GroupDefinition{Name: "Existing Group", OwnerID: s.IDs["user1"]}
+	db.On("CreateGroups", []models.GroupDefinition{marketing, existing}, false).Return([]models.GroupCreationResult{
+		{Group: &models.Group{ID: s.IDs["bulkGroup"], Name: "Marketing", Members: []models.Member{
+			{ID: s.IDs["member"], GroupID: s.IDs["bulkGroup"], UserID: s.IDs["user1"], Creator: true},
+			{ID: uuid.New(), GroupID: s.IDs["bulkGroup"], UserID: s.IDs["user2"]},
+		}}},
+		{Err: apperrors.NewConflict("group name", "Existing Group")},
+	}, nil)
+	db.On("CreateGroups", []models.GroupDefinition{marketing, existing}, true).Return([]models.GroupCreationResult{
+		{Err: &apperrors.Error{Type: apperrors.Conflict, Message: "group not created as another group of the batch failed"}},
+		{Err: apperrors.NewConflict("group name", "Existing Group")},
+	}, nil)
+
+	db.On("GetGroupSettings", s.IDs["user1"], s.IDs["group1"]).
+		Return(models.Group{SlowModeSeconds: 30, SlowModeSet: true}.EffectiveSettings(), nil)
+	db.On("GetGroupSettings", s.IDs["user2"], s.IDs["group1"]).
+		Return(models.EffectiveSettings{}, apperrors.NewForbidden(fmt.Sprintf("User %v is not a member of group %v", s.IDs["user2"], s.IDs["group1"])))
+
+	// group1 has its creator, two admins and five basic members
+	db.On("GetGroupRoleCounts", s.IDs["user1"], s.IDs["group1"]).
+		Return(map[string]int64{"creator": 1, "co-owner": 0, "admin": 2, "deleter": 0, "basic": 5}, nil)
+	db.On("GetGroupRoleCounts", s.IDs["user2"], s.IDs["group1"]).
+		Return(nil, apperrors.NewForbidden(fmt.Sprintf("User %v is not a member of group %v", s.IDs["user2"], s.IDs["group1"])))
+
+	announcement := func(value bool) interface{} {
+		return mock.MatchedBy(func(settings models.GroupSettings) bool {
+			return settings.IsAnnouncement != nil && *settings.IsAnnouncement == value
+		})
+	}
+	db.On("UpdateGroupSettings", s.IDs["user1"], s.IDs["group1"], announcement(true)).
+		Return(models.Group{ID: s.IDs["group1"], Name: "Announcements", Announcement: true}, nil)
+	db.On("UpdateGroupSettings", s.IDs["user1"], s.IDs["group1"], announcement(false)).
+		Return(models.Group{ID: s.IDs["group1"], Name: "Announcements"}, nil)
+	slowMode := func(value int) interface{} {
+		return mock.MatchedBy(func(settings models.GroupSettings) bool {
+			return settings.SlowModeSeconds != nil && *settings.SlowModeSeconds == value
+		})
+	}
+	db.On("UpdateGroupSettings", s.IDs["user1"], s.IDs["group1"], slowMode(0)).
+		Return(models.Group{ID: s.IDs["group1"], Name: "Announcements"}, nil)
+	db.On("UpdateGroupSettings", s.IDs["user1"], s.IDs["group1"], slowMode(models.MAX_SLOW_MODE_SECONDS)).
+		Return(models.Group{ID: s.IDs["group1"], Name: "Announcements", SlowModeSeconds: models.MAX_SLOW_MODE_SECONDS}, nil)
+	db.On("UpdateGroupSettings", s.IDs["user2"], s.IDs["group1"], mock.Anything).
+		Return(models.Group{}, apperrors.NewForbidden("User has no right to change group settings"))
+	db.On("ValidateGroupSettings", s.IDs["user1"], s.IDs["group1"], slowMode(models.MAX_SLOW_MODE_SECONDS+1)).
+		Return(models.SettingsReport{Problems: []string{"slow mode must be between 0 and 21600 seconds"}, Changes: []string{"slowModeSeconds"}}, nil)
+	db.On("ValidateGroupSettings", s.IDs["user1"], s.IDs["group1"], announcement(true)).
+		Return(models.SettingsReport{Valid: true, Problems: []string{}, Changes: []string{"isAnnouncement"}}, nil)
+	db.On("ValidateGroupSettings", s.IDs["user2"], s.IDs["group2"], mock.Anything).
+		Return(models.SettingsReport{}, apperrors.NewForbidden("User has no right to change group settings"))
+
 	// Handlers don't handle emitter errors so there is no need to mock one
 	emiter := new(mockqueue.MockEmitter)
 	emiter.On("Emit", mock.Anything).Return(nil)
@@ -113,48 +228,39 @@ func (s *GroupTestSuite) TestGetUserGroups() {
 	}
 }
 
-func (s *GroupTestSuite) TestCreateGroup() {
+func (s *GroupTestSuite) TestGetActiveGroupCount() {
 	gin.SetMode(gin.TestMode)
 
 	testCases := []struct {
 		desc               string
 		userID             string
-		data               map[string]interface{}
-		returnVal          bool
 		expectedStatusCode int
-		expectedResponse   interface{}
+		expectedResponse   gin.H
 	}{
 		{
-			desc:               "CreateGroupBadUserID",
+			desc:               "GetActiveGroupCountInvalidID",
 			userID:             s.IDs["user1"].String()[:2],
-			data:               map[string]interface{}{"name": "New Group"},
-			returnVal:          false,
 			expectedStatusCode: http.StatusBadRequest,
 			expectedResponse:   gin.H{"err": "invalid ID"},
 		},
 		{
-			desc:               "CreateGroupNoName",
+			desc:               "GetActiveGroupCountSuccess",
 			userID:             s.IDs["user1"].String(),
-			data:               map[string]interface{}{"name": ""},
-			returnVal:          false,
-			expectedStatusCode: http.StatusBadRequest,
-			expectedResponse:   gin.H{"err": "name not specified"},
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   gin.H{"count": float64(2)},
 		},
 		{
-			desc:               "CreateGroupSuccess",
-			userID:             s.IDs["user1"].String(),
-			data:               map[string]interface{}{"name": "New Group"},
-			returnVal:          true,
-			expectedStatusCode: http.StatusCreated,
-			expectedResponse:   models.Group{Name: "New Group", Members: []models.Member{{ID: s.IDs["member"]}}},
+			desc:               "GetActiveGroupCountNoGroups",
+			userID:             s.IDs["user2"].String(),
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   gin.H{"count": float64(0)},
 		},
 	}
 
 	for _, tC := range testCases {
 		s.Run(tC.desc, func() {
 
-			requestBody, _ := json.Marshal(tC.data)
-			req, _ := http.NewRequest("POST", "/api/group/create", bytes.NewBuffer(requestBody))
+			req, _ := http.NewRequest(http.MethodGet, "/api/groups/count", nil)
 
 			w := httptest.NewRecorder()
 			_, engine := gin.CreateTestContext(w)
@@ -162,34 +268,1049 @@ func (s *GroupTestSuite) TestCreateGroup() {
 			engine.Use(func(c *gin.Context) {
 				c.Set("userID", tC.userID)
 			})
+			engine.Handle(http.MethodGet, "/api/groups/count", s.server.GetActiveGroupCount)
+			engine.ServeHTTP(w, req)
+			response := w.Result()
+			defer response.Body.Close()
 
-			engine.Handle(http.MethodPost, "/api/group/create", s.server.CreateGroup)
+			s.Equal(tC.expectedStatusCode, response.StatusCode)
+
+			var msg gin.H
+			if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+				s.Fail(err.Error())
+			}
+
+			s.Equal(tC.expectedResponse, msg)
+		})
+	}
+}
+
+func (s *GroupTestSuite) TestGetSharedGroups() {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		desc               string
+		targetID           string
+		query              string
+		expectedStatusCode int
+		expectedResponse   interface{}
+	}{
+		{
+			desc:               "GetSharedGroupsBadUserID",
+			targetID:           s.IDs["user2"].String()[:2],
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "invalid user ID"},
+		},
+		{
+			desc:               "GetSharedGroupsBadLimit",
+			targetID:           s.IDs["user2"].String(),
+			query:              "?limit=abc",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "limit is not a valid number"},
+		},
+		{
+			desc:               "GetSharedGroupsPartialOverlap",
+			targetID:           s.IDs["user2"].String(),
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   []models.Group{{ID: s.IDs["group1"]}},
+		},
+		{
+			desc:               "GetSharedGroupsPageEmpty",
+			targetID:           s.IDs["user2"].String(),
+			query:              "?limit=1&offset=1",
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			desc:               "GetSharedGroupsSortedPage",
+			targetID:           s.IDs["user2"].String(),
+			query:              "?limit=1&offset=1&sort=name_desc",
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   []models.Group{{ID: s.IDs["group1"]}},
+		},
+		{
+			desc:               "GetSharedGroupsBadSort",
+			targetID:           s.IDs["user2"].String(),
+			query:              "?sort=joined_desc",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "invalid sort value: joined_desc, allowed values: created_desc, created_asc, name_asc, name_desc, updated_desc"},
+		},
+		{
+			desc:               "GetSharedGroupsBadCursor",
+			targetID:           s.IDs["user2"].String(),
+			query:              "?cursor=abc!",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "invalid cursor"},
+		},
+		{
+			desc:               "GetSharedGroupsCursorOfOtherSort",
+			targetID:           s.IDs["user2"].String(),
+			query:              "?sort=created_desc&cursor=" + encodeCursor(models.Cursor{Sort: models.SORT_NAME_ASC, Name: "group1", ID: s.IDs["group1"]}),
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "cursor doesn't match sort"},
+		},
+		{
+			desc:               "GetSharedGroupsCursorWithoutTime",
+			targetID:           s.IDs["user2"].String(),
+			query:              "?cursor=" + encodeCursor(models.Cursor{Sort: models.SORT_CREATED_DESC, ID: s.IDs["group1"]}),
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "invalid cursor"},
+		},
+		{
+			desc:               "GetSharedGroupsCursorWithOffset",
+			targetID:           s.IDs["user2"].String(),
+			query:              "?sort=name_asc&offset=1&cursor=" + encodeCursor(models.Cursor{Sort: models.SORT_NAME_ASC, Name: "group1", ID: s.IDs["group1"]}),
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "cursor can't be combined with offset"},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+
+			req, _ := http.NewRequest(http.MethodGet, "/api/shared/"+tC.targetID+tC.query, nil)
+
+			w := httptest.NewRecorder()
+			_, engine := gin.CreateTestContext(w)
+
+			engine.Use(func(c *gin.Context) {
+				c.Set("userID", s.IDs["user1"].String())
+			})
+			engine.Handle(http.MethodGet, "/api/shared/:userID", s.server.GetSharedGroups)
 			engine.ServeHTTP(w, req)
 			response := w.Result()
 			defer response.Body.Close()
 
 			s.Equal(tC.expectedStatusCode, response.StatusCode)
 
-			var respBody interface{}
-			if tC.returnVal {
-				group := models.Group{}
-				if err := json.NewDecoder(response.Body).Decode(&group); err != nil {
+			switch expected := tC.expectedResponse.(type) {
+			case []models.Group:
+				respBody := []models.Group{}
+				if err := json.NewDecoder(response.Body).Decode(&respBody); err != nil {
 					s.Fail(err.Error())
 				}
-				respBody = group
-			} else {
+				s.Equal(expected, respBody)
+			case gin.H:
 				var msg gin.H
 				if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
 					s.Fail(err.Error())
 				}
-				respBody = msg
+				s.Equal(expected, msg)
 			}
+		})
+	}
+}
 
-			s.Equal(tC.expectedResponse, respBody)
+func (s *GroupTestSuite) TestGetSharedGroupsCursor() {
+	gin.SetMode(gin.TestMode)
+
+	engine := gin.New()
+	engine.Use(func(c *gin.Context) {
+		c.Set("userID", s.IDs["user1"].String())
+	})
+	engine.Handle(http.MethodGet, "/api/shared/:userID", s.server.GetSharedGroups)
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/shared/"+s.IDs["user2"].String()+"?limit=1&sort=name_asc", nil)
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	s.Equal(http.StatusOK, w.Code)
+	cursor := w.Header().Get(handlers.NEXT_CURSOR_HEADER)
+	s.Equal(encodeCursor(models.Cursor{Sort: models.SORT_NAME_ASC, Name: "group1", ID: s.IDs["group1"]}), cursor)
+
+	req, _ = http.NewRequest(http.MethodGet, "/api/shared/"+s.IDs["user2"].String()+"?limit=1&sort=name_asc&cursor="+cursor, nil)
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	s.Equal(http.StatusNoContent, w.Code)
+	s.Empty(w.Header().Get(handlers.NEXT_CURSOR_HEADER))
+}
+
+func (s *GroupTestSuite) TestGetGroupsModifiedSince() {
+	gin.SetMode(gin.TestMode)
+
+	last := s.since.Add(3 * time.Minute)
+
+	testCases := []struct {
+		desc               string
+		query              string
+		expectedStatusCode int
+		expectedResponse   interface{}
+	}{
+		{
+			desc:               "GetGroupsModifiedSinceBadSince",
+			query:              "?since=yesterday",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "invalid since timestamp"},
+		},
+		{
+			desc:               "GetGroupsModifiedSinceBadAfterID",
+			query:              "?since=" + s.since.Format(time.RFC3339Nano) + "&afterID=abc",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "invalid group ID"},
+		},
+		{
+			desc:               "GetGroupsModifiedSinceChanges",
+			query:              "?since=" + s.since.Format(time.RFC3339Nano),
+			expectedStatusCode: http.StatusOK,
+			expectedResponse: []models.GroupChange{
+				{Group: models.Group{ID: s.IDs["group1"], Name: "Created", UpdatedAt: s.since.Add(time.Minute)}},
+				{Group: models.Group{ID: s.IDs["group2"], Name: "Updated", Announcement: true, UpdatedAt: s.since.Add(2 * time.Minute)}},
+				{Group: models.Group{ID: s.IDs["group3"], Name: "Deleted", UpdatedAt: last}, Deleted: true},
+			},
+		},
+		{
+			desc:               "GetGroupsModifiedSinceNextPageEmpty",
+			query:              "?since=" + last.Format(time.RFC3339Nano) + "&afterID=" + s.IDs["group3"].String(),
+			expectedStatusCode: http.StatusNoContent,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+
+			req, _ := http.NewRequest(http.MethodGet, "/internal/groups/modified"+tC.query, nil)
+
+			w := httptest.NewRecorder()
+			_, engine := gin.CreateTestContext(w)
+
+			engine.Handle(http.MethodGet, "/internal/groups/modified", s.server.GetGroupsModifiedSince)
+			engine.ServeHTTP(w, req)
+			response := w.Result()
+			defer response.Body.Close()
+
+			s.Equal(tC.expectedStatusCode, response.StatusCode)
+
+			switch expected := tC.expectedResponse.(type) {
+			case []models.GroupChange:
+				respBody := []models.GroupChange{}
+				if err := json.NewDecoder(response.Body).Decode(&respBody); err != nil {
+					s.Fail(err.Error())
+				}
+				s.Equal(expected, respBody)
+			case gin.H:
+				var msg gin.H
+				if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+					s.Fail(err.Error())
+				}
+				s.Equal(expected, msg)
+			}
+		})
+	}
+}
+
+func (s *GroupTestSuite) TestCheckGroupsExist() {
+	gin.SetMode(gin.TestMode)
+
+	tooMany := make([]string, handlers.MAX_GROUP_EXISTENCE_BATCH+1)
+	for i := range tooMany {
+		tooMany[i] = uuid.NewString()
+	}
+
+	testCases := []struct {
+		desc               string
+		data               map[string]interface{}
+		expectedStatusCode int
+		expectedResponse   gin.H
+	}{
+		{
+			desc:               "CheckGroupsExistNoGroups",
+			data:               map[string]interface{}{"groupIDs": []string{}},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "groups not specified"},
+		},
+		{
+			desc:               "CheckGroupsExistInvalidID",
+			data:               map[string]interface{}{"groupIDs": []string{"abc"}},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "invalid group ID"},
+		},
+		{
+			desc:               "CheckGroupsExistTooMany",
+			data:               map[string]interface{}{"groupIDs": tooMany},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": fmt.Sprintf("at most %d groups can be checked at once", handlers.MAX_GROUP_EXISTENCE_BATCH)},
+		},
+		{
+			desc: "CheckGroupsExistMixed",
+			data: map[string]interface{}{"groupIDs": []string{
+				s.IDs["group1"].String(), s.IDs["groupDeleted"].String(), s.IDs["group1"].String(), s.IDs["groupUnknown"].String(),
+			}},
+			expectedStatusCode: http.StatusOK,
+			expectedResponse: gin.H{
+				"existing": []interface{}{s.IDs["group1"].String()},
+				"deleted":  []interface{}{s.IDs["groupDeleted"].String()},
+				"missing":  []interface{}{s.IDs["groupUnknown"].String()},
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+
+			requestBody, _ := json.Marshal(tC.data)
+			req, _ := http.NewRequest(http.MethodPost, "/internal/groups/exist", bytes.NewBuffer(requestBody))
+
+			w := httptest.NewRecorder()
+			_, engine := gin.CreateTestContext(w)
+
+			engine.Handle(http.MethodPost, "/internal/groups/exist", s.server.CheckGroupsExist)
+			engine.ServeHTTP(w, req)
+			response := w.Result()
+			defer response.Body.Close()
+
+			s.Equal(tC.expectedStatusCode, response.StatusCode)
+
+			var msg gin.H
+			if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+				s.Fail(err.Error())
+			}
+
+			s.Equal(tC.expectedResponse, msg)
 		})
 	}
 }
 
+func (s *GroupTestSuite) TestBulkCreateGroups() {
+	gin.SetMode(gin.TestMode)
+
+	tooMany := make([]gin.H, handlers.MAX_BULK_GROUPS+1)
+	for i := range tooMany {
+		tooMany[i] = gin.H{"name": fmt.Sprintf("Group %d", i), "ownerID": s.IDs["user1"]}
+	}
+	marketing := gin.H{"name": "  Marketing ", "ownerID": s.IDs["user1"], "members": []uuid.UUID{s.IDs["user2"]}}
+	existing := gin.H{"name": "Existing Group", "ownerID": s.IDs["user1"]}
+	unnamed := gin.H{"name": " ", "ownerID": s.IDs["user2"]}
+
+	testCases := []struct {
+		desc               string
+		data               map[string]interface{}
+		expectedStatusCode int
+		expectedResponse   gin.H
+	}{
+		{
+			desc:               "BulkCreateGroupsNoGroups",
+			data:               map[string]interface{}{"groups": []gin.H{}},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "groups not specified"},
+		},
+		{
+			desc:               "BulkCreateGroupsTooMany",
+			data:               map[string]interface{}{"groups": tooMany},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": fmt.Sprintf("at most %d groups can be created at once", handlers.MAX_BULK_GROUPS)},
+		},
+		{
+			desc:               "BulkCreateGroupsPerGroup",
+			data:               map[string]interface{}{"groups": []gin.H{marketing, unnamed, existing}},
+			expectedStatusCode: http.StatusOK,
+			expectedResponse: gin.H{"results": []interface{}{
+				map[string]interface{}{"index": float64(0), "groupID": s.IDs["bulkGroup"].String(), "created": true},
+				map[string]interface{}{"index": float64(1), "created": false, "err": "group name cannot be empty"},
+				map[string]interface{}{"index": float64(2), "created": false, "err": "resource: group name with value: Existing Group already exists"},
+			}},
+		},
+		{
+			desc:               "BulkCreateGroupsAllOrNothingInvalidName",
+			data:               map[string]interface{}{"groups": []gin.H{marketing, unnamed, existing}, "allOrNothing": true},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			expectedResponse:   gin.H{"err": "group 1: group name cannot be empty"},
+		},
+		{
+			desc:               "BulkCreateGroupsAllOrNothingRolledBack",
+			data:               map[string]interface{}{"groups": []gin.H{marketing, existing}, "allOrNothing": true},
+			expectedStatusCode: http.StatusOK,
+			expectedResponse: gin.H{"results": []interface{}{
+				map[string]interface{}{"index": float64(0), "created": false, "err": "group not created as another group of the batch failed"},
+				map[string]interface{}{"index": float64(1), "created": false, "err": "resource: group name with value: Existing Group already exists"},
+			}},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+
+			requestBody, _ := json.Marshal(tC.data)
+			req, _ := http.NewRequest(http.MethodPost, "/internal/groups/bulk", bytes.NewBuffer(requestBody))
+
+			w := httptest.NewRecorder()
+			_, engine := gin.CreateTestContext(w)
+
+			engine.Handle(http.MethodPost, "/internal/groups/bulk", s.server.BulkCreateGroups)
+			engine.ServeHTTP(w, req)
+			response := w.Result()
+			defer response.Body.Close()
+
+			s.Equal(tC.expectedStatusCode, response.StatusCode)
+
+			var msg gin.H
+			if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+				s.Fail(err.Error())
+			}
+
+			s.Equal(tC.expectedResponse, msg)
+		})
+	}
+}
+
+func (s *GroupTestSuite) TestCreateGroup() {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		desc               string
+		userID             string
+		data               map[string]interface{}
+		returnVal          bool
+		expectedStatusCode int
+		expectedResponse   interface{}
+	}{
+		{
+			desc:               "CreateGroupBadUserID",
+			userID:             s.IDs["user1"].String()[:2],
+			data:               map[string]interface{}{"name": "New Group"},
+			returnVal:          false,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "invalid ID"},
+		},
+		{
+			desc:               "CreateGroupNoName",
+			userID:             s.IDs["user1"].String(),
+			data:               map[string]interface{}{"name": ""},
+			returnVal:          false,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "name not specified"},
+		},
+		{
+			desc:               "CreateGroupWhitespaceName",
+			userID:             s.IDs["user1"].String(),
+			data:               map[string]interface{}{"name": "   "},
+			returnVal:          false,
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			expectedResponse:   gin.H{"err": "group name cannot be empty"},
+		},
+		{
+			desc:               "CreateGroupNameTooLong",
+			userID:             s.IDs["user1"].String(),
+			data:               map[string]interface{}{"name": strings.Repeat("a", 101)},
+			returnVal:          false,
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			expectedResponse:   gin.H{"err": "group name cannot be longer than 100 characters", "field": "name", "limit": float64(models.MAX_GROUP_NAME_LENGTH)},
+		},
+		{
+			desc:               "CreateGroupZeroWidthName",
+			userID:             s.IDs["user1"].String(),
+			data:               map[string]interface{}{"name": "New\u200bGroup"},
+			returnVal:          false,
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			expectedResponse:   gin.H{"err": "group name contains control or invisible characters"},
+		},
+		{
+			desc:               "CreateGroupNameTrimmed",
+			userID:             s.IDs["user1"].String(),
+			data:               map[string]interface{}{"name": "  New Group  "},
+			returnVal:          true,
+			expectedStatusCode: http.StatusCreated,
+			expectedResponse:   models.Group{Name: "New Group", Members: []models.Member{{ID: s.IDs["member"]}}},
+		},
+		{
+			desc:               "CreateGroupNameTaken",
+			userID:             s.IDs["user1"].String(),
+			data:               map[string]interface{}{"name": "Existing Group"},
+			returnVal:          false,
+			expectedStatusCode: http.StatusConflict,
+			expectedResponse:   gin.H{"err": "resource: group name with value: Existing Group already exists"},
+		},
+		{
+			desc:               "CreateGroupNameTakenByOtherUser",
+			userID:             s.IDs["user2"].String(),
+			data:               map[string]interface{}{"name": "Existing Group"},
+			returnVal:          true,
+			expectedStatusCode: http.StatusCreated,
+			expectedResponse:   models.Group{Name: "Existing Group", Members: []models.Member{{ID: s.IDs["member"]}}},
+		},
+		{
+			desc:               "CreateGroupLimitReached",
+			userID:             s.IDs["user2"].String(),
+			data:               map[string]interface{}{"name": "One Too Many"},
+			returnVal:          false,
+			expectedStatusCode: http.StatusConflict,
+			expectedResponse:   gin.H{"err": "user cannot create more than 200 groups"},
+		},
+		{
+			desc:               "CreateGroupSuccess",
+			userID:             s.IDs["user1"].String(),
+			data:               map[string]interface{}{"name": "New Group"},
+			returnVal:          true,
+			expectedStatusCode: http.StatusCreated,
+			expectedResponse:   models.Group{Name: "New Group", Members: []models.Member{{ID: s.IDs["member"]}}},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+
+			requestBody, _ := json.Marshal(tC.data)
+			req, _ := http.NewRequest("POST", "/api/group/create", bytes.NewBuffer(requestBody))
+
+			w := httptest.NewRecorder()
+			_, engine := gin.CreateTestContext(w)
+
+			engine.Use(func(c *gin.Context) {
+				c.Set("userID", tC.userID)
+			})
+
+			engine.Handle(http.MethodPost, "/api/group/create", s.server.CreateGroup)
+			engine.ServeHTTP(w, req)
+			response := w.Result()
+			defer response.Body.Close()
+
+			s.Equal(tC.expectedStatusCode, response.StatusCode)
+
+			var respBody interface{}
+			if tC.returnVal {
+				group := models.Group{}
+				if err := json.NewDecoder(response.Body).Decode(&group); err != nil {
+					s.Fail(err.Error())
+				}
+				respBody = group
+			} else {
+				var msg gin.H
+				if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+					s.Fail(err.Error())
+				}
+				respBody = msg
+			}
+
+			s.Equal(tC.expectedResponse, respBody)
+		})
+	}
+}
+
+func (s *GroupTestSuite) TestGetGroupRoleCounts() {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		desc               string
+		userID             string
+		groupID            string
+		expectedStatusCode int
+		expectedResponse   gin.H
+	}{
+		{
+			desc:               "GetGroupRoleCountsInvalidGroupID",
+			userID:             s.IDs["user1"].String(),
+			groupID:            "1",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "invalid group ID"},
+		},
+		{
+			desc:               "GetGroupRoleCountsNotMember",
+			userID:             s.IDs["user2"].String(),
+			groupID:            s.IDs["group1"].String(),
+			expectedStatusCode: http.StatusForbidden,
+			expectedResponse:   gin.H{"err": fmt.Sprintf("Forbidden action. Reason: User %v is not a member of group %v", s.IDs["user2"], s.IDs["group1"])},
+		},
+		{
+			desc:               "GetGroupRoleCountsSuccess",
+			userID:             s.IDs["user1"].String(),
+			groupID:            s.IDs["group1"].String(),
+			expectedStatusCode: http.StatusOK,
+			expectedResponse: gin.H{"roles": map[string]interface{}{
+				"creator": float64(1), "co-owner": float64(0), "admin": float64(2), "deleter": float64(0), "basic": float64(5),
+			}},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+
+			req, _ := http.NewRequest(http.MethodGet, "/api/group/"+tC.groupID+"/roles", nil)
+
+			w := httptest.NewRecorder()
+			_, engine := gin.CreateTestContext(w)
+			engine.Use(func(c *gin.Context) {
+				c.Set("userID", tC.userID)
+			})
+
+			engine.Handle(http.MethodGet, "/api/group/:groupID/roles", s.server.GetGroupRoleCounts)
+			engine.ServeHTTP(w, req)
+			response := w.Result()
+			defer response.Body.Close()
+
+			s.Equal(tC.expectedStatusCode, response.StatusCode)
+
+			var msg gin.H
+			if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+				s.Fail(err.Error())
+			}
+
+			s.Equal(tC.expectedResponse, msg)
+		})
+	}
+}
+
+func (s *GroupTestSuite) TestGetGroupSettings() {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		desc               string
+		userID             string
+		groupID            string
+		expectedStatusCode int
+		expectedResponse   gin.H
+	}{
+		{
+			desc:               "GetGroupSettingsInvalidGroupID",
+			userID:             s.IDs["user1"].String(),
+			groupID:            "1",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "invalid group ID"},
+		},
+		{
+			desc:               "GetGroupSettingsNotMember",
+			userID:             s.IDs["user2"].String(),
+			groupID:            s.IDs["group1"].String(),
+			expectedStatusCode: http.StatusForbidden,
+			expectedResponse:   gin.H{"err": fmt.Sprintf("Forbidden action. Reason: User %v is not a member of group %v", s.IDs["user2"], s.IDs["group1"])},
+		},
+		{
+			desc:               "GetGroupSettingsDefaultsMerged",
+			userID:             s.IDs["user1"].String(),
+			groupID:            s.IDs["group1"].String(),
+			expectedStatusCode: http.StatusOK,
+			expectedResponse: gin.H{
+				"isAnnouncement":  map[string]interface{}{"value": false, "explicit": false},
+				"slowModeSeconds": map[string]interface{}{"value": float64(30), "explicit": true},
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+
+			req, _ := http.NewRequest(http.MethodGet, "/api/group/"+tC.groupID+"/settings", nil)
+
+			w := httptest.NewRecorder()
+			_, engine := gin.CreateTestContext(w)
+			engine.Use(func(c *gin.Context) {
+				c.Set("userID", tC.userID)
+			})
+
+			engine.Handle(http.MethodGet, "/api/group/:groupID/settings", s.server.GetGroupSettings)
+			engine.ServeHTTP(w, req)
+			response := w.Result()
+			defer response.Body.Close()
+
+			s.Equal(tC.expectedStatusCode, response.StatusCode)
+
+			var msg gin.H
+			if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+				s.Fail(err.Error())
+			}
+
+			s.Equal(tC.expectedResponse, msg)
+		})
+	}
+}
+
+func (s *GroupTestSuite) TestUpdateGroupSettings() {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		desc               string
+		userID             string
+		groupID            string
+		query              string
+		data               map[string]interface{}
+		returnVal          bool
+		expectedStatusCode int
+		expectedResponse   interface{}
+	}{
+		{
+			desc:               "UpdateGroupSettingsBadGroupID",
+			userID:             s.IDs["user1"].String(),
+			groupID:            s.IDs["group1"].String()[:2],
+			data:               map[string]interface{}{"isAnnouncement": true},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "invalid group ID"},
+		},
+		{
+			desc:               "UpdateGroupSettingsNothingSet",
+			userID:             s.IDs["user1"].String(),
+			groupID:            s.IDs["group1"].String(),
+			data:               map[string]interface{}{},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "no settings specified"},
+		},
+		{
+			desc:               "UpdateGroupSettingsNotCreator",
+			userID:             s.IDs["user2"].String(),
+			groupID:            s.IDs["group1"].String(),
+			data:               map[string]interface{}{"isAnnouncement": true},
+			expectedStatusCode: http.StatusForbidden,
+			expectedResponse:   gin.H{"err": "Forbidden action. Reason: User has no right to change group settings"},
+		},
+		{
+			desc:               "UpdateGroupSettingsAnnouncementOn",
+			userID:             s.IDs["user1"].String(),
+			groupID:            s.IDs["group1"].String(),
+			data:               map[string]interface{}{"isAnnouncement": true},
+			returnVal:          true,
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   models.Group{ID: s.IDs["group1"], Name: "Announcements", Announcement: true},
+		},
+		{
+			desc:               "UpdateGroupSettingsAnnouncementOff",
+			userID:             s.IDs["user1"].String(),
+			groupID:            s.IDs["group1"].String(),
+			data:               map[string]interface{}{"isAnnouncement": false},
+			returnVal:          true,
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   models.Group{ID: s.IDs["group1"], Name: "Announcements"},
+		},
+		{
+			desc:               "UpdateGroupSettingsSlowModeNegative",
+			userID:             s.IDs["user1"].String(),
+			groupID:            s.IDs["group1"].String(),
+			data:               map[string]interface{}{"slowModeSeconds": -1},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "slow mode must be between 0 and 21600 seconds"},
+		},
+		{
+			desc:               "UpdateGroupSettingsSlowModeTooLong",
+			userID:             s.IDs["user1"].String(),
+			groupID:            s.IDs["group1"].String(),
+			data:               map[string]interface{}{"slowModeSeconds": models.MAX_SLOW_MODE_SECONDS + 1},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "slow mode must be between 0 and 21600 seconds"},
+		},
+		{
+			desc:               "UpdateGroupSettingsSlowModeOff",
+			userID:             s.IDs["user1"].String(),
+			groupID:            s.IDs["group1"].String(),
+			data:               map[string]interface{}{"slowModeSeconds": 0},
+			returnVal:          true,
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   models.Group{ID: s.IDs["group1"], Name: "Announcements"},
+		},
+		{
+			desc:               "UpdateGroupSettingsSlowModeMax",
+			userID:             s.IDs["user1"].String(),
+			groupID:            s.IDs["group1"].String(),
+			data:               map[string]interface{}{"slowModeSeconds": models.MAX_SLOW_MODE_SECONDS},
+			returnVal:          true,
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   models.Group{ID: s.IDs["group1"], Name: "Announcements", SlowModeSeconds: models.MAX_SLOW_MODE_SECONDS},
+		},
+		{
+			desc:               "UpdateGroupSettingsInvalidValidateOnly",
+			userID:             s.IDs["user1"].String(),
+			groupID:            s.IDs["group1"].String(),
+			query:              "?validate_only=maybe",
+			data:               map[string]interface{}{"isAnnouncement": true},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "invalid validate_only value"},
+		},
+		{
+			desc:               "UpdateGroupSettingsValidateOnlyNotMember",
+			userID:             s.IDs["user2"].String(),
+			groupID:            s.IDs["group2"].String(),
+			query:              "?validate_only=true",
+			data:               map[string]interface{}{"isAnnouncement": true},
+			expectedStatusCode: http.StatusForbidden,
+			expectedResponse:   gin.H{"err": "Forbidden action. Reason: User has no right to change group settings"},
+		},
+		{
+			desc:               "UpdateGroupSettingsValidateOnlyProblems",
+			userID:             s.IDs["user1"].String(),
+			groupID:            s.IDs["group1"].String(),
+			query:              "?validate_only=true",
+			data:               map[string]interface{}{"slowModeSeconds": models.MAX_SLOW_MODE_SECONDS + 1},
+			expectedStatusCode: http.StatusOK,
+			expectedResponse: gin.H{
+				"valid":    false,
+				"problems": []interface{}{"slow mode must be between 0 and 21600 seconds"},
+				"changes":  []interface{}{"slowModeSeconds"},
+			},
+		},
+		{
+			desc:               "UpdateGroupSettingsValidateOnlyValid",
+			userID:             s.IDs["user1"].String(),
+			groupID:            s.IDs["group1"].String(),
+			query:              "?validate_only=true",
+			data:               map[string]interface{}{"isAnnouncement": true},
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   gin.H{"valid": true, "problems": []interface{}{}, "changes": []interface{}{"isAnnouncement"}},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+
+			requestBody, _ := json.Marshal(tC.data)
+			req, _ := http.NewRequest(http.MethodPatch, "/api/group/"+tC.groupID+"/settings"+tC.query, bytes.NewBuffer(requestBody))
+
+			w := httptest.NewRecorder()
+			_, engine := gin.CreateTestContext(w)
+
+			engine.Use(func(c *gin.Context) {
+				c.Set("userID", tC.userID)
+			})
+			engine.Handle(http.MethodPatch, "/api/group/:groupID/settings", s.server.UpdateGroupSettings)
+			engine.ServeHTTP(w, req)
+			response := w.Result()
+			defer response.Body.Close()
+
+			s.Equal(tC.expectedStatusCode, response.StatusCode)
+
+			var respBody interface{}
+			if tC.returnVal {
+				group := models.Group{}
+				if err := json.NewDecoder(response.Body).Decode(&group); err != nil {
+					s.Fail(err.Error())
+				}
+				respBody = group
+			} else {
+				var msg gin.H
+				if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+					s.Fail(err.Error())
+				}
+				respBody = msg
+			}
+
+			s.Equal(tC.expectedResponse, respBody)
+		})
+	}
+}
+
+func (s *GroupTestSuite) TestAdminGroupMetrics() {
+	gin.SetMode(gin.TestMode)
+
+	computedAt := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC).Format(time.RFC3339)
+	testCases := []struct {
+		desc               string
+		query              string
+		expectedStatusCode int
+		expectedResponse   gin.H
+	}{
+		{
+			desc:               "AdminMetricsInvalidFlag",
+			query:              "?includeDeleted=maybe",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "invalid includeDeleted value"},
+		},
+		{
+			desc:               "AdminMetricsLive",
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   gin.H{"groups": float64(3), "memberships": float64(9), "averageGroupSize": float64(3), "computedAt": computedAt},
+		},
+		{
+			desc:               "AdminMetricsCached",
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   gin.H{"groups": float64(3), "memberships": float64(9), "averageGroupSize": float64(3), "computedAt": computedAt},
+		},
+		{
+			desc:               "AdminMetricsIncludeDeleted",
+			query:              "?includeDeleted=true",
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   gin.H{"groups": float64(3), "memberships": float64(9), "averageGroupSize": float64(3), "deletedGroups": float64(1), "computedAt": computedAt},
+		},
+		{
+			desc:               "AdminMetricsIncludeDeletedCached",
+			query:              "?includeDeleted=1",
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   gin.H{"groups": float64(3), "memberships": float64(9), "averageGroupSize": float64(3), "deletedGroups": float64(1), "computedAt": computedAt},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+
+			req, _ := http.NewRequest(http.MethodGet, "/internal/groups/metrics"+tC.query, nil)
+
+			w := httptest.NewRecorder()
+			_, engine := gin.CreateTestContext(w)
+
+			engine.Handle(http.MethodGet, "/internal/groups/metrics", s.server.AdminGroupMetrics)
+			engine.ServeHTTP(w, req)
+			response := w.Result()
+			defer response.Body.Close()
+
+			s.Equal(tC.expectedStatusCode, response.StatusCode)
+
+			var msg gin.H
+			if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+				s.Fail(err.Error())
+			}
+			s.Equal(tC.expectedResponse, msg)
+		})
+	}
+}
+
+func (s *GroupTestSuite) TestGetGroupStats() {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		desc               string
+		userID             string
+		groupID            string
+		returnVal          bool
+		expectedStatusCode int
+		expectedResponse   interface{}
+	}{
+		{
+			desc:               "GetGroupStatsNoGroup",
+			userID:             s.IDs["user1"].String(),
+			groupID:            "1",
+			returnVal:          false,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "invalid group ID"},
+		},
+		{
+			desc:               "GetGroupStatsNoRights",
+			userID:             s.IDs["user2"].String(),
+			groupID:            s.IDs["group1"].String(),
+			returnVal:          false,
+			expectedStatusCode: http.StatusForbidden,
+			expectedResponse:   gin.H{"err": "user " + s.IDs["user2"].String() + " has no right to view stats of group " + s.IDs["group1"].String()},
+		},
+		{
+			desc:               "GetGroupStatsNotMember",
+			userID:             s.IDs["user2"].String(),
+			groupID:            s.IDs["group2"].String(),
+			returnVal:          false,
+			expectedStatusCode: http.StatusForbidden,
+			expectedResponse:   gin.H{"err": "user " + s.IDs["user2"].String() + " has no right to view stats of group " + s.IDs["group2"].String()},
+		},
+		{
+			desc:               "GetGroupStatsSuccess",
+			userID:             s.IDs["user1"].String(),
+			groupID:            s.IDs["group1"].String(),
+			returnVal:          true,
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   models.GroupStats{Members: 5, PendingInvites: 2, JoinedLast7Days: 1, JoinedLast30Days: 3},
+		},
+		{
+			// GetGroupStats is mocked to be called only once so this one has to be served from cache
+			desc:               "GetGroupStatsCached",
+			userID:             s.IDs["user1"].String(),
+			groupID:            s.IDs["group1"].String(),
+			returnVal:          true,
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   models.GroupStats{Members: 5, PendingInvites: 2, JoinedLast7Days: 1, JoinedLast30Days: 3},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+
+			req, _ := http.NewRequest("GET", "/api/group/"+tC.groupID+"/stats", nil)
+
+			w := httptest.NewRecorder()
+			_, engine := gin.CreateTestContext(w)
+
+			engine.Use(func(c *gin.Context) {
+				c.Set("userID", tC.userID)
+			})
+			engine.Handle(http.MethodGet, "/api/group/:groupID/stats", s.server.GetGroupStats)
+			engine.ServeHTTP(w, req)
+			response := w.Result()
+			defer response.Body.Close()
+
+			s.Equal(tC.expectedStatusCode, response.StatusCode)
+
+			var respBody interface{}
+			if tC.returnVal {
+				stats := models.GroupStats{}
+				if err := json.NewDecoder(response.Body).Decode(&stats); err != nil {
+					s.Fail(err.Error())
+				}
+				respBody = stats
+			} else {
+				var msg gin.H
+				if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+					s.Fail(err.Error())
+				}
+				respBody = msg
+			}
+
+			s.Equal(tC.expectedResponse, respBody)
+		})
+	}
+}
+
+func (s *GroupTestSuite) TestGetGroupCard() {
+	gin.SetMode(gin.TestMode)
+
+	get := func(userID, groupID, ifNoneMatch string) *http.Response {
+		req, _ := http.NewRequest(http.MethodGet, "/api/group/"+groupID+"/card", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+
+		w := httptest.NewRecorder()
+		_, engine := gin.CreateTestContext(w)
+		engine.Use(func(c *gin.Context) {
+			c.Set("userID", userID)
+		})
+		engine.Handle(http.MethodGet, "/api/group/:groupID/card", s.server.GetGroupCard)
+		engine.ServeHTTP(w, req)
+		return w.Result()
+	}
+
+	s.Run("GetGroupCardInvalidGroupID", func() {
+		response := get(s.IDs["user1"].String(), "1", "")
+		defer response.Body.Close()
+
+		s.Equal(http.StatusBadRequest, response.StatusCode)
+		var msg gin.H
+		_ = json.NewDecoder(response.Body).Decode(&msg)
+		s.Equal(gin.H{"err": "invalid group ID"}, msg)
+	})
+
+	s.Run("GetGroupCardNotMember", func() {
+		response := get(s.IDs["user2"].String(), s.IDs["group1"].String(), "")
+		defer response.Body.Close()
+
+		s.Equal(http.StatusForbidden, response.StatusCode)
+		s.Empty(response.Header.Get("ETag"))
+		var msg gin.H
+		_ = json.NewDecoder(response.Body).Decode(&msg)
+		s.Equal(gin.H{"err": fmt.Sprintf("Forbidden action. Reason: User %v is not a member of group %v", s.IDs["user2"], s.IDs["group1"])}, msg)
+	})
+
+	var etag string
+	s.Run("GetGroupCardMember", func() {
+		response := get(s.IDs["user1"].String(), s.IDs["group1"].String(), "")
+		defer response.Body.Close()
+
+		s.Equal(http.StatusOK, response.StatusCode)
+		s.Equal(handlers.GROUP_CARD_CACHE_CONTROL, response.Header.Get("Cache-Control"))
+		etag = response.Header.Get("ETag")
+		s.NotEmpty(etag)
+
+		var card models.GroupCard
+		if err := json.NewDecoder(response.Body).Decode(&card); err != nil {
+			s.Fail(err.Error())
+		}
+		s.Equal(models.GroupCard{ID: s.IDs["group1"], Name: "group1", Picture: "picture", MemberCount: 5}, card)
+	})
+
+	s.Run("GetGroupCardNotModified", func() {
+		response := get(s.IDs["user1"].String(), s.IDs["group1"].String(), etag)
+		defer response.Body.Close()
+
+		s.Equal(http.StatusNotModified, response.StatusCode)
+		s.Equal(etag, response.Header.Get("ETag"))
+	})
+
+	s.Run("GetGroupCardStaleETag", func() {
+		response := get(s.IDs["user1"].String(), s.IDs["group1"].String(), `"stale"`)
+		defer response.Body.Close()
+
+		s.Equal(http.StatusOK, response.StatusCode)
+	})
+}
+
 func TestGroupSuite(t *testing.T) {
 	suite.Run(t, &GroupTestSuite{})
 }
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"strconv"
+)
+
+var errBadImage = errors.New("bad image")
+var errCropOutOfBounds = errors.New("crop rectangle doesn't fit within image")
+var errImageTooLarge = errors.New("image dimensions exceed")
+
+// processImage validates uploaded image and crops it when crop is set, it returns file that should be uploaded
+// together with its size
+func processImage(file multipart.File, size int64, maxDimension int, crop *image.Rectangle) (multipart.File, int64, error) {
+	if err := checkImageDimensions(file, maxDimension); err != nil {
+		return nil, 0, err
+	}
+	if crop == nil {
+		return file, size, nil
+	}
+	cropped, err := cropImage(file, *crop)
+	if err != nil {
+		return nil, 0, err
+	}
+	return cropped, cropped.Size(), nil
+}
+
+// checkImageDimensions reads only the header of an image to verify that its width and height don't exceed
+// maxDimension, so oversized images are rejected before being decoded into memory. Reader is rewound afterwards.
+func checkImageDimensions(file io.ReadSeeker, maxDimension int) error {
+	config, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return errBadImage
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return errBadImage
+	}
+	if config.Width > maxDimension || config.Height > maxDimension {
+		return fmt.Errorf("%w %dpx", errImageTooLarge, maxDimension)
+	}
+	return nil
+}
+
+// parseCropRect builds crop rectangle from x, y, width and height values. It returns nil when none of them
+// is set, setting only some of them is an error
+func parseCropRect(value func(string) string) (*image.Rectangle, error) {
+	keys := []string{"x", "y", "width", "height"}
+	values := make([]int, len(keys))
+	var set int
+	for i, key := range keys {
+		if value(key) == "" {
+			continue
+		}
+		v, err := strconv.Atoi(value(key))
+		if err != nil {
+			return nil, fmt.Errorf("invalid crop %s", key)
+		}
+		values[i] = v
+		set++
+	}
+	if set == 0 {
+		return nil, nil
+	}
+	if set != len(keys) {
+		return nil, errors.New("crop requires x, y, width and height")
+	}
+	if values[2] <= 0 || values[3] <= 0 {
+		return nil, errors.New("crop width and height must be positive")
+	}
+
+	rect := image.Rect(values[0], values[1], values[0]+values[2], values[1]+values[3])
+	return &rect, nil
+}
+
+// cropImage decodes image, cuts out rect and encodes the result in image's original format
+func cropImage(file io.Reader, rect image.Rectangle) (*memoryFile, error) {
+	img, format, err := image.Decode(file)
+	if err != nil {
+		return nil, errBadImage
+	}
+	// rect is relative to image's top left corner
+	rect = rect.Add(img.Bounds().Min)
+	if !rect.In(img.Bounds()) {
+		return nil, errCropOutOfBounds
+	}
+	cropper, ok := img.(interface {
+		SubImage(r image.Rectangle) image.Image
+	})
+	if !ok {
+		return nil, errBadImage
+	}
+	cropped := cropper.SubImage(rect)
+
+	var buf bytes.Buffer
+	switch format {
+	case "jpeg":
+		err = jpeg.Encode(&buf, cropped, nil)
+	case "png":
+		err = png.Encode(&buf, cropped)
+	default:
+		err = errBadImage
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &memoryFile{Reader: bytes.NewReader(buf.Bytes())}, nil
+}
+
+// memoryFile lets image processed in memory be uploaded like a multipart file
+type memoryFile struct {
+	*bytes.Reader
+}
+
+func (memoryFile) Close() error {
+	return nil
+}
@@ -1,11 +1,12 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
-	"strconv"
 
 	"github.com/Slimo300/MicroservicesChatApp/backend/lib/apperrors"
 	"github.com/Slimo300/MicroservicesChatApp/backend/lib/events"
+	"github.com/Slimo300/chat-groupservice/internal/models"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
@@ -18,17 +19,53 @@ func (s *Server) GetUserInvites(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"err": "Invalid ID"})
 		return
 	}
-	num, err := strconv.Atoi(c.Query("num"))
-	if err != nil || num <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"err": "number of messages is not a valid number"})
+	page, err := parsePagination(c.Request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+		return
+	}
+	invites, err := s.requestDB(c).GetUserInvites(userUID, page.Limit, page.Offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"err": err.Error()})
+		return
+	}
+
+	if len(invites) == 0 {
+		c.Status(http.StatusNoContent)
 		return
 	}
-	offset, err := strconv.Atoi(c.Query("offset"))
-	if err != nil || offset < 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"err": "offset is not a valid number"})
+
+	c.JSON(http.StatusOK, formatTimes(c, invites))
+}
+
+// GetSentInvites returns invites issued by caller. Only awaiting invites are returned unless "status" query
+// parameter asks for other status or for "all" of them
+func (s *Server) GetSentInvites(c *gin.Context) {
+	userID := c.GetString("userID")
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid ID"})
+		return
+	}
+	status := models.INVITE_AWAITING
+	switch name := c.Query("status"); name {
+	case "":
+	case "all":
+		status = 0
+	default:
+		status, err = models.ParseInviteStatus(name)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+	}
+	page, err := parsePagination(c.Request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
 		return
 	}
-	invites, err := s.DB.GetUserInvites(userUID, num, offset)
+
+	invites, err := s.requestDB(c).GetSentInvites(userUUID, status, page.Limit, page.Offset)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"err": err.Error()})
 		return
@@ -39,7 +76,31 @@ func (s *Server) GetUserInvites(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, invites)
+	c.JSON(http.StatusOK, formatTimes(c, invites))
+}
+
+// GetInviteStatus returns current status of an invite so its issuer can check whether it was answered
+func (s *Server) GetInviteStatus(c *gin.Context) {
+	userID := c.GetString("userID")
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid ID"})
+		return
+	}
+	inviteID := c.Param("inviteID")
+	inviteUUID, err := uuid.Parse(inviteID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid invite id"})
+		return
+	}
+
+	invite, err := s.requestDB(c).GetInvite(userUUID, inviteUUID)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{"err": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, formatTimes(c, gin.H{"inviteID": invite.ID, "status": invite.Status.String(), "created": invite.Created, "modified": invite.Modified}))
 }
 
 func (s *Server) CreateInvite(c *gin.Context) {
@@ -51,8 +112,9 @@ func (s *Server) CreateInvite(c *gin.Context) {
 	}
 
 	payload := struct {
-		GroupID string `json:"group"`
-		Target  string `json:"target"`
+		GroupID string              `json:"group"`
+		Target  string              `json:"target"`
+		Rights  models.InviteRights `json:"rights"`
 	}{}
 
 	// getting req body
@@ -71,13 +133,13 @@ func (s *Server) CreateInvite(c *gin.Context) {
 		return
 	}
 
-	invite, err := s.DB.AddInvite(userUID, targetUUID, groupUID)
+	invite, err := s.requestDB(c).AddInvite(userUID, targetUUID, groupUID, payload.Rights)
 	if err != nil {
 		c.JSON(apperrors.Status(err), gin.H{"err": err.Error()})
 		return
 	}
 
-	_ = s.Emitter.Emit(events.InviteSentEvent{
+	s.emitAsync(events.InviteSentEvent{
 		ID:       invite.ID,
 		IssuerID: invite.IssId,
 		Issuer: events.User{
@@ -94,7 +156,7 @@ func (s *Server) CreateInvite(c *gin.Context) {
 		Modified: invite.Modified,
 	})
 
-	c.JSON(http.StatusCreated, invite)
+	c.JSON(http.StatusCreated, formatTimes(c, invite))
 }
 
 func (s *Server) RespondGroupInvite(c *gin.Context) {
@@ -119,30 +181,95 @@ func (s *Server) RespondGroupInvite(c *gin.Context) {
 		return
 	}
 
-	invite, group, member, err := s.DB.AnswerInvite(userUUID, inviteUUID, *payload.Answer)
+	invite, group, member, err := s.requestDB(c).AnswerInvite(userUUID, inviteUUID, *payload.Answer)
 	if err != nil {
 		c.JSON(apperrors.Status(err), gin.H{"err": err.Error()})
 		return
 	}
 
+	if err := s.emitInviteAnswered(invite, member); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"err": err.Error()})
+		return
+	}
+
+	if !*payload.Answer {
+		c.JSON(http.StatusOK, formatTimes(c, gin.H{"invite": invite}))
+		return
+	}
+
+	c.JSON(http.StatusOK, formatTimes(c, gin.H{"invite": invite, "group": group}))
+}
+
+const MAX_BULK_INVITES = 50
+
+func (s *Server) BulkAcceptInvites(c *gin.Context) {
+	userID := c.GetString("userID")
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid ID"})
+		return
+	}
+
+	payload := struct {
+		Invites []string `json:"invites" binding:"required"`
+	}{}
+	if err := c.ShouldBindJSON(&payload); err != nil || len(payload.Invites) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invites not specified"})
+		return
+	}
+	if len(payload.Invites) > MAX_BULK_INVITES {
+		c.JSON(http.StatusBadRequest, gin.H{"err": fmt.Sprintf("at most %d invites can be accepted at once", MAX_BULK_INVITES)})
+		return
+	}
+
+	type inviteResult struct {
+		InviteID string        `json:"inviteID"`
+		Accepted bool          `json:"accepted"`
+		Group    *models.Group `json:"group,omitempty"`
+		Err      string        `json:"err,omitempty"`
+	}
+
+	seen := make(map[string]bool)
+	results := []inviteResult{}
+	for _, inviteID := range payload.Invites {
+		if seen[inviteID] {
+			continue
+		}
+		seen[inviteID] = true
+
+		inviteUUID, err := uuid.Parse(inviteID)
+		if err != nil {
+			results = append(results, inviteResult{InviteID: inviteID, Err: "invalid invite id"})
+			continue
+		}
+
+		invite, group, member, err := s.requestDB(c).AnswerInvite(userUUID, inviteUUID, true)
+		if err != nil {
+			results = append(results, inviteResult{InviteID: inviteID, Err: err.Error()})
+			continue
+		}
+		// invite is accepted already, error only tells that other services weren't notified
+		if err := s.emitInviteAnswered(invite, member); err != nil {
+			results = append(results, inviteResult{InviteID: inviteID, Accepted: true, Group: group, Err: err.Error()})
+			continue
+		}
+
+		results = append(results, inviteResult{InviteID: inviteID, Accepted: true, Group: group})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+// emitInviteAnswered emits events informing about invite being answered and, if it was accepted, about new member.
+// Only failure of emitting new member is returned
+func (s *Server) emitInviteAnswered(invite *models.Invite, member *models.Member) error {
 	if member != nil {
-		_ = s.Emitter.Emit(events.MemberCreatedEvent{
-			ID:      member.ID,
-			GroupID: member.GroupID,
-			UserID:  member.UserID,
-			User: events.User{
-				UserName: member.User.UserName,
-				Picture:  member.User.Picture,
-			},
-			Adding:           member.Adding,
-			DeletingMembers:  member.DeletingMembers,
-			DeletingMessages: member.DeletingMessages,
-			Admin:            member.Admin,
-			Creator:          member.Creator,
-		})
+		if err := s.emitSync(memberCreatedEvent(*member)); err != nil {
+			return err
+		}
 	}
 	if invite != nil {
-		_ = s.Emitter.Emit(events.InviteRespondedEvent{
+		s.emitAsync(events.InviteRespondedEvent{
 			ID:       invite.ID,
 			IssuerID: invite.IssId,
 			TargetID: invite.TargetID,
@@ -159,10 +286,5 @@ func (s *Server) RespondGroupInvite(c *gin.Context) {
 			Modified: invite.Modified,
 		})
 	}
-	if !*payload.Answer {
-		c.JSON(http.StatusOK, gin.H{"invite": invite})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{"invite": invite, "group": group})
+	return nil
 }
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/Slimo300/MicroservicesChatApp/backend/lib/apperrors"
 	mockqueue "github.com/Slimo300/MicroservicesChatApp/backend/lib/msgqueue/mock"
@@ -22,8 +23,9 @@ import (
 
 type InvitesTestSuite struct {
 	suite.Suite
-	IDs    map[string]uuid.UUID
-	server *handlers.Server
+	IDs     map[string]uuid.UUID
+	created time.Time
+	server  *handlers.Server
 }
 
 func (s *InvitesTestSuite) SetupSuite() {
@@ -41,21 +43,30 @@ func (s *InvitesTestSuite) SetupSuite() {
 	s.IDs["invitedUserMember"] = uuid.MustParse("27df64da-a103-49fb-9724-151cdb2943b5")
 	s.IDs["invitedUserInvited"] = uuid.MustParse("34234be4-fe92-49cb-9ddd-76ba9f410266")
 	s.IDs["group"] = uuid.MustParse("b646e70f-3c8f-4782-84a3-0b34b0f9aecf")
+	s.IDs["userAddingOnly"] = uuid.MustParse("4f0e7b52-9d8e-4b1a-a3a6-2c5f1e8d7b90")
+	s.IDs["inviteAlreadyMember"] = uuid.MustParse("c3a1e5f7-2b4d-4c6e-8f0a-1b3d5f7a9c2e")
 
 	db := new(dbmock.MockGroupsDB)
 	db.On("GetUserInvites", s.IDs["userOK"], 1, 0).Return([]models.Invite{{ID: s.IDs["inviteOK"]}}, nil)
 	db.On("GetUserInvites", s.IDs["userWithoutInvites"], 1, 0).Return([]models.Invite{}, nil)
+	db.On("GetSentInvites", s.IDs["userOK"], models.INVITE_AWAITING, 50, 0).Return([]models.Invite{{ID: s.IDs["inviteOK"]}}, nil)
+	db.On("GetSentInvites", s.IDs["userOK"], models.InviteStatus(0), 1, 1).Return([]models.Invite{{ID: s.IDs["inviteAnswered"]}}, nil)
+	db.On("GetSentInvites", s.IDs["userOK"], models.INVITE_DECLINE, 50, 0).Return([]models.Invite{}, nil)
 
-	db.On("AddInvite", s.IDs["userNoRights"], s.IDs["invitedUserOK"], s.IDs["group"]).
+	db.On("AddInvite", s.IDs["userNoRights"], s.IDs["invitedUserOK"], s.IDs["group"], models.InviteRights{}).
 		Return(&models.Invite{}, apperrors.NewForbidden(fmt.Sprintf("User %v has no rights to add new members to group %v", s.IDs["userNoRights"], s.IDs["group"])))
-	db.On("AddInvite", s.IDs["userOK"], s.IDs["invitedUserNotFound"], s.IDs["group"]).
+	db.On("AddInvite", s.IDs["userOK"], s.IDs["invitedUserNotFound"], s.IDs["group"], models.InviteRights{}).
 		Return(&models.Invite{}, apperrors.NewNotFound("user", s.IDs["invitedUserNotFound"].String()))
-	db.On("AddInvite", s.IDs["userOK"], s.IDs["invitedUserMember"], s.IDs["group"]).
+	db.On("AddInvite", s.IDs["userOK"], s.IDs["invitedUserMember"], s.IDs["group"], models.InviteRights{}).
 		Return(&models.Invite{}, apperrors.NewForbidden(fmt.Sprintf("User %v already is already a member of group %v", s.IDs["invitedUserMember"], s.IDs["group"])))
-	db.On("AddInvite", s.IDs["userOK"], s.IDs["invitedUserInvited"], s.IDs["group"]).
+	db.On("AddInvite", s.IDs["userOK"], s.IDs["invitedUserInvited"], s.IDs["group"], models.InviteRights{}).
 		Return(&models.Invite{}, apperrors.NewForbidden(fmt.Sprintf("User %v already invited to group %v", s.IDs["invitedUserInvited"], s.IDs["group"])))
-	db.On("AddInvite", s.IDs["userOK"], s.IDs["invitedUserOK"], s.IDs["group"]).
+	db.On("AddInvite", s.IDs["userOK"], s.IDs["invitedUserOK"], s.IDs["group"], models.InviteRights{}).
 		Return(&models.Invite{ID: s.IDs["inviteOK"]}, nil)
+	db.On("AddInvite", s.IDs["userOK"], s.IDs["invitedUserOK"], s.IDs["group"], models.InviteRights{Admin: true}).
+		Return(&models.Invite{ID: s.IDs["inviteOK"], Rights: models.InviteRights{Admin: true}}, nil)
+	db.On("AddInvite", s.IDs["userAddingOnly"], s.IDs["invitedUserOK"], s.IDs["group"], models.InviteRights{Admin: true}).
+		Return(nil, apperrors.NewForbidden(fmt.Sprintf("User %v cannot grant rights to new members of group %v", s.IDs["userAddingOnly"], s.IDs["group"])))
 
 	db.On("AnswerInvite", s.IDs["userOK"], s.IDs["inviteOK"], true).Return(&models.Invite{ID: s.IDs["inviteOK"]}, &models.Group{ID: s.IDs["group"]}, nil, nil)
 	db.On("AnswerInvite", s.IDs["userOK"], s.IDs["inviteOK"], false).Return(&models.Invite{ID: s.IDs["inviteOK"]}, nil, nil, nil)
@@ -63,6 +74,20 @@ func (s *InvitesTestSuite) SetupSuite() {
 		Return(nil, nil, nil, apperrors.NewNotFound("invite", s.IDs["inviteNotFound"].String()))
 	db.On("AnswerInvite", s.IDs["userOK"], s.IDs["inviteAnswered"], mock.Anything).
 		Return(nil, nil, nil, apperrors.NewForbidden("invite already answered"))
+	db.On("AnswerInvite", s.IDs["userOK"], s.IDs["inviteAlreadyMember"], true).
+		Return(nil, nil, nil, &apperrors.Error{Type: apperrors.Conflict, Message: "user is already a member of this group"})
+
+	s.IDs["inviteDeclined"] = uuid.MustParse("7d2f4b6a-8c1e-4a3f-9b5d-e6f8a0c2d4b1")
+	s.created = time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	answered := s.created.Add(time.Hour)
+	db.On("GetInvite", s.IDs["userOK"], s.IDs["inviteOK"]).
+		Return(&models.Invite{ID: s.IDs["inviteOK"], Status: models.INVITE_AWAITING, Created: s.created, Modified: s.created}, nil)
+	db.On("GetInvite", s.IDs["userOK"], s.IDs["inviteAnswered"]).
+		Return(&models.Invite{ID: s.IDs["inviteAnswered"], Status: models.INVITE_ACCEPT, Created: s.created, Modified: answered}, nil)
+	db.On("GetInvite", s.IDs["userOK"], s.IDs["inviteDeclined"]).
+		Return(&models.Invite{ID: s.IDs["inviteDeclined"], Status: models.INVITE_DECLINE, Created: s.created, Modified: answered}, nil)
+	db.On("GetInvite", s.IDs["userNoRights"], s.IDs["inviteOK"]).
+		Return(nil, apperrors.NewNotFound("invite", s.IDs["inviteOK"].String()))
 
 	emiter := new(mockqueue.MockEmitter)
 	emiter.On("Emit", mock.Anything).Return(nil)
@@ -188,6 +213,22 @@ func (s *InvitesTestSuite) TestSendGroupInvite() {
 			expectedStatusCode: http.StatusCreated,
 			expectedResponse:   models.Invite{ID: s.IDs["inviteOK"]},
 		},
+		{
+			desc:               "inviteWithRightsNoRights",
+			id:                 s.IDs["userAddingOnly"].String(),
+			data:               map[string]interface{}{"group": s.IDs["group"].String(), "target": s.IDs["invitedUserOK"].String(), "rights": map[string]interface{}{"admin": true}},
+			returnVal:          false,
+			expectedStatusCode: http.StatusForbidden,
+			expectedResponse:   gin.H{"err": fmt.Sprintf("Forbidden action. Reason: User %v cannot grant rights to new members of group %v", s.IDs["userAddingOnly"], s.IDs["group"])},
+		},
+		{
+			desc:               "inviteWithRightsSuccess",
+			id:                 s.IDs["userOK"].String(),
+			data:               map[string]interface{}{"group": s.IDs["group"].String(), "target": s.IDs["invitedUserOK"].String(), "rights": map[string]interface{}{"admin": true}},
+			returnVal:          true,
+			expectedStatusCode: http.StatusCreated,
+			expectedResponse:   models.Invite{ID: s.IDs["inviteOK"], Rights: models.InviteRights{Admin: true}},
+		},
 	}
 
 	for _, tC := range testCases {
@@ -230,6 +271,36 @@ func (s *InvitesTestSuite) TestSendGroupInvite() {
 	}
 }
 
+// Created invite is encoded with timestamps in format requested by client like other invites
+func (s *InvitesTestSuite) TestSendGroupInviteEpochMillis() {
+	gin.SetMode(gin.TestMode)
+
+	db := new(dbmock.MockGroupsDB)
+	db.On("AddInvite", s.IDs["userOK"], s.IDs["invitedUserOK"], s.IDs["group"], models.InviteRights{}).
+		Return(&models.Invite{ID: s.IDs["inviteOK"], Created: s.created, Modified: s.created}, nil)
+	server := *s.server
+	server.DB = db
+
+	requestBody, _ := json.Marshal(map[string]interface{}{"group": s.IDs["group"].String(), "target": s.IDs["invitedUserOK"].String()})
+	req, _ := http.NewRequest("POST", "/api/invite?time_format=epoch_ms", bytes.NewReader(requestBody))
+
+	w := httptest.NewRecorder()
+	_, engine := gin.CreateTestContext(w)
+	engine.Use(func(c *gin.Context) {
+		c.Set("userID", s.IDs["userOK"].String())
+	})
+	engine.Handle(http.MethodPost, "/api/invite", server.CreateInvite)
+	engine.ServeHTTP(w, req)
+
+	s.Equal(http.StatusCreated, w.Code)
+	var msg gin.H
+	if err := json.NewDecoder(w.Body).Decode(&msg); err != nil {
+		s.Fail(err.Error())
+	}
+	s.Equal(float64(s.created.UnixMilli()), msg["created"])
+	s.Equal(float64(s.created.UnixMilli()), msg["modified"])
+}
+
 func (s *InvitesTestSuite) TestRespondGroupInvite() {
 	gin.SetMode(gin.TestMode)
 
@@ -287,6 +358,15 @@ func (s *InvitesTestSuite) TestRespondGroupInvite() {
 			expectedStatusCode: http.StatusForbidden,
 			expectedResponse:   gin.H{"err": "Forbidden action. Reason: invite already answered"},
 		},
+		{
+			desc:               "respondInviteAlreadyMember",
+			userID:             s.IDs["userOK"].String(),
+			inviteID:           s.IDs["inviteAlreadyMember"].String(),
+			data:               map[string]interface{}{"answer": true},
+			returnVal:          false,
+			expectedStatusCode: http.StatusConflict,
+			expectedResponse:   gin.H{"err": "user is already a member of this group"},
+		},
 		{
 			desc:               "respondInviteNo",
 			userID:             s.IDs["userOK"].String(),
@@ -376,6 +456,296 @@ func (s *InvitesTestSuite) TestRespondGroupInvite() {
 	}
 }
 
+type bulkInviteResult struct {
+	InviteID string        `json:"inviteID"`
+	Accepted bool          `json:"accepted"`
+	Group    *models.Group `json:"group"`
+	Err      string        `json:"err"`
+}
+
+func (s *InvitesTestSuite) TestGetSentInvites() {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		desc               string
+		userID             string
+		query              string
+		expectedStatusCode int
+		expectedResponse   interface{}
+	}{
+		{
+			desc:               "getSentInvitesInvalidID",
+			userID:             s.IDs["userOK"].String()[:2],
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "invalid ID"},
+		},
+		{
+			desc:               "getSentInvitesInvalidStatus",
+			userID:             s.IDs["userOK"].String(),
+			query:              "?status=expired",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "unknown invite status: expired"},
+		},
+		{
+			desc:               "getSentInvitesInvalidLimit",
+			userID:             s.IDs["userOK"].String(),
+			query:              "?limit=abc",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "limit is not a valid number"},
+		},
+		{
+			desc:               "getSentInvitesNoInvites",
+			userID:             s.IDs["userOK"].String(),
+			query:              "?status=declined",
+			expectedStatusCode: http.StatusNoContent,
+			expectedResponse:   nil,
+		},
+		{
+			desc:               "getSentInvitesAwaitingByDefault",
+			userID:             s.IDs["userOK"].String(),
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   []models.Invite{{ID: s.IDs["inviteOK"]}},
+		},
+		{
+			desc:               "getSentInvitesAllPaginated",
+			userID:             s.IDs["userOK"].String(),
+			query:              "?status=all&limit=1&offset=1",
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   []models.Invite{{ID: s.IDs["inviteAnswered"]}},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+
+			req, _ := http.NewRequest(http.MethodGet, "/api/invites/sent"+tC.query, nil)
+
+			w := httptest.NewRecorder()
+			_, engine := gin.CreateTestContext(w)
+			engine.Use(func(c *gin.Context) {
+				c.Set("userID", tC.userID)
+			})
+
+			engine.Handle(http.MethodGet, "/api/invites/sent", s.server.GetSentInvites)
+			engine.ServeHTTP(w, req)
+			response := w.Result()
+			defer response.Body.Close()
+
+			s.Equal(tC.expectedStatusCode, response.StatusCode)
+
+			switch tC.expectedResponse.(type) {
+			case gin.H:
+				var msg gin.H
+				if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+					s.Fail(err.Error())
+				}
+				s.Equal(tC.expectedResponse, msg)
+			case []models.Invite:
+				var invites []models.Invite
+				if err := json.NewDecoder(response.Body).Decode(&invites); err != nil {
+					s.Fail(err.Error())
+				}
+				s.Equal(tC.expectedResponse, invites)
+			}
+		})
+	}
+}
+
+func (s *InvitesTestSuite) TestGetInviteStatus() {
+	gin.SetMode(gin.TestMode)
+
+	created := s.created.Format(time.RFC3339)
+	answered := s.created.Add(time.Hour).Format(time.RFC3339)
+
+	testCases := []struct {
+		desc               string
+		userID             string
+		inviteID           string
+		query              string
+		expectedStatusCode int
+		expectedResponse   gin.H
+	}{
+		{
+			desc:               "getInviteStatusInvalidInviteID",
+			userID:             s.IDs["userOK"].String(),
+			inviteID:           s.IDs["inviteOK"].String()[:2],
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "invalid invite id"},
+		},
+		{
+			desc:               "getInviteStatusNotVisible",
+			userID:             s.IDs["userNoRights"].String(),
+			inviteID:           s.IDs["inviteOK"].String(),
+			expectedStatusCode: http.StatusNotFound,
+			expectedResponse:   gin.H{"err": fmt.Sprintf("resource: invite with value: %v not found", s.IDs["inviteOK"])},
+		},
+		{
+			desc:               "getInviteStatusAwaiting",
+			userID:             s.IDs["userOK"].String(),
+			inviteID:           s.IDs["inviteOK"].String(),
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   gin.H{"inviteID": s.IDs["inviteOK"].String(), "status": "awaiting", "created": created, "modified": created},
+		},
+		{
+			desc:               "getInviteStatusAccepted",
+			userID:             s.IDs["userOK"].String(),
+			inviteID:           s.IDs["inviteAnswered"].String(),
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   gin.H{"inviteID": s.IDs["inviteAnswered"].String(), "status": "accepted", "created": created, "modified": answered},
+		},
+		{
+			desc:               "getInviteStatusDeclined",
+			userID:             s.IDs["userOK"].String(),
+			inviteID:           s.IDs["inviteDeclined"].String(),
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   gin.H{"inviteID": s.IDs["inviteDeclined"].String(), "status": "declined", "created": created, "modified": answered},
+		},
+		{
+			desc:               "getInviteStatusRFC3339",
+			userID:             s.IDs["userOK"].String(),
+			inviteID:           s.IDs["inviteAnswered"].String(),
+			query:              "?time_format=rfc3339",
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   gin.H{"inviteID": s.IDs["inviteAnswered"].String(), "status": "accepted", "created": created, "modified": answered},
+		},
+		{
+			desc:               "getInviteStatusEpochMillis",
+			userID:             s.IDs["userOK"].String(),
+			inviteID:           s.IDs["inviteAnswered"].String(),
+			query:              "?time_format=epoch_ms",
+			expectedStatusCode: http.StatusOK,
+			expectedResponse: gin.H{"inviteID": s.IDs["inviteAnswered"].String(), "status": "accepted",
+				"created": float64(s.created.UnixMilli()), "modified": float64(s.created.Add(time.Hour).UnixMilli())},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+
+			req, _ := http.NewRequest(http.MethodGet, "/api/invites/"+tC.inviteID+tC.query, nil)
+
+			w := httptest.NewRecorder()
+			_, engine := gin.CreateTestContext(w)
+			engine.Use(func(c *gin.Context) {
+				c.Set("userID", tC.userID)
+			})
+
+			engine.Handle(http.MethodGet, "/api/invites/:inviteID", s.server.GetInviteStatus)
+			engine.ServeHTTP(w, req)
+			response := w.Result()
+			defer response.Body.Close()
+
+			s.Equal(tC.expectedStatusCode, response.StatusCode)
+
+			var msg gin.H
+			if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+				s.Fail(err.Error())
+			}
+
+			s.Equal(tC.expectedResponse, msg)
+		})
+	}
+}
+
+func (s *InvitesTestSuite) TestBulkAcceptInvites() {
+	gin.SetMode(gin.TestMode)
+
+	tooMany := make([]string, handlers.MAX_BULK_INVITES+1)
+	for i := range tooMany {
+		tooMany[i] = uuid.NewString()
+	}
+
+	testCases := []struct {
+		desc               string
+		id                 string
+		data               map[string]interface{}
+		returnVal          bool
+		expectedStatusCode int
+		expectedResponse   interface{}
+	}{
+		{
+			desc:               "bulkAcceptInvalidUserID",
+			id:                 s.IDs["userOK"].String()[:2],
+			data:               map[string]interface{}{"invites": []string{s.IDs["inviteOK"].String()}},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "invalid ID"},
+		},
+		{
+			desc:               "bulkAcceptNoInvites",
+			id:                 s.IDs["userOK"].String(),
+			data:               map[string]interface{}{"invites": []string{}},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "invites not specified"},
+		},
+		{
+			desc:               "bulkAcceptTooManyInvites",
+			id:                 s.IDs["userOK"].String(),
+			data:               map[string]interface{}{"invites": tooMany},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": fmt.Sprintf("at most %d invites can be accepted at once", handlers.MAX_BULK_INVITES)},
+		},
+		{
+			desc: "bulkAcceptMixed",
+			id:   s.IDs["userOK"].String(),
+			data: map[string]interface{}{"invites": []string{
+				s.IDs["inviteOK"].String(),
+				s.IDs["inviteAnswered"].String(),
+				s.IDs["inviteNotFound"].String(),
+				"invalid",
+				s.IDs["inviteOK"].String(),
+			}},
+			returnVal:          true,
+			expectedStatusCode: http.StatusOK,
+			expectedResponse: []bulkInviteResult{
+				{InviteID: s.IDs["inviteOK"].String(), Accepted: true, Group: &models.Group{ID: s.IDs["group"]}},
+				{InviteID: s.IDs["inviteAnswered"].String(), Err: "Forbidden action. Reason: invite already answered"},
+				{InviteID: s.IDs["inviteNotFound"].String(), Err: fmt.Sprintf("resource: invite with value: %v not found", s.IDs["inviteNotFound"])},
+				{InviteID: "invalid", Err: "invalid invite id"},
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+
+			requestBody, _ := json.Marshal(tC.data)
+			req, _ := http.NewRequest("POST", "/api/invites/accept", bytes.NewReader(requestBody))
+
+			w := httptest.NewRecorder()
+			_, engine := gin.CreateTestContext(w)
+			engine.Use(func(c *gin.Context) {
+				c.Set("userID", tC.id)
+			})
+
+			engine.Handle(http.MethodPost, "/api/invites/accept", s.server.BulkAcceptInvites)
+			engine.ServeHTTP(w, req)
+			response := w.Result()
+			defer response.Body.Close()
+
+			s.Equal(tC.expectedStatusCode, response.StatusCode)
+
+			var respBody interface{}
+			if tC.returnVal {
+				var results struct {
+					Results []bulkInviteResult `json:"results"`
+				}
+				if err := json.NewDecoder(response.Body).Decode(&results); err != nil {
+					s.Fail(err.Error())
+				}
+				respBody = results.Results
+			} else {
+				var msg gin.H
+				if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+					s.Fail(err.Error())
+				}
+				respBody = msg
+			}
+
+			s.Equal(tC.expectedResponse, respBody)
+		})
+	}
+}
+
 func TestInvitesSuite(t *testing.T) {
 	suite.Run(t, &InvitesTestSuite{})
 }
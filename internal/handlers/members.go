@@ -1,15 +1,474 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/Slimo300/MicroservicesChatApp/backend/lib/apperrors"
 	"github.com/Slimo300/MicroservicesChatApp/backend/lib/events"
+	groupevents "github.com/Slimo300/chat-groupservice/internal/events"
 	"github.com/Slimo300/chat-groupservice/internal/models"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
+// membership checks can be cached by callers only briefly as rights can be revoked at any moment
+const MEMBERSHIP_CACHE_CONTROL = "private, max-age=10"
+
+// respondMemberError responds to an error of removing or demoting a member, when it would leave group without
+// its owner response carries a code so clients can offer deleting the group instead
+func respondMemberError(c *gin.Context, err error) {
+	if errors.Is(err, models.ErrLastOwner) {
+		c.JSON(apperrors.Status(err), gin.H{"err": err.Error(), "code": models.LAST_OWNER})
+		return
+	}
+	c.JSON(apperrors.Status(err), gin.H{"err": err.Error()})
+}
+
+func (s *Server) GetMembership(c *gin.Context) {
+	userID := c.GetString("userID")
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid ID"})
+		return
+	}
+	groupID := c.Param("groupID")
+	groupUUID, err := uuid.Parse(groupID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid group ID"})
+		return
+	}
+	targetID := c.Param("userID")
+	targetUUID, err := uuid.Parse(targetID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid user ID"})
+		return
+	}
+
+	member, err := s.requestDB(c).GetMembership(userUUID, groupUUID, targetUUID)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{"err": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, formatTimes(c, member))
+}
+
+// GetRecentMembers lists members of a group starting from the most recently joined ones, "within" query parameter
+// (e.g. "24h") limits them to members who joined during given period and "fields" selects returned member fields.
+// Full pages carry cursor of the next one in NEXT_CURSOR_HEADER
+func (s *Server) GetRecentMembers(c *gin.Context) {
+	userID := c.GetString("userID")
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid ID"})
+		return
+	}
+	groupID := c.Param("groupID")
+	groupUUID, err := uuid.Parse(groupID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid group ID"})
+		return
+	}
+	var since time.Time
+	if within := c.Query("within"); within != "" {
+		window, err := time.ParseDuration(within)
+		if err != nil || window <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"err": "invalid within duration"})
+			return
+		}
+		since = time.Now().Add(-window)
+	}
+	page, err := parsePagination(c.Request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+		return
+	}
+
+	sort, err := s.parseSort(c.Request, SORTED_MEMBERS)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+		return
+	}
+
+	after, err := parseCursor(c.Request, sort, page)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+		return
+	}
+
+	members, err := s.requestDB(c).GetRecentMembers(userUUID, groupUUID, since, sort, after, page.Limit, page.Offset)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{"err": err.Error()})
+		return
+	}
+
+	if len(members) == 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	if len(members) == page.Limit {
+		setNextCursor(c, memberCursor(sort, members[len(members)-1]))
+	}
+
+	fields := parseFields(c.Request, memberFields)
+	if fields == nil {
+		c.JSON(http.StatusOK, formatTimes(c, members))
+		return
+	}
+	projections := make([]interface{}, 0, len(members))
+	for _, member := range members {
+		projection, err := project(formatTimes(c, member), fields)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"err": err.Error()})
+			return
+		}
+		projections = append(projections, projection)
+	}
+	c.JSON(http.StatusOK, projections)
+}
+
+// CheckMembership tells other services whether user is a member of a group and what is their role
+func (s *Server) CheckMembership(c *gin.Context) {
+	groupID := c.Param("groupID")
+	groupUUID, err := uuid.Parse(groupID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid group ID"})
+		return
+	}
+	userID := c.Param("userID")
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid user ID"})
+		return
+	}
+
+	member, err := s.requestDB(c).FindMember(groupUUID, userUUID)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{"err": err.Error()})
+		return
+	}
+
+	c.Header("Cache-Control", MEMBERSHIP_CACHE_CONTROL)
+	if member == nil {
+		c.JSON(http.StatusOK, gin.H{"member": false})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"member": true, "role": member.RoleName()})
+}
+
+// number of streamed members after which response is flushed to the client
+const MEMBER_STREAM_FLUSH_EVERY = 100
+
+// StreamGroupMembers writes every member of a group as newline-delimited JSON, members are written as they
+// are read from the database so full scans of big groups don't need to be buffered
+func (s *Server) StreamGroupMembers(c *gin.Context) {
+	groupID := c.Param("groupID")
+	groupUUID, err := uuid.Parse(groupID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid group ID"})
+		return
+	}
+
+	encoder := json.NewEncoder(c.Writer)
+	streamed := 0
+	err = s.requestDB(c).StreamGroupMembers(groupUUID, func(member models.Member) error {
+		if streamed == 0 {
+			c.Header("Content-Type", "application/x-ndjson")
+			c.Status(http.StatusOK)
+		}
+		if err := encoder.Encode(formatTimes(c, member)); err != nil {
+			return err
+		}
+		streamed++
+		if streamed%MEMBER_STREAM_FLUSH_EVERY == 0 {
+			c.Writer.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		// when some members have already been sent the only thing left is to cut the stream short
+		if streamed == 0 {
+			c.JSON(apperrors.Status(err), gin.H{"err": err.Error()})
+		}
+		return
+	}
+	if streamed == 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	c.Writer.Flush()
+}
+
+// GetMyPermissions returns caller's role in a group and actions the role allows
+func (s *Server) GetMyPermissions(c *gin.Context) {
+	userID := c.GetString("userID")
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid ID"})
+		return
+	}
+	groupID := c.Param("groupID")
+	groupUUID, err := uuid.Parse(groupID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid group ID"})
+		return
+	}
+
+	member, err := s.requestDB(c).FindMember(groupUUID, userUUID)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{"err": err.Error()})
+		return
+	}
+	if member == nil {
+		err := apperrors.NewForbidden(fmt.Sprintf("User %v is not a member of group %v", userUUID, groupUUID))
+		c.JSON(apperrors.Status(err), gin.H{"err": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"role": member.RoleName(), "permissions": member.Permissions()})
+}
+
+// GetGroupWithMembership returns group together with caller's membership in it, it replaces fetching group and
+// membership separately when client opens a group. Returned group fields can be selected with "fields"
+func (s *Server) GetGroupWithMembership(c *gin.Context) {
+	userID := c.GetString("userID")
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid ID"})
+		return
+	}
+	groupID := c.Param("groupID")
+	groupUUID, err := uuid.Parse(groupID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid group ID"})
+		return
+	}
+
+	member, err := s.requestDB(c).GetGroupWithMembership(userUUID, groupUUID)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{"err": err.Error()})
+		return
+	}
+	if member == nil {
+		err := apperrors.NewForbidden(fmt.Sprintf("User %v is not a member of group %v", userUUID, groupUUID))
+		c.JSON(apperrors.Status(err), gin.H{"err": err.Error()})
+		return
+	}
+
+	group, err := project(formatTimes(c, member.Group), parseFields(c.Request, groupFields))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"err": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, formatTimes(c, gin.H{
+		"group": group,
+		"membership": gin.H{
+			"ID":          member.ID,
+			"role":        member.RoleName(),
+			"permissions": member.Permissions(),
+			"nickname":    member.Nickname,
+			"joined":      member.Joined,
+		},
+	}))
+}
+
+const MAX_MEMBERSHIP_BATCH = 100
+
+// parseGroupIDsQuery reads distinct groups given with "groupID" query parameters in order of their first
+// appearance, it responds with 400 when there are none, too many or some of them are invalid
+func parseGroupIDsQuery(c *gin.Context) ([]uuid.UUID, bool) {
+	seen := make(map[uuid.UUID]bool)
+	var groupIDs []uuid.UUID
+	for _, groupID := range c.QueryArray("groupID") {
+		groupUUID, err := uuid.Parse(groupID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": "invalid group ID"})
+			return nil, false
+		}
+		if seen[groupUUID] {
+			continue
+		}
+		seen[groupUUID] = true
+		groupIDs = append(groupIDs, groupUUID)
+	}
+	if len(groupIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "groups not specified"})
+		return nil, false
+	}
+	if len(groupIDs) > MAX_MEMBERSHIP_BATCH {
+		c.JSON(http.StatusBadRequest, gin.H{"err": fmt.Sprintf("at most %d groups can be checked at once", MAX_MEMBERSHIP_BATCH)})
+		return nil, false
+	}
+	return groupIDs, true
+}
+
+// GetMyMembershipsForGroups returns caller's roles in groups given with "groupID" query parameters, groups caller
+// isn't a member of are omitted
+func (s *Server) GetMyMembershipsForGroups(c *gin.Context) {
+	userID := c.GetString("userID")
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid ID"})
+		return
+	}
+
+	groupIDs, ok := parseGroupIDsQuery(c)
+	if !ok {
+		return
+	}
+
+	members, err := s.requestDB(c).GetUserMemberships(userUUID, groupIDs)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{"err": err.Error()})
+		return
+	}
+
+	if len(members) == 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	type membership struct {
+		GroupID uuid.UUID `json:"groupID"`
+		Role    string    `json:"role"`
+	}
+	memberships := make([]membership, 0, len(members))
+	for _, member := range members {
+		memberships = append(memberships, membership{GroupID: member.GroupID, Role: member.RoleName()})
+	}
+
+	c.JSON(http.StatusOK, formatTimes(c, memberships))
+}
+
+// GetMyRolesAndCapabilities returns caller's role in every group given with "groupID" query parameters together
+// with capabilities given with "capability" parameters (all of them when none is given). Groups caller isn't
+// a member of are present with member set to false and no capabilities
+func (s *Server) GetMyRolesAndCapabilities(c *gin.Context) {
+	userID := c.GetString("userID")
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid ID"})
+		return
+	}
+
+	capabilities := c.QueryArray("capability")
+	known := models.Member{}.Permissions()
+	if len(capabilities) == 0 {
+		for capability := range known {
+			capabilities = append(capabilities, capability)
+		}
+	}
+	for _, capability := range capabilities {
+		if _, ok := known[capability]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"err": fmt.Sprintf("unknown capability: %s", capability)})
+			return
+		}
+	}
+
+	groupIDs, ok := parseGroupIDsQuery(c)
+	if !ok {
+		return
+	}
+
+	members, err := s.requestDB(c).GetUserMemberships(userUUID, groupIDs)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{"err": err.Error()})
+		return
+	}
+	membersByGroup := make(map[uuid.UUID]models.Member, len(members))
+	for _, member := range members {
+		membersByGroup[member.GroupID] = member
+	}
+
+	type roleAndCapabilities struct {
+		GroupID      uuid.UUID       `json:"groupID"`
+		Member       bool            `json:"member"`
+		Role         string          `json:"role,omitempty"`
+		Capabilities map[string]bool `json:"capabilities"`
+	}
+	results := make([]roleAndCapabilities, 0, len(groupIDs))
+	for _, groupID := range groupIDs {
+		result := roleAndCapabilities{GroupID: groupID, Capabilities: make(map[string]bool, len(capabilities))}
+		member, isMember := membersByGroup[groupID]
+		var permissions map[string]bool
+		if isMember {
+			result.Member = true
+			result.Role = member.RoleName()
+			permissions = member.Permissions()
+		}
+		for _, capability := range capabilities {
+			result.Capabilities[capability] = permissions[capability]
+		}
+		results = append(results, result)
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+const MAX_USER_MEMBERSHIP_BATCH = 200
+
+// GetMembershipsForUsers returns roles of users given with "userID" query parameters in a group, keyed by user ID.
+// Every requested user is present in response, users who aren't members have member set to false
+func (s *Server) GetMembershipsForUsers(c *gin.Context) {
+	userID := c.GetString("userID")
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid ID"})
+		return
+	}
+	groupID := c.Param("groupID")
+	groupUUID, err := uuid.Parse(groupID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid group ID"})
+		return
+	}
+
+	seen := make(map[uuid.UUID]bool)
+	var userIDs []uuid.UUID
+	for _, targetID := range c.QueryArray("userID") {
+		targetUUID, err := uuid.Parse(targetID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": "invalid user ID"})
+			return
+		}
+		if seen[targetUUID] {
+			continue
+		}
+		seen[targetUUID] = true
+		userIDs = append(userIDs, targetUUID)
+	}
+	if len(userIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "users not specified"})
+		return
+	}
+	if len(userIDs) > MAX_USER_MEMBERSHIP_BATCH {
+		c.JSON(http.StatusBadRequest, gin.H{"err": fmt.Sprintf("at most %d users can be checked at once", MAX_USER_MEMBERSHIP_BATCH)})
+		return
+	}
+
+	members, err := s.requestDB(c).GetGroupMemberships(userUUID, groupUUID, userIDs)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{"err": err.Error()})
+		return
+	}
+
+	memberships := make(map[string]gin.H, len(userIDs))
+	for _, id := range userIDs {
+		memberships[id.String()] = gin.H{"member": false}
+	}
+	for _, member := range members {
+		memberships[member.UserID.String()] = gin.H{"member": true, "role": member.RoleName()}
+	}
+
+	c.Header("Cache-Control", MEMBERSHIP_CACHE_CONTROL)
+	c.JSON(http.StatusOK, formatTimes(c, memberships))
+}
+
 func (s *Server) GrantPriv(c *gin.Context) {
 	userID := c.GetString("userID")
 	userUUID, err := uuid.Parse(userID)
@@ -35,37 +494,30 @@ func (s *Server) GrantPriv(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
 		return
 	}
-	if rights.Adding == 0 && rights.DeletingMessages == 0 && rights.DeletingMembers == 0 && rights.Admin == 0 {
+	if rights.Adding == 0 && rights.DeletingMessages == 0 && rights.DeletingMembers == 0 && rights.Admin == 0 && rights.CoOwner == 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"err": "no action specified"})
 		return
 	}
 
-	member, err := s.DB.GrantRights(userUUID, groupUUID, memberUUID, rights)
+	member, err := s.requestDB(c).GrantRights(userUUID, groupUUID, memberUUID, rights)
 	if err != nil {
 		c.JSON(apperrors.Status(err), gin.H{"err": err.Error()})
 		return
 	}
 
 	if member != nil {
-		_ = s.Emitter.Emit(events.MemberUpdatedEvent{
-			ID:      member.ID,
-			GroupID: member.GroupID,
-			UserID:  member.UserID,
-			User: events.User{
-				UserName: member.User.UserName,
-				Picture:  member.User.Picture,
-			},
-			DeletingMessages: member.DeletingMessages,
-			DeletingMembers:  member.DeletingMembers,
-			Adding:           member.Adding,
-			Admin:            member.Admin,
-		})
+		if err := s.emitSync(memberUpdatedEvent(*member)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"err": err.Error()})
+			return
+		}
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "member updated"})
 }
 
-func (s *Server) DeleteUserFromGroup(c *gin.Context) {
+// HandoverMemberConfig hands role and rights of a member over to another member, e.g. to a successor of
+// a moderator. Nickname can be handed over too and source can be cleared in the same transaction
+func (s *Server) HandoverMemberConfig(c *gin.Context) {
 	userID := c.GetString("userID")
 	userUUID, err := uuid.Parse(userID)
 	if err != nil {
@@ -85,13 +537,283 @@ func (s *Server) DeleteUserFromGroup(c *gin.Context) {
 		return
 	}
 
-	member, err := s.DB.DeleteMember(userUUID, groupUUID, memberUUID)
+	payload := struct {
+		TargetID string `json:"targetID"`
+		models.MemberHandover
+	}{}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+		return
+	}
+	targetUUID, err := uuid.Parse(payload.TargetID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid target member ID"})
+		return
+	}
+
+	source, target, err := s.requestDB(c).HandoverMember(userUUID, groupUUID, memberUUID, targetUUID, payload.MemberHandover)
 	if err != nil {
 		c.JSON(apperrors.Status(err), gin.H{"err": err.Error()})
 		return
 	}
 
-	_ = s.Emitter.Emit(events.MemberDeletedEvent{ID: member.ID, GroupID: member.GroupID, UserID: member.UserID})
+	changed := []*models.Member{target}
+	if payload.ClearSource {
+		changed = append(changed, source)
+	}
+	for _, member := range changed {
+		if err := s.emitSync(memberUpdatedEvent(*member)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"err": err.Error()})
+			return
+		}
+		if payload.Nickname {
+			s.emitAsync(groupevents.MemberNicknameChangedEvent{
+				ID:       member.ID,
+				GroupID:  member.GroupID,
+				UserID:   member.UserID,
+				Nickname: member.Nickname,
+			})
+		}
+	}
+
+	c.JSON(http.StatusOK, formatTimes(c, gin.H{"source": source, "target": target}))
+}
+
+const MAX_BULK_ROLE_CHANGES = 50
+
+// BulkChangeMemberRoles assigns roles to many members of a group at once, results are reported for each member
+// so some changes can be rejected while the rest is applied
+func (s *Server) BulkChangeMemberRoles(c *gin.Context) {
+	userID := c.GetString("userID")
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid ID"})
+		return
+	}
+	groupID := c.Param("groupID")
+	groupUUID, err := uuid.Parse(groupID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid group ID"})
+		return
+	}
+
+	payload := struct {
+		Members []struct {
+			UserID string `json:"userID"`
+			Role   string `json:"role"`
+		} `json:"members" binding:"required"`
+	}{}
+	if err := c.ShouldBindJSON(&payload); err != nil || len(payload.Members) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "members not specified"})
+		return
+	}
+	if len(payload.Members) > MAX_BULK_ROLE_CHANGES {
+		c.JSON(http.StatusBadRequest, gin.H{"err": fmt.Sprintf("at most %d roles can be changed at once", MAX_BULK_ROLE_CHANGES)})
+		return
+	}
+
+	type roleChangeResult struct {
+		UserID  string `json:"userID"`
+		Role    string `json:"role"`
+		Changed bool   `json:"changed"`
+		Err     string `json:"err,omitempty"`
+	}
+
+	seen := make(map[string]bool)
+	results := []roleChangeResult{}
+	// positions of results waiting for database
+	pending := make(map[uuid.UUID]int)
+	var changes []models.RoleChange
+	for _, change := range payload.Members {
+		// one ID can be spelled in many ways (e.g. in upper case), valid ones are compared once parsed
+		changeUUID, err := uuid.Parse(change.UserID)
+		key := change.UserID
+		if err == nil {
+			key = changeUUID.String()
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		result := roleChangeResult{UserID: change.UserID, Role: change.Role}
+		if err != nil {
+			result.Err = "invalid user id"
+		} else if err := models.CheckGrantableRole(change.Role); err != nil {
+			result.Err = err.Error()
+		} else {
+			pending[changeUUID] = len(results)
+			changes = append(changes, models.RoleChange{UserID: changeUUID, Role: change.Role})
+		}
+		results = append(results, result)
+	}
+
+	if len(changes) > 0 {
+		changed, err := s.requestDB(c).ChangeMemberRoles(userUUID, groupUUID, changes)
+		if err != nil {
+			c.JSON(apperrors.Status(err), gin.H{"err": err.Error()})
+			return
+		}
+		for _, change := range changed {
+			result := &results[pending[change.UserID]]
+			if change.Err != nil {
+				result.Err = change.Err.Error()
+				continue
+			}
+			result.Changed = true
+			// role is changed already, error only tells that other services weren't notified
+			if err := s.emitSync(memberUpdatedEvent(*change.Member)); err != nil {
+				result.Err = err.Error()
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+// StepDown lowers caller's own role in a group by one level
+func (s *Server) StepDown(c *gin.Context) {
+	userID := c.GetString("userID")
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid ID"})
+		return
+	}
+	groupID := c.Param("groupID")
+	groupUUID, err := uuid.Parse(groupID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid group ID"})
+		return
+	}
+
+	member, err := s.requestDB(c).StepDown(userUUID, groupUUID)
+	if err != nil {
+		respondMemberError(c, err)
+		return
+	}
+
+	if err := s.emitSync(memberUpdatedEvent(*member)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"err": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, formatTimes(c, gin.H{"member": member}))
+}
+
+// memberUpdatedEvent and memberCreatedEvent describe member to other services. Shared events have no co-owner
+// flag, co-owners are published as admins so that they keep their rights elsewhere
+func memberUpdatedEvent(member models.Member) events.MemberUpdatedEvent {
+	return events.MemberUpdatedEvent{
+		ID:      member.ID,
+		GroupID: member.GroupID,
+		UserID:  member.UserID,
+		User: events.User{
+			UserName: member.User.UserName,
+			Picture:  member.User.Picture,
+		},
+		DeletingMessages: member.DeletingMessages,
+		DeletingMembers:  member.DeletingMembers,
+		Adding:           member.Adding,
+		Admin:            member.Admin || member.CoOwner,
+	}
+}
+
+func memberCreatedEvent(member models.Member) events.MemberCreatedEvent {
+	return events.MemberCreatedEvent{
+		ID:      member.ID,
+		GroupID: member.GroupID,
+		UserID:  member.UserID,
+		User: events.User{
+			UserName: member.User.UserName,
+			Picture:  member.User.Picture,
+		},
+		Adding:           member.Adding,
+		DeletingMembers:  member.DeletingMembers,
+		DeletingMessages: member.DeletingMessages,
+		Admin:            member.Admin || member.CoOwner,
+		Creator:          member.Creator,
+	}
+}
+
+func (s *Server) SetGroupNickname(c *gin.Context) {
+	userID := c.GetString("userID")
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid ID"})
+		return
+	}
+	groupID := c.Param("groupID")
+	groupUUID, err := uuid.Parse(groupID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid group ID"})
+		return
+	}
+	memberID := c.Param("memberID")
+	memberUUID, err := uuid.Parse(memberID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid member ID"})
+		return
+	}
+
+	payload := struct {
+		Nickname string `json:"nickname"`
+	}{}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+		return
+	}
+	nickname, err := models.NormalizeNickname(payload.Nickname)
+	if err != nil {
+		respondInvalidText(c, err)
+		return
+	}
+
+	member, err := s.requestDB(c).SetNickname(userUUID, groupUUID, memberUUID, nickname)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{"err": err.Error()})
+		return
+	}
+
+	s.emitAsync(groupevents.MemberNicknameChangedEvent{
+		ID:       member.ID,
+		GroupID:  member.GroupID,
+		UserID:   member.UserID,
+		Nickname: nickname,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "nickname updated"})
+}
+
+func (s *Server) DeleteUserFromGroup(c *gin.Context) {
+	userID := c.GetString("userID")
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid ID"})
+		return
+	}
+	groupID := c.Param("groupID")
+	groupUUID, err := uuid.Parse(groupID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid group ID"})
+		return
+	}
+	memberID := c.Param("memberID")
+	memberUUID, err := uuid.Parse(memberID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid member ID"})
+		return
+	}
+
+	member, err := s.requestDB(c).DeleteMember(userUUID, groupUUID, memberUUID)
+	if err != nil {
+		respondMemberError(c, err)
+		return
+	}
+
+	if err := s.emitSync(events.MemberDeletedEvent{ID: member.ID, GroupID: member.GroupID, UserID: member.UserID}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"err": err.Error()})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "member deleted"})
 }
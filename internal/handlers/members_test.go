@@ -3,14 +3,20 @@ package handlers_test
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Slimo300/MicroservicesChatApp/backend/lib/apperrors"
+	"github.com/Slimo300/MicroservicesChatApp/backend/lib/events"
 	mockqueue "github.com/Slimo300/MicroservicesChatApp/backend/lib/msgqueue/mock"
 	mockdb "github.com/Slimo300/chat-groupservice/internal/database/mock"
+	groupevents "github.com/Slimo300/chat-groupservice/internal/events"
 	"github.com/Slimo300/chat-groupservice/internal/handlers"
 	"github.com/Slimo300/chat-groupservice/internal/models"
 	"github.com/gin-gonic/gin"
@@ -22,6 +28,7 @@ import (
 type MembersTestSuite struct {
 	suite.Suite
 	IDs    map[string]uuid.UUID
+	joined time.Time
 	server *handlers.Server
 }
 
@@ -35,14 +42,61 @@ func (s *MembersTestSuite) SetupSuite() {
 	s.IDs["memberOK"] = uuid.MustParse("6aeada0c-b6f9-4b96-9797-c05d92259171")
 	s.IDs["memberNotFound"] = uuid.MustParse("4eec521e-ed3d-4fd3-953e-986d77eda6ed")
 	s.IDs["memberHighRank"] = uuid.MustParse("a59aac0f-f575-4412-818f-21a52f1da02d")
+	s.IDs["userMember"] = uuid.MustParse("0f3cbe67-4c3b-4a8e-9d4c-3a4f4b6e2d15")
+	s.IDs["userNotMember"] = uuid.MustParse("c1b1a7d2-0a63-4f6e-bb7e-7f8d51a3e9c4")
+
+	s.IDs["groupJoined"] = uuid.MustParse("5b7e0c3a-9d21-4f6a-8c45-2e1f0a9b8c7d")
+	s.IDs["groupNotJoined"] = uuid.MustParse("e2a4c6d8-1b3f-4e5a-9c7d-0f2b4d6e8a1c")
 
 	db := new(mockdb.MockGroupsDB)
 
+	db.On("ChangeMemberRoles", s.IDs["userOK"], s.IDs["groupOK"], []models.RoleChange{
+		{UserID: s.IDs["userMember"], Role: "admin"},
+		{UserID: s.IDs["memberHighRank"], Role: "basic"},
+		{UserID: s.IDs["userNotMember"], Role: "deleter"},
+	}).Return([]models.RoleChangeResult{
+		{UserID: s.IDs["userMember"], Member: &models.Member{ID: s.IDs["memberOK"], UserID: s.IDs["userMember"], GroupID: s.IDs["groupOK"], Admin: true}},
+		{UserID: s.IDs["memberHighRank"], Err: apperrors.NewForbidden(fmt.Sprintf("User %v cannot alter member %v", s.IDs["userOK"], s.IDs["memberHighRank"]))},
+		{UserID: s.IDs["userNotMember"], Err: apperrors.NewNotFound("member", s.IDs["userNotMember"].String())},
+	}, nil)
+	db.On("ChangeMemberRoles", s.IDs["userWithoutRights"], s.IDs["groupOK"], mock.Anything).
+		Return(nil, apperrors.NewForbidden(fmt.Sprintf("User %v has no right to alter members in group %v", s.IDs["userWithoutRights"], s.IDs["groupOK"])))
+
+	// newest members first, the oldest one joined before a day long window
+	s.joined = time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	recent := []models.Member{
+		{ID: s.IDs["memberOK"], GroupID: s.IDs["groupOK"], UserID: s.IDs["userMember"], Joined: s.joined},
+		{ID: s.IDs["memberHighRank"], GroupID: s.IDs["groupOK"], UserID: s.IDs["userWithoutRights"], Joined: s.joined.Add(-20 * time.Hour)},
+	}
+	day := mock.MatchedBy(func(since time.Time) bool {
+		return time.Since(since) > 24*time.Hour-time.Minute && time.Since(since) < 24*time.Hour+time.Minute
+	})
+	db.On("GetRecentMembers", s.IDs["userOK"], s.IDs["groupOK"], day, models.SORT_JOINED_DESC, (*models.Cursor)(nil), 50, 0).Return(recent, nil)
+	db.On("GetRecentMembers", s.IDs["userOK"], s.IDs["groupOK"], time.Time{}, models.SORT_ACTIVITY_DESC, (*models.Cursor)(nil), 50, 0).Return(recent[1:], nil)
+	db.On("GetRecentMembers", s.IDs["userOK"], s.IDs["groupOK"], time.Time{}, models.SORT_JOINED_DESC, (*models.Cursor)(nil), 50, 0).
+		Return(append(recent, models.Member{ID: s.IDs["memberNotFound"], GroupID: s.IDs["groupOK"], UserID: s.IDs["userOK"], Joined: s.joined.Add(-72 * time.Hour)}), nil)
+	db.On("GetRecentMembers", s.IDs["userOK"], s.IDs["groupOK"], time.Time{}, models.SORT_JOINED_DESC, (*models.Cursor)(nil), 10, 50).Return([]models.Member{}, nil)
+	// the only member sorted by activity was never active, so next page holds remaining inactive members
+	db.On("GetRecentMembers", s.IDs["userOK"], s.IDs["groupOK"], time.Time{}, models.SORT_ACTIVITY_DESC, (*models.Cursor)(nil), 1, 0).Return(recent[1:], nil)
+	db.On("GetRecentMembers", s.IDs["userOK"], s.IDs["groupOK"], time.Time{}, models.SORT_ACTIVITY_DESC, &models.Cursor{Sort: models.SORT_ACTIVITY_DESC, ID: s.IDs["memberHighRank"]}, 1, 0).
+		Return([]models.Member{}, nil)
+	db.On("GetRecentMembers", s.IDs["userNotMember"], s.IDs["groupOK"], mock.Anything, models.SORT_JOINED_DESC, mock.Anything, 50, 0).
+		Return(nil, apperrors.NewForbidden(fmt.Sprintf("User %v is not a member of group %v", s.IDs["userNotMember"], s.IDs["groupOK"])))
+
+	db.On("GetUserMemberships", s.IDs["userOK"], []uuid.UUID{s.IDs["groupOK"], s.IDs["groupJoined"], s.IDs["groupNotJoined"]}).Return([]models.Member{
+		{GroupID: s.IDs["groupOK"], Creator: true},
+		{GroupID: s.IDs["groupJoined"]},
+	}, nil)
+	db.On("GetUserMemberships", s.IDs["userOK"], []uuid.UUID{s.IDs["groupNotJoined"]}).Return([]models.Member{}, nil)
+
 	db.On("DeleteMember", s.IDs["userOK"], s.IDs["groupOK"], s.IDs["memberOK"]).Return(&models.Member{ID: s.IDs["memberOK"]}, nil)
 	db.On("DeleteMember", s.IDs["userWithoutRights"], s.IDs["groupOK"], s.IDs["memberOK"]).
 		Return(nil, apperrors.NewForbidden(fmt.Sprintf("User %v has no right to delete members in group %v", s.IDs["userWithoutRights"], s.IDs["groupOK"])))
 	db.On("DeleteMember", s.IDs["userOK"], s.IDs["groupOK"], s.IDs["memberNotFound"]).
 		Return(nil, apperrors.NewNotFound("member", s.IDs["memberNotFound"].String()))
+	// userOK is a creator of groupOK and tries to leave it
+	s.IDs["memberCreator"] = uuid.MustParse("c2f1e0d9-8b7a-4c6d-9e5f-4a3b2c1d0e9f")
+	db.On("DeleteMember", s.IDs["userOK"], s.IDs["groupOK"], s.IDs["memberCreator"]).Return(nil, models.ErrLastOwner)
 	db.On("DeleteMember", s.IDs["userOK"], s.IDs["groupOK"], s.IDs["memberHighRank"]).
 		Return(nil, apperrors.NewForbidden(fmt.Sprintf("User %v cannot delete member %v", s.IDs["userOK"], s.IDs["memberHighRank"])))
 
@@ -54,100 +108,1390 @@ func (s *MembersTestSuite) SetupSuite() {
 	db.On("GrantRights", s.IDs["userOK"], s.IDs["groupOK"], s.IDs["memberHighRank"], mock.Anything).
 		Return(nil, apperrors.NewForbidden(fmt.Sprintf("User %v cannot alter member %v", s.IDs["userOK"], s.IDs["memberHighRank"])))
 
-	db.On("DeleteGroup", s.IDs["userWithoutRights"], s.IDs["groupOK"]).
-		Return(models.Group{}, apperrors.NewForbidden("User has no right to delete group"))
-	db.On("DeleteGroup", s.IDs["userOK"], s.IDs["groupOK"]).
-		Return(models.Group{ID: s.IDs["groupOK"]}, nil)
+	db.On("GetMembership", s.IDs["userOK"], s.IDs["groupOK"], s.IDs["userMember"]).
+		Return(&models.Member{ID: s.IDs["memberOK"], GroupID: s.IDs["groupOK"], UserID: s.IDs["userMember"]}, nil)
+	db.On("GetMembership", s.IDs["userWithoutRights"], s.IDs["groupOK"], s.IDs["userMember"]).
+		Return(nil, apperrors.NewForbidden(fmt.Sprintf("User %v has no right to view members of group %v", s.IDs["userWithoutRights"], s.IDs["groupOK"])))
+	db.On("GetMembership", s.IDs["userOK"], s.IDs["groupOK"], s.IDs["userNotMember"]).
+		Return(nil, apperrors.NewNotFound("member", s.IDs["userNotMember"].String()))
+
+	db.On("FindMember", s.IDs["groupOK"], s.IDs["userOK"]).Return(&models.Member{ID: s.IDs["memberOK"], Creator: true}, nil)
+	db.On("FindMember", s.IDs["groupOK"], s.IDs["userMember"]).Return(&models.Member{ID: s.IDs["memberOK"]}, nil)
+	db.On("FindMember", s.IDs["groupOK"], s.IDs["userNotMember"]).Return(nil, nil)
+	db.On("GetGroupWithMembership", s.IDs["userOK"], s.IDs["groupOK"]).Return(&models.Member{
+		ID: s.IDs["memberOK"], GroupID: s.IDs["groupOK"], Admin: true, Nickname: "boss", Joined: s.joined,
+		Group: models.Group{ID: s.IDs["groupOK"], Name: "Group", SlowModeSeconds: 30},
+	}, nil)
+	db.On("GetGroupWithMembership", s.IDs["userNotMember"], s.IDs["groupOK"]).Return(nil, nil)
+
+	db.On("StepDown", s.IDs["userOK"], s.IDs["groupOK"]).Return(nil, models.ErrLastOwner)
+	db.On("StepDown", s.IDs["userMember"], s.IDs["groupOK"]).
+		Return(&models.Member{ID: s.IDs["memberOK"], GroupID: s.IDs["groupOK"], UserID: s.IDs["userMember"], Adding: true}, nil)
+	db.On("StepDown", s.IDs["userWithoutRights"], s.IDs["groupOK"]).Return(nil, apperrors.NewBadRequest("member has no role to step down from"))
+
+	db.On("SetNickname", s.IDs["userOK"], s.IDs["groupOK"], s.IDs["memberOK"], "nick").
+		Return(&models.Member{ID: s.IDs["memberOK"], GroupID: s.IDs["groupOK"], UserID: s.IDs["userOK"], Nickname: "nick"}, nil)
+	db.On("SetNickname", s.IDs["userOK"], s.IDs["groupOK"], s.IDs["memberHighRank"], "nick").
+		Return(nil, apperrors.NewForbidden(fmt.Sprintf("User %v cannot change nickname of member %v", s.IDs["userOK"], s.IDs["memberHighRank"])))
+
+	db.On("DeleteGroup", s.IDs["userWithoutRights"], s.IDs["groupOK"]).
+		Return(models.Group{}, apperrors.NewForbidden("User has no right to delete group"))
+	db.On("DeleteGroup", s.IDs["userOK"], s.IDs["groupOK"]).
+		Return(models.Group{ID: s.IDs["groupOK"]}, nil)
+
+	// memberHighRank hands admin role and nickname over to memberOK and is left a basic member
+	handover := models.MemberHandover{Nickname: true, ClearSource: true}
+	db.On("HandoverMember", s.IDs["userOK"], s.IDs["groupOK"], s.IDs["memberHighRank"], s.IDs["memberOK"], handover).Return(
+		&models.Member{ID: s.IDs["memberHighRank"], GroupID: s.IDs["groupOK"], UserID: s.IDs["userWithoutRights"]},
+		&models.Member{ID: s.IDs["memberOK"], GroupID: s.IDs["groupOK"], UserID: s.IDs["userMember"], Admin: true, Nickname: "mod"}, nil)
+	db.On("HandoverMember", s.IDs["userOK"], s.IDs["groupOK"], s.IDs["memberHighRank"], s.IDs["memberOK"], models.MemberHandover{}).Return(
+		&models.Member{ID: s.IDs["memberHighRank"], GroupID: s.IDs["groupOK"], UserID: s.IDs["userWithoutRights"], Admin: true},
+		&models.Member{ID: s.IDs["memberOK"], GroupID: s.IDs["groupOK"], UserID: s.IDs["userMember"], Admin: true}, nil)
+	db.On("HandoverMember", s.IDs["userWithoutRights"], s.IDs["groupOK"], s.IDs["memberHighRank"], s.IDs["memberOK"], mock.Anything).
+		Return(nil, nil, apperrors.NewForbidden(fmt.Sprintf("User %v cannot alter member %v", s.IDs["userWithoutRights"], s.IDs["memberOK"])))
+	db.On("HandoverMember", s.IDs["userOK"], s.IDs["groupOK"], s.IDs["memberHighRank"], s.IDs["memberNotFound"], mock.Anything).
+		Return(nil, nil, apperrors.NewNotFound("member", s.IDs["memberNotFound"].String()))
+
+	emiter := new(mockqueue.MockEmitter)
+	emiter.On("Emit", mock.Anything).Return(nil)
+
+	s.server = handlers.NewServer(db, nil, nil, emiter)
+}
+
+func (s *MembersTestSuite) TestGetMembership() {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		desc               string
+		userID             string
+		groupID            string
+		targetID           string
+		returnVal          bool
+		expectedStatusCode int
+		expectedResponse   interface{}
+	}{
+		{
+			desc:               "GetMembershipBadUserID",
+			userID:             s.IDs["userOK"].String()[:2],
+			groupID:            s.IDs["groupOK"].String(),
+			targetID:           s.IDs["userMember"].String(),
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "invalid ID"},
+		},
+		{
+			desc:               "GetMembershipBadGroupID",
+			userID:             s.IDs["userOK"].String(),
+			groupID:            s.IDs["groupOK"].String()[:2],
+			targetID:           s.IDs["userMember"].String(),
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "invalid group ID"},
+		},
+		{
+			desc:               "GetMembershipBadTargetID",
+			userID:             s.IDs["userOK"].String(),
+			groupID:            s.IDs["groupOK"].String(),
+			targetID:           s.IDs["userMember"].String()[:2],
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "invalid user ID"},
+		},
+		{
+			desc:               "GetMembershipNoRights",
+			userID:             s.IDs["userWithoutRights"].String(),
+			groupID:            s.IDs["groupOK"].String(),
+			targetID:           s.IDs["userMember"].String(),
+			expectedStatusCode: http.StatusForbidden,
+			expectedResponse:   gin.H{"err": fmt.Sprintf("Forbidden action. Reason: User %v has no right to view members of group %v", s.IDs["userWithoutRights"].String(), s.IDs["groupOK"].String())},
+		},
+		{
+			desc:               "GetMembershipNotFound",
+			userID:             s.IDs["userOK"].String(),
+			groupID:            s.IDs["groupOK"].String(),
+			targetID:           s.IDs["userNotMember"].String(),
+			expectedStatusCode: http.StatusNotFound,
+			expectedResponse:   gin.H{"err": fmt.Sprintf("resource: member with value: %v not found", s.IDs["userNotMember"].String())},
+		},
+		{
+			desc:               "GetMembershipSuccess",
+			userID:             s.IDs["userOK"].String(),
+			groupID:            s.IDs["groupOK"].String(),
+			targetID:           s.IDs["userMember"].String(),
+			returnVal:          true,
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   models.Member{ID: s.IDs["memberOK"], GroupID: s.IDs["groupOK"], UserID: s.IDs["userMember"]},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+			req, _ := http.NewRequest(http.MethodGet, "/group/"+tC.groupID+"/membership/"+tC.targetID, nil)
+
+			w := httptest.NewRecorder()
+			_, engine := gin.CreateTestContext(w)
+			engine.Use(func(c *gin.Context) {
+				c.Set("userID", tC.userID)
+			})
+
+			engine.Handle(http.MethodGet, "/group/:groupID/membership/:userID", s.server.GetMembership)
+			engine.ServeHTTP(w, req)
+			response := w.Result()
+			defer response.Body.Close()
+
+			s.Equal(tC.expectedStatusCode, response.StatusCode)
+
+			var respBody interface{}
+			if tC.returnVal {
+				member := models.Member{}
+				if err := json.NewDecoder(response.Body).Decode(&member); err != nil {
+					s.Fail(err.Error())
+				}
+				respBody = member
+			} else {
+				var msg gin.H
+				if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+					s.Fail(err.Error())
+				}
+				respBody = msg
+			}
+
+			s.Equal(tC.expectedResponse, respBody)
+		})
+	}
+}
+
+func (s *MembersTestSuite) TestGrantPriv() {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		desc               string
+		userID             string
+		groupID            string
+		memberID           string
+		data               map[string]interface{}
+		expectedStatusCode int
+		expectedResponse   interface{}
+	}{
+		{
+			desc:               "UpdateRightsBadUserID",
+			userID:             s.IDs["userOK"].String()[:2],
+			groupID:            s.IDs["groupOK"].String(),
+			memberID:           s.IDs["memberOK"].String(),
+			data:               map[string]interface{}{"adding": -1},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "invalid ID"},
+		},
+		{
+			desc:               "UpdateRightsBadGroupID",
+			userID:             s.IDs["userOK"].String(),
+			groupID:            s.IDs["groupOK"].String()[:2],
+			memberID:           s.IDs["memberOK"].String(),
+			data:               map[string]interface{}{"adding": -1},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "invalid group ID"},
+		},
+		{
+			desc:               "UpdateRightsBadMemberID",
+			userID:             s.IDs["userOK"].String(),
+			groupID:            s.IDs["groupOK"].String(),
+			memberID:           s.IDs["memberOK"].String()[:2],
+			data:               map[string]interface{}{"adding": -1},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "invalid member ID"},
+		},
+		{
+			desc:               "UpdateRightsNoAction",
+			userID:             s.IDs["userOK"].String(),
+			groupID:            s.IDs["groupOK"].String(),
+			memberID:           s.IDs["memberOK"].String(),
+			data:               map[string]interface{}{},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "no action specified"},
+		},
+		{
+			desc:               "UpdateRightsNoRights",
+			userID:             s.IDs["userWithoutRights"].String(),
+			groupID:            s.IDs["groupOK"].String(),
+			memberID:           s.IDs["memberOK"].String(),
+			data:               map[string]interface{}{"adding": -1},
+			expectedStatusCode: http.StatusForbidden,
+			expectedResponse:   gin.H{"err": fmt.Sprintf("Forbidden action. Reason: User %v has no right to alter members in group %v", s.IDs["userWithoutRights"].String(), s.IDs["groupOK"].String())},
+		},
+		{
+			desc:               "UpdateRightsNotFound",
+			userID:             s.IDs["userOK"].String(),
+			groupID:            s.IDs["groupOK"].String(),
+			memberID:           s.IDs["memberNotFound"].String(),
+			data:               map[string]interface{}{"adding": -1},
+			expectedStatusCode: http.StatusNotFound,
+			expectedResponse:   gin.H{"err": fmt.Sprintf("resource: member with value: %v not found", s.IDs["memberNotFound"].String())},
+		},
+		{
+			desc:               "UpdateRightsHighRank",
+			userID:             s.IDs["userOK"].String(),
+			groupID:            s.IDs["groupOK"].String(),
+			memberID:           s.IDs["memberHighRank"].String(),
+			data:               map[string]interface{}{"adding": -1},
+			expectedStatusCode: http.StatusForbidden,
+			expectedResponse:   gin.H{"err": fmt.Sprintf("Forbidden action. Reason: User %v cannot alter member %v", s.IDs["userOK"].String(), s.IDs["memberHighRank"].String())},
+		},
+		{
+			desc:               "UpdateRightsSuccess",
+			userID:             s.IDs["userOK"].String(),
+			groupID:            s.IDs["groupOK"].String(),
+			memberID:           s.IDs["memberOK"].String(),
+			data:               map[string]interface{}{"adding": -1},
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   gin.H{"message": "member updated"},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+
+			requestBody, _ := json.Marshal(tC.data)
+			req, _ := http.NewRequest(http.MethodPut, "/group/"+tC.groupID+"/member/"+tC.memberID, bytes.NewBuffer(requestBody))
+
+			w := httptest.NewRecorder()
+			_, engine := gin.CreateTestContext(w)
+			engine.Use(func(c *gin.Context) {
+				c.Set("userID", tC.userID)
+			})
+
+			engine.Handle(http.MethodPut, "/group/:groupID/member/:memberID", s.server.GrantPriv)
+			engine.ServeHTTP(w, req)
+			response := w.Result()
+			defer response.Body.Close()
+
+			s.Equal(tC.expectedStatusCode, response.StatusCode)
+
+			var msg gin.H
+			if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+				s.Fail(err.Error())
+			}
+
+			s.Equal(tC.expectedResponse, msg)
+		})
+	}
+}
+
+func (s *MembersTestSuite) TestHandoverMemberConfig() {
+	gin.SetMode(gin.TestMode)
+
+	emiter := new(mockqueue.MockEmitter)
+	emiter.On("Emit", mock.Anything).Return(nil)
+	server := *s.server
+	server.Emitter = emiter
+
+	testCases := []struct {
+		desc               string
+		userID             string
+		memberID           string
+		data               map[string]interface{}
+		expectedStatusCode int
+		expectedResponse   gin.H
+	}{
+		{
+			desc:               "HandoverBadMemberID",
+			userID:             s.IDs["userOK"].String(),
+			memberID:           "1",
+			data:               map[string]interface{}{"targetID": s.IDs["memberOK"]},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "invalid member ID"},
+		},
+		{
+			desc:               "HandoverBadTargetID",
+			userID:             s.IDs["userOK"].String(),
+			memberID:           s.IDs["memberHighRank"].String(),
+			data:               map[string]interface{}{"targetID": "1"},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "invalid target member ID"},
+		},
+		{
+			desc:               "HandoverNoRights",
+			userID:             s.IDs["userWithoutRights"].String(),
+			memberID:           s.IDs["memberHighRank"].String(),
+			data:               map[string]interface{}{"targetID": s.IDs["memberOK"]},
+			expectedStatusCode: http.StatusForbidden,
+			expectedResponse:   gin.H{"err": fmt.Sprintf("Forbidden action. Reason: User %v cannot alter member %v", s.IDs["userWithoutRights"], s.IDs["memberOK"])},
+		},
+		{
+			desc:               "HandoverTargetNotFound",
+			userID:             s.IDs["userOK"].String(),
+			memberID:           s.IDs["memberHighRank"].String(),
+			data:               map[string]interface{}{"targetID": s.IDs["memberNotFound"]},
+			expectedStatusCode: http.StatusNotFound,
+			expectedResponse:   gin.H{"err": fmt.Sprintf("resource: member with value: %v not found", s.IDs["memberNotFound"])},
+		},
+		{
+			desc:               "HandoverCopy",
+			userID:             s.IDs["userOK"].String(),
+			memberID:           s.IDs["memberHighRank"].String(),
+			data:               map[string]interface{}{"targetID": s.IDs["memberOK"]},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			desc:               "HandoverCopyAndClear",
+			userID:             s.IDs["userOK"].String(),
+			memberID:           s.IDs["memberHighRank"].String(),
+			data:               map[string]interface{}{"targetID": s.IDs["memberOK"], "nickname": true, "clearSource": true},
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+
+			requestBody, _ := json.Marshal(tC.data)
+			req, _ := http.NewRequest(http.MethodPost, "/group/"+s.IDs["groupOK"].String()+"/member/"+tC.memberID+"/handover", bytes.NewBuffer(requestBody))
+
+			w := httptest.NewRecorder()
+			_, engine := gin.CreateTestContext(w)
+			engine.Use(func(c *gin.Context) {
+				c.Set("userID", tC.userID)
+			})
+
+			engine.Handle(http.MethodPost, "/group/:groupID/member/:memberID/handover", server.HandoverMemberConfig)
+			engine.ServeHTTP(w, req)
+			response := w.Result()
+			defer response.Body.Close()
+
+			s.Equal(tC.expectedStatusCode, response.StatusCode)
+
+			var msg gin.H
+			if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+				s.Fail(err.Error())
+			}
+			if tC.expectedResponse != nil {
+				s.Equal(tC.expectedResponse, msg)
+				return
+			}
+			s.Equal(s.IDs["memberHighRank"].String(), msg["source"].(map[string]interface{})["ID"])
+			s.Equal(true, msg["target"].(map[string]interface{})["admin"])
+		})
+	}
+
+	server.WaitForEmits()
+	// copy updates only the target, clearing updates source too and nickname is announced for both
+	emiter.AssertCalled(s.T(), "Emit", events.MemberUpdatedEvent{ID: s.IDs["memberOK"], GroupID: s.IDs["groupOK"], UserID: s.IDs["userMember"], Admin: true})
+	emiter.AssertCalled(s.T(), "Emit", events.MemberUpdatedEvent{ID: s.IDs["memberHighRank"], GroupID: s.IDs["groupOK"], UserID: s.IDs["userWithoutRights"]})
+	emiter.AssertCalled(s.T(), "Emit", groupevents.MemberNicknameChangedEvent{ID: s.IDs["memberOK"], GroupID: s.IDs["groupOK"], UserID: s.IDs["userMember"], Nickname: "mod"})
+	emiter.AssertCalled(s.T(), "Emit", groupevents.MemberNicknameChangedEvent{ID: s.IDs["memberHighRank"], GroupID: s.IDs["groupOK"], UserID: s.IDs["userWithoutRights"]})
+	emiter.AssertNumberOfCalls(s.T(), "Emit", 5)
+}
+
+func (s *MembersTestSuite) TestGetMyMembershipsForGroups() {
+	gin.SetMode(gin.TestMode)
+
+	tooMany := ""
+	for i := 0; i <= handlers.MAX_MEMBERSHIP_BATCH; i++ {
+		tooMany += "&groupID=" + uuid.NewString()
+	}
+
+	testCases := []struct {
+		desc               string
+		query              string
+		expectedStatusCode int
+		expectedResponse   interface{}
+	}{
+		{
+			desc:               "GetMembershipsNoGroups",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "groups not specified"},
+		},
+		{
+			desc:               "GetMembershipsBadGroupID",
+			query:              "?groupID=" + s.IDs["groupOK"].String()[:2],
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "invalid group ID"},
+		},
+		{
+			desc:               "GetMembershipsTooMany",
+			query:              "?" + tooMany[1:],
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": fmt.Sprintf("at most %d groups can be checked at once", handlers.MAX_MEMBERSHIP_BATCH)},
+		},
+		{
+			desc: "GetMembershipsMixed",
+			query: "?groupID=" + s.IDs["groupOK"].String() + "&groupID=" + s.IDs["groupJoined"].String() +
+				"&groupID=" + s.IDs["groupOK"].String() + "&groupID=" + s.IDs["groupNotJoined"].String(),
+			expectedStatusCode: http.StatusOK,
+			expectedResponse: []gin.H{
+				{"groupID": s.IDs["groupOK"].String(), "role": "creator"},
+				{"groupID": s.IDs["groupJoined"].String(), "role": "basic"},
+			},
+		},
+		{
+			desc:               "GetMembershipsNoneJoined",
+			query:              "?groupID=" + s.IDs["groupNotJoined"].String(),
+			expectedStatusCode: http.StatusNoContent,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+
+			req, _ := http.NewRequest(http.MethodGet, "/api/memberships"+tC.query, nil)
+
+			w := httptest.NewRecorder()
+			_, engine := gin.CreateTestContext(w)
+
+			engine.Use(func(c *gin.Context) {
+				c.Set("userID", s.IDs["userOK"].String())
+			})
+			engine.Handle(http.MethodGet, "/api/memberships", s.server.GetMyMembershipsForGroups)
+			engine.ServeHTTP(w, req)
+			response := w.Result()
+			defer response.Body.Close()
+
+			s.Equal(tC.expectedStatusCode, response.StatusCode)
+
+			switch expected := tC.expectedResponse.(type) {
+			case []gin.H:
+				var memberships []gin.H
+				if err := json.NewDecoder(response.Body).Decode(&memberships); err != nil {
+					s.Fail(err.Error())
+				}
+				s.Equal(expected, memberships)
+			case gin.H:
+				var msg gin.H
+				if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+					s.Fail(err.Error())
+				}
+				s.Equal(expected, msg)
+			}
+		})
+	}
+}
+
+func (s *MembersTestSuite) TestGetMyRolesAndCapabilities() {
+	gin.SetMode(gin.TestMode)
+
+	groupAdmin := uuid.MustParse("a1c3e5f7-1a3c-4e5f-8a1c-3e5f7a1c3e5f")
+	groupBasic := uuid.MustParse("b2d4f6a8-2b4d-4f6a-9b2d-4f6a8b2d4f6a")
+
+	db := new(mockdb.MockGroupsDB)
+	db.On("GetUserMemberships", s.IDs["userOK"], []uuid.UUID{s.IDs["groupOK"], groupAdmin, groupBasic, s.IDs["groupNotJoined"]}).Return([]models.Member{
+		{GroupID: s.IDs["groupOK"], Creator: true},
+		{GroupID: groupAdmin, Admin: true},
+		{GroupID: groupBasic, Adding: true},
+	}, nil)
+	db.On("GetUserMemberships", s.IDs["userOK"], []uuid.UUID{s.IDs["groupNotJoined"]}).Return([]models.Member{}, nil)
+
+	server := *s.server
+	server.DB = db
+
+	tooMany := ""
+	for i := 0; i <= handlers.MAX_MEMBERSHIP_BATCH; i++ {
+		tooMany += "&groupID=" + uuid.NewString()
+	}
+	allGroups := "groupID=" + s.IDs["groupOK"].String() + "&groupID=" + groupAdmin.String() + "&groupID=" + groupBasic.String() +
+		"&groupID=" + s.IDs["groupNotJoined"].String()
+
+	testCases := []struct {
+		desc               string
+		query              string
+		expectedStatusCode int
+		expectedResponse   interface{}
+	}{
+		{
+			desc:               "RolesNoGroups",
+			query:              "?capability=invite",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "groups not specified"},
+		},
+		{
+			desc:               "RolesBadGroupID",
+			query:              "?groupID=" + s.IDs["groupOK"].String()[:2],
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "invalid group ID"},
+		},
+		{
+			desc:               "RolesTooManyGroups",
+			query:              "?" + tooMany[1:],
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": fmt.Sprintf("at most %d groups can be checked at once", handlers.MAX_MEMBERSHIP_BATCH)},
+		},
+		{
+			desc:               "RolesUnknownCapability",
+			query:              "?" + allGroups + "&capability=flyAway",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "unknown capability: flyAway"},
+		},
+		{
+			desc:               "RolesSelectedCapabilities",
+			query:              "?" + allGroups + "&capability=" + models.CAN_INVITE + "&capability=" + models.CAN_EDIT_MEMBERS + "&capability=" + models.CAN_DELETE_GROUP,
+			expectedStatusCode: http.StatusOK,
+			expectedResponse: []gin.H{
+				{"groupID": s.IDs["groupOK"].String(), "member": true, "role": "creator", "capabilities": map[string]interface{}{"invite": true, "editMembers": true, "deleteGroup": true}},
+				{"groupID": groupAdmin.String(), "member": true, "role": "admin", "capabilities": map[string]interface{}{"invite": true, "editMembers": true, "deleteGroup": false}},
+				{"groupID": groupBasic.String(), "member": true, "role": "basic", "capabilities": map[string]interface{}{"invite": true, "editMembers": false, "deleteGroup": false}},
+				{"groupID": s.IDs["groupNotJoined"].String(), "member": false, "capabilities": map[string]interface{}{"invite": false, "editMembers": false, "deleteGroup": false}},
+			},
+		},
+		{
+			desc:               "RolesAllCapabilitiesNotMember",
+			query:              "?groupID=" + s.IDs["groupNotJoined"].String(),
+			expectedStatusCode: http.StatusOK,
+			expectedResponse: []gin.H{
+				{"groupID": s.IDs["groupNotJoined"].String(), "member": false, "capabilities": map[string]interface{}{
+					"invite": false, "removeMembers": false, "editMembers": false, "editGroup": false,
+					"setAnnouncement": false, "deleteGroup": false, "exportGroup": false,
+				}},
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+
+			req, _ := http.NewRequest(http.MethodGet, "/api/memberships/capabilities"+tC.query, nil)
+
+			w := httptest.NewRecorder()
+			_, engine := gin.CreateTestContext(w)
+
+			engine.Use(func(c *gin.Context) {
+				c.Set("userID", s.IDs["userOK"].String())
+			})
+			engine.Handle(http.MethodGet, "/api/memberships/capabilities", server.GetMyRolesAndCapabilities)
+			engine.ServeHTTP(w, req)
+			response := w.Result()
+			defer response.Body.Close()
+
+			s.Equal(tC.expectedStatusCode, response.StatusCode)
+
+			switch expected := tC.expectedResponse.(type) {
+			case []gin.H:
+				var results []gin.H
+				if err := json.NewDecoder(response.Body).Decode(&results); err != nil {
+					s.Fail(err.Error())
+				}
+				s.Equal(expected, results)
+			case gin.H:
+				var msg gin.H
+				if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+					s.Fail(err.Error())
+				}
+				s.Equal(expected, msg)
+			}
+		})
+	}
+}
+
+func (s *MembersTestSuite) TestGetMembershipsForUsers() {
+	gin.SetMode(gin.TestMode)
+
+	member := uuid.MustParse("e4a6c8e0-2a4c-4e6a-8c2e-4a6c8e0a2c4e")
+	nonMember := uuid.MustParse("f5b7d9f1-3b5d-4f7b-9d3f-5b7d9f1b3d5f")
+
+	db := new(mockdb.MockGroupsDB)
+	db.On("GetGroupMemberships", s.IDs["userOK"], s.IDs["groupOK"], []uuid.UUID{member, nonMember, s.IDs["userOK"]}).Return([]models.Member{
+		{UserID: member, Admin: true},
+		{UserID: s.IDs["userOK"], Creator: true},
+	}, nil)
+	db.On("GetGroupMemberships", s.IDs["userWithoutRights"], s.IDs["groupOK"], []uuid.UUID{member}).
+		Return(nil, apperrors.NewForbidden(fmt.Sprintf("User %v is not a member of group %v", s.IDs["userWithoutRights"], s.IDs["groupOK"])))
+
+	server := *s.server
+	server.DB = db
+
+	tooMany := ""
+	for i := 0; i <= handlers.MAX_USER_MEMBERSHIP_BATCH; i++ {
+		tooMany += "&userID=" + uuid.NewString()
+	}
+
+	testCases := []struct {
+		desc               string
+		userID             uuid.UUID
+		groupID            string
+		query              string
+		expectedStatusCode int
+		expectedResponse   gin.H
+	}{
+		{
+			desc:               "GetMembershipsForUsersBadGroupID",
+			userID:             s.IDs["userOK"],
+			groupID:            "1",
+			query:              "?userID=" + member.String(),
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "invalid group ID"},
+		},
+		{
+			desc:               "GetMembershipsForUsersNoUsers",
+			userID:             s.IDs["userOK"],
+			groupID:            s.IDs["groupOK"].String(),
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "users not specified"},
+		},
+		{
+			desc:               "GetMembershipsForUsersBadUserID",
+			userID:             s.IDs["userOK"],
+			groupID:            s.IDs["groupOK"].String(),
+			query:              "?userID=1",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "invalid user ID"},
+		},
+		{
+			desc:               "GetMembershipsForUsersTooMany",
+			userID:             s.IDs["userOK"],
+			groupID:            s.IDs["groupOK"].String(),
+			query:              "?" + tooMany[1:],
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": fmt.Sprintf("at most %d users can be checked at once", handlers.MAX_USER_MEMBERSHIP_BATCH)},
+		},
+		{
+			desc:               "GetMembershipsForUsersNotMember",
+			userID:             s.IDs["userWithoutRights"],
+			groupID:            s.IDs["groupOK"].String(),
+			query:              "?userID=" + member.String(),
+			expectedStatusCode: http.StatusForbidden,
+			expectedResponse:   gin.H{"err": fmt.Sprintf("Forbidden action. Reason: User %v is not a member of group %v", s.IDs["userWithoutRights"], s.IDs["groupOK"])},
+		},
+		{
+			desc:    "GetMembershipsForUsersMixed",
+			userID:  s.IDs["userOK"],
+			groupID: s.IDs["groupOK"].String(),
+			query: "?userID=" + member.String() + "&userID=" + nonMember.String() + "&userID=" + member.String() +
+				"&userID=" + s.IDs["userOK"].String(),
+			expectedStatusCode: http.StatusOK,
+			expectedResponse: gin.H{
+				member.String():          map[string]interface{}{"member": true, "role": "admin"},
+				nonMember.String():       map[string]interface{}{"member": false},
+				s.IDs["userOK"].String(): map[string]interface{}{"member": true, "role": "creator"},
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+			req, _ := http.NewRequest(http.MethodGet, "/api/group/"+tC.groupID+"/memberships"+tC.query, nil)
+
+			w := httptest.NewRecorder()
+			_, engine := gin.CreateTestContext(w)
+			engine.Use(func(c *gin.Context) {
+				c.Set("userID", tC.userID.String())
+			})
+			engine.Handle(http.MethodGet, "/api/group/:groupID/memberships", server.GetMembershipsForUsers)
+			engine.ServeHTTP(w, req)
+			response := w.Result()
+			defer response.Body.Close()
+
+			s.Equal(tC.expectedStatusCode, response.StatusCode)
+
+			var msg gin.H
+			if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+				s.Fail(err.Error())
+			}
+			s.Equal(tC.expectedResponse, msg)
+		})
+	}
+}
+
+type roleChangeResult struct {
+	UserID  string `json:"userID"`
+	Role    string `json:"role"`
+	Changed bool   `json:"changed"`
+	Err     string `json:"err"`
+}
+
+func (s *MembersTestSuite) TestBulkChangeMemberRoles() {
+	gin.SetMode(gin.TestMode)
+
+	tooMany := make([]gin.H, handlers.MAX_BULK_ROLE_CHANGES+1)
+	for i := range tooMany {
+		tooMany[i] = gin.H{"userID": uuid.NewString(), "role": "admin"}
+	}
+
+	testCases := []struct {
+		desc               string
+		userID             string
+		data               map[string]interface{}
+		returnVal          bool
+		expectedStatusCode int
+		expectedResponse   interface{}
+	}{
+		{
+			desc:               "BulkChangeRolesNoMembers",
+			userID:             s.IDs["userOK"].String(),
+			data:               map[string]interface{}{"members": []gin.H{}},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "members not specified"},
+		},
+		{
+			desc:               "BulkChangeRolesTooMany",
+			userID:             s.IDs["userOK"].String(),
+			data:               map[string]interface{}{"members": tooMany},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": fmt.Sprintf("at most %d roles can be changed at once", handlers.MAX_BULK_ROLE_CHANGES)},
+		},
+		{
+			desc:               "BulkChangeRolesNoRights",
+			userID:             s.IDs["userWithoutRights"].String(),
+			data:               map[string]interface{}{"members": []gin.H{{"userID": s.IDs["userMember"].String(), "role": "admin"}}},
+			expectedStatusCode: http.StatusForbidden,
+			expectedResponse:   gin.H{"err": fmt.Sprintf("Forbidden action. Reason: User %v has no right to alter members in group %v", s.IDs["userWithoutRights"], s.IDs["groupOK"])},
+		},
+		{
+			desc:   "BulkChangeRolesPartialSuccess",
+			userID: s.IDs["userOK"].String(),
+			data: map[string]interface{}{"members": []gin.H{
+				{"userID": s.IDs["userMember"].String(), "role": "admin"},
+				{"userID": s.IDs["memberHighRank"].String(), "role": "basic"},
+				{"userID": s.IDs["userNotMember"].String(), "role": "deleter"},
+				{"userID": s.IDs["memberNotFound"].String(), "role": "creator"},
+				{"userID": "invalid", "role": "admin"},
+				{"userID": s.IDs["userMember"].String(), "role": "basic"},
+				{"userID": strings.ToUpper(s.IDs["userMember"].String()), "role": "deleter"},
+			}},
+			returnVal:          true,
+			expectedStatusCode: http.StatusOK,
+			expectedResponse: []roleChangeResult{
+				{UserID: s.IDs["userMember"].String(), Role: "admin", Changed: true},
+				{UserID: s.IDs["memberHighRank"].String(), Role: "basic", Err: fmt.Sprintf("Forbidden action. Reason: User %v cannot alter member %v", s.IDs["userOK"], s.IDs["memberHighRank"])},
+				{UserID: s.IDs["userNotMember"].String(), Role: "deleter", Err: fmt.Sprintf("resource: member with value: %v not found", s.IDs["userNotMember"])},
+				{UserID: s.IDs["memberNotFound"].String(), Role: "creator", Err: "creator role cannot be granted"},
+				{UserID: "invalid", Role: "admin", Err: "invalid user id"},
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+
+			requestBody, _ := json.Marshal(tC.data)
+			req, _ := http.NewRequest(http.MethodPost, "/api/group/"+s.IDs["groupOK"].String()+"/roles", bytes.NewReader(requestBody))
+
+			w := httptest.NewRecorder()
+			_, engine := gin.CreateTestContext(w)
+
+			engine.Use(func(c *gin.Context) {
+				c.Set("userID", tC.userID)
+			})
+			engine.Handle(http.MethodPost, "/api/group/:groupID/roles", s.server.BulkChangeMemberRoles)
+			engine.ServeHTTP(w, req)
+			response := w.Result()
+			defer response.Body.Close()
+
+			s.Equal(tC.expectedStatusCode, response.StatusCode)
+
+			var respBody interface{}
+			if tC.returnVal {
+				var results struct {
+					Results []roleChangeResult `json:"results"`
+				}
+				if err := json.NewDecoder(response.Body).Decode(&results); err != nil {
+					s.Fail(err.Error())
+				}
+				respBody = results.Results
+			} else {
+				var msg gin.H
+				if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+					s.Fail(err.Error())
+				}
+				respBody = msg
+			}
+
+			s.Equal(tC.expectedResponse, respBody)
+		})
+	}
+}
+
+// Shared events have no co-owner flag, co-owners are published with admin rights
+func (s *MembersTestSuite) TestBulkChangeMemberRolesCoOwnerEvent() {
+	gin.SetMode(gin.TestMode)
+
+	db := new(mockdb.MockGroupsDB)
+	db.On("ChangeMemberRoles", s.IDs["userOK"], s.IDs["groupOK"], []models.RoleChange{{UserID: s.IDs["userMember"], Role: "co-owner"}}).
+		Return([]models.RoleChangeResult{
+			{UserID: s.IDs["userMember"], Member: &models.Member{ID: s.IDs["memberOK"], UserID: s.IDs["userMember"], GroupID: s.IDs["groupOK"], CoOwner: true}},
+		}, nil)
+	emiter := new(mockqueue.MockEmitter)
+	emiter.On("Emit", mock.Anything).Return(nil)
+
+	server := *s.server
+	server.DB = db
+	server.Emitter = emiter
+
+	requestBody, _ := json.Marshal(gin.H{"members": []gin.H{{"userID": s.IDs["userMember"].String(), "role": "co-owner"}}})
+	req, _ := http.NewRequest(http.MethodPost, "/api/group/"+s.IDs["groupOK"].String()+"/roles", bytes.NewReader(requestBody))
+
+	w := httptest.NewRecorder()
+	_, engine := gin.CreateTestContext(w)
+	engine.Use(func(c *gin.Context) {
+		c.Set("userID", s.IDs["userOK"].String())
+	})
+	engine.Handle(http.MethodPost, "/api/group/:groupID/roles", server.BulkChangeMemberRoles)
+	engine.ServeHTTP(w, req)
+
+	s.Equal(http.StatusOK, w.Code)
+	emiter.AssertCalled(s.T(), "Emit", events.MemberUpdatedEvent{ID: s.IDs["memberOK"], GroupID: s.IDs["groupOK"], UserID: s.IDs["userMember"], Admin: true})
+}
+
+// Roles are already changed when emitting fails, so results are still reported
+func (s *MembersTestSuite) TestBulkChangeMemberRolesEmitFailure() {
+	gin.SetMode(gin.TestMode)
+
+	emiter := new(mockqueue.MockEmitter)
+	emiter.On("Emit", mock.Anything).Return(errors.New("broker unavailable"))
+	server := *s.server
+	server.Emitter = emiter
+
+	requestBody, _ := json.Marshal(gin.H{"members": []gin.H{
+		{"userID": s.IDs["userMember"].String(), "role": "admin"},
+		{"userID": s.IDs["memberHighRank"].String(), "role": "basic"},
+		{"userID": s.IDs["userNotMember"].String(), "role": "deleter"},
+	}})
+	req, _ := http.NewRequest(http.MethodPost, "/api/group/"+s.IDs["groupOK"].String()+"/roles", bytes.NewReader(requestBody))
+
+	w := httptest.NewRecorder()
+	_, engine := gin.CreateTestContext(w)
+	engine.Use(func(c *gin.Context) {
+		c.Set("userID", s.IDs["userOK"].String())
+	})
+	engine.Handle(http.MethodPost, "/api/group/:groupID/roles", server.BulkChangeMemberRoles)
+	engine.ServeHTTP(w, req)
+
+	s.Equal(http.StatusOK, w.Code)
+	var results struct {
+		Results []roleChangeResult `json:"results"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&results); err != nil {
+		s.Fail(err.Error())
+	}
+	s.Equal(roleChangeResult{UserID: s.IDs["userMember"].String(), Role: "admin", Changed: true, Err: "broker unavailable"}, results.Results[0])
+	s.Len(results.Results, 3)
+}
+
+func (s *MembersTestSuite) TestGetRecentMembers() {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		desc               string
+		userID             string
+		query              string
+		expectedStatusCode int
+		expectedResponse   interface{}
+	}{
+		{
+			desc:               "GetRecentMembersBadWindow",
+			userID:             s.IDs["userOK"].String(),
+			query:              "?within=yesterday",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "invalid within duration"},
+		},
+		{
+			desc:               "GetRecentMembersNotMember",
+			userID:             s.IDs["userNotMember"].String(),
+			expectedStatusCode: http.StatusForbidden,
+			expectedResponse:   gin.H{"err": fmt.Sprintf("Forbidden action. Reason: User %v is not a member of group %v", s.IDs["userNotMember"], s.IDs["groupOK"])},
+		},
+		{
+			desc:               "GetRecentMembersWindow",
+			userID:             s.IDs["userOK"].String(),
+			query:              "?within=24h",
+			expectedStatusCode: http.StatusOK,
+			expectedResponse: []models.Member{
+				{ID: s.IDs["memberOK"], GroupID: s.IDs["groupOK"], UserID: s.IDs["userMember"], Joined: s.joined},
+				{ID: s.IDs["memberHighRank"], GroupID: s.IDs["groupOK"], UserID: s.IDs["userWithoutRights"], Joined: s.joined.Add(-20 * time.Hour)},
+			},
+		},
+		{
+			desc:               "GetRecentMembersNoWindow",
+			userID:             s.IDs["userOK"].String(),
+			expectedStatusCode: http.StatusOK,
+			expectedResponse: []models.Member{
+				{ID: s.IDs["memberOK"], GroupID: s.IDs["groupOK"], UserID: s.IDs["userMember"], Joined: s.joined},
+				{ID: s.IDs["memberHighRank"], GroupID: s.IDs["groupOK"], UserID: s.IDs["userWithoutRights"], Joined: s.joined.Add(-20 * time.Hour)},
+				{ID: s.IDs["memberNotFound"], GroupID: s.IDs["groupOK"], UserID: s.IDs["userOK"], Joined: s.joined.Add(-72 * time.Hour)},
+			},
+		},
+		{
+			desc:               "GetRecentMembersFields",
+			userID:             s.IDs["userOK"].String(),
+			query:              "?fields=ID,nickname,password",
+			expectedStatusCode: http.StatusOK,
+			expectedResponse: []gin.H{
+				{"ID": s.IDs["memberOK"].String(), "nickname": ""},
+				{"ID": s.IDs["memberHighRank"].String(), "nickname": ""},
+				{"ID": s.IDs["memberNotFound"].String(), "nickname": ""},
+			},
+		},
+		{
+			desc:               "GetRecentMembersSorted",
+			userID:             s.IDs["userOK"].String(),
+			query:              "?sort=activity_desc",
+			expectedStatusCode: http.StatusOK,
+			expectedResponse: []models.Member{
+				{ID: s.IDs["memberHighRank"], GroupID: s.IDs["groupOK"], UserID: s.IDs["userWithoutRights"], Joined: s.joined.Add(-20 * time.Hour)},
+			},
+		},
+		{
+			desc:               "GetRecentMembersBadSort",
+			userID:             s.IDs["userOK"].String(),
+			query:              "?sort=created_desc",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "invalid sort value: created_desc, allowed values: joined_desc, joined_asc, activity_desc, name_asc, name_desc"},
+		},
+		{
+			desc:               "GetRecentMembersCursorOfOtherSort",
+			userID:             s.IDs["userOK"].String(),
+			query:              "?cursor=" + encodeCursor(models.Cursor{Sort: models.SORT_ACTIVITY_DESC, ID: s.IDs["memberOK"]}),
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "cursor doesn't match sort"},
+		},
+		{
+			desc:               "GetRecentMembersPageEmpty",
+			userID:             s.IDs["userOK"].String(),
+			query:              "?limit=10&offset=50",
+			expectedStatusCode: http.StatusNoContent,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+
+			req, _ := http.NewRequest(http.MethodGet, "/api/group/"+s.IDs["groupOK"].String()+"/members/recent"+tC.query, nil)
+
+			w := httptest.NewRecorder()
+			_, engine := gin.CreateTestContext(w)
+
+			engine.Use(func(c *gin.Context) {
+				c.Set("userID", tC.userID)
+			})
+			engine.Handle(http.MethodGet, "/api/group/:groupID/members/recent", s.server.GetRecentMembers)
+			engine.ServeHTTP(w, req)
+			response := w.Result()
+			defer response.Body.Close()
+
+			s.Equal(tC.expectedStatusCode, response.StatusCode)
+
+			switch expected := tC.expectedResponse.(type) {
+			case []models.Member:
+				var members []models.Member
+				if err := json.NewDecoder(response.Body).Decode(&members); err != nil {
+					s.Fail(err.Error())
+				}
+				s.Equal(expected, members)
+			case []gin.H:
+				var members []gin.H
+				if err := json.NewDecoder(response.Body).Decode(&members); err != nil {
+					s.Fail(err.Error())
+				}
+				s.Equal(expected, members)
+			case gin.H:
+				var msg gin.H
+				if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+					s.Fail(err.Error())
+				}
+				s.Equal(expected, msg)
+			}
+		})
+	}
+}
+
+func (s *MembersTestSuite) TestGetRecentMembersCursor() {
+	gin.SetMode(gin.TestMode)
+
+	engine := gin.New()
+	engine.Use(func(c *gin.Context) {
+		c.Set("userID", s.IDs["userOK"].String())
+	})
+	engine.Handle(http.MethodGet, "/api/group/:groupID/members/recent", s.server.GetRecentMembers)
+
+	path := "/api/group/" + s.IDs["groupOK"].String() + "/members/recent?limit=1&sort=activity_desc"
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	s.Equal(http.StatusOK, w.Code)
+	cursor := w.Header().Get(handlers.NEXT_CURSOR_HEADER)
+	s.Equal(encodeCursor(models.Cursor{Sort: models.SORT_ACTIVITY_DESC, ID: s.IDs["memberHighRank"]}), cursor)
+
+	req, _ = http.NewRequest(http.MethodGet, path+"&cursor="+cursor, nil)
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	s.Equal(http.StatusNoContent, w.Code)
+}
+
+func (s *MembersTestSuite) TestGetMyPermissions() {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		desc               string
+		userID             string
+		groupID            string
+		expectedStatusCode int
+		expectedResponse   gin.H
+	}{
+		{
+			desc:               "GetMyPermissionsBadGroupID",
+			userID:             s.IDs["userOK"].String(),
+			groupID:            s.IDs["groupOK"].String()[:2],
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "invalid group ID"},
+		},
+		{
+			desc:               "GetMyPermissionsNotMember",
+			userID:             s.IDs["userNotMember"].String(),
+			groupID:            s.IDs["groupOK"].String(),
+			expectedStatusCode: http.StatusForbidden,
+			expectedResponse:   gin.H{"err": fmt.Sprintf("Forbidden action. Reason: User %v is not a member of group %v", s.IDs["userNotMember"], s.IDs["groupOK"])},
+		},
+		{
+			desc:               "GetMyPermissionsCreator",
+			userID:             s.IDs["userOK"].String(),
+			groupID:            s.IDs["groupOK"].String(),
+			expectedStatusCode: http.StatusOK,
+			expectedResponse: gin.H{"role": "creator", "permissions": map[string]interface{}{
+				"invite": true, "removeMembers": true, "editMembers": true, "editGroup": true,
+				"setAnnouncement": true, "deleteGroup": true, "exportGroup": true,
+			}},
+		},
+		{
+			desc:               "GetMyPermissionsBasic",
+			userID:             s.IDs["userMember"].String(),
+			groupID:            s.IDs["groupOK"].String(),
+			expectedStatusCode: http.StatusOK,
+			expectedResponse: gin.H{"role": "basic", "permissions": map[string]interface{}{
+				"invite": false, "removeMembers": false, "editMembers": false, "editGroup": false,
+				"setAnnouncement": false, "deleteGroup": false, "exportGroup": false,
+			}},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+
+			req, _ := http.NewRequest(http.MethodGet, "/api/group/"+tC.groupID+"/permissions", nil)
+
+			w := httptest.NewRecorder()
+			_, engine := gin.CreateTestContext(w)
+
+			engine.Use(func(c *gin.Context) {
+				c.Set("userID", tC.userID)
+			})
+			engine.Handle(http.MethodGet, "/api/group/:groupID/permissions", s.server.GetMyPermissions)
+			engine.ServeHTTP(w, req)
+			response := w.Result()
+			defer response.Body.Close()
 
-	emiter := new(mockqueue.MockEmitter)
-	emiter.On("Emit", mock.Anything).Return(nil)
+			s.Equal(tC.expectedStatusCode, response.StatusCode)
 
-	s.server = handlers.NewServer(db, nil, nil, emiter)
+			var msg gin.H
+			if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+				s.Fail(err.Error())
+			}
+
+			s.Equal(tC.expectedResponse, msg)
+		})
+	}
 }
 
-func (s *MembersTestSuite) TestGrantPriv() {
+func (s *MembersTestSuite) TestGetGroupWithMembership() {
 	gin.SetMode(gin.TestMode)
 
+	type membership struct {
+		ID          uuid.UUID       `json:"ID"`
+		Role        string          `json:"role"`
+		Permissions map[string]bool `json:"permissions"`
+		Nickname    string          `json:"nickname"`
+		Joined      time.Time       `json:"joined"`
+	}
+	type groupWithMembership struct {
+		Group      models.Group `json:"group"`
+		Membership membership   `json:"membership"`
+	}
+	type groupProjection struct {
+		Group gin.H `json:"group"`
+	}
+
 	testCases := []struct {
 		desc               string
 		userID             string
 		groupID            string
-		memberID           string
-		data               map[string]interface{}
+		query              string
 		expectedStatusCode int
 		expectedResponse   interface{}
 	}{
 		{
-			desc:               "UpdateRightsBadUserID",
-			userID:             s.IDs["userOK"].String()[:2],
+			desc:               "GetGroupWithMembershipBadGroupID",
+			userID:             s.IDs["userOK"].String(),
+			groupID:            s.IDs["groupOK"].String()[:2],
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "invalid group ID"},
+		},
+		{
+			desc:               "GetGroupWithMembershipNotMember",
+			userID:             s.IDs["userNotMember"].String(),
 			groupID:            s.IDs["groupOK"].String(),
-			memberID:           s.IDs["memberOK"].String(),
-			data:               map[string]interface{}{"adding": -1},
+			expectedStatusCode: http.StatusForbidden,
+			expectedResponse:   gin.H{"err": fmt.Sprintf("Forbidden action. Reason: User %v is not a member of group %v", s.IDs["userNotMember"], s.IDs["groupOK"])},
+		},
+		{
+			desc:               "GetGroupWithMembershipMember",
+			userID:             s.IDs["userOK"].String(),
+			groupID:            s.IDs["groupOK"].String(),
+			expectedStatusCode: http.StatusOK,
+			expectedResponse: groupWithMembership{
+				Group: models.Group{ID: s.IDs["groupOK"], Name: "Group", SlowModeSeconds: 30},
+				Membership: membership{
+					ID:   s.IDs["memberOK"],
+					Role: "admin",
+					Permissions: map[string]bool{
+						"invite": true, "removeMembers": true, "editMembers": true, "editGroup": true,
+						"setAnnouncement": false, "deleteGroup": false, "exportGroup": false,
+					},
+					Nickname: "boss",
+					Joined:   s.joined,
+				},
+			},
+		},
+		{
+			desc:               "GetGroupWithMembershipFields",
+			userID:             s.IDs["userOK"].String(),
+			groupID:            s.IDs["groupOK"].String(),
+			query:              "?fields=name,slowModeSeconds,Members",
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   groupProjection{Group: gin.H{"name": "Group", "slowModeSeconds": float64(30)}},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+
+			req, _ := http.NewRequest(http.MethodGet, "/api/group/"+tC.groupID+tC.query, nil)
+
+			w := httptest.NewRecorder()
+			_, engine := gin.CreateTestContext(w)
+
+			engine.Use(func(c *gin.Context) {
+				c.Set("userID", tC.userID)
+			})
+			engine.Handle(http.MethodGet, "/api/group/:groupID", s.server.GetGroupWithMembership)
+			engine.ServeHTTP(w, req)
+			response := w.Result()
+			defer response.Body.Close()
+
+			s.Equal(tC.expectedStatusCode, response.StatusCode)
+
+			switch tC.expectedResponse.(type) {
+			case groupWithMembership:
+				var respBody groupWithMembership
+				if err := json.NewDecoder(response.Body).Decode(&respBody); err != nil {
+					s.Fail(err.Error())
+				}
+				s.Equal(tC.expectedResponse, respBody)
+			case groupProjection:
+				var respBody groupProjection
+				if err := json.NewDecoder(response.Body).Decode(&respBody); err != nil {
+					s.Fail(err.Error())
+				}
+				s.Equal(tC.expectedResponse, respBody)
+			default:
+				var msg gin.H
+				if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+					s.Fail(err.Error())
+				}
+				s.Equal(tC.expectedResponse, msg)
+			}
+		})
+	}
+}
+
+func (s *MembersTestSuite) TestCheckMembership() {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		desc               string
+		groupID            string
+		userID             string
+		expectedStatusCode int
+		expectedResponse   gin.H
+	}{
+		{
+			desc:               "CheckMembershipBadGroupID",
+			groupID:            s.IDs["groupOK"].String()[:2],
+			userID:             s.IDs["userOK"].String(),
 			expectedStatusCode: http.StatusBadRequest,
-			expectedResponse:   gin.H{"err": "invalid ID"},
+			expectedResponse:   gin.H{"err": "invalid group ID"},
 		},
 		{
-			desc:               "UpdateRightsBadGroupID",
+			desc:               "CheckMembershipBadUserID",
+			groupID:            s.IDs["groupOK"].String(),
+			userID:             s.IDs["userOK"].String()[:2],
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "invalid user ID"},
+		},
+		{
+			desc:               "CheckMembershipCreator",
+			groupID:            s.IDs["groupOK"].String(),
+			userID:             s.IDs["userOK"].String(),
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   gin.H{"member": true, "role": "creator"},
+		},
+		{
+			desc:               "CheckMembershipBasic",
+			groupID:            s.IDs["groupOK"].String(),
+			userID:             s.IDs["userMember"].String(),
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   gin.H{"member": true, "role": "basic"},
+		},
+		{
+			desc:               "CheckMembershipNotMember",
+			groupID:            s.IDs["groupOK"].String(),
+			userID:             s.IDs["userNotMember"].String(),
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   gin.H{"member": false},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+
+			req, _ := http.NewRequest(http.MethodGet, "/internal/group/"+tC.groupID+"/membership/"+tC.userID, nil)
+
+			w := httptest.NewRecorder()
+			_, engine := gin.CreateTestContext(w)
+
+			engine.Handle(http.MethodGet, "/internal/group/:groupID/membership/:userID", s.server.CheckMembership)
+			engine.ServeHTTP(w, req)
+			response := w.Result()
+			defer response.Body.Close()
+
+			s.Equal(tC.expectedStatusCode, response.StatusCode)
+
+			var msg gin.H
+			if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+				s.Fail(err.Error())
+			}
+
+			s.Equal(tC.expectedResponse, msg)
+		})
+	}
+}
+
+func (s *MembersTestSuite) TestStepDown() {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		desc               string
+		userID             string
+		groupID            string
+		expectedStatusCode int
+		expectedResponse   interface{}
+	}{
+		{
+			desc:               "StepDownBadGroupID",
 			userID:             s.IDs["userOK"].String(),
 			groupID:            s.IDs["groupOK"].String()[:2],
-			memberID:           s.IDs["memberOK"].String(),
-			data:               map[string]interface{}{"adding": -1},
 			expectedStatusCode: http.StatusBadRequest,
 			expectedResponse:   gin.H{"err": "invalid group ID"},
 		},
 		{
-			desc:               "UpdateRightsBadMemberID",
+			desc:               "StepDownCreator",
 			userID:             s.IDs["userOK"].String(),
 			groupID:            s.IDs["groupOK"].String(),
-			memberID:           s.IDs["memberOK"].String()[:2],
-			data:               map[string]interface{}{"adding": -1},
+			expectedStatusCode: http.StatusConflict,
+			expectedResponse:   gin.H{"err": models.ErrLastOwner.Error(), "code": models.LAST_OWNER},
+		},
+		{
+			desc:               "StepDownBasicMember",
+			userID:             s.IDs["userWithoutRights"].String(),
+			groupID:            s.IDs["groupOK"].String(),
 			expectedStatusCode: http.StatusBadRequest,
-			expectedResponse:   gin.H{"err": "invalid member ID"},
+			expectedResponse:   gin.H{"err": "Bad request. Reason: member has no role to step down from"},
 		},
 		{
-			desc:               "UpdateRightsNoAction",
+			desc:               "StepDownAdmin",
+			userID:             s.IDs["userMember"].String(),
+			groupID:            s.IDs["groupOK"].String(),
+			expectedStatusCode: http.StatusOK,
+			expectedResponse: struct {
+				Member models.Member `json:"member"`
+			}{models.Member{ID: s.IDs["memberOK"], GroupID: s.IDs["groupOK"], UserID: s.IDs["userMember"], Adding: true}},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+
+			req, _ := http.NewRequest(http.MethodPost, "/group/"+tC.groupID+"/stepdown", nil)
+
+			w := httptest.NewRecorder()
+			_, engine := gin.CreateTestContext(w)
+			engine.Use(func(c *gin.Context) {
+				c.Set("userID", tC.userID)
+			})
+
+			engine.Handle(http.MethodPost, "/group/:groupID/stepdown", s.server.StepDown)
+			engine.ServeHTTP(w, req)
+			response := w.Result()
+			defer response.Body.Close()
+
+			s.Equal(tC.expectedStatusCode, response.StatusCode)
+
+			expected, _ := json.Marshal(tC.expectedResponse)
+			body, _ := io.ReadAll(response.Body)
+			s.JSONEq(string(expected), string(body))
+		})
+	}
+}
+
+func (s *MembersTestSuite) TestSetGroupNickname() {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		desc               string
+		userID             string
+		groupID            string
+		memberID           string
+		data               map[string]interface{}
+		expectedStatusCode int
+		expectedResponse   interface{}
+	}{
+		{
+			desc:               "SetNicknameBadMemberID",
 			userID:             s.IDs["userOK"].String(),
 			groupID:            s.IDs["groupOK"].String(),
-			memberID:           s.IDs["memberOK"].String(),
-			data:               map[string]interface{}{},
+			memberID:           s.IDs["memberOK"].String()[:2],
+			data:               map[string]interface{}{"nickname": "nick"},
 			expectedStatusCode: http.StatusBadRequest,
-			expectedResponse:   gin.H{"err": "no action specified"},
+			expectedResponse:   gin.H{"err": "invalid member ID"},
 		},
 		{
-			desc:               "UpdateRightsNoRights",
-			userID:             s.IDs["userWithoutRights"].String(),
+			desc:               "SetNicknameTooLong",
+			userID:             s.IDs["userOK"].String(),
 			groupID:            s.IDs["groupOK"].String(),
 			memberID:           s.IDs["memberOK"].String(),
-			data:               map[string]interface{}{"adding": -1},
-			expectedStatusCode: http.StatusForbidden,
-			expectedResponse:   gin.H{"err": fmt.Sprintf("Forbidden action. Reason: User %v has no right to alter members in group %v", s.IDs["userWithoutRights"].String(), s.IDs["groupOK"].String())},
+			data:               map[string]interface{}{"nickname": "nicknamenicknamenicknamenicknamenickname"},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			expectedResponse:   gin.H{"err": "nickname cannot be longer than 32 characters", "field": "nickname", "limit": float64(models.MAX_NICKNAME_LENGTH)},
 		},
 		{
-			desc:               "UpdateRightsNotFound",
+			desc:               "SetNicknameZeroWidth",
 			userID:             s.IDs["userOK"].String(),
 			groupID:            s.IDs["groupOK"].String(),
-			memberID:           s.IDs["memberNotFound"].String(),
-			data:               map[string]interface{}{"adding": -1},
-			expectedStatusCode: http.StatusNotFound,
-			expectedResponse:   gin.H{"err": fmt.Sprintf("resource: member with value: %v not found", s.IDs["memberNotFound"].String())},
+			memberID:           s.IDs["memberOK"].String(),
+			data:               map[string]interface{}{"nickname": "ni\u200bck"},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			expectedResponse:   gin.H{"err": "nickname contains control or invisible characters"},
 		},
 		{
-			desc:               "UpdateRightsHighRank",
+			desc:               "SetNicknameOtherMember",
 			userID:             s.IDs["userOK"].String(),
 			groupID:            s.IDs["groupOK"].String(),
 			memberID:           s.IDs["memberHighRank"].String(),
-			data:               map[string]interface{}{"adding": -1},
+			data:               map[string]interface{}{"nickname": "nick"},
 			expectedStatusCode: http.StatusForbidden,
-			expectedResponse:   gin.H{"err": fmt.Sprintf("Forbidden action. Reason: User %v cannot alter member %v", s.IDs["userOK"].String(), s.IDs["memberHighRank"].String())},
+			expectedResponse:   gin.H{"err": fmt.Sprintf("Forbidden action. Reason: User %v cannot change nickname of member %v", s.IDs["userOK"], s.IDs["memberHighRank"])},
 		},
 		{
-			desc:               "UpdateRightsSuccess",
+			desc:               "SetNicknameSuccess",
 			userID:             s.IDs["userOK"].String(),
 			groupID:            s.IDs["groupOK"].String(),
 			memberID:           s.IDs["memberOK"].String(),
-			data:               map[string]interface{}{"adding": -1},
+			data:               map[string]interface{}{"nickname": " nick "},
 			expectedStatusCode: http.StatusOK,
-			expectedResponse:   gin.H{"message": "member updated"},
+			expectedResponse:   gin.H{"message": "nickname updated"},
 		},
 	}
 
@@ -155,7 +1499,7 @@ func (s *MembersTestSuite) TestGrantPriv() {
 		s.Run(tC.desc, func() {
 
 			requestBody, _ := json.Marshal(tC.data)
-			req, _ := http.NewRequest(http.MethodPut, "/group/"+tC.groupID+"/member/"+tC.memberID, bytes.NewBuffer(requestBody))
+			req, _ := http.NewRequest(http.MethodPut, "/group/"+tC.groupID+"/member/"+tC.memberID+"/nickname", bytes.NewBuffer(requestBody))
 
 			w := httptest.NewRecorder()
 			_, engine := gin.CreateTestContext(w)
@@ -163,7 +1507,7 @@ func (s *MembersTestSuite) TestGrantPriv() {
 				c.Set("userID", tC.userID)
 			})
 
-			engine.Handle(http.MethodPut, "/group/:groupID/member/:memberID", s.server.GrantPriv)
+			engine.Handle(http.MethodPut, "/group/:groupID/member/:memberID/nickname", s.server.SetGroupNickname)
 			engine.ServeHTTP(w, req)
 			response := w.Result()
 			defer response.Body.Close()
@@ -239,6 +1583,14 @@ func (s *MembersTestSuite) TestDeleteMember() {
 			expectedStatusCode: http.StatusForbidden,
 			expectedResponse:   gin.H{"err": fmt.Sprintf("Forbidden action. Reason: User %v cannot delete member %v", s.IDs["userOK"].String(), s.IDs["memberHighRank"].String())},
 		},
+		{
+			desc:               "DeleteMemberLastOwner",
+			userID:             s.IDs["userOK"].String(),
+			groupID:            s.IDs["groupOK"].String(),
+			memberID:           s.IDs["memberCreator"].String(),
+			expectedStatusCode: http.StatusConflict,
+			expectedResponse:   gin.H{"err": models.ErrLastOwner.Error(), "code": models.LAST_OWNER},
+		},
 		{
 			desc:               "DeleteMemberSuccess",
 			userID:             s.IDs["userOK"].String(),
@@ -344,6 +1696,97 @@ func (s *MembersTestSuite) TestDeleteGroup() {
 	}
 }
 
+func (s *MembersTestSuite) TestStreamGroupMembers() {
+	gin.SetMode(gin.TestMode)
+
+	const total = 250
+	groupNotFound := uuid.MustParse("c2e4a6b8-0d2f-4a6c-8e0a-2c4e6a8c0e2a")
+
+	w := httptest.NewRecorder()
+	// lines already sent to the client each time database layer hands over the next member
+	var sentBefore []int
+
+	db := new(mockdb.MockGroupsDB)
+	db.On("StreamGroupMembers", s.IDs["groupOK"], mock.Anything).Run(func(args mock.Arguments) {
+		fn := args.Get(1).(func(models.Member) error)
+		for i := 0; i < total; i++ {
+			sentBefore = append(sentBefore, bytes.Count(w.Body.Bytes(), []byte("\n")))
+			username := fmt.Sprintf("user%d", i)
+			_ = fn(models.Member{ID: uuid.New(), GroupID: s.IDs["groupOK"], UserID: uuid.New(), User: models.User{UserName: username}, DisplayName: username})
+		}
+	}).Return(nil)
+	db.On("StreamGroupMembers", groupNotFound, mock.Anything).Return(apperrors.NewNotFound("group", groupNotFound.String()))
+
+	server := *s.server
+	server.DB = db
+
+	_, engine := gin.CreateTestContext(w)
+	engine.Handle(http.MethodGet, "/internal/group/:groupID/members/stream", server.StreamGroupMembers)
+
+	req, _ := http.NewRequest(http.MethodGet, "/internal/group/"+s.IDs["groupOK"].String()+"/members/stream", nil)
+	engine.ServeHTTP(w, req)
+
+	s.Equal(http.StatusOK, w.Code)
+	s.Equal("application/x-ndjson", w.Header().Get("Content-Type"))
+	s.True(w.Flushed)
+
+	decoder := json.NewDecoder(w.Body)
+	streamed := 0
+	for decoder.More() {
+		var member models.Member
+		if err := decoder.Decode(&member); err != nil {
+			s.Fail(err.Error())
+			break
+		}
+		s.Equal(s.IDs["groupOK"], member.GroupID)
+		// members are streamed with their users like paginated members are returned
+		s.Equal(fmt.Sprintf("user%d", streamed), member.User.UserName)
+		s.Equal(fmt.Sprintf("user%d", streamed), member.DisplayName)
+		streamed++
+	}
+	s.Equal(total, streamed)
+
+	// every member is written before the next one is read
+	for i, sent := range sentBefore {
+		s.Equal(i, sent)
+	}
+
+	for _, tC := range []struct {
+		desc               string
+		groupID            string
+		expectedStatusCode int
+		expectedResponse   gin.H
+	}{
+		{
+			desc:               "StreamMembersInvalidGroupID",
+			groupID:            "1",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "invalid group ID"},
+		},
+		{
+			desc:               "StreamMembersGroupNotFound",
+			groupID:            groupNotFound.String(),
+			expectedStatusCode: http.StatusNotFound,
+			expectedResponse:   gin.H{"err": fmt.Sprintf("resource: group with value: %v not found", groupNotFound)},
+		},
+	} {
+		s.Run(tC.desc, func() {
+			req, _ := http.NewRequest(http.MethodGet, "/internal/group/"+tC.groupID+"/members/stream", nil)
+
+			w := httptest.NewRecorder()
+			_, engine := gin.CreateTestContext(w)
+			engine.Handle(http.MethodGet, "/internal/group/:groupID/members/stream", server.StreamGroupMembers)
+			engine.ServeHTTP(w, req)
+
+			s.Equal(tC.expectedStatusCode, w.Code)
+
+			var msg gin.H
+			_ = json.NewDecoder(w.Body).Decode(&msg)
+			s.Equal(tC.expectedResponse, msg)
+		})
+	}
+}
+
 func TestMembers(t *testing.T) {
 	suite.Run(t, &MembersTestSuite{})
 }
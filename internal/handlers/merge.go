@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Slimo300/MicroservicesChatApp/backend/lib/apperrors"
+	"github.com/Slimo300/MicroservicesChatApp/backend/lib/events"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// MergeUserMemberships moves memberships of a duplicate user account to the account it was merged into
+func (s *Server) MergeUserMemberships(c *gin.Context) {
+	payload := struct {
+		FromUserID string `json:"fromUserID"`
+		ToUserID   string `json:"toUserID"`
+	}{}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+		return
+	}
+	fromUUID, err := uuid.Parse(payload.FromUserID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid fromUserID"})
+		return
+	}
+	toUUID, err := uuid.Parse(payload.ToUserID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "invalid toUserID"})
+		return
+	}
+
+	merge, err := s.requestDB(c).MergeUserMemberships(fromUUID, toUUID)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{"err": err.Error()})
+		return
+	}
+
+	for _, member := range merge.Deleted {
+		if err := s.emitSync(events.MemberDeletedEvent{ID: member.ID, GroupID: member.GroupID, UserID: member.UserID}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"err": err.Error()})
+			return
+		}
+	}
+	// memberships changed owner, other services have to know it before merge is reported as done
+	for _, member := range merge.Updated {
+		if err := s.emitSync(memberUpdatedEvent(member)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"err": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"updated": len(merge.Updated), "deleted": len(merge.Deleted)})
+}
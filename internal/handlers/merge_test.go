@@ -0,0 +1,134 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Slimo300/MicroservicesChatApp/backend/lib/apperrors"
+	"github.com/Slimo300/MicroservicesChatApp/backend/lib/events"
+	mockqueue "github.com/Slimo300/MicroservicesChatApp/backend/lib/msgqueue/mock"
+	mockdb "github.com/Slimo300/chat-groupservice/internal/database/mock"
+	"github.com/Slimo300/chat-groupservice/internal/handlers"
+	"github.com/Slimo300/chat-groupservice/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type MergeTestSuite struct {
+	suite.Suite
+	IDs    map[string]uuid.UUID
+	emiter *mockqueue.MockEmitter
+	server *handlers.Server
+}
+
+func (s *MergeTestSuite) SetupSuite() {
+	s.IDs = make(map[string]uuid.UUID)
+	s.IDs["userFrom"] = uuid.MustParse("5b1f7c0e-3f8d-4a6e-9a3b-2c47e0f1d9a8")
+	s.IDs["userTo"] = uuid.MustParse("e2d4a6b8-1c3e-4f5a-8b7c-9d0e1f2a3b4c")
+	s.IDs["userLonely"] = uuid.MustParse("0a9b8c7d-6e5f-4a3b-8c1d-0e9f8a7b6c5d")
+	s.IDs["userNotFound"] = uuid.MustParse("9c8b7a6d-5e4f-4d3c-8b2a-1f0e9d8c7b6a")
+	s.IDs["group1"] = uuid.MustParse("3e5f7a9b-1d3f-4b5d-9f1b-3d5f7a9b1d3f")
+	s.IDs["group2"] = uuid.MustParse("7a9b1d3f-5b7d-4f1b-8d5f-7a9b1d3f5b7d")
+	s.IDs["memberMoved"] = uuid.MustParse("1f3b5d7f-9b1d-4d5f-a9b1-d3f5b7d9f1b3")
+	s.IDs["memberKept"] = uuid.MustParse("4d6f8b0d-2f4b-4b8d-8f2b-4d6f8b0d2f4b")
+	s.IDs["memberDuplicate"] = uuid.MustParse("8b0d2f4b-6d8f-4f2b-9d6f-8b0d2f4b6d8f")
+
+	db := new(mockdb.MockGroupsDB)
+	db.On("MergeUserMemberships", s.IDs["userFrom"], s.IDs["userTo"]).Return(models.MembershipMerge{
+		Updated: []models.Member{
+			{ID: s.IDs["memberMoved"], GroupID: s.IDs["group1"], UserID: s.IDs["userTo"]},
+			{ID: s.IDs["memberKept"], GroupID: s.IDs["group2"], UserID: s.IDs["userTo"], Admin: true},
+		},
+		Deleted: []models.Member{{ID: s.IDs["memberDuplicate"], GroupID: s.IDs["group2"], UserID: s.IDs["userFrom"]}},
+	}, nil)
+	db.On("MergeUserMemberships", s.IDs["userLonely"], s.IDs["userTo"]).Return(models.MembershipMerge{
+		Updated: []models.Member{{ID: s.IDs["memberMoved"], GroupID: s.IDs["group1"], UserID: s.IDs["userTo"]}},
+	}, nil)
+	db.On("MergeUserMemberships", s.IDs["userFrom"], s.IDs["userNotFound"]).
+		Return(models.MembershipMerge{}, apperrors.NewNotFound("user", s.IDs["userNotFound"].String()))
+
+	s.emiter = new(mockqueue.MockEmitter)
+	s.emiter.On("Emit", mock.Anything).Return(nil)
+
+	s.server = handlers.NewServer(db, nil, nil, s.emiter)
+}
+
+func (s *MergeTestSuite) TestMergeUserMemberships() {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		desc               string
+		data               map[string]interface{}
+		expectedStatusCode int
+		expectedResponse   gin.H
+	}{
+		{
+			desc:               "MergeInvalidFromUserID",
+			data:               map[string]interface{}{"fromUserID": "1", "toUserID": s.IDs["userTo"]},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "invalid fromUserID"},
+		},
+		{
+			desc:               "MergeInvalidToUserID",
+			data:               map[string]interface{}{"fromUserID": s.IDs["userFrom"], "toUserID": "1"},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "invalid toUserID"},
+		},
+		{
+			desc:               "MergeTargetNotFound",
+			data:               map[string]interface{}{"fromUserID": s.IDs["userFrom"], "toUserID": s.IDs["userNotFound"]},
+			expectedStatusCode: http.StatusNotFound,
+			expectedResponse:   gin.H{"err": "resource: user with value: " + s.IDs["userNotFound"].String() + " not found"},
+		},
+		{
+			desc:               "MergeNonOverlapping",
+			data:               map[string]interface{}{"fromUserID": s.IDs["userLonely"], "toUserID": s.IDs["userTo"]},
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   gin.H{"updated": float64(1), "deleted": float64(0)},
+		},
+		{
+			desc:               "MergeOverlapping",
+			data:               map[string]interface{}{"fromUserID": s.IDs["userFrom"], "toUserID": s.IDs["userTo"]},
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   gin.H{"updated": float64(2), "deleted": float64(1)},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+
+			requestBody, _ := json.Marshal(tC.data)
+			req, _ := http.NewRequest(http.MethodPost, "/internal/users/merge", bytes.NewBuffer(requestBody))
+
+			w := httptest.NewRecorder()
+			_, engine := gin.CreateTestContext(w)
+
+			engine.Handle(http.MethodPost, "/internal/users/merge", s.server.MergeUserMemberships)
+			engine.ServeHTTP(w, req)
+			response := w.Result()
+			defer response.Body.Close()
+
+			s.Equal(tC.expectedStatusCode, response.StatusCode)
+
+			var msg gin.H
+			if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+				s.Fail(err.Error())
+			}
+
+			s.Equal(tC.expectedResponse, msg)
+		})
+	}
+
+	s.emiter.AssertCalled(s.T(), "Emit", events.MemberDeletedEvent{ID: s.IDs["memberDuplicate"], GroupID: s.IDs["group2"], UserID: s.IDs["userFrom"]})
+	s.emiter.AssertCalled(s.T(), "Emit", events.MemberUpdatedEvent{ID: s.IDs["memberKept"], GroupID: s.IDs["group2"], UserID: s.IDs["userTo"], Admin: true})
+	s.emiter.AssertNumberOfCalls(s.T(), "Emit", 4)
+}
+
+func TestMerge(t *testing.T) {
+	suite.Run(t, new(MergeTestSuite))
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+const (
+	DEFAULT_PAGE_LIMIT = 50
+	MAX_PAGE_LIMIT     = 200
+)
+
+var (
+	errInvalidLimit  = errors.New("limit is not a valid number")
+	errInvalidOffset = errors.New("offset is not a valid number")
+)
+
+// pagination holds limit and offset of a paginated request
+type pagination struct {
+	Limit  int
+	Offset int
+}
+
+// parsePagination reads limit and offset query parameters. Limit defaults to DEFAULT_PAGE_LIMIT and is capped
+// at MAX_PAGE_LIMIT, offset defaults to 0. For compatibility "num" is accepted as an alias of "limit"
+func parsePagination(r *http.Request) (pagination, error) {
+	query := r.URL.Query()
+	p := pagination{Limit: DEFAULT_PAGE_LIMIT}
+
+	limit := query.Get("limit")
+	if limit == "" {
+		limit = query.Get("num")
+	}
+	if limit != "" {
+		num, err := strconv.Atoi(limit)
+		if err != nil || num <= 0 {
+			return pagination{}, errInvalidLimit
+		}
+		p.Limit = num
+	}
+	if p.Limit > MAX_PAGE_LIMIT {
+		p.Limit = MAX_PAGE_LIMIT
+	}
+
+	if offset := query.Get("offset"); offset != "" {
+		num, err := strconv.Atoi(offset)
+		if err != nil || num < 0 {
+			return pagination{}, errInvalidOffset
+		}
+		p.Offset = num
+	}
+
+	return p, nil
+}
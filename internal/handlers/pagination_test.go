@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type PaginationTestSuite struct {
+	suite.Suite
+}
+
+func (s *PaginationTestSuite) TestParsePagination() {
+	testCases := []struct {
+		desc          string
+		query         string
+		expectedPage  pagination
+		expectedError error
+	}{
+		{
+			desc:         "PaginationDefaults",
+			query:        "",
+			expectedPage: pagination{Limit: DEFAULT_PAGE_LIMIT, Offset: 0},
+		},
+		{
+			desc:         "PaginationValid",
+			query:        "?limit=20&offset=40",
+			expectedPage: pagination{Limit: 20, Offset: 40},
+		},
+		{
+			desc:         "PaginationNumAlias",
+			query:        "?num=10&offset=5",
+			expectedPage: pagination{Limit: 10, Offset: 5},
+		},
+		{
+			desc:         "PaginationLimitPreferredOverNum",
+			query:        "?limit=15&num=10",
+			expectedPage: pagination{Limit: 15, Offset: 0},
+		},
+		{
+			desc:         "PaginationOverCap",
+			query:        "?limit=1000",
+			expectedPage: pagination{Limit: MAX_PAGE_LIMIT, Offset: 0},
+		},
+		{
+			desc:          "PaginationMalformedLimit",
+			query:         "?limit=abc",
+			expectedError: errInvalidLimit,
+		},
+		{
+			desc:          "PaginationZeroLimit",
+			query:         "?limit=0",
+			expectedError: errInvalidLimit,
+		},
+		{
+			desc:          "PaginationNegativeLimit",
+			query:         "?limit=-5",
+			expectedError: errInvalidLimit,
+		},
+		{
+			desc:         "PaginationZeroOffset",
+			query:        "?offset=0",
+			expectedPage: pagination{Limit: DEFAULT_PAGE_LIMIT, Offset: 0},
+		},
+		{
+			desc:          "PaginationMalformedOffset",
+			query:         "?offset=1.5",
+			expectedError: errInvalidOffset,
+		},
+		{
+			desc:          "PaginationNegativeOffset",
+			query:         "?offset=-1",
+			expectedError: errInvalidOffset,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+			page, err := parsePagination(httptest.NewRequest("GET", "/invites"+tC.query, nil))
+
+			s.Equal(tC.expectedError, err)
+			if tC.expectedError == nil {
+				s.Equal(tC.expectedPage, page)
+			}
+		})
+	}
+}
+
+func TestPaginationSuite(t *testing.T) {
+	suite.Run(t, &PaginationTestSuite{})
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const REMOTE_IMAGE_TIMEOUT = 10 * time.Second
+const MAX_REMOTE_IMAGE_REDIRECTS = 3
+
+var errDisallowedAddress = errors.New("image URL points to a disallowed address")
+var errDisallowedScheme = errors.New("image URL must use http or https")
+var errRemoteImage = errors.New("couldn't fetch image")
+var errRemoteImageTooLarge = errors.New("remote image exceeds")
+
+// networks that aren't reachable from the internet and aren't covered by net.IP methods
+var blockedNetworks = mustParseCIDRs("0.0.0.0/8", "100.64.0.0/10", "198.18.0.0/15", "240.0.0.0/4")
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// isDisallowedIP tells whether address belongs to loopback, private, link local or other internal range
+// remote images can't be fetched from
+func isDisallowedIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return true
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkImageURL accepts only absolute http and https URLs
+func checkImageURL(u *url.URL) error {
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errDisallowedScheme
+	}
+	return nil
+}
+
+// NewRemoteImageClient returns client fetching remote images which refuses to connect to internal addresses.
+// Address is checked right before connecting, after name resolution, so it also covers redirects and names
+// resolving to internal addresses. Proxies from environment aren't used as they would hide the real address
+func NewRemoteImageClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isDisallowedIP(ip) {
+				return errDisallowedAddress
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   timeout,
+			ResponseHeaderTimeout: timeout,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= MAX_REMOTE_IMAGE_REDIRECTS {
+				return fmt.Errorf("%w: too many redirects", errRemoteImage)
+			}
+			return checkImageURL(req.URL)
+		},
+	}
+}
+
+// fetchRemoteImage downloads image of at most maxBytes and returns it together with its sniffed content type
+func (s *Server) fetchRemoteImage(ctx context.Context, rawURL string, maxBytes int64) (*memoryFile, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, "", errDisallowedScheme
+	}
+	if err := checkImageURL(u); err != nil {
+		return nil, "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, "", err
+	}
+	resp, err := s.RemoteImages.Do(req)
+	if err != nil {
+		// keeping only the reason, error returned by client reveals what the address resolved to
+		for _, reason := range []error{errDisallowedAddress, errDisallowedScheme, errRemoteImage} {
+			if errors.Is(err, reason) {
+				return nil, "", reason
+			}
+		}
+		return nil, "", errRemoteImage
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("%w: remote server responded with %d", errRemoteImage, resp.StatusCode)
+	}
+	if resp.ContentLength > maxBytes {
+		return nil, "", fmt.Errorf("%w %d bytes", errRemoteImageTooLarge, maxBytes)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", errRemoteImage, err)
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, "", fmt.Errorf("%w %d bytes", errRemoteImageTooLarge, maxBytes)
+	}
+
+	return &memoryFile{Reader: bytes.NewReader(data)}, http.DetectContentType(data), nil
+}
+
+// remoteImageStatus maps error of fetching remote image to response status
+func remoteImageStatus(err error) int {
+	switch {
+	case errors.Is(err, errDisallowedAddress), errors.Is(err, errDisallowedScheme):
+		return http.StatusBadRequest
+	case errors.Is(err, errRemoteImageTooLarge):
+		return http.StatusRequestEntityTooLarge
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+// SetGroupPictureFromURL sets group's picture to an image hosted elsewhere. Image goes through the same
+// validation, moderation and optional crop (given in query) as uploaded ones
+func (s *Server) SetGroupPictureFromURL(c *gin.Context) {
+	userID := c.GetString("userID")
+	userUID, err := uuid.Parse(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "Invalid ID"})
+		return
+	}
+	groupID := c.Param("groupID")
+	groupUID, err := uuid.Parse(groupID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "Invalid group ID"})
+		return
+	}
+
+	payload := struct {
+		URL string `json:"url"`
+	}{}
+	if err := c.ShouldBindJSON(&payload); err != nil || payload.URL == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "image URL not specified"})
+		return
+	}
+	crop, err := parseCropRect(c.Query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+		return
+	}
+
+	if !s.canSetPicture(c, userUID, groupUID) {
+		return
+	}
+
+	file, mimeType, err := s.fetchRemoteImage(c.Request.Context(), payload.URL, s.MaxBodyBytes)
+	if err != nil {
+		c.JSON(remoteImageStatus(err), gin.H{"err": err.Error()})
+		return
+	}
+	if !isAllowedImageType(mimeType) {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "image extention not allowed"})
+		return
+	}
+
+	s.setGroupPicture(c, file, file.Size(), mimeType, crop, userUID, groupUID)
+}
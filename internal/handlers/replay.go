@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Slimo300/MicroservicesChatApp/backend/lib/apperrors"
+	"github.com/Slimo300/chat-groupservice/internal/eventlistener"
+	"github.com/gin-gonic/gin"
+)
+
+// PauseConsumer stops consuming a topic until ResumeConsumer is called, HTTP requests are still served.
+// It's meant for incidents when events of a topic can't be trusted
+func (s *Server) PauseConsumer(c *gin.Context) {
+	s.setConsumerPaused(c, true)
+}
+
+// ResumeConsumer continues consuming a topic paused with PauseConsumer
+func (s *Server) ResumeConsumer(c *gin.Context) {
+	s.setConsumerPaused(c, false)
+}
+
+func (s *Server) setConsumerPaused(c *gin.Context, paused bool) {
+	if s.Pauser == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"err": "pausing consumer is not available"})
+		return
+	}
+
+	payload := struct {
+		Topic string `json:"topic"`
+	}{}
+	if err := c.ShouldBindJSON(&payload); err != nil || payload.Topic == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "topic not specified"})
+		return
+	}
+
+	change := s.Pauser.Resume
+	if paused {
+		change = s.Pauser.Pause
+	}
+	if err := change(payload.Topic); err != nil {
+		c.JSON(apperrors.Status(err), gin.H{"err": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"pausedTopics": s.Pauser.PausedTopics()})
+}
+
+// ReplayEvents makes listener process already consumed events once again
+func (s *Server) ReplayEvents(c *gin.Context) {
+	if s.Replayer == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"err": "event replay is not available"})
+		return
+	}
+
+	payload := struct {
+		Topic     string    `json:"topic"`
+		EventName string    `json:"eventName"`
+		Since     time.Time `json:"since"`
+		Offset    *int64    `json:"offset"`
+		Confirm   bool      `json:"confirm"`
+	}{}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+		return
+	}
+	if payload.Topic == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "topic not specified"})
+		return
+	}
+	if !payload.Since.IsZero() && payload.Offset != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "since and offset cannot be used together"})
+		return
+	}
+	if payload.Offset != nil && *payload.Offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "offset cannot be negative"})
+		return
+	}
+	// replaying whole topic is expensive so it has to be requested explicitly, listener also refuses
+	// offsets that aren't retained anymore
+	wholeTopic := payload.Since.IsZero() && (payload.Offset == nil || *payload.Offset == 0)
+	if wholeTopic && !payload.Confirm {
+		c.JSON(http.StatusBadRequest, gin.H{"err": "replaying whole topic requires confirm flag"})
+		return
+	}
+
+	messages, err := s.Replayer.Replay(eventlistener.ReplayRequest{
+		Topic:     payload.Topic,
+		EventName: payload.EventName,
+		Since:     payload.Since,
+		Offset:    payload.Offset,
+		Confirmed: payload.Confirm,
+	})
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{"err": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"messages": messages})
+}
@@ -0,0 +1,230 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Slimo300/MicroservicesChatApp/backend/lib/apperrors"
+	"github.com/Slimo300/chat-groupservice/internal/eventlistener"
+	"github.com/Slimo300/chat-groupservice/internal/handlers"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type ReplayTestSuite struct {
+	suite.Suite
+	since  time.Time
+	server *handlers.Server
+}
+
+func (s *ReplayTestSuite) SetupSuite() {
+	s.since = time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	offset := int64(10)
+
+	replayer := new(eventlistener.MockReplayer)
+	replayer.On("Replay", eventlistener.ReplayRequest{Topic: "users", EventName: "users.picturemodified", Since: s.since}).Return(int64(7), nil)
+	replayer.On("Replay", eventlistener.ReplayRequest{Topic: "users", Offset: &offset}).Return(int64(3), nil)
+	replayer.On("Replay", eventlistener.ReplayRequest{Topic: "users", Confirmed: true}).Return(int64(20), nil)
+	replayer.On("Replay", eventlistener.ReplayRequest{Topic: "groups", Since: s.since}).Return(int64(0), apperrors.NewBadRequest("topic groups is not consumed by this service"))
+
+	pauser := new(eventlistener.MockPauser)
+	pauser.On("Pause", "users").Return(nil)
+	pauser.On("Pause", "groups").Return(apperrors.NewBadRequest("topic groups is not consumed by this service"))
+	pauser.On("Resume", "users").Return(nil)
+	pauser.On("PausedTopics").Return([]string{"users"}).Once()
+	pauser.On("PausedTopics").Return([]string{})
+
+	s.server = handlers.NewServer(nil, nil, nil, nil)
+	s.server.Replayer = replayer
+	s.server.Pauser = pauser
+	s.server.InternalAPIKey = "secret"
+}
+
+func (s *ReplayTestSuite) TestReplayEvents() {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		desc               string
+		key                string
+		data               map[string]interface{}
+		expectedStatusCode int
+		expectedResponse   gin.H
+	}{
+		{
+			desc:               "ReplayInvalidKey",
+			key:                "wrong",
+			data:               map[string]interface{}{"topic": "users", "confirm": true},
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedResponse:   gin.H{"err": "invalid internal key"},
+		},
+		{
+			desc:               "ReplayNoTopic",
+			key:                "secret",
+			data:               map[string]interface{}{"since": s.since},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "topic not specified"},
+		},
+		{
+			desc:               "ReplaySinceAndOffset",
+			key:                "secret",
+			data:               map[string]interface{}{"topic": "users", "since": s.since, "offset": 10},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "since and offset cannot be used together"},
+		},
+		{
+			desc:               "ReplayNegativeOffset",
+			key:                "secret",
+			data:               map[string]interface{}{"topic": "users", "offset": -1},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "offset cannot be negative"},
+		},
+		{
+			desc:               "ReplayWholeTopicNotConfirmed",
+			key:                "secret",
+			data:               map[string]interface{}{"topic": "users"},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "replaying whole topic requires confirm flag"},
+		},
+		{
+			desc:               "ReplayOffsetZeroNotConfirmed",
+			key:                "secret",
+			data:               map[string]interface{}{"topic": "users", "offset": 0},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "replaying whole topic requires confirm flag"},
+		},
+		{
+			desc:               "ReplayUnknownTopic",
+			key:                "secret",
+			data:               map[string]interface{}{"topic": "groups", "since": s.since},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "Bad request. Reason: topic groups is not consumed by this service"},
+		},
+		{
+			desc:               "ReplaySince",
+			key:                "secret",
+			data:               map[string]interface{}{"topic": "users", "eventName": "users.picturemodified", "since": s.since},
+			expectedStatusCode: http.StatusAccepted,
+			expectedResponse:   gin.H{"messages": float64(7)},
+		},
+		{
+			desc:               "ReplayOffset",
+			key:                "secret",
+			data:               map[string]interface{}{"topic": "users", "offset": 10},
+			expectedStatusCode: http.StatusAccepted,
+			expectedResponse:   gin.H{"messages": float64(3)},
+		},
+		{
+			desc:               "ReplayWholeTopic",
+			key:                "secret",
+			data:               map[string]interface{}{"topic": "users", "confirm": true},
+			expectedStatusCode: http.StatusAccepted,
+			expectedResponse:   gin.H{"messages": float64(20)},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+
+			requestBody, _ := json.Marshal(tC.data)
+
+			req, _ := http.NewRequest("POST", "/internal/events/replay", bytes.NewBuffer(requestBody))
+			req.Header.Set("X-Internal-Key", tC.key)
+
+			w := httptest.NewRecorder()
+			_, engine := gin.CreateTestContext(w)
+
+			engine.Use(s.server.MustInternalKey())
+			engine.Handle(http.MethodPost, "/internal/events/replay", s.server.ReplayEvents)
+			engine.ServeHTTP(w, req)
+			response := w.Result()
+			defer response.Body.Close()
+
+			s.Equal(tC.expectedStatusCode, response.StatusCode)
+
+			var msg gin.H
+			if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+				s.Fail(err.Error())
+			}
+
+			s.Equal(tC.expectedResponse, msg)
+		})
+	}
+}
+
+func (s *ReplayTestSuite) TestPauseConsumer() {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		desc               string
+		path               string
+		data               map[string]interface{}
+		expectedStatusCode int
+		expectedResponse   gin.H
+	}{
+		{
+			desc:               "PauseNoTopic",
+			path:               "/internal/events/pause",
+			data:               map[string]interface{}{},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "topic not specified"},
+		},
+		{
+			desc:               "PauseUnknownTopic",
+			path:               "/internal/events/pause",
+			data:               map[string]interface{}{"topic": "groups"},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   gin.H{"err": "Bad request. Reason: topic groups is not consumed by this service"},
+		},
+		{
+			desc:               "PauseSuccess",
+			path:               "/internal/events/pause",
+			data:               map[string]interface{}{"topic": "users"},
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   gin.H{"pausedTopics": []interface{}{"users"}},
+		},
+		{
+			desc:               "ResumeSuccess",
+			path:               "/internal/events/resume",
+			data:               map[string]interface{}{"topic": "users"},
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   gin.H{"pausedTopics": []interface{}{}},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+
+			requestBody, _ := json.Marshal(tC.data)
+
+			req, _ := http.NewRequest("POST", tC.path, bytes.NewBuffer(requestBody))
+			req.Header.Set("X-Internal-Key", "secret")
+
+			w := httptest.NewRecorder()
+			_, engine := gin.CreateTestContext(w)
+
+			engine.Use(s.server.MustInternalKey())
+			engine.Handle(http.MethodPost, "/internal/events/pause", s.server.PauseConsumer)
+			engine.Handle(http.MethodPost, "/internal/events/resume", s.server.ResumeConsumer)
+			engine.ServeHTTP(w, req)
+			response := w.Result()
+			defer response.Body.Close()
+
+			s.Equal(tC.expectedStatusCode, response.StatusCode)
+
+			var msg gin.H
+			if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+				s.Fail(err.Error())
+			}
+
+			s.Equal(tC.expectedResponse, msg)
+		})
+	}
+}
+
+func TestReplaySuite(t *testing.T) {
+	suite.Run(t, &ReplayTestSuite{})
+}
@@ -0,0 +1,25 @@
+package handlers
+
+import "context"
+
+// semaphore limits how many goroutines can run a section of code at once
+type semaphore chan struct{}
+
+func newSemaphore(limit int) semaphore {
+	return make(semaphore, limit)
+}
+
+// acquire waits for a free slot, it gives up when ctx is done before one is freed
+func (s semaphore) acquire(ctx context.Context) error {
+	select {
+	case s <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release frees slot taken by acquire
+func (s semaphore) release() {
+	<-s
+}
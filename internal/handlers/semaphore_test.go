@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SemaphoreTestSuite struct {
+	suite.Suite
+}
+
+func (s *SemaphoreTestSuite) TestLimitsConcurrency() {
+	const limit = 3
+	sem := newSemaphore(limit)
+
+	var running, maxRunning int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := sem.acquire(context.Background()); err != nil {
+				s.Fail(err.Error())
+				return
+			}
+			defer sem.release()
+
+			current := atomic.AddInt32(&running, 1)
+			for {
+				max := atomic.LoadInt32(&maxRunning)
+				if current <= max || atomic.CompareAndSwapInt32(&maxRunning, max, current) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+		}()
+	}
+	wg.Wait()
+
+	s.Equal(int32(limit), maxRunning)
+}
+
+func (s *SemaphoreTestSuite) TestAcquireTimeout() {
+	sem := newSemaphore(1)
+	s.NoError(sem.acquire(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	s.ErrorIs(sem.acquire(ctx), context.DeadlineExceeded)
+
+	sem.release()
+	s.NoError(sem.acquire(context.Background()))
+}
+
+func TestSemaphore(t *testing.T) {
+	suite.Run(t, new(SemaphoreTestSuite))
+}
@@ -1,34 +1,139 @@
 package handlers
 
 import (
+	"context"
+	"crypto/rsa"
+	"crypto/subtle"
+	"net"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/Slimo300/MicroservicesChatApp/backend/lib/msgqueue"
 
 	"github.com/Slimo300/chat-groupservice/internal/database"
+	"github.com/Slimo300/chat-groupservice/internal/eventlistener"
+	"github.com/Slimo300/chat-groupservice/internal/models"
+	"github.com/Slimo300/chat-groupservice/internal/moderation"
 	"github.com/Slimo300/chat-groupservice/internal/storage"
 	tokens "github.com/Slimo300/chat-tokenservice/pkg/client"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 const MAX_BODY_BYTES = 4194304
+const MAX_IMAGE_DIMENSION = 4096
+const STATS_CACHE_TTL = time.Minute
+const PLATFORM_METRICS_CACHE_TTL = 5 * time.Minute
+const REQUEST_TIMEOUT = 30 * time.Second
+const AVATAR_MAX_AGE = 24 * time.Hour
+const MAX_CONCURRENT_IMAGE_OPS = 4
+
+// PublicKeyProvider gives the key verifying signatures of access tokens
+type PublicKeyProvider interface {
+	GetPublicKey() *rsa.PublicKey
+}
 
 type Server struct {
-	DB           database.DBLayer
-	Storage      storage.StorageLayer
-	TokenClient  tokens.TokenClient
-	MaxBodyBytes int64
-	Emitter      msgqueue.EventEmiter
+	DB                database.DBLayer
+	Storage           storage.StorageLayer
+	TokenClient       tokens.TokenClient
+	MaxBodyBytes      int64
+	MaxImageDimension int
+	RequestTimeout    time.Duration
+	AvatarMaxAge      time.Duration
+	Emitter           msgqueue.EventEmiter
+	Replayer          eventlistener.Replayer
+	Pauser            eventlistener.Pauser
+	InternalAPIKey    string
+	// TokenKeys verifies service tokens on internal routes, they're refused when it's nil
+	TokenKeys PublicKeyProvider
+	// ReadOnly makes service reject all requests changing state
+	ReadOnly bool
+	// IPRateLimit is number of requests per second a single client IP can make on average with bursts of
+	// up to IPRateBurst requests, clients aren't rate limited when it's 0
+	IPRateLimit float64
+	IPRateBurst int
+	// RateLimitExempt are networks (e.g. of other services) whose requests aren't rate limited
+	RateLimitExempt []*net.IPNet
+	// TrustedProxies are networks of proxies whose X-Forwarded-For header identifies clients, no proxy
+	// is trusted when it's nil
+	TrustedProxies []*net.IPNet
+	// KeyPrefix is the namespace in storage under which group pictures are stored
+	KeyPrefix string
+	// Moderator screens uploaded pictures, they aren't screened when it's nil
+	Moderator moderation.Moderator
+	// ModerationFailOpen accepts pictures that couldn't be screened instead of rejecting them
+	ModerationFailOpen bool
+	// RemoteImages fetches pictures set from URL, it must refuse connecting to internal addresses
+	RemoteImages *http.Client
+
+	// defaultSorts overrides default sorts of lists, keyed by list name
+	defaultSorts map[string]models.Sort
+
+	statsCache *ttlCache[uuid.UUID, models.GroupStats]
+	// platformMetrics is keyed by whether deleted groups were counted
+	platformMetrics *ttlCache[bool, models.PlatformGroupMetrics]
+	// imageOps bounds number of images decoded at once, each one can take a lot of memory
+	imageOps semaphore
+	// pendingEmits tracks events still being emitted in background
+	pendingEmits *sync.WaitGroup
 }
 
 func NewServer(db database.DBLayer, storage storage.StorageLayer, tokenClient tokens.TokenClient, emiter msgqueue.EventEmiter) *Server {
 	return &Server{
-		DB:           db,
-		Storage:      storage,
-		MaxBodyBytes: MAX_BODY_BYTES,
-		TokenClient:  tokenClient,
-		Emitter:      emiter,
+		DB:                db,
+		Storage:           storage,
+		MaxBodyBytes:      MAX_BODY_BYTES,
+		MaxImageDimension: MAX_IMAGE_DIMENSION,
+		RequestTimeout:    REQUEST_TIMEOUT,
+		AvatarMaxAge:      AVATAR_MAX_AGE,
+		TokenClient:       tokenClient,
+		Emitter:           emiter,
+		statsCache:        newTTLCache[uuid.UUID, models.GroupStats](STATS_CACHE_TTL),
+		platformMetrics:   newTTLCache[bool, models.PlatformGroupMetrics](PLATFORM_METRICS_CACHE_TTL),
+		imageOps:          newSemaphore(MAX_CONCURRENT_IMAGE_OPS),
+		RemoteImages:      NewRemoteImageClient(REMOTE_IMAGE_TIMEOUT),
+		pendingEmits:      new(sync.WaitGroup),
+	}
+}
+
+// SetMaxConcurrentImageOps changes how many uploaded images can be processed at once, it should be called
+// before server starts handling requests
+func (s *Server) SetMaxConcurrentImageOps(limit int) {
+	s.imageOps = newSemaphore(limit)
+}
+
+// SetPlatformMetricsTTL changes how long platform group metrics are cached, it should be called before
+// server starts handling requests
+func (s *Server) SetPlatformMetricsTTL(ttl time.Duration) {
+	s.platformMetrics = newTTLCache[bool, models.PlatformGroupMetrics](ttl)
+}
+
+// Health reports that service is up, whether it accepts writes and which consumed topics are paused
+func (s *Server) Health(c *gin.Context) {
+	health := gin.H{"status": "ok", "readOnly": s.ReadOnly}
+	if s.Pauser != nil {
+		health["pausedTopics"] = s.Pauser.PausedTopics()
+	}
+	c.JSON(http.StatusOK, health)
+}
+
+// requestDB returns database layer bound to request's context so queries are cancelled together with request,
+// layers without context support are returned as they are
+func (s *Server) requestDB(c *gin.Context) database.DBLayer {
+	if db, ok := s.DB.(database.ContextDBLayer); ok {
+		return db.WithContext(c.Request.Context())
+	}
+	return s.DB
+}
+
+// requestStorage returns storage layer bound to ctx, layers without context support are returned as they are
+func (s *Server) requestStorage(ctx context.Context) storage.StorageLayer {
+	if st, ok := s.Storage.(storage.ContextStorageLayer); ok {
+		return st.WithContext(ctx)
 	}
+	return s.Storage
 }
 
 // middleware for checking database connection
@@ -41,3 +146,20 @@ func (s *Server) CheckDatabase() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// middleware guarding internal endpoints, it requires X-Internal-Key header matching configured key. Requests
+// of services already authenticated with a scoped token (serviceID set) don't need it
+func (s *Server) MustInternalKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("serviceID") != "" {
+			c.Next()
+			return
+		}
+		key := c.GetHeader("X-Internal-Key")
+		if s.InternalAPIKey == "" || subtle.ConstantTimeCompare([]byte(key), []byte(s.InternalAPIKey)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"err": "invalid internal key"})
+			return
+		}
+		c.Next()
+	}
+}
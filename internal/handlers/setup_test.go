@@ -2,18 +2,20 @@ package handlers_test
 
 import (
 	"bytes"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"image"
 	"image/color"
 	"image/png"
 	"mime/multipart"
 	"net/textproto"
-)
 
-func createImage() *image.RGBA {
-	width := 200
-	height := 100
+	"github.com/Slimo300/chat-groupservice/internal/models"
+	"github.com/Slimo300/chat-groupservice/internal/storage"
+)
 
+func createImage(width, height int) *image.RGBA {
 	upLeft := image.Point{0, 0}
 	lowRight := image.Point{width, height}
 
@@ -39,10 +41,32 @@ func createImage() *image.RGBA {
 	return img
 }
 
+// imageKey returns storage key of image encoded the same way as in test forms
+func imageKey(img image.Image) string {
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, img)
+	key, _ := storage.ContentKey("", &buf)
+	return key
+}
+
 func createTestFormFile(fileName, cType string) (*bytes.Buffer, *multipart.Writer, error) {
+	return createTestFormFileWithImage(fileName, cType, createImage(200, 100))
+}
+
+func createTestFormFileWithImage(fileName, cType string, img image.Image) (*bytes.Buffer, *multipart.Writer, error) {
+	return createTestFormFileWithFields(fileName, cType, img, nil)
+}
+
+func createTestFormFileWithFields(fileName, cType string, img image.Image, fields map[string]string) (*bytes.Buffer, *multipart.Writer, error) {
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
 
+	for key, value := range fields {
+		if err := writer.WriteField(key, value); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	h := make(textproto.MIMEHeader)
 	h.Set("Content-Disposition",
 		fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
@@ -53,9 +77,15 @@ func createTestFormFile(fileName, cType string) (*bytes.Buffer, *multipart.Write
 		return nil, nil, err
 	}
 
-	if err = png.Encode(part, createImage()); err != nil {
+	if err = png.Encode(part, img); err != nil {
 		return nil, nil, err
 	}
 	writer.Close()
 	return body, writer, nil
 }
+
+// encodeCursor encodes cursor the same way it's sent in next cursor header
+func encodeCursor(cursor models.Cursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
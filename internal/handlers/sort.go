@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Slimo300/chat-groupservice/internal/models"
+)
+
+// Lists clients can choose order of with "sort" query parameter
+const (
+	SORTED_USER_GROUPS   = "groups"
+	SORTED_SHARED_GROUPS = "shared_groups"
+	SORTED_MEMBERS       = "members"
+)
+
+// sortOptions holds sorts each list accepts, the first one is used when client doesn't choose any
+// and no other default was configured
+var sortOptions = map[string][]models.Sort{
+	SORTED_USER_GROUPS: {models.SORT_NAME_ASC, models.SORT_NAME_DESC, models.SORT_CREATED_DESC,
+		models.SORT_CREATED_ASC, models.SORT_UPDATED_DESC},
+	SORTED_SHARED_GROUPS: {models.SORT_CREATED_DESC, models.SORT_CREATED_ASC, models.SORT_NAME_ASC,
+		models.SORT_NAME_DESC, models.SORT_UPDATED_DESC},
+	SORTED_MEMBERS: {models.SORT_JOINED_DESC, models.SORT_JOINED_ASC, models.SORT_ACTIVITY_DESC,
+		models.SORT_NAME_ASC, models.SORT_NAME_DESC},
+}
+
+func isSortAllowed(list string, sort models.Sort) bool {
+	for _, allowed := range sortOptions[list] {
+		if allowed == sort {
+			return true
+		}
+	}
+	return false
+}
+
+// SetDefaultSorts changes sorts used when client doesn't choose any, keyed by list name. Lists missing
+// from defaults keep their built in default. It should be called before server starts handling requests
+func (s *Server) SetDefaultSorts(defaults map[string]string) error {
+	sorts := make(map[string]models.Sort, len(defaults))
+	for list, sort := range defaults {
+		if _, ok := sortOptions[list]; !ok {
+			return fmt.Errorf("unknown sorted list: %s", list)
+		}
+		if !isSortAllowed(list, models.Sort(sort)) {
+			return fmt.Errorf("sort %s is not allowed for list %s", sort, list)
+		}
+		sorts[list] = models.Sort(sort)
+	}
+	s.defaultSorts = sorts
+	return nil
+}
+
+// parseSort reads "sort" query parameter of given list, sorts not allowed for the list are rejected
+func (s *Server) parseSort(r *http.Request, list string) (models.Sort, error) {
+	sort := models.Sort(r.URL.Query().Get("sort"))
+	if sort == "" {
+		if def, ok := s.defaultSorts[list]; ok {
+			return def, nil
+		}
+		return sortOptions[list][0], nil
+	}
+	if !isSortAllowed(list, sort) {
+		allowed := make([]string, 0, len(sortOptions[list]))
+		for _, option := range sortOptions[list] {
+			allowed = append(allowed, string(option))
+		}
+		return "", fmt.Errorf("invalid sort value: %s, allowed values: %s", sort, strings.Join(allowed, ", "))
+	}
+	return sort, nil
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Slimo300/chat-groupservice/internal/models"
+	"github.com/stretchr/testify/suite"
+)
+
+type SortTestSuite struct {
+	suite.Suite
+}
+
+func (s *SortTestSuite) TestParseSortAllowed() {
+	server := &Server{}
+	for list, sorts := range sortOptions {
+		for _, sort := range sorts {
+			s.Run(list+"_"+string(sort), func() {
+				parsed, err := server.parseSort(httptest.NewRequest("GET", "/list?sort="+string(sort), nil), list)
+				s.NoError(err)
+				s.Equal(sort, parsed)
+			})
+		}
+	}
+}
+
+func (s *SortTestSuite) TestParseSort() {
+	testCases := []struct {
+		desc         string
+		list         string
+		query        string
+		defaults     map[string]string
+		expectedSort models.Sort
+		expectError  bool
+	}{
+		{
+			desc:         "SortGroupsDefault",
+			list:         SORTED_USER_GROUPS,
+			expectedSort: models.SORT_NAME_ASC,
+		},
+		{
+			desc:         "SortSharedGroupsDefault",
+			list:         SORTED_SHARED_GROUPS,
+			expectedSort: models.SORT_CREATED_DESC,
+		},
+		{
+			desc:         "SortMembersDefault",
+			list:         SORTED_MEMBERS,
+			expectedSort: models.SORT_JOINED_DESC,
+		},
+		{
+			desc:         "SortConfiguredDefault",
+			list:         SORTED_MEMBERS,
+			defaults:     map[string]string{SORTED_MEMBERS: "activity_desc"},
+			expectedSort: models.SORT_ACTIVITY_DESC,
+		},
+		{
+			desc:         "SortOverridesConfiguredDefault",
+			list:         SORTED_MEMBERS,
+			query:        "?sort=name_asc",
+			defaults:     map[string]string{SORTED_MEMBERS: "activity_desc"},
+			expectedSort: models.SORT_NAME_ASC,
+		},
+		{
+			desc:        "SortNotAllowedForList",
+			list:        SORTED_USER_GROUPS,
+			query:       "?sort=joined_desc",
+			expectError: true,
+		},
+		{
+			desc:        "SortUnknown",
+			list:        SORTED_MEMBERS,
+			query:       "?sort=random",
+			expectError: true,
+		},
+		{
+			desc:        "SortInjection",
+			list:        SORTED_MEMBERS,
+			query:       "?sort=joined_at%20DESC%3B%20DROP%20TABLE%20members",
+			expectError: true,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+			server := &Server{}
+			s.NoError(server.SetDefaultSorts(tC.defaults))
+
+			sort, err := server.parseSort(httptest.NewRequest("GET", "/list"+tC.query, nil), tC.list)
+			if tC.expectError {
+				s.Error(err)
+				return
+			}
+			s.NoError(err)
+			s.Equal(tC.expectedSort, sort)
+		})
+	}
+}
+
+func (s *SortTestSuite) TestSetDefaultSortsInvalid() {
+	server := &Server{}
+	s.EqualError(server.SetDefaultSorts(map[string]string{"invites": "name_asc"}), "unknown sorted list: invites")
+	s.EqualError(server.SetDefaultSorts(map[string]string{SORTED_USER_GROUPS: "activity_desc"}),
+		"sort activity_desc is not allowed for list groups")
+}
+
+func TestSortSuite(t *testing.T) {
+	suite.Run(t, &SortTestSuite{})
+}
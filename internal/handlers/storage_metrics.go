@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"mime/multipart"
+	"time"
+
+	"github.com/Slimo300/chat-groupservice/internal/metrics"
+	"github.com/Slimo300/chat-groupservice/internal/storage"
+)
+
+var (
+	storageDuration = metrics.NewHistogramVec("groupservice_storage_operation_duration_seconds",
+		"Duration of storage operations in seconds", metrics.DefBuckets, "operation")
+	storageOperations = metrics.NewCounterVec("groupservice_storage_operations_total",
+		"Number of storage operations by result", "operation", "result")
+	storageUploadedBytes = metrics.NewCounter("groupservice_storage_uploaded_bytes_total",
+		"Number of bytes successfully uploaded to storage")
+)
+
+// uploadFile uploads file to storage and records metrics of the operation
+func (s *Server) uploadFile(ctx context.Context, file multipart.File, key, contentType string, size int64) error {
+	err := observeStorage("upload", func() error {
+		return s.requestStorage(ctx).UploadFile(file, key, contentType)
+	})
+	if err == nil {
+		storageUploadedBytes.Add(float64(size))
+	}
+	return err
+}
+
+// getFile fetches file from storage and records metrics of the operation, file not being modified
+// isn't counted as a failure
+func (s *Server) getFile(ctx context.Context, key string, modifiedSince time.Time) (file *storage.File, err error) {
+	start := time.Now()
+	file, err = s.requestStorage(ctx).GetFile(key, modifiedSince)
+	storageDuration.WithLabelValues("get").Observe(time.Since(start).Seconds())
+
+	result := "success"
+	if err != nil && !errors.Is(err, storage.ErrNotModified) {
+		result = "failure"
+	}
+	storageOperations.WithLabelValues("get", result).Inc()
+
+	return file, err
+}
+
+// deleteFile deletes file from storage and records metrics of the operation
+func (s *Server) deleteFile(ctx context.Context, key string) error {
+	return observeStorage("delete", func() error {
+		return s.requestStorage(ctx).DeleteFile(key)
+	})
+}
+
+func observeStorage(operation string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	storageDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	storageOperations.WithLabelValues(operation, result).Inc()
+
+	return err
+}
@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Slimo300/chat-groupservice/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// respondInvalidText responds to a text field rejected by models' validation, when value was too long
+// response tells which field it was and what its limit is
+func respondInvalidText(c *gin.Context, err error) {
+	var tooLong *models.TextTooLongError
+	if errors.As(err, &tooLong) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"err": err.Error(), "field": tooLong.Field.Name, "limit": tooLong.Field.MaxLength})
+		return
+	}
+	c.JSON(http.StatusUnprocessableEntity, gin.H{"err": err.Error()})
+}
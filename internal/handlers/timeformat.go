@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Formats of timestamps clients can choose with "time_format" query parameter
+const (
+	TIME_FORMAT_RFC3339  = "rfc3339"
+	TIME_FORMAT_EPOCH_MS = "epoch_ms"
+)
+
+// ParseTimeFormat checks value of "time_format" query parameter, empty value means default RFC3339 format
+func ParseTimeFormat(format string) (string, error) {
+	switch format {
+	case "", TIME_FORMAT_RFC3339:
+		return TIME_FORMAT_RFC3339, nil
+	case TIME_FORMAT_EPOCH_MS:
+		return TIME_FORMAT_EPOCH_MS, nil
+	default:
+		return "", fmt.Errorf("invalid time_format value: %s", format)
+	}
+}
+
+// formatTimes wraps response body so that its timestamps are encoded in format requested by client
+func formatTimes(c *gin.Context, v interface{}) interface{} {
+	format, err := ParseTimeFormat(c.Query("time_format"))
+	if err != nil || format == TIME_FORMAT_RFC3339 {
+		return v
+	}
+	return epochMillis{value: v}
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textType      = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// epochMillis marshals its value the way encoding/json does except for timestamps which are encoded
+// as milliseconds since epoch
+type epochMillis struct {
+	value interface{}
+}
+
+func (e epochMillis) MarshalJSON() ([]byte, error) {
+	return json.Marshal(toEpochMillis(reflect.ValueOf(e.value)))
+}
+
+// toEpochMillis rebuilds v as plain maps and slices with time.Time values replaced by epoch milliseconds,
+// values with their own JSON encoding are left for encoding/json
+func toEpochMillis(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Type() == timeType {
+		return v.Interface().(time.Time).UnixMilli()
+	}
+	if v.Kind() != reflect.Pointer && v.Kind() != reflect.Interface && (v.Type().Implements(marshalerType) || v.Type().Implements(textType)) {
+		return v.Interface()
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return toEpochMillis(v.Elem())
+	case reflect.Struct:
+		fields := make(map[string]interface{})
+		addFields(v, fields)
+		return fields
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface()
+		}
+		fallthrough
+	case reflect.Array:
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			items[i] = toEpochMillis(v.Index(i))
+		}
+		return items
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		entries := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			entries[mapKey(iter.Key())] = toEpochMillis(iter.Value())
+		}
+		return entries
+	default:
+		return v.Interface()
+	}
+}
+
+// addFields puts exported fields of struct v to fields under their JSON names, fields of untagged embedded
+// structs are promoted like encoding/json does
+func addFields(v reflect.Value, fields map[string]interface{}) {
+	typ := v.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag, hasTag := field.Tag.Lookup("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		value := v.Field(i)
+		if field.Anonymous && !hasTag && field.Type.Kind() == reflect.Struct && field.Type != timeType {
+			addFields(value, fields)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if strings.Contains(options, "omitempty") && isEmpty(value) {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = toEpochMillis(value)
+	}
+}
+
+// isEmpty tells whether value is omitted by encoding/json when its field has omitempty option
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Struct:
+		return false
+	default:
+		return v.IsZero()
+	}
+}
+
+func mapKey(key reflect.Value) string {
+	if marshaler, ok := key.Interface().(encoding.TextMarshaler); ok {
+		if text, err := marshaler.MarshalText(); err == nil {
+			return string(text)
+		}
+	}
+	return fmt.Sprint(key.Interface())
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Slimo300/chat-groupservice/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+type TimeFormatTestSuite struct {
+	suite.Suite
+}
+
+func (s *TimeFormatTestSuite) TestParseTimeFormat() {
+	for _, format := range []string{"", "rfc3339"} {
+		parsed, err := ParseTimeFormat(format)
+		s.NoError(err)
+		s.Equal(TIME_FORMAT_RFC3339, parsed)
+	}
+
+	parsed, err := ParseTimeFormat("epoch_ms")
+	s.NoError(err)
+	s.Equal(TIME_FORMAT_EPOCH_MS, parsed)
+
+	_, err = ParseTimeFormat("unix")
+	s.EqualError(err, "invalid time_format value: unix")
+}
+
+func (s *TimeFormatTestSuite) TestFormatTimes() {
+	joined := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	active := joined.Add(time.Hour)
+	groupID := uuid.MustParse("e7564f8c-8917-4527-a020-1db4b901d4b9")
+
+	members := []models.Member{
+		{ID: groupID, GroupID: groupID, Joined: joined, LastActive: &active, User: models.User{UserName: "user"}},
+		{ID: groupID, GroupID: groupID, Joined: joined},
+	}
+	change := models.GroupChange{Group: models.Group{ID: groupID, Created: joined, UpdatedAt: active}, Deleted: true}
+
+	testCases := []struct {
+		desc     string
+		query    string
+		value    interface{}
+		expected func(map[string]interface{})
+	}{
+		{
+			desc:  "FormatTimesDefault",
+			value: members[0],
+			expected: func(m map[string]interface{}) {
+				s.Equal(joined.Format(time.RFC3339), m["joined"])
+				s.Equal(active.Format(time.RFC3339), m["lastActive"])
+			},
+		},
+		{
+			desc:  "FormatTimesEpochMillis",
+			query: "?time_format=epoch_ms",
+			value: gin.H{"members": members, "change": change},
+			expected: func(m map[string]interface{}) {
+				list := m["members"].([]interface{})
+				first, second := list[0].(map[string]interface{}), list[1].(map[string]interface{})
+				s.Equal(float64(joined.UnixMilli()), first["joined"])
+				s.Equal(float64(active.UnixMilli()), first["lastActive"])
+				s.Nil(second["lastActive"])
+				s.Equal(groupID.String(), first["groupID"])
+				s.Equal("user", first["User"].(map[string]interface{})["username"])
+
+				// fields of embedded group are promoted and deletion time isn't exposed
+				group := m["change"].(map[string]interface{})
+				s.Equal(float64(joined.UnixMilli()), group["created"])
+				s.Equal(float64(active.UnixMilli()), group["updatedAt"])
+				s.Equal(true, group["deleted"])
+				s.NotContains(group, "DeletedAt")
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", "/"+tC.query, nil)
+
+			data, err := json.Marshal(formatTimes(c, tC.value))
+			s.NoError(err)
+			var decoded map[string]interface{}
+			s.NoError(json.Unmarshal(data, &decoded))
+			tC.expected(decoded)
+		})
+	}
+}
+
+func TestTimeFormat(t *testing.T) {
+	suite.Run(t, &TimeFormatTestSuite{})
+}
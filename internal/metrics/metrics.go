@@ -0,0 +1,328 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefBuckets are default histogram buckets (in seconds) suited for measuring latency of network calls
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// collector is implemented by every metric that can be exposed by Registry
+type collector interface {
+	write(w io.Writer)
+}
+
+// Registry holds metrics and exposes them in Prometheus text format
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+}
+
+// DefaultRegistry is a registry used by package level constructors
+var DefaultRegistry = NewRegistry()
+
+// NewRegistry creates empty Registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+// Write writes all registered metrics to w
+func (r *Registry) Write(w io.Writer) {
+	r.mu.Lock()
+	collectors := make([]collector, len(r.collectors))
+	copy(collectors, r.collectors)
+	r.mu.Unlock()
+
+	for _, c := range collectors {
+		c.write(w)
+	}
+}
+
+// Handler returns http.Handler exposing metrics from DefaultRegistry
+func Handler() http.Handler {
+	return HandlerFor(DefaultRegistry)
+}
+
+// HandlerFor returns http.Handler exposing metrics from given registry
+func HandlerFor(r *Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.Write(w)
+	})
+}
+
+// vec holds children of a labeled metric
+type vec[T any] struct {
+	name     string
+	help     string
+	typ      string
+	labels   []string
+	mu       sync.Mutex
+	children map[string]*T
+	values   map[string][]string
+	create   func() *T
+}
+
+func newVec[T any](name, help, typ string, labels []string, create func() *T) *vec[T] {
+	return &vec[T]{
+		name:     name,
+		help:     help,
+		typ:      typ,
+		labels:   labels,
+		children: make(map[string]*T),
+		values:   make(map[string][]string),
+		create:   create,
+	}
+}
+
+func (v *vec[T]) with(values ...string) *T {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.name, len(v.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	child, ok := v.children[key]
+	if !ok {
+		child = v.create()
+		v.children[key] = child
+		v.values[key] = values
+	}
+	return child
+}
+
+// each calls fn for every child in a stable order
+func (v *vec[T]) each(fn func(values []string, child *T)) {
+	v.mu.Lock()
+	keys := make([]string, 0, len(v.children))
+	for key := range v.children {
+		keys = append(keys, key)
+	}
+	v.mu.Unlock()
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		v.mu.Lock()
+		child, values := v.children[key], v.values[key]
+		v.mu.Unlock()
+		fn(values, child)
+	}
+}
+
+// Escapers of text format, label values escape backslash, double quote and line feed while help texts only
+// backslash and line feed. Other characters (including non ASCII ones) are written as they are
+var (
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func (v *vec[T]) writeHeader(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", v.name, helpEscaper.Replace(v.help), v.name, v.typ)
+}
+
+func formatLabels(names, values []string, extra ...string) string {
+	pairs := make([]string, 0, len(names)+len(extra)/2)
+	for i, name := range names {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, name, labelValueEscaper.Replace(values[i])))
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, extra[i], labelValueEscaper.Replace(extra[i+1])))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return fmt.Sprintf("%g", f)
+}
+
+// Counter is a monotonically increasing value
+type Counter struct {
+	mu    sync.Mutex
+	value float64
+}
+
+// Inc increments counter by 1
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add increments counter by given non-negative value
+func (c *Counter) Add(v float64) {
+	if v < 0 {
+		return
+	}
+	c.mu.Lock()
+	c.value += v
+	c.mu.Unlock()
+}
+
+// Value returns current value of a counter
+func (c *Counter) Value() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.value
+}
+
+// CounterVec is a counter partitioned by labels
+type CounterVec struct {
+	*vec[Counter]
+}
+
+// NewCounterVec creates CounterVec and registers it in DefaultRegistry
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{vec: newVec(name, help, "counter", labels, func() *Counter { return &Counter{} })}
+	DefaultRegistry.register(c)
+	return c
+}
+
+// NewCounter creates counter without labels and registers it in DefaultRegistry
+func NewCounter(name, help string) *Counter {
+	return NewCounterVec(name, help).WithLabelValues()
+}
+
+// WithLabelValues returns counter for given label values
+func (c *CounterVec) WithLabelValues(values ...string) *Counter {
+	return c.with(values...)
+}
+
+func (c *CounterVec) write(w io.Writer) {
+	c.writeHeader(w)
+	c.each(func(values []string, child *Counter) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, formatLabels(c.labels, values), formatFloat(child.Value()))
+	})
+}
+
+// Gauge is a value that can go up and down
+type Gauge struct {
+	mu    sync.Mutex
+	value float64
+}
+
+// Set sets gauge to given value
+func (g *Gauge) Set(v float64) {
+	g.mu.Lock()
+	g.value = v
+	g.mu.Unlock()
+}
+
+// Add adds given value (which can be negative) to gauge
+func (g *Gauge) Add(v float64) {
+	g.mu.Lock()
+	g.value += v
+	g.mu.Unlock()
+}
+
+// Value returns current value of a gauge
+func (g *Gauge) Value() float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.value
+}
+
+// GaugeVec is a gauge partitioned by labels
+type GaugeVec struct {
+	*vec[Gauge]
+}
+
+// NewGaugeVec creates GaugeVec and registers it in DefaultRegistry
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{vec: newVec(name, help, "gauge", labels, func() *Gauge { return &Gauge{} })}
+	DefaultRegistry.register(g)
+	return g
+}
+
+// NewGauge creates gauge without labels and registers it in DefaultRegistry
+func NewGauge(name, help string) *Gauge {
+	return NewGaugeVec(name, help).WithLabelValues()
+}
+
+// WithLabelValues returns gauge for given label values
+func (g *GaugeVec) WithLabelValues(values ...string) *Gauge {
+	return g.with(values...)
+}
+
+func (g *GaugeVec) write(w io.Writer) {
+	g.writeHeader(w)
+	g.each(func(values []string, child *Gauge) {
+		fmt.Fprintf(w, "%s%s %s\n", g.name, formatLabels(g.labels, values), formatFloat(child.Value()))
+	})
+}
+
+// Histogram counts observations in configurable buckets
+type Histogram struct {
+	mu      sync.Mutex
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+// Observe adds single observation to histogram
+func (h *Histogram) Observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, upper := range h.buckets {
+		if v <= upper {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// HistogramVec is a histogram partitioned by labels
+type HistogramVec struct {
+	*vec[Histogram]
+}
+
+// NewHistogramVec creates HistogramVec and registers it in DefaultRegistry
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	sorted := make([]float64, len(buckets))
+	copy(sorted, buckets)
+	sort.Float64s(sorted)
+
+	h := &HistogramVec{vec: newVec(name, help, "histogram", labels, func() *Histogram {
+		return &Histogram{buckets: sorted, counts: make([]uint64, len(sorted))}
+	})}
+	DefaultRegistry.register(h)
+	return h
+}
+
+// WithLabelValues returns histogram for given label values
+func (h *HistogramVec) WithLabelValues(values ...string) *Histogram {
+	return h.with(values...)
+}
+
+func (h *HistogramVec) write(w io.Writer) {
+	h.writeHeader(w)
+	h.each(func(values []string, child *Histogram) {
+		child.mu.Lock()
+		defer child.mu.Unlock()
+
+		for i, upper := range child.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, values, "le", formatFloat(upper)), child.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, values, "le", "+Inf"), child.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, formatLabels(h.labels, values), formatFloat(child.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, formatLabels(h.labels, values), child.count)
+	})
+}
@@ -0,0 +1,87 @@
+package metrics_test
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Slimo300/chat-groupservice/internal/metrics"
+	"github.com/stretchr/testify/suite"
+)
+
+type MetricsTestSuite struct {
+	suite.Suite
+	counter   *metrics.CounterVec
+	gauge     *metrics.Gauge
+	histogram *metrics.HistogramVec
+}
+
+func (s *MetricsTestSuite) SetupSuite() {
+	s.counter = metrics.NewCounterVec("test_operations_total", "Test operations", "operation", "result")
+	s.gauge = metrics.NewGauge("test_in_progress", "Test operations in progress")
+	s.histogram = metrics.NewHistogramVec("test_duration_seconds", "Test durations", []float64{0.1, 1}, "operation")
+}
+
+func (s *MetricsTestSuite) scrape() string {
+	w := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	body, err := io.ReadAll(w.Result().Body)
+	if err != nil {
+		s.Fail(err.Error())
+	}
+	return string(body)
+}
+
+func (s *MetricsTestSuite) TestCounter() {
+	s.counter.WithLabelValues("upload", "success").Inc()
+	s.counter.WithLabelValues("upload", "success").Add(2)
+	s.counter.WithLabelValues("upload", "failure").Inc()
+	s.counter.WithLabelValues("upload", "failure").Add(-5)
+
+	s.Equal(float64(3), s.counter.WithLabelValues("upload", "success").Value())
+
+	out := s.scrape()
+	s.Contains(out, "# TYPE test_operations_total counter")
+	s.Contains(out, `test_operations_total{operation="upload",result="success"} 3`)
+	s.Contains(out, `test_operations_total{operation="upload",result="failure"} 1`)
+}
+
+func (s *MetricsTestSuite) TestGauge() {
+	s.gauge.Set(5)
+	s.gauge.Add(-2)
+
+	s.Contains(s.scrape(), "test_in_progress 3")
+}
+
+func (s *MetricsTestSuite) TestHistogram() {
+	s.histogram.WithLabelValues("delete").Observe(0.05)
+	s.histogram.WithLabelValues("delete").Observe(0.5)
+	s.histogram.WithLabelValues("delete").Observe(5)
+
+	out := s.scrape()
+	s.Contains(out, "# TYPE test_duration_seconds histogram")
+	s.Contains(out, `test_duration_seconds_bucket{operation="delete",le="0.1"} 1`)
+	s.Contains(out, `test_duration_seconds_bucket{operation="delete",le="1"} 2`)
+	s.Contains(out, `test_duration_seconds_bucket{operation="delete",le="+Inf"} 3`)
+	s.Contains(out, `test_duration_seconds_count{operation="delete"} 3`)
+	s.Contains(out, `test_duration_seconds_sum{operation="delete"} 5.55`)
+}
+
+func (s *MetricsTestSuite) TestEscaping() {
+	escaped := metrics.NewCounterVec("test_escaped_total", "Help with \\ and\nnew line", "value")
+	escaped.WithLabelValues("back\\slash \"quoted\"\nnext\ttab zażółć").Inc()
+
+	out := s.scrape()
+	s.Contains(out, "# HELP test_escaped_total Help with \\\\ and\\nnew line\n")
+	// only backslash, double quote and line feed are escaped, the rest is written as it is
+	s.Contains(out, "test_escaped_total{value=\"back\\\\slash \\\"quoted\\\"\\nnext\ttab zażółć\"} 1")
+}
+
+func (s *MetricsTestSuite) TestWrongLabelCount() {
+	s.Panics(func() { s.counter.WithLabelValues("upload") })
+}
+
+func TestMetricsSuite(t *testing.T) {
+	suite.Run(t, &MetricsTestSuite{})
+}
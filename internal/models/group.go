@@ -1,19 +1,148 @@
 package models
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type Group struct {
 	ID      uuid.UUID `gorm:"primaryKey" json:"ID"`
 	Name    string    `gorm:"column:name" json:"name"`
-	Picture string    `gorm:"column:picture_url" json:"pictureUrl"`
+	Picture string    `gorm:"column:picture_url;size:191;index" json:"pictureUrl"`
 	Created time.Time `gorm:"column:created" json:"created"`
-	Members []Member  `gorm:"foreignKey:GroupID"`
+	// Announcement groups are meant to be displayed differently by clients, message service may allow
+	// only admins to post there
+	Announcement bool `gorm:"column:is_announcement" json:"isAnnouncement"`
+	// SlowModeSeconds is minimal interval between messages of a member enforced by message service, 0 disables it
+	SlowModeSeconds int `gorm:"column:slow_mode_seconds" json:"slowModeSeconds"`
+	// AnnouncementSet and SlowModeSet tell whether settings were ever changed by group's members
+	AnnouncementSet bool      `gorm:"column:is_announcement_set" json:"-"`
+	SlowModeSet     bool      `gorm:"column:slow_mode_seconds_set" json:"-"`
+	UpdatedAt       time.Time `gorm:"column:updated_at;index" json:"updatedAt"`
+	// deleted groups are kept so services syncing changes can learn about deletion
+	DeletedAt gorm.DeletedAt `gorm:"column:deleted_at" json:"-"`
+	Members   []Member       `gorm:"foreignKey:GroupID"`
 }
 
 func (Group) TableName() string {
 	return "groups"
 }
+
+// GroupChange is a group returned by changes feed together with information whether it was deleted
+type GroupChange struct {
+	Group
+	Deleted bool `json:"deleted"`
+}
+
+// MAX_SLOW_MODE_SECONDS is the longest slow mode interval a group can have (6 hours)
+const MAX_SLOW_MODE_SECONDS = 21600
+
+// Defaults of settings of groups which members never changed them
+const (
+	DEFAULT_ANNOUNCEMENT      = false
+	DEFAULT_SLOW_MODE_SECONDS = 0
+)
+
+// ResolvedSetting is the effective value of a setting, Explicit tells whether it was set by group's members
+// or it's a server default
+type ResolvedSetting[T any] struct {
+	Value    T    `json:"value"`
+	Explicit bool `json:"explicit"`
+}
+
+// EffectiveSettings holds every setting of a group with defaults filled in for settings that were never set
+type EffectiveSettings struct {
+	IsAnnouncement  ResolvedSetting[bool] `json:"isAnnouncement"`
+	SlowModeSeconds ResolvedSetting[int]  `json:"slowModeSeconds"`
+}
+
+// EffectiveSettings resolves group's settings. Groups changed before set flags were stored have them unset,
+// so a value other than the default is also treated as set explicitly
+func (g Group) EffectiveSettings() EffectiveSettings {
+	settings := EffectiveSettings{
+		IsAnnouncement:  ResolvedSetting[bool]{Value: DEFAULT_ANNOUNCEMENT},
+		SlowModeSeconds: ResolvedSetting[int]{Value: DEFAULT_SLOW_MODE_SECONDS},
+	}
+	if g.AnnouncementSet || g.Announcement != DEFAULT_ANNOUNCEMENT {
+		settings.IsAnnouncement = ResolvedSetting[bool]{Value: g.Announcement, Explicit: true}
+	}
+	if g.SlowModeSet || g.SlowModeSeconds != DEFAULT_SLOW_MODE_SECONDS {
+		settings.SlowModeSeconds = ResolvedSetting[int]{Value: g.SlowModeSeconds, Explicit: true}
+	}
+	return settings
+}
+
+// GroupSettings holds changes of group settings, nil fields are left as they are
+type GroupSettings struct {
+	IsAnnouncement  *bool `json:"isAnnouncement"`
+	SlowModeSeconds *int  `json:"slowModeSeconds"`
+}
+
+// Problems lists every changed setting that has a value outside of allowed range
+func (s GroupSettings) Problems() (problems []string) {
+	if s.SlowModeSeconds != nil && (*s.SlowModeSeconds < 0 || *s.SlowModeSeconds > MAX_SLOW_MODE_SECONDS) {
+		problems = append(problems, fmt.Sprintf("slow mode must be between 0 and %d seconds", MAX_SLOW_MODE_SECONDS))
+	}
+	return problems
+}
+
+// Validate checks whether changed settings have allowed values
+func (s GroupSettings) Validate() error {
+	if problems := s.Problems(); len(problems) > 0 {
+		return errors.New(problems[0])
+	}
+	return nil
+}
+
+// SettingsReport describes what would happen if settings change was applied
+type SettingsReport struct {
+	Valid    bool     `json:"valid"`
+	Problems []string `json:"problems"`
+	// Changes holds names of settings which value would be different after applying the change
+	Changes []string `json:"changes"`
+}
+
+// GroupDefinition describes a group created by an import together with its initial members, owner becomes
+// group's creator and members join it as basic members
+type GroupDefinition struct {
+	Name    string      `json:"name"`
+	OwnerID uuid.UUID   `json:"ownerID"`
+	Members []uuid.UUID `json:"members"`
+}
+
+// GroupCreationResult holds outcome of creating a single group of an import, Group is set when it was created
+// and Err when it wasn't
+type GroupCreationResult struct {
+	Group *Group
+	Err   error
+}
+
+// GroupCard is a compact projection of a group used to render previews of shared groups
+type GroupCard struct {
+	ID          uuid.UUID `json:"ID"`
+	Name        string    `json:"name"`
+	Picture     string    `json:"pictureUrl"`
+	MemberCount int64     `json:"memberCount"`
+}
+
+// GroupStats holds aggregated information about group activity
+type GroupStats struct {
+	Members          int64 `gorm:"column:members" json:"members"`
+	PendingInvites   int64 `gorm:"column:pending_invites" json:"pendingInvites"`
+	JoinedLast7Days  int64 `gorm:"column:joined_last_7_days" json:"joinedLast7Days"`
+	JoinedLast30Days int64 `gorm:"column:joined_last_30_days" json:"joinedLast30Days"`
+}
+
+// PlatformGroupMetrics holds totals of all groups of the platform, deleted groups are counted only
+// in DeletedGroups which is reported on request
+type PlatformGroupMetrics struct {
+	Groups           int64     `json:"groups"`
+	Memberships      int64     `json:"memberships"`
+	AverageGroupSize float64   `json:"averageGroupSize"`
+	DeletedGroups    *int64    `json:"deletedGroups,omitempty"`
+	ComputedAt       time.Time `json:"computedAt"`
+}
@@ -0,0 +1,55 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/Slimo300/chat-groupservice/internal/models"
+	"github.com/stretchr/testify/suite"
+)
+
+type GroupTestSuite struct {
+	suite.Suite
+}
+
+func (s *GroupTestSuite) TestEffectiveSettings() {
+	testCases := []struct {
+		desc     string
+		group    models.Group
+		expected models.EffectiveSettings
+	}{
+		{
+			desc:  "NeverSet",
+			group: models.Group{},
+			expected: models.EffectiveSettings{
+				IsAnnouncement:  models.ResolvedSetting[bool]{Value: models.DEFAULT_ANNOUNCEMENT},
+				SlowModeSeconds: models.ResolvedSetting[int]{Value: models.DEFAULT_SLOW_MODE_SECONDS},
+			},
+		},
+		{
+			desc:  "SetToDefault",
+			group: models.Group{SlowModeSet: true},
+			expected: models.EffectiveSettings{
+				IsAnnouncement:  models.ResolvedSetting[bool]{Value: models.DEFAULT_ANNOUNCEMENT},
+				SlowModeSeconds: models.ResolvedSetting[int]{Value: 0, Explicit: true},
+			},
+		},
+		{
+			desc:  "SetBeforeFlagsWereStored",
+			group: models.Group{Announcement: true, SlowModeSeconds: 30},
+			expected: models.EffectiveSettings{
+				IsAnnouncement:  models.ResolvedSetting[bool]{Value: true, Explicit: true},
+				SlowModeSeconds: models.ResolvedSetting[int]{Value: 30, Explicit: true},
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+			s.Equal(tC.expected, tC.group.EffectiveSettings())
+		})
+	}
+}
+
+func TestGroups(t *testing.T) {
+	suite.Run(t, &GroupTestSuite{})
+}
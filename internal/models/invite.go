@@ -1,6 +1,7 @@
 package models
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,6 +15,30 @@ const (
 	INVITE_DECLINE
 )
 
+// String returns name of invite status reported to clients
+func (s InviteStatus) String() string {
+	switch s {
+	case INVITE_AWAITING:
+		return "awaiting"
+	case INVITE_ACCEPT:
+		return "accepted"
+	case INVITE_DECLINE:
+		return "declined"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseInviteStatus returns invite status with given name
+func ParseInviteStatus(name string) (InviteStatus, error) {
+	for _, status := range []InviteStatus{INVITE_AWAITING, INVITE_ACCEPT, INVITE_DECLINE} {
+		if status.String() == name {
+			return status, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown invite status: %s", name)
+}
+
 type Invite struct {
 	ID       uuid.UUID    `gorm:"primaryKey" json:"ID"`
 	IssId    uuid.UUID    `gorm:"column:iss_id;size:191" json:"issID"`
@@ -23,6 +48,7 @@ type Invite struct {
 	GroupID  uuid.UUID    `gorm:"column:group_id;size:191" json:"groupID"`
 	Group    Group        `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"group"`
 	Status   InviteStatus `gorm:"column:status" json:"status"`
+	Rights   InviteRights `gorm:"embedded;embeddedPrefix:rights_" json:"rights"`
 	Created  time.Time    `gorm:"column:created" json:"created"`
 	Modified time.Time    `gorm:"column:modified" json:"modified"`
 }
@@ -30,3 +56,11 @@ type Invite struct {
 func (Invite) TableName() string {
 	return "invites"
 }
+
+// InviteRights holds rights that invited user will be granted after accepting an invite
+type InviteRights struct {
+	Adding           bool `gorm:"column:adding" json:"adding"`
+	DeletingMembers  bool `gorm:"column:deleting_members" json:"deletingMembers"`
+	DeletingMessages bool `gorm:"column:deleting_messages" json:"deletingMessages"`
+	Admin            bool `gorm:"column:admin" json:"admin"`
+}
@@ -1,29 +1,47 @@
 package models
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
+	"time"
 
+	"github.com/Slimo300/MicroservicesChatApp/backend/lib/apperrors"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type Member struct {
-	ID               uuid.UUID `gorm:"primaryKey" json:"ID"`
-	GroupID          uuid.UUID `gorm:"column:group_id;uniqueIndex:idx_first;size:191" json:"groupID"`
-	UserID           uuid.UUID `gorm:"column:user_id;uniqueIndex:idx_first;size:191" json:"userID"`
-	User             User      `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	Group            Group     `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
-	Adding           bool      `gorm:"column:adding" json:"adding"`
-	DeletingMembers  bool      `gorm:"column:deleting_members" json:"deletingMembers"`
-	DeletingMessages bool      `gorm:"column:deleting_messages" json:"deletingMessages"`
-	Admin            bool      `gorm:"column:setting" json:"admin"`
-	Creator          bool      `gorm:"column:creator" json:"creator"`
+	ID               uuid.UUID  `gorm:"primaryKey" json:"ID"`
+	GroupID          uuid.UUID  `gorm:"column:group_id;uniqueIndex:idx_first;index:idx_group_joined,priority:1;size:191" json:"groupID"`
+	UserID           uuid.UUID  `gorm:"column:user_id;uniqueIndex:idx_first;index:idx_member_user;size:191" json:"userID"`
+	User             User       `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Group            Group      `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	Adding           bool       `gorm:"column:adding" json:"adding"`
+	DeletingMembers  bool       `gorm:"column:deleting_members" json:"deletingMembers"`
+	DeletingMessages bool       `gorm:"column:deleting_messages" json:"deletingMessages"`
+	Admin            bool       `gorm:"column:setting" json:"admin"`
+	CoOwner          bool       `gorm:"column:co_owner" json:"coOwner"`
+	Creator          bool       `gorm:"column:creator" json:"creator"`
+	Joined           time.Time  `gorm:"column:joined_at;index:idx_group_joined,priority:2" json:"joined"`
+	LastActive       *time.Time `gorm:"column:last_active_at" json:"lastActive"`
+	Nickname         string     `gorm:"column:nickname;size:64" json:"nickname"`
+	DisplayName      string     `gorm:"-" json:"displayName"`
 }
 
 func (Member) TableName() string {
 	return "members"
 }
 
+// AfterFind fills member's display name which is a nickname or global username when nickname isn't set
+func (m *Member) AfterFind(tx *gorm.DB) error {
+	m.DisplayName = m.Nickname
+	if m.DisplayName == "" {
+		m.DisplayName = m.User.UserName
+	}
+	return nil
+}
+
 // Here are methods and constants responsible for resolving users rights in a group when they try to alter
 // other members of a group
 
@@ -31,6 +49,7 @@ type role int
 
 const (
 	CREATOR role = iota + 1
+	CO_OWNER
 	ADMIN
 	DELETER
 	BASIC
@@ -50,10 +69,109 @@ func (m Member) CanAlter(target Member) bool {
 	return m.role(true) < target.role(true)
 }
 
+// CanPromote checks whether member can leave target with target's current rights, nobody can raise
+// other members above their own role. It's checked after new rights were applied to target
+func (m Member) CanPromote(target Member) bool {
+	return m.role(true) <= target.role(true)
+}
+
+// CanInvite checks whether member can invite new users to a group granting them given rights.
+// Inviting with any rights requires the same rank that is needed to alter a basic member
+func (m Member) CanInvite(rights InviteRights) bool {
+	if rights != (InviteRights{}) {
+		return m.CanAlter(Member{})
+	}
+	return m.Adding || m.Admin || m.CoOwner || m.Creator
+}
+
+// CanEditGroup checks whether member can change group's picture and settings other than announcement flag.
+// It also allows viewing memberships of other members
+func (m Member) CanEditGroup() bool {
+	return m.Admin || m.CoOwner || m.Creator
+}
+
+// CanSetAnnouncement checks whether member can change group's announcement flag
+func (m Member) CanSetAnnouncement() bool {
+	return m.CoOwner || m.Creator
+}
+
+// CanDeleteGroup checks whether member can delete a group, it's the only action reserved for creator
+// alone as co-owners can't delete a group nor remove its creator
+func (m Member) CanDeleteGroup() bool {
+	return m.Creator
+}
+
+// CanExportGroup checks whether member can export all data of a group
+func (m Member) CanExportGroup() bool {
+	return m.CoOwner || m.Creator
+}
+
+// LAST_OWNER is a code of ErrLastOwner returned to clients
+const LAST_OWNER = "LAST_OWNER"
+
+// ErrLastOwner is returned when an action would leave a group without its owner
+var ErrLastOwner = &apperrors.Error{Type: apperrors.Conflict, Message: "group cannot be left without its owner, delete the group instead"}
+
+// CheckOwnerRemains must pass before member leaves a group or loses their role. Creator is the only owner
+// of a group and the only one who can delete it, so group would be left ownerless once creator is gone
+func (m Member) CheckOwnerRemains() error {
+	if m.Creator {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+// Capabilities of a member returned by Permissions
+const (
+	CAN_INVITE           = "invite"
+	CAN_REMOVE_MEMBERS   = "removeMembers"
+	CAN_EDIT_MEMBERS     = "editMembers"
+	CAN_EDIT_GROUP       = "editGroup"
+	CAN_SET_ANNOUNCEMENT = "setAnnouncement"
+	CAN_DELETE_GROUP     = "deleteGroup"
+	CAN_EXPORT_GROUP     = "exportGroup"
+)
+
+// Permissions resolves what member can do in a group, it's built from the same checks database layer uses
+// to authorize actions so clients can hide actions that would be rejected
+func (m Member) Permissions() map[string]bool {
+	return map[string]bool{
+		CAN_INVITE:           m.CanInvite(InviteRights{}),
+		CAN_REMOVE_MEMBERS:   m.CanDelete(Member{}),
+		CAN_EDIT_MEMBERS:     m.CanAlter(Member{}),
+		CAN_EDIT_GROUP:       m.CanEditGroup(),
+		CAN_SET_ANNOUNCEMENT: m.CanSetAnnouncement(),
+		CAN_DELETE_GROUP:     m.CanDeleteGroup(),
+		CAN_EXPORT_GROUP:     m.CanExportGroup(),
+	}
+}
+
+// RoleNames lists names of roles returned by RoleName from the highest to the lowest one
+var RoleNames = []string{"creator", "co-owner", "admin", "deleter", "basic"}
+
+// RoleName returns name of member's highest role in a group
+func (m Member) RoleName() string {
+	switch m.role(false) {
+	case CREATOR:
+		return "creator"
+	case CO_OWNER:
+		return "co-owner"
+	case ADMIN:
+		return "admin"
+	case DELETER:
+		return "deleter"
+	default:
+		return "basic"
+	}
+}
+
 func (m Member) role(noDeleter bool) role {
 	if m.Creator {
 		return CREATOR
 	}
+	if m.CoOwner {
+		return CO_OWNER
+	}
 	if m.Admin {
 		return ADMIN
 	}
@@ -78,6 +196,7 @@ type MemberRights struct {
 	DeletingMessages operation `json:"deletingMessages,omitempty"`
 	DeletingMembers  operation `json:"deletingMembers,omitempty"`
 	Admin            operation `json:"admin,omitempty"`
+	CoOwner          operation `json:"coOwner,omitempty"`
 }
 
 func (m *Member) ApplyRights(rights MemberRights) error {
@@ -99,6 +218,51 @@ func (m *Member) ApplyRights(rights MemberRights) error {
 	return nil
 }
 
+// StepDown lowers member's role by one level, co-owners become admins, admins become basic members and
+// deleters lose right to delete members. Creator can't step down as there would be nobody owning a group
+func (m *Member) StepDown() error {
+	switch m.role(false) {
+	case CREATOR:
+		return errors.New("creator cannot step down")
+	case CO_OWNER:
+		m.CoOwner = false
+		m.Admin = true
+	case ADMIN:
+		m.Admin = false
+	case DELETER:
+		m.DeletingMembers = false
+	default:
+		return errors.New("member has no role to step down from")
+	}
+	return nil
+}
+
+// CheckGrantableRole returns an error when role can't be assigned to a member, creator role is never
+// granted as a group has exactly one creator
+func CheckGrantableRole(role string) error {
+	switch role {
+	case "co-owner", "admin", "deleter", "basic":
+		return nil
+	case "creator":
+		return errors.New("creator role cannot be granted")
+	default:
+		return fmt.Errorf("unknown role: %s", role)
+	}
+}
+
+// SetRole changes member's rights so RoleName returns given role, rights not tied to roles are left as they are
+func (m *Member) SetRole(role string) error {
+	if err := CheckGrantableRole(role); err != nil {
+		return err
+	}
+	m.CoOwner = role == "co-owner"
+	m.Admin = role == "admin"
+	if role != "admin" && role != "co-owner" {
+		m.DeletingMembers = role == "deleter"
+	}
+	return nil
+}
+
 func (m *Member) grant(field string) {
 	reflect.ValueOf(m).Elem().FieldByName(field).SetBool(true)
 }
@@ -106,3 +270,84 @@ func (m *Member) grant(field string) {
 func (m *Member) revoke(field string) {
 	reflect.ValueOf(m).Elem().FieldByName(field).SetBool(false)
 }
+
+// MemberHandover selects what is handed over from one member to another besides role and rights
+type MemberHandover struct {
+	// Nickname copies source's nickname to target
+	Nickname bool `json:"nickname"`
+	// ClearSource leaves source a basic member without rights, and without nickname when it's handed over too
+	ClearSource bool `json:"clearSource"`
+}
+
+// HandOver copies role and rights of source to target on behalf of member m. Member must be able to alter target,
+// and source too when it's cleared, and can't leave target above own role. Creator role is never handed over
+// as a group has exactly one creator
+func (m Member) HandOver(source, target *Member, handover MemberHandover) error {
+	if source.ID == target.ID {
+		return apperrors.NewBadRequest("source and target must be different members")
+	}
+	if source.Creator {
+		return apperrors.NewForbidden("creator role cannot be handed over")
+	}
+	if !m.CanAlter(*target) {
+		return apperrors.NewForbidden(fmt.Sprintf("User %v cannot alter member %v", m.UserID, target.ID))
+	}
+	if handover.ClearSource && !m.CanAlter(*source) {
+		return apperrors.NewForbidden(fmt.Sprintf("User %v cannot alter member %v", m.UserID, source.ID))
+	}
+
+	target.Adding = source.Adding
+	target.DeletingMessages = source.DeletingMessages
+	target.DeletingMembers = source.DeletingMembers
+	target.Admin = source.Admin
+	target.CoOwner = source.CoOwner
+	if !m.CanPromote(*target) {
+		return apperrors.NewForbidden(fmt.Sprintf("User %v cannot grant member %v role %v", m.UserID, target.ID, target.RoleName()))
+	}
+	if handover.Nickname {
+		target.setNickname(source.Nickname)
+	}
+
+	if handover.ClearSource {
+		source.Adding = false
+		source.DeletingMessages = false
+		source.DeletingMembers = false
+		source.Admin = false
+		source.CoOwner = false
+		if handover.Nickname {
+			source.setNickname("")
+		}
+	}
+	return nil
+}
+
+// setNickname changes nickname keeping display name in line with it
+func (m *Member) setNickname(nickname string) {
+	m.Nickname = nickname
+	m.DisplayName = nickname
+	if m.DisplayName == "" {
+		m.DisplayName = m.User.UserName
+	}
+}
+
+// RoleChange requests assigning a role to a member of a group
+type RoleChange struct {
+	UserID uuid.UUID `json:"userID"`
+	Role   string    `json:"role"`
+}
+
+// RoleChangeResult holds outcome of a single RoleChange, Member is set when role was changed and Err
+// when change was rejected
+type RoleChangeResult struct {
+	UserID uuid.UUID
+	Member *Member
+	Err    error
+}
+
+// MembershipMerge describes memberships changed when one user's memberships were moved to another user
+type MembershipMerge struct {
+	// Updated are memberships that were moved or had their rights raised
+	Updated []Member
+	// Deleted are duplicate memberships of merged user that were removed
+	Deleted []Member
+}
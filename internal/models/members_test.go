@@ -19,6 +19,8 @@ type MemberTestSuite struct {
 	admin    models.Member
 	admin2   models.Member
 	admin3   models.Member
+	coOwner  models.Member
+	coOwner2 models.Member
 	creator  models.Member
 	creator2 models.Member
 }
@@ -32,6 +34,8 @@ func (s *MemberTestSuite) SetupSuite() {
 	s.admin = models.Member{ID: uuid.New(), Admin: true}
 	s.admin2 = models.Member{ID: uuid.New(), Admin: true}
 	s.admin3 = models.Member{ID: uuid.New(), Admin: true}
+	s.coOwner = models.Member{ID: uuid.New(), CoOwner: true}
+	s.coOwner2 = models.Member{ID: uuid.New(), CoOwner: true}
 	s.creator = models.Member{ID: uuid.New(), Creator: true}
 	s.creator2 = models.Member{ID: uuid.New(), Creator: true}
 }
@@ -40,11 +44,19 @@ func (s *MemberTestSuite) TestCanDelete() {
 	s.True(s.creator.CanDelete(s.basic))
 	s.True(s.creator.CanDelete(s.deleter))
 	s.True(s.creator.CanDelete(s.admin))
+	s.True(s.creator.CanDelete(s.coOwner))
 	s.False(s.creator.CanDelete(s.creator2))
 
+	s.True(s.coOwner.CanDelete(s.basic))
+	s.True(s.coOwner.CanDelete(s.deleter))
+	s.True(s.coOwner.CanDelete(s.admin))
+	s.False(s.coOwner.CanDelete(s.coOwner2))
+	s.False(s.coOwner.CanDelete(s.creator))
+
 	s.True(s.admin.CanDelete(s.basic))
 	s.True(s.admin.CanDelete(s.deleter))
 	s.False(s.admin.CanDelete(s.admin2))
+	s.False(s.admin.CanDelete(s.coOwner))
 	s.False(s.admin.CanDelete(s.creator))
 
 	s.True(s.deleter.CanDelete(s.basic))
@@ -60,6 +72,7 @@ func (s *MemberTestSuite) TestCanDelete() {
 	s.True(s.basic.CanDelete(s.basic))
 	s.True(s.deleter.CanDelete(s.deleter))
 	s.True(s.admin.CanDelete(s.admin))
+	s.True(s.coOwner.CanDelete(s.coOwner))
 	s.False(s.creator.CanDelete(s.creator))
 }
 
@@ -67,11 +80,19 @@ func (s *MemberTestSuite) TestCanAlter() {
 	s.True(s.creator.CanAlter(s.basic))
 	s.True(s.creator.CanAlter(s.deleter))
 	s.True(s.creator.CanAlter(s.admin))
+	s.True(s.creator.CanAlter(s.coOwner))
 	s.False(s.creator.CanAlter(s.creator))
 
+	s.True(s.coOwner.CanAlter(s.basic))
+	s.True(s.coOwner.CanAlter(s.deleter))
+	s.True(s.coOwner.CanAlter(s.admin))
+	s.False(s.coOwner.CanAlter(s.coOwner2))
+	s.False(s.coOwner.CanAlter(s.creator))
+
 	s.True(s.admin.CanAlter(s.basic))
 	s.True(s.admin.CanAlter(s.deleter))
 	s.False(s.admin.CanAlter(s.admin))
+	s.False(s.admin.CanAlter(s.coOwner))
 	s.False(s.admin.CanAlter(s.creator))
 
 	s.False(s.deleter.CanAlter(s.basic))
@@ -85,6 +106,51 @@ func (s *MemberTestSuite) TestCanAlter() {
 	s.False(s.basic.CanAlter(s.creator))
 }
 
+func (s *MemberTestSuite) TestCanPromote() {
+	s.True(s.creator.CanPromote(s.coOwner))
+	s.True(s.creator.CanPromote(s.admin))
+
+	s.True(s.coOwner.CanPromote(s.coOwner2))
+	s.True(s.coOwner.CanPromote(s.admin))
+	s.True(s.coOwner.CanPromote(s.basic))
+
+	s.False(s.admin.CanPromote(s.coOwner))
+	s.True(s.admin.CanPromote(s.admin2))
+	s.True(s.admin.CanPromote(s.deleter))
+
+	s.False(s.deleter.CanPromote(s.admin))
+	s.False(s.basic.CanPromote(s.coOwner))
+}
+
+func (s *MemberTestSuite) TestCanInvite() {
+	adding := models.Member{ID: uuid.New(), Adding: true}
+	noRights := models.InviteRights{}
+	adminRights := models.InviteRights{Admin: true}
+	addingRights := models.InviteRights{Adding: true, DeletingMessages: true}
+
+	s.True(s.creator.CanInvite(noRights))
+	s.True(s.creator.CanInvite(adminRights))
+	s.True(s.creator.CanInvite(addingRights))
+
+	s.True(s.coOwner.CanInvite(noRights))
+	s.True(s.coOwner.CanInvite(adminRights))
+	s.True(s.coOwner.CanInvite(addingRights))
+
+	s.True(s.admin.CanInvite(noRights))
+	s.True(s.admin.CanInvite(adminRights))
+	s.True(s.admin.CanInvite(addingRights))
+
+	s.True(adding.CanInvite(noRights))
+	s.False(adding.CanInvite(adminRights))
+	s.False(adding.CanInvite(addingRights))
+
+	s.False(s.deleter.CanInvite(noRights))
+	s.False(s.deleter.CanInvite(adminRights))
+
+	s.False(s.basic.CanInvite(noRights))
+	s.False(s.basic.CanInvite(adminRights))
+}
+
 func (s *MemberTestSuite) TestApplyRights() {
 	s.False(s.basic3.Adding)
 
@@ -126,6 +192,210 @@ func (s *MemberTestSuite) TestApplyRights() {
 
 }
 
+func (s *MemberTestSuite) TestStepDown() {
+	admin := models.Member{ID: uuid.New(), Admin: true, Adding: true, DeletingMembers: true}
+	s.NoError(admin.StepDown())
+	s.False(admin.Admin)
+	s.True(admin.Adding)
+	s.True(admin.DeletingMembers)
+
+	s.NoError(admin.StepDown())
+	s.False(admin.DeletingMembers)
+	s.True(admin.Adding)
+
+	s.EqualError(admin.StepDown(), "member has no role to step down from")
+
+	coOwner := models.Member{ID: uuid.New(), CoOwner: true}
+	s.NoError(coOwner.StepDown())
+	s.False(coOwner.CoOwner)
+	s.Equal("admin", coOwner.RoleName())
+
+	creator := models.Member{ID: uuid.New(), Creator: true, Admin: true}
+	s.EqualError(creator.StepDown(), "creator cannot step down")
+	s.True(creator.Admin)
+}
+
+func (s *MemberTestSuite) TestRoleNames() {
+	roles := []string{}
+	for _, member := range []models.Member{s.creator, s.coOwner, s.admin, s.deleter, s.basic} {
+		roles = append(roles, member.RoleName())
+	}
+	s.Equal(models.RoleNames, roles)
+}
+
+func (s *MemberTestSuite) TestCheckOwnerRemains() {
+	s.ErrorIs(s.creator.CheckOwnerRemains(), models.ErrLastOwner)
+	s.NoError(s.coOwner.CheckOwnerRemains())
+	s.NoError(s.admin.CheckOwnerRemains())
+	s.NoError(s.basic.CheckOwnerRemains())
+}
+
+func (s *MemberTestSuite) TestSetRole() {
+	member := models.Member{ID: uuid.New(), Adding: true}
+
+	s.NoError(member.SetRole("co-owner"))
+	s.Equal("co-owner", member.RoleName())
+
+	s.NoError(member.SetRole("admin"))
+	s.Equal("admin", member.RoleName())
+	s.False(member.CoOwner)
+
+	s.NoError(member.SetRole("deleter"))
+	s.Equal("deleter", member.RoleName())
+
+	s.NoError(member.SetRole("basic"))
+	s.Equal("basic", member.RoleName())
+	s.True(member.Adding)
+
+	s.EqualError(member.SetRole("creator"), "creator role cannot be granted")
+	s.EqualError(member.SetRole("owner"), "unknown role: owner")
+	s.False(member.Creator)
+}
+
+func (s *MemberTestSuite) TestHandOver() {
+	testCases := []struct {
+		desc           string
+		issuer         models.Member
+		source         models.Member
+		target         models.Member
+		handover       models.MemberHandover
+		expectedError  string
+		expectedSource models.Member
+		expectedTarget models.Member
+	}{
+		{
+			desc:          "HandOverToSelf",
+			issuer:        s.creator,
+			source:        s.admin,
+			target:        s.admin,
+			expectedError: "Bad request. Reason: source and target must be different members",
+		},
+		{
+			desc:          "HandOverCreator",
+			issuer:        s.creator,
+			source:        s.creator2,
+			target:        s.basic,
+			expectedError: "Forbidden action. Reason: creator role cannot be handed over",
+		},
+		{
+			desc:          "HandOverTargetNotAlterable",
+			issuer:        s.admin,
+			source:        s.basic,
+			target:        s.admin2,
+			expectedError: "Forbidden action. Reason: User 00000000-0000-0000-0000-000000000000 cannot alter member " + s.admin2.ID.String(),
+		},
+		{
+			desc:          "HandOverSourceNotAlterable",
+			issuer:        s.admin,
+			source:        s.coOwner,
+			target:        s.basic,
+			handover:      models.MemberHandover{ClearSource: true},
+			expectedError: "Forbidden action. Reason: User 00000000-0000-0000-0000-000000000000 cannot alter member " + s.coOwner.ID.String(),
+		},
+		{
+			desc:          "HandOverAboveIssuer",
+			issuer:        s.admin,
+			source:        s.coOwner,
+			target:        s.basic,
+			expectedError: "Forbidden action. Reason: User 00000000-0000-0000-0000-000000000000 cannot grant member " + s.basic.ID.String() + " role co-owner",
+		},
+		{
+			desc:           "HandOverCopy",
+			issuer:         s.coOwner,
+			source:         models.Member{ID: s.admin.ID, Admin: true, Adding: true, Nickname: "mod"},
+			target:         models.Member{ID: s.basic.ID, DeletingMessages: true, Nickname: "new"},
+			expectedSource: models.Member{ID: s.admin.ID, Admin: true, Adding: true, Nickname: "mod"},
+			expectedTarget: models.Member{ID: s.basic.ID, Admin: true, Adding: true, Nickname: "new"},
+		},
+		{
+			desc:           "HandOverCopyAndClear",
+			issuer:         s.coOwner,
+			source:         models.Member{ID: s.admin.ID, Admin: true, Adding: true, Nickname: "mod"},
+			target:         models.Member{ID: s.basic.ID, User: models.User{UserName: "successor"}},
+			handover:       models.MemberHandover{Nickname: true, ClearSource: true},
+			expectedSource: models.Member{ID: s.admin.ID},
+			expectedTarget: models.Member{ID: s.basic.ID, User: models.User{UserName: "successor"}, Admin: true, Adding: true, Nickname: "mod", DisplayName: "mod"},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+			source, target := tC.source, tC.target
+			err := tC.issuer.HandOver(&source, &target, tC.handover)
+			if tC.expectedError != "" {
+				s.EqualError(err, tC.expectedError)
+				return
+			}
+			s.NoError(err)
+			s.Equal(tC.expectedSource, source)
+			s.Equal(tC.expectedTarget, target)
+		})
+	}
+}
+
+func (s *MemberTestSuite) TestPermissions() {
+	testCases := []struct {
+		desc     string
+		member   models.Member
+		expected map[string]bool
+	}{
+		{
+			desc:   "PermissionsBasic",
+			member: s.basic,
+			expected: map[string]bool{
+				models.CAN_INVITE: false, models.CAN_REMOVE_MEMBERS: false, models.CAN_EDIT_MEMBERS: false, models.CAN_EDIT_GROUP: false,
+				models.CAN_SET_ANNOUNCEMENT: false, models.CAN_DELETE_GROUP: false, models.CAN_EXPORT_GROUP: false,
+			},
+		},
+		{
+			desc:   "PermissionsAdding",
+			member: models.Member{ID: uuid.New(), Adding: true},
+			expected: map[string]bool{
+				models.CAN_INVITE: true, models.CAN_REMOVE_MEMBERS: false, models.CAN_EDIT_MEMBERS: false, models.CAN_EDIT_GROUP: false,
+				models.CAN_SET_ANNOUNCEMENT: false, models.CAN_DELETE_GROUP: false, models.CAN_EXPORT_GROUP: false,
+			},
+		},
+		{
+			desc:   "PermissionsDeleter",
+			member: s.deleter,
+			expected: map[string]bool{
+				models.CAN_INVITE: false, models.CAN_REMOVE_MEMBERS: true, models.CAN_EDIT_MEMBERS: false, models.CAN_EDIT_GROUP: false,
+				models.CAN_SET_ANNOUNCEMENT: false, models.CAN_DELETE_GROUP: false, models.CAN_EXPORT_GROUP: false,
+			},
+		},
+		{
+			desc:   "PermissionsAdmin",
+			member: s.admin,
+			expected: map[string]bool{
+				models.CAN_INVITE: true, models.CAN_REMOVE_MEMBERS: true, models.CAN_EDIT_MEMBERS: true, models.CAN_EDIT_GROUP: true,
+				models.CAN_SET_ANNOUNCEMENT: false, models.CAN_DELETE_GROUP: false, models.CAN_EXPORT_GROUP: false,
+			},
+		},
+		{
+			desc:   "PermissionsCoOwner",
+			member: s.coOwner,
+			expected: map[string]bool{
+				models.CAN_INVITE: true, models.CAN_REMOVE_MEMBERS: true, models.CAN_EDIT_MEMBERS: true, models.CAN_EDIT_GROUP: true,
+				models.CAN_SET_ANNOUNCEMENT: true, models.CAN_DELETE_GROUP: false, models.CAN_EXPORT_GROUP: true,
+			},
+		},
+		{
+			desc:   "PermissionsCreator",
+			member: s.creator,
+			expected: map[string]bool{
+				models.CAN_INVITE: true, models.CAN_REMOVE_MEMBERS: true, models.CAN_EDIT_MEMBERS: true, models.CAN_EDIT_GROUP: true,
+				models.CAN_SET_ANNOUNCEMENT: true, models.CAN_DELETE_GROUP: true, models.CAN_EXPORT_GROUP: true,
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+			s.Equal(tC.expected, tC.member.Permissions())
+		})
+	}
+}
+
 func TestMembers(t *testing.T) {
 	suite.Run(t, &MemberTestSuite{})
 }
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const MAX_NICKNAME_LENGTH = 32
+const MAX_GROUP_NAME_LENGTH = 100
+
+var errInvalidCharacters = errors.New("contains control or invisible characters")
+
+// TextField describes a free text field users can set together with its length limit, every text field
+// is checked with the same rules so limits aren't scattered across handlers
+type TextField struct {
+	// Name is a name of the field in requests
+	Name string
+	// Label is a name of the field used in error messages
+	Label     string
+	MaxLength int
+}
+
+var (
+	NicknameField  = TextField{Name: "nickname", Label: "nickname", MaxLength: MAX_NICKNAME_LENGTH}
+	GroupNameField = TextField{Name: "name", Label: "group name", MaxLength: MAX_GROUP_NAME_LENGTH}
+)
+
+// TextTooLongError is returned when value of a text field has more characters than field's limit
+type TextTooLongError struct {
+	Field TextField
+}
+
+func (e *TextTooLongError) Error() string {
+	return fmt.Sprintf("%s cannot be longer than %d characters", e.Field.Label, e.Field.MaxLength)
+}
+
+// Check returns an error when already normalized value is too long or contains characters
+// that can't be displayed safely
+func (f TextField) Check(value string) error {
+	if utf8.RuneCountInString(value) > f.MaxLength {
+		return &TextTooLongError{Field: f}
+	}
+	if err := checkCharacters(value); err != nil {
+		return fmt.Errorf("%s %v", f.Label, err)
+	}
+	return nil
+}
+
+// NormalizeNickname trims nickname and checks whether it can be displayed safely.
+// Empty nickname is valid and means that nickname is reset
+func NormalizeNickname(nickname string) (string, error) {
+	nickname = normalize(nickname)
+	if err := NicknameField.Check(nickname); err != nil {
+		return "", err
+	}
+	return nickname, nil
+}
+
+// NormalizeGroupName trims group name, converts it to NFC form and checks whether it can be displayed safely
+func NormalizeGroupName(name string) (string, error) {
+	name = normalize(name)
+	if name == "" {
+		return "", errors.New("group name cannot be empty")
+	}
+	if err := GroupNameField.Check(name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// normalize trims whitespace and composes characters so that visually identical names are stored
+// the same way and their length is counted in characters users see
+func normalize(s string) string {
+	return norm.NFC.String(strings.TrimSpace(s))
+}
+
+// checkCharacters rejects control characters and invisible formatting characters like zero-width space
+// which can be used to impersonate other names
+func checkCharacters(s string) error {
+	for _, r := range s {
+		if unicode.IsControl(r) || unicode.Is(unicode.Cf, r) {
+			return errInvalidCharacters
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package models_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Slimo300/chat-groupservice/internal/models"
+	"github.com/stretchr/testify/suite"
+)
+
+type NamesTestSuite struct {
+	suite.Suite
+}
+
+func (s *NamesTestSuite) TestNormalizeNickname() {
+	testCases := []struct {
+		desc     string
+		nickname string
+		expected string
+		err      bool
+	}{
+		{desc: "NicknameTrimmed", nickname: "  nick  ", expected: "nick"},
+		{desc: "NicknameEmpty", nickname: "   ", expected: ""},
+		{desc: "NicknameUnicode", nickname: "Żółw", expected: "Żółw"},
+		{desc: "NicknameMaxLength", nickname: strings.Repeat("ż", models.MAX_NICKNAME_LENGTH), expected: strings.Repeat("ż", models.MAX_NICKNAME_LENGTH)},
+		{desc: "NicknameTooLong", nickname: strings.Repeat("a", models.MAX_NICKNAME_LENGTH+1), err: true},
+		{desc: "NicknameControlCharacter", nickname: "ni\x07ck", err: true},
+		{desc: "NicknameZeroWidth", nickname: "ni\u200bck", err: true},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+			nickname, err := models.NormalizeNickname(tC.nickname)
+			if tC.err {
+				s.Error(err)
+				return
+			}
+			s.NoError(err)
+			s.Equal(tC.expected, nickname)
+		})
+	}
+}
+
+func (s *NamesTestSuite) TestNormalizeGroupName() {
+	testCases := []struct {
+		desc     string
+		name     string
+		expected string
+		err      string
+	}{
+		{desc: "GroupNameTrimmed", name: "\t My Group \n", expected: "My Group"},
+		{desc: "GroupNameNFC", name: "Cafe\u0301", expected: "Caf\u00e9"},
+		{desc: "GroupNameMaxLength", name: strings.Repeat("ż", models.MAX_GROUP_NAME_LENGTH), expected: strings.Repeat("ż", models.MAX_GROUP_NAME_LENGTH)},
+		{desc: "GroupNameWhitespaceOnly", name: " \t\n ", err: "group name cannot be empty"},
+		{desc: "GroupNameTooLong", name: strings.Repeat("a", models.MAX_GROUP_NAME_LENGTH+1), err: "group name cannot be longer than 100 characters"},
+		{desc: "GroupNameZeroWidth", name: "\u200bMy\u200dGroup\ufeff", err: "group name contains control or invisible characters"},
+		{desc: "GroupNameControlCharacter", name: "My\x00Group", err: "group name contains control or invisible characters"},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+			name, err := models.NormalizeGroupName(tC.name)
+			if tC.err != "" {
+				s.EqualError(err, tC.err)
+				return
+			}
+			s.NoError(err)
+			s.Equal(tC.expected, name)
+		})
+	}
+}
+
+func (s *NamesTestSuite) TestTextFieldLimits() {
+	for _, field := range []models.TextField{models.NicknameField, models.GroupNameField} {
+		s.Run(field.Name, func() {
+			s.NoError(field.Check(strings.Repeat("ż", field.MaxLength)))
+
+			err := field.Check(strings.Repeat("ż", field.MaxLength+1))
+			var tooLong *models.TextTooLongError
+			if s.ErrorAs(err, &tooLong) {
+				s.Equal(field, tooLong.Field)
+			}
+		})
+	}
+}
+
+func TestNames(t *testing.T) {
+	suite.Run(t, &NamesTestSuite{})
+}
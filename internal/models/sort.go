@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Sort is an order of a list chosen by client with "sort" query parameter
+type Sort string
+
+const (
+	SORT_NAME_ASC      Sort = "name_asc"
+	SORT_NAME_DESC     Sort = "name_desc"
+	SORT_CREATED_ASC   Sort = "created_asc"
+	SORT_CREATED_DESC  Sort = "created_desc"
+	SORT_UPDATED_DESC  Sort = "updated_desc"
+	SORT_JOINED_ASC    Sort = "joined_asc"
+	SORT_JOINED_DESC   Sort = "joined_desc"
+	SORT_ACTIVITY_DESC Sort = "activity_desc"
+)
+
+// ByName tells whether list is sorted by a name rather than by a time
+func (s Sort) ByName() bool {
+	return s == SORT_NAME_ASC || s == SORT_NAME_DESC
+}
+
+// Cursor is a position in a sorted list, next page starts after the row with given sort value and ID. Name holds
+// the value of sorts by name and Time of the other ones, nil Time means row was sorted by a time that isn't set
+type Cursor struct {
+	Sort Sort       `json:"s"`
+	Name string     `json:"n,omitempty"`
+	Time *time.Time `json:"t,omitempty"`
+	ID   uuid.UUID  `json:"id"`
+}
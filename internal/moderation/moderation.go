@@ -0,0 +1,61 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Verdict is the result of screening a single image
+type Verdict struct {
+	Flagged bool   `json:"flagged"`
+	Reason  string `json:"reason"`
+}
+
+// Moderator screens user uploaded images before they are shown to other users
+type Moderator interface {
+	Check(ctx context.Context, image io.Reader, contentType string) (Verdict, error)
+}
+
+// HTTPModerator sends images to an external classifier as POST requests with raw image as a body,
+// classifier answers with JSON encoded Verdict
+type HTTPModerator struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewHTTPModerator creates moderator using classifier at url, requests taking longer than timeout are cancelled
+func NewHTTPModerator(url string, timeout time.Duration) *HTTPModerator {
+	return &HTTPModerator{
+		URL:    url,
+		Client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Check asks classifier about image, error is returned when classifier couldn't be reached or gave no verdict
+func (m *HTTPModerator) Check(ctx context.Context, image io.Reader, contentType string) (Verdict, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.URL, image)
+	if err != nil {
+		return Verdict{}, err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := m.Client.Do(req)
+	if err != nil {
+		return Verdict{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Verdict{}, fmt.Errorf("moderation endpoint responded with status %d", resp.StatusCode)
+	}
+
+	var verdict Verdict
+	if err := json.NewDecoder(resp.Body).Decode(&verdict); err != nil {
+		return Verdict{}, fmt.Errorf("invalid moderation response: %w", err)
+	}
+	return verdict, nil
+}
@@ -0,0 +1,100 @@
+package moderation_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Slimo300/chat-groupservice/internal/moderation"
+	"github.com/stretchr/testify/suite"
+)
+
+type ModerationTestSuite struct {
+	suite.Suite
+}
+
+func (s *ModerationTestSuite) TestCheck() {
+	testCases := []struct {
+		desc            string
+		status          int
+		body            string
+		delay           time.Duration
+		expectedVerdict moderation.Verdict
+		expectError     bool
+	}{
+		{
+			desc:            "ModerationApproved",
+			status:          http.StatusOK,
+			body:            `{"flagged": false}`,
+			expectedVerdict: moderation.Verdict{},
+		},
+		{
+			desc:            "ModerationFlagged",
+			status:          http.StatusOK,
+			body:            `{"flagged": true, "reason": "nudity"}`,
+			expectedVerdict: moderation.Verdict{Flagged: true, Reason: "nudity"},
+		},
+		{
+			desc:        "ModerationServerError",
+			status:      http.StatusInternalServerError,
+			expectError: true,
+		},
+		{
+			desc:        "ModerationInvalidResponse",
+			status:      http.StatusOK,
+			body:        "ok",
+			expectError: true,
+		},
+		{
+			desc:        "ModerationTimeout",
+			status:      http.StatusOK,
+			body:        `{"flagged": false}`,
+			delay:       200 * time.Millisecond,
+			expectError: true,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+			var received []byte
+			var contentType string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received, _ = io.ReadAll(r.Body)
+				contentType = r.Header.Get("Content-Type")
+				time.Sleep(tC.delay)
+				w.WriteHeader(tC.status)
+				_, _ = w.Write([]byte(tC.body))
+			}))
+			defer server.Close()
+
+			moderator := moderation.NewHTTPModerator(server.URL, 100*time.Millisecond)
+			verdict, err := moderator.Check(context.Background(), bytes.NewReader([]byte("image")), "image/png")
+			if tC.expectError {
+				s.Error(err)
+				return
+			}
+			s.NoError(err)
+			s.Equal(tC.expectedVerdict, verdict)
+			s.Equal([]byte("image"), received)
+			s.Equal("image/png", contentType)
+		})
+	}
+}
+
+func (s *ModerationTestSuite) TestCheckEndpointDown() {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	moderator := moderation.NewHTTPModerator(url, time.Second)
+	_, err := moderator.Check(context.Background(), bytes.NewReader([]byte("image")), "image/png")
+	s.Error(err)
+}
+
+func TestModeration(t *testing.T) {
+	suite.Run(t, &ModerationTestSuite{})
+}
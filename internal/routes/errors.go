@@ -0,0 +1,37 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/Slimo300/MicroservicesChatApp/backend/lib/apperrors"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestSizeLimiter rejects requests with body larger than limit with 413. Bodies without declared
+// length are cut at limit so binding them fails
+func RequestSizeLimiter(limit int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > limit {
+			c.Header("Connection", "close")
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"err": apperrors.NewPayloadTooLarge(limit, c.Request.ContentLength).Error()})
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		c.Next()
+	}
+}
+
+// Recovery responds with 500 in the same shape as other errors when handler panics
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecovery(func(c *gin.Context, _ any) {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"err": apperrors.NewInternal().Error()})
+	})
+}
+
+func noRoute(c *gin.Context) {
+	c.JSON(http.StatusNotFound, gin.H{"err": "resource not found"})
+}
+
+func noMethod(c *gin.Context) {
+	c.JSON(http.StatusMethodNotAllowed, gin.H{"err": "method not allowed"})
+}
@@ -0,0 +1,110 @@
+package routes_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Slimo300/chat-groupservice/internal/handlers"
+	"github.com/Slimo300/chat-groupservice/internal/routes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type ErrorsTestSuite struct {
+	suite.Suite
+}
+
+func (s *ErrorsTestSuite) TestJSONErrors() {
+	gin.SetMode(gin.TestMode)
+
+	limited := gin.New()
+	limited.Use(routes.Recovery(), routes.RequestSizeLimiter(8))
+	limited.POST("/upload", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "success"})
+	})
+	limited.GET("/panic", func(c *gin.Context) {
+		panic("handler failed")
+	})
+
+	testCases := []struct {
+		desc               string
+		engine             *gin.Engine
+		method             string
+		path               string
+		body               string
+		expectedStatusCode int
+		expectedResponse   gin.H
+	}{
+		{
+			desc:               "ErrorsNoRoute",
+			engine:             routes.Setup(handlers.NewServer(nil, nil, nil, nil), "*"),
+			method:             http.MethodGet,
+			path:               "/unknown",
+			expectedStatusCode: http.StatusNotFound,
+			expectedResponse:   gin.H{"err": "resource not found"},
+		},
+		{
+			desc:               "ErrorsNoMethod",
+			engine:             routes.Setup(handlers.NewServer(nil, nil, nil, nil), "*"),
+			method:             http.MethodDelete,
+			path:               "/metrics",
+			expectedStatusCode: http.StatusMethodNotAllowed,
+			expectedResponse:   gin.H{"err": "method not allowed"},
+		},
+		{
+			desc:               "ErrorsBodyTooLarge",
+			engine:             limited,
+			method:             http.MethodPost,
+			path:               "/upload",
+			body:               "0123456789",
+			expectedStatusCode: http.StatusRequestEntityTooLarge,
+			expectedResponse:   gin.H{"err": "Max payload size of 8 exceeded. Actual payload size: 10"},
+		},
+		{
+			desc:               "ErrorsPanic",
+			engine:             limited,
+			method:             http.MethodGet,
+			path:               "/panic",
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedResponse:   gin.H{"err": "Internal server error."},
+		},
+		{
+			// engine keeps serving requests after a handler panicked
+			desc:               "ErrorsAfterPanic",
+			engine:             limited,
+			method:             http.MethodPost,
+			path:               "/upload",
+			body:               "ok",
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   gin.H{"message": "success"},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+			req, _ := http.NewRequest(tC.method, tC.path, strings.NewReader(tC.body))
+
+			w := httptest.NewRecorder()
+			tC.engine.ServeHTTP(w, req)
+			response := w.Result()
+			defer response.Body.Close()
+
+			s.Equal(tC.expectedStatusCode, response.StatusCode)
+			s.Equal("application/json; charset=utf-8", response.Header.Get("Content-Type"))
+
+			var msg gin.H
+			if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+				s.Fail(err.Error())
+			}
+
+			s.Equal(tC.expectedResponse, msg)
+		})
+	}
+}
+
+func TestErrors(t *testing.T) {
+	suite.Run(t, new(ErrorsTestSuite))
+}
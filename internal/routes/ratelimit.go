@@ -0,0 +1,102 @@
+package routes
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RATE_LIMIT_SWEEP_INTERVAL is how often buckets of clients that stopped sending requests are dropped
+const RATE_LIMIT_SWEEP_INTERVAL = time.Minute
+
+// IPRateLimiter sheds traffic of clients sending more than rate requests per second on average with up to
+// burst requests at once, excess requests get 429 with Retry-After. Clients are identified by gin's ClientIP
+// so X-Forwarded-For is honored only from trusted proxies. Requests from exempt networks aren't limited
+func IPRateLimiter(rate float64, burst int, exempt []*net.IPNet) gin.HandlerFunc {
+	limiter := newIPLimiter(rate, burst)
+	return func(c *gin.Context) {
+		ip := c.ClientIP()
+		if isExempt(net.ParseIP(ip), exempt) {
+			c.Next()
+			return
+		}
+		if ok, retryAfter := limiter.allow(ip, time.Now()); !ok {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"err": "too many requests"})
+			return
+		}
+		c.Next()
+	}
+}
+
+func isExempt(ip net.IP, exempt []*net.IPNet) bool {
+	if ip == nil {
+		return false
+	}
+	for _, network := range exempt {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ipLimiter keeps a token bucket for every client
+type ipLimiter struct {
+	mu        sync.Mutex
+	rate      float64
+	burst     float64
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newIPLimiter(rate float64, burst int) *ipLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &ipLimiter{rate: rate, burst: float64(burst), buckets: make(map[string]*tokenBucket)}
+}
+
+// allow takes a token from client's bucket, when bucket is empty it returns how long until a token is available
+func (l *ipLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) >= RATE_LIMIT_SWEEP_INTERVAL {
+		l.sweep(now)
+	}
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = bucket
+	}
+	bucket.tokens = math.Min(l.burst, bucket.tokens+now.Sub(bucket.last).Seconds()*l.rate)
+	bucket.last = now
+
+	if bucket.tokens < 1 {
+		return false, time.Duration((1 - bucket.tokens) / l.rate * float64(time.Second))
+	}
+	bucket.tokens--
+	return true, 0
+}
+
+// sweep drops buckets which refilled completely, such clients start with a full bucket anyway
+func (l *ipLimiter) sweep(now time.Time) {
+	for key, bucket := range l.buckets {
+		if bucket.tokens+now.Sub(bucket.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
@@ -0,0 +1,110 @@
+package routes_test
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Slimo300/chat-groupservice/internal/handlers"
+	"github.com/Slimo300/chat-groupservice/internal/routes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type RateLimitTestSuite struct {
+	suite.Suite
+}
+
+// newEngine sets up service allowing bursts of two requests, the rate is so low that no request is
+// allowed again during a test
+func (s *RateLimitTestSuite) newEngine() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	_, internal, _ := net.ParseCIDR("10.0.0.0/8")
+	_, proxy, _ := net.ParseCIDR("192.0.2.1/32")
+
+	server := handlers.NewServer(nil, nil, nil, nil)
+	server.IPRateLimit = 0.01
+	server.IPRateBurst = 2
+	server.RateLimitExempt = []*net.IPNet{internal}
+	server.TrustedProxies = []*net.IPNet{proxy}
+	return routes.Setup(server, "*")
+}
+
+func (s *RateLimitTestSuite) get(engine *gin.Engine, remoteAddr, forwardedFor string) *http.Response {
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.RemoteAddr = remoteAddr
+	if forwardedFor != "" {
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w.Result()
+}
+
+func (s *RateLimitTestSuite) TestLimitedClient() {
+	engine := s.newEngine()
+
+	s.Equal(http.StatusOK, s.get(engine, "203.0.113.7:4000", "").StatusCode)
+	s.Equal(http.StatusOK, s.get(engine, "203.0.113.7:4001", "").StatusCode)
+
+	response := s.get(engine, "203.0.113.7:4002", "")
+	s.Equal(http.StatusTooManyRequests, response.StatusCode)
+	s.Equal("100", response.Header.Get("Retry-After"))
+
+	// other clients have their own buckets
+	s.Equal(http.StatusOK, s.get(engine, "203.0.113.8:4000", "").StatusCode)
+}
+
+func (s *RateLimitTestSuite) TestForwardedClient() {
+	engine := s.newEngine()
+
+	// clients behind trusted proxy are told apart by X-Forwarded-For
+	s.Equal(http.StatusOK, s.get(engine, "192.0.2.1:4000", "198.51.100.1").StatusCode)
+	s.Equal(http.StatusOK, s.get(engine, "192.0.2.1:4000", "198.51.100.1").StatusCode)
+	s.Equal(http.StatusTooManyRequests, s.get(engine, "192.0.2.1:4000", "198.51.100.1").StatusCode)
+	s.Equal(http.StatusOK, s.get(engine, "192.0.2.1:4000", "198.51.100.2").StatusCode)
+
+	// header from untrusted client is ignored so it can't claim to be exempt
+	s.Equal(http.StatusOK, s.get(engine, "203.0.113.7:4000", "10.0.0.1").StatusCode)
+	s.Equal(http.StatusOK, s.get(engine, "203.0.113.7:4000", "10.0.0.1").StatusCode)
+	s.Equal(http.StatusTooManyRequests, s.get(engine, "203.0.113.7:4000", "10.0.0.1").StatusCode)
+}
+
+func (s *RateLimitTestSuite) TestNoTrustedProxies() {
+	gin.SetMode(gin.TestMode)
+
+	_, internal, _ := net.ParseCIDR("10.0.0.0/8")
+	server := handlers.NewServer(nil, nil, nil, nil)
+	server.IPRateLimit = 0.01
+	server.IPRateBurst = 2
+	server.RateLimitExempt = []*net.IPNet{internal}
+	engine := routes.Setup(server, "*")
+
+	// forged header neither gives client a new identity nor makes it exempt
+	s.Equal(http.StatusOK, s.get(engine, "203.0.113.7:4000", "198.51.100.1").StatusCode)
+	s.Equal(http.StatusOK, s.get(engine, "203.0.113.7:4000", "198.51.100.2").StatusCode)
+	s.Equal(http.StatusTooManyRequests, s.get(engine, "203.0.113.7:4000", "10.0.0.1").StatusCode)
+}
+
+func (s *RateLimitTestSuite) TestExemptClient() {
+	engine := s.newEngine()
+
+	for i := 0; i < 10; i++ {
+		s.Equal(http.StatusOK, s.get(engine, "10.1.2.3:4000", "").StatusCode)
+	}
+}
+
+func (s *RateLimitTestSuite) TestDisabled() {
+	gin.SetMode(gin.TestMode)
+	engine := routes.Setup(handlers.NewServer(nil, nil, nil, nil), "*")
+
+	for i := 0; i < 10; i++ {
+		s.Equal(http.StatusOK, s.get(engine, "203.0.113.7:4000", "").StatusCode)
+	}
+}
+
+func TestRateLimitSuite(t *testing.T) {
+	suite.Run(t, &RateLimitTestSuite{})
+}
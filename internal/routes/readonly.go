@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readOnlyAllowed lists routes with methods other than GET, HEAD and OPTIONS that are served in read-only mode.
+// They either don't change state of groups or are needed by operators during incidents read-only mode is meant for.
+// Replaying events isn't one of them, since processing them writes to the database
+var readOnlyAllowed = map[string]bool{
+	http.MethodPost + " /internal/groups/exist":  true,
+	http.MethodPost + " /internal/events/pause":  true,
+	http.MethodPost + " /internal/events/resume": true,
+}
+
+// ReadOnlyMiddleware rejects all requests that may change state with 503 when readOnly is set,
+// only GET, HEAD and OPTIONS requests and routes in readOnlyAllowed are let through
+func ReadOnlyMiddleware(readOnly bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			if readOnly && !readOnlyAllowed[c.Request.Method+" "+c.FullPath()] {
+				c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"err": "service is in maintenance mode, only reads are allowed"})
+				return
+			}
+		}
+		c.Next()
+	}
+}
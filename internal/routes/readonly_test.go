@@ -0,0 +1,104 @@
+package routes_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Slimo300/chat-groupservice/internal/handlers"
+	"github.com/Slimo300/chat-groupservice/internal/routes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type ReadOnlyTestSuite struct {
+	suite.Suite
+	engine *gin.Engine
+}
+
+func (s *ReadOnlyTestSuite) SetupSuite() {
+	gin.SetMode(gin.TestMode)
+
+	server := handlers.NewServer(nil, nil, nil, nil)
+	server.ReadOnly = true
+	s.engine = routes.Setup(server, "*")
+}
+
+func (s *ReadOnlyTestSuite) TestReadOnly() {
+	testCases := []struct {
+		desc               string
+		method             string
+		path               string
+		expectedStatusCode int
+		expectedResponse   gin.H
+	}{
+		{
+			desc:               "ReadOnlyWriteBlocked",
+			method:             http.MethodPost,
+			path:               "/groups/group",
+			expectedStatusCode: http.StatusServiceUnavailable,
+			expectedResponse:   gin.H{"err": "service is in maintenance mode, only reads are allowed"},
+		},
+		{
+			desc:               "ReadOnlyInternalWriteBlocked",
+			method:             http.MethodPost,
+			path:               "/internal/users/merge",
+			expectedStatusCode: http.StatusServiceUnavailable,
+			expectedResponse:   gin.H{"err": "service is in maintenance mode, only reads are allowed"},
+		},
+		{
+			// request reaches internal key check instead of being rejected for read-only mode
+			desc:               "ReadOnlyNonMutatingPostAllowed",
+			method:             http.MethodPost,
+			path:               "/internal/groups/exist",
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedResponse:   gin.H{"err": "invalid internal key"},
+		},
+		{
+			desc:               "ReadOnlyConsumerControlAllowed",
+			method:             http.MethodPost,
+			path:               "/internal/events/pause",
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedResponse:   gin.H{"err": "invalid internal key"},
+		},
+		{
+			desc:               "ReadOnlyReplayBlocked",
+			method:             http.MethodPost,
+			path:               "/internal/events/replay",
+			expectedStatusCode: http.StatusServiceUnavailable,
+			expectedResponse:   gin.H{"err": "service is in maintenance mode, only reads are allowed"},
+		},
+		{
+			desc:               "ReadOnlyReadAllowed",
+			method:             http.MethodGet,
+			path:               "/health",
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   gin.H{"status": "ok", "readOnly": true},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+			req, _ := http.NewRequest(tC.method, tC.path, nil)
+
+			w := httptest.NewRecorder()
+			s.engine.ServeHTTP(w, req)
+			response := w.Result()
+			defer response.Body.Close()
+
+			s.Equal(tC.expectedStatusCode, response.StatusCode)
+
+			var msg gin.H
+			if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+				s.Fail(err.Error())
+			}
+
+			s.Equal(tC.expectedResponse, msg)
+		})
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	suite.Run(t, new(ReadOnlyTestSuite))
+}
@@ -2,34 +2,100 @@ package routes
 
 import (
 	"github.com/Slimo300/chat-groupservice/internal/handlers"
+	"github.com/Slimo300/chat-groupservice/internal/metrics"
 	tokens "github.com/Slimo300/chat-tokenservice/pkg/client"
-	limits "github.com/gin-contrib/size"
 	"github.com/gin-gonic/gin"
 )
 
 func Setup(server *handlers.Server, origin string) *gin.Engine {
 
-	engine := gin.Default()
+	engine := gin.New()
+	engine.HandleMethodNotAllowed = true
+	engine.NoRoute(noRoute)
+	engine.NoMethod(noMethod)
 
-	engine.Use(CORSMiddleware(origin))
+	// gin trusts every proxy by default, clients would be identified by X-Forwarded-For set by themselves
+	var proxies []string
+	for _, network := range server.TrustedProxies {
+		proxies = append(proxies, network.String())
+	}
+	// networks were already validated so this can't fail
+	_ = engine.SetTrustedProxies(proxies)
+
+	engine.Use(gin.Logger(), Recovery())
+	// abusive clients are cut off before their requests reach any handler
+	if server.IPRateLimit > 0 {
+		engine.Use(IPRateLimiter(server.IPRateLimit, server.IPRateBurst, server.RateLimitExempt))
+	}
+	engine.Use(CORSMiddleware(origin), ReadOnlyMiddleware(server.ReadOnly), TimeFormatMiddleware())
+
+	engine.GET("/metrics", gin.WrapH(metrics.Handler()))
+	engine.GET("/health", server.Health)
 
 	api := engine.Group("/groups")
-	api.Use(limits.RequestSizeLimiter(server.MaxBodyBytes))
-	apiAuth := api.Use(tokens.MustAuth(server.TokenClient))
+	api.Use(RequestSizeLimiter(server.MaxBodyBytes))
+	api.Use(tokens.MustAuth(server.TokenClient))
+
+	// streaming endpoints aren't covered by request timeout as their responses can't be buffered
+	api.GET("/group/:groupID/export", server.ExportGroup)
+	api.GET("/group/:groupID/image", server.GetGroupAvatar)
+
+	apiAuth := api.Group("", TimeoutMiddleware(server.RequestTimeout))
 
 	apiAuth.GET("/group", server.GetUserGroups)
 	apiAuth.POST("/group", server.CreateGroup)
+	apiAuth.GET("/groups/count", server.GetActiveGroupCount)
+	apiAuth.GET("/shared/:userID", server.GetSharedGroups)
+	apiAuth.GET("/group/:groupID", server.GetGroupWithMembership)
 	apiAuth.DELETE("/group/:groupID", server.DeleteGroup)
+	apiAuth.GET("/group/:groupID/stats", server.GetGroupStats)
+	apiAuth.GET("/group/:groupID/card", server.GetGroupCard)
+	apiAuth.GET("/group/:groupID/settings", server.GetGroupSettings)
+	apiAuth.PATCH("/group/:groupID/settings", server.UpdateGroupSettings)
 
 	apiAuth.POST("/group/:groupID/image", server.SetGroupProfilePicture)
+	apiAuth.POST("/group/:groupID/image/url", server.SetGroupPictureFromURL)
 	apiAuth.DELETE("/group/:groupID/image", server.DeleteGroupProfilePicture)
 
+	apiAuth.GET("/group/:groupID/membership/:userID", server.GetMembership)
+	apiAuth.GET("/memberships", server.GetMyMembershipsForGroups)
+	apiAuth.GET("/memberships/capabilities", server.GetMyRolesAndCapabilities)
+	apiAuth.GET("/group/:groupID/memberships", server.GetMembershipsForUsers)
+	apiAuth.GET("/group/:groupID/permissions", server.GetMyPermissions)
+	apiAuth.GET("/group/:groupID/members/recent", server.GetRecentMembers)
 	apiAuth.DELETE("/group/:groupID/member/:memberID", server.DeleteUserFromGroup)
 	apiAuth.PATCH("/group/:groupID/member/:memberID", server.GrantPriv)
+	apiAuth.PUT("/group/:groupID/member/:memberID/nickname", server.SetGroupNickname)
+	apiAuth.POST("/group/:groupID/member/:memberID/handover", server.HandoverMemberConfig)
+	apiAuth.POST("/group/:groupID/stepdown", server.StepDown)
+	apiAuth.GET("/group/:groupID/roles", server.GetGroupRoleCounts)
+	apiAuth.POST("/group/:groupID/roles", server.BulkChangeMemberRoles)
 
 	apiAuth.GET("/invites", server.GetUserInvites)
 	apiAuth.POST("/invites", server.CreateInvite)
+	apiAuth.GET("/invites/sent", server.GetSentInvites)
+	apiAuth.GET("/invites/:inviteID", server.GetInviteStatus)
 	apiAuth.PUT("/invites/:inviteID", server.RespondGroupInvite)
+	apiAuth.POST("/invites/accept", server.BulkAcceptInvites)
+
+	// internal routes can be called only by other services, either with a token carrying internal scope
+	// or with internal key, user tokens are rejected
+	internal := engine.Group("/internal")
+	internal.Use(MustScope(server.TokenKeys, INTERNAL_SCOPE), server.MustInternalKey())
+
+	internal.GET("/group/:groupID/members/stream", server.StreamGroupMembers)
+
+	internal = internal.Group("", TimeoutMiddleware(server.RequestTimeout))
+
+	internal.GET("/group/:groupID/membership/:userID", server.CheckMembership)
+	internal.GET("/groups/modified", server.GetGroupsModifiedSince)
+	internal.POST("/groups/exist", server.CheckGroupsExist)
+	internal.POST("/groups/bulk", server.BulkCreateGroups)
+	internal.GET("/groups/metrics", RequireScope(server.TokenKeys, ADMIN_SCOPE), server.AdminGroupMetrics)
+	internal.POST("/events/replay", server.ReplayEvents)
+	internal.POST("/events/pause", server.PauseConsumer)
+	internal.POST("/events/resume", server.ResumeConsumer)
+	internal.POST("/users/merge", server.MergeUserMemberships)
 
 	return engine
 }
@@ -0,0 +1,170 @@
+package routes
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Slimo300/chat-groupservice/internal/handlers"
+	"github.com/gin-gonic/gin"
+)
+
+// INTERNAL_SCOPE is the scope tokens of other services must carry to call internal endpoints
+const INTERNAL_SCOPE = "internal:groupservice"
+
+// ADMIN_SCOPE is additionally required from tokens calling internal endpoints meant for platform admins
+const ADMIN_SCOPE = "admin:groupservice"
+
+// TOKEN_AUDIENCE must be among audiences of tokens checked for scope, tokens issued for other services are refused
+const TOKEN_AUDIENCE = "groupservice"
+
+var (
+	errMalformedToken = errors.New("malformed token")
+	errInvalidToken   = errors.New("invalid token")
+	errExpiredToken   = errors.New("token expired")
+	errWrongAudience  = errors.New("token not meant for this service")
+	errMissingToken   = errors.New("bearer token required")
+)
+
+// audience is aud claim which may be a single string or a list of them
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+func (a audience) contains(aud string) bool {
+	for _, granted := range a {
+		if granted == aud {
+			return true
+		}
+	}
+	return false
+}
+
+// serviceClaims are claims of a token checked for scope, user tokens don't have scope claim
+type serviceClaims struct {
+	Subject   string   `json:"sub"`
+	Audience  audience `json:"aud"`
+	Scope     string   `json:"scope"`
+	ExpiresAt int64    `json:"exp"`
+}
+
+// hasScope tells whether space separated scope claim contains given scope
+func (c serviceClaims) hasScope(scope string) bool {
+	for _, granted := range strings.Fields(c.Scope) {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// MustScope lets through requests with a bearer token carrying given scope and sets token's subject as
+// "serviceID". Valid tokens without the scope, which includes every user token, get 403. Requests without
+// bearer token are passed on so that services still authenticating with internal key keep working
+func MustScope(keys handlers.PublicKeyProvider, scope string) gin.HandlerFunc {
+	return checkScope(keys, scope, false)
+}
+
+// RequireScope works like MustScope except that requests without bearer token get 401, it guards endpoints
+// which internal key alone doesn't give access to
+func RequireScope(keys handlers.PublicKeyProvider, scope string) gin.HandlerFunc {
+	return checkScope(keys, scope, true)
+}
+
+func checkScope(keys handlers.PublicKeyProvider, scope string, tokenRequired bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		if !strings.HasPrefix(header, "Bearer ") {
+			if tokenRequired {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"err": errMissingToken.Error()})
+				return
+			}
+			c.Next()
+			return
+		}
+		token := strings.TrimPrefix(header, "Bearer ")
+		if keys == nil || keys.GetPublicKey() == nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"err": "tokens can't be verified"})
+			return
+		}
+
+		claims, err := parseToken(token, keys.GetPublicKey())
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"err": err.Error()})
+			return
+		}
+		if !claims.hasScope(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"err": "token lacks required scope " + scope})
+			return
+		}
+
+		c.Set("serviceID", claims.Subject)
+		c.Next()
+	}
+}
+
+// parseToken verifies RS256 signature, expiration and audience of a JWT and returns its claims. Other algorithms
+// are refused so that token can't pick how it's verified
+func parseToken(token string, key *rsa.PublicKey) (serviceClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return serviceClaims{}, errMalformedToken
+	}
+
+	var header struct {
+		Algorithm string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return serviceClaims{}, errMalformedToken
+	}
+	if header.Algorithm != "RS256" {
+		return serviceClaims{}, errInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return serviceClaims{}, errMalformedToken
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return serviceClaims{}, errInvalidToken
+	}
+
+	var claims serviceClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return serviceClaims{}, errMalformedToken
+	}
+	if claims.ExpiresAt == 0 || time.Now().Unix() >= claims.ExpiresAt {
+		return serviceClaims{}, errExpiredToken
+	}
+	if !claims.Audience.contains(TOKEN_AUDIENCE) {
+		return serviceClaims{}, errWrongAudience
+	}
+	return claims, nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
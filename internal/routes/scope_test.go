@@ -0,0 +1,266 @@
+package routes_test
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Slimo300/chat-groupservice/internal/handlers"
+	"github.com/Slimo300/chat-groupservice/internal/routes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type staticKey struct {
+	key *rsa.PublicKey
+}
+
+func (k staticKey) GetPublicKey() *rsa.PublicKey {
+	return k.key
+}
+
+type ScopeTestSuite struct {
+	suite.Suite
+	key    *rsa.PrivateKey
+	engine *gin.Engine
+}
+
+func (s *ScopeTestSuite) SetupSuite() {
+	gin.SetMode(gin.TestMode)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	s.Require().NoError(err)
+	s.key = key
+
+	server := handlers.NewServer(nil, nil, nil, nil)
+	server.InternalAPIKey = "secret"
+
+	s.engine = gin.New()
+	internal := s.engine.Group("/internal", routes.MustScope(staticKey{key: &key.PublicKey}, routes.INTERNAL_SCOPE), server.MustInternalKey())
+	caller := func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"serviceID": c.GetString("serviceID")})
+	}
+	internal.GET("/caller", caller)
+	internal.GET("/admin", routes.RequireScope(staticKey{key: &key.PublicKey}, routes.ADMIN_SCOPE), caller)
+}
+
+// signToken creates JWT with given header algorithm signed with RS256 by key
+func signToken(key *rsa.PrivateKey, alg string, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, _ := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func (s *ScopeTestSuite) TestMustScope() {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	s.Require().NoError(err)
+	exp := time.Now().Add(time.Hour).Unix()
+
+	testCases := []struct {
+		desc               string
+		token              string
+		internalKey        string
+		expectedStatusCode int
+		expectedResponse   gin.H
+	}{
+		{
+			desc:               "ScopeServiceToken",
+			token:              signToken(s.key, "RS256", map[string]interface{}{"sub": "messageservice", "scope": "internal:messageservice internal:groupservice", "aud": routes.TOKEN_AUDIENCE, "exp": exp}),
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   gin.H{"serviceID": "messageservice"},
+		},
+		{
+			desc:               "ScopeUserToken",
+			token:              signToken(s.key, "RS256", map[string]interface{}{"sub": "e95bd1fc-ec1f-472c-b7f3-6b39aa7a90c4", "aud": routes.TOKEN_AUDIENCE, "exp": exp}),
+			expectedStatusCode: http.StatusForbidden,
+			expectedResponse:   gin.H{"err": "token lacks required scope internal:groupservice"},
+		},
+		{
+			desc:               "ScopeUserTokenWithInternalKey",
+			token:              signToken(s.key, "RS256", map[string]interface{}{"sub": "e95bd1fc-ec1f-472c-b7f3-6b39aa7a90c4", "aud": routes.TOKEN_AUDIENCE, "exp": exp}),
+			internalKey:        "secret",
+			expectedStatusCode: http.StatusForbidden,
+			expectedResponse:   gin.H{"err": "token lacks required scope internal:groupservice"},
+		},
+		{
+			desc:               "ScopeOtherService",
+			token:              signToken(s.key, "RS256", map[string]interface{}{"sub": "searchservice", "scope": "internal:searchservice", "aud": routes.TOKEN_AUDIENCE, "exp": exp}),
+			expectedStatusCode: http.StatusForbidden,
+			expectedResponse:   gin.H{"err": "token lacks required scope internal:groupservice"},
+		},
+		{
+			desc:               "ScopeExpired",
+			token:              signToken(s.key, "RS256", map[string]interface{}{"sub": "messageservice", "scope": "internal:groupservice", "aud": routes.TOKEN_AUDIENCE, "exp": time.Now().Add(-time.Minute).Unix()}),
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedResponse:   gin.H{"err": "token expired"},
+		},
+		{
+			desc:               "ScopeAudienceList",
+			token:              signToken(s.key, "RS256", map[string]interface{}{"sub": "messageservice", "scope": "internal:groupservice", "aud": []string{"messageservice", routes.TOKEN_AUDIENCE}, "exp": exp}),
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   gin.H{"serviceID": "messageservice"},
+		},
+		{
+			desc:               "ScopeWrongAudience",
+			token:              signToken(s.key, "RS256", map[string]interface{}{"sub": "messageservice", "scope": "internal:groupservice", "aud": "searchservice", "exp": exp}),
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedResponse:   gin.H{"err": "token not meant for this service"},
+		},
+		{
+			desc:               "ScopeNoAudience",
+			token:              signToken(s.key, "RS256", map[string]interface{}{"sub": "messageservice", "scope": "internal:groupservice", "exp": exp}),
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedResponse:   gin.H{"err": "token not meant for this service"},
+		},
+		{
+			desc:               "ScopeWrongKey",
+			token:              signToken(otherKey, "RS256", map[string]interface{}{"sub": "messageservice", "scope": "internal:groupservice", "aud": routes.TOKEN_AUDIENCE, "exp": exp}),
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedResponse:   gin.H{"err": "invalid token"},
+		},
+		{
+			desc:               "ScopeWrongAlgorithm",
+			token:              signToken(s.key, "none", map[string]interface{}{"sub": "messageservice", "scope": "internal:groupservice", "aud": routes.TOKEN_AUDIENCE, "exp": exp}),
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedResponse:   gin.H{"err": "invalid token"},
+		},
+		{
+			desc:               "ScopeMalformed",
+			token:              "not.a-token",
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedResponse:   gin.H{"err": "malformed token"},
+		},
+		{
+			desc:               "ScopeInternalKey",
+			internalKey:        "secret",
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   gin.H{"serviceID": ""},
+		},
+		{
+			desc:               "ScopeNoCredentials",
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedResponse:   gin.H{"err": "invalid internal key"},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+			req, _ := http.NewRequest(http.MethodGet, "/internal/caller", nil)
+			if tC.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tC.token)
+			}
+			if tC.internalKey != "" {
+				req.Header.Set("X-Internal-Key", tC.internalKey)
+			}
+
+			w := httptest.NewRecorder()
+			s.engine.ServeHTTP(w, req)
+			response := w.Result()
+			defer response.Body.Close()
+
+			s.Equal(tC.expectedStatusCode, response.StatusCode)
+
+			var msg gin.H
+			if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+				s.Fail(err.Error())
+			}
+			s.Equal(tC.expectedResponse, msg)
+		})
+	}
+}
+
+func (s *ScopeTestSuite) TestRequireScope() {
+	exp := time.Now().Add(time.Hour).Unix()
+
+	testCases := []struct {
+		desc               string
+		token              string
+		internalKey        string
+		expectedStatusCode int
+		expectedResponse   gin.H
+	}{
+		{
+			desc:               "RequireScopeAdminToken",
+			token:              signToken(s.key, "RS256", map[string]interface{}{"sub": "adminpanel", "scope": "internal:groupservice admin:groupservice", "aud": routes.TOKEN_AUDIENCE, "exp": exp}),
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   gin.H{"serviceID": "adminpanel"},
+		},
+		{
+			desc:               "RequireScopeInternalToken",
+			token:              signToken(s.key, "RS256", map[string]interface{}{"sub": "messageservice", "scope": "internal:groupservice", "aud": routes.TOKEN_AUDIENCE, "exp": exp}),
+			expectedStatusCode: http.StatusForbidden,
+			expectedResponse:   gin.H{"err": "token lacks required scope admin:groupservice"},
+		},
+		{
+			desc:               "RequireScopeInternalKey",
+			internalKey:        "secret",
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedResponse:   gin.H{"err": "bearer token required"},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+			req, _ := http.NewRequest(http.MethodGet, "/internal/admin", nil)
+			if tC.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tC.token)
+			}
+			if tC.internalKey != "" {
+				req.Header.Set("X-Internal-Key", tC.internalKey)
+			}
+
+			w := httptest.NewRecorder()
+			s.engine.ServeHTTP(w, req)
+			response := w.Result()
+			defer response.Body.Close()
+
+			s.Equal(tC.expectedStatusCode, response.StatusCode)
+
+			var msg gin.H
+			if err := json.NewDecoder(response.Body).Decode(&msg); err != nil {
+				s.Fail(err.Error())
+			}
+			s.Equal(tC.expectedResponse, msg)
+		})
+	}
+}
+
+func (s *ScopeTestSuite) TestSetupVerifiesWithTokenKeys() {
+	token := signToken(s.key, "RS256", map[string]interface{}{"sub": "messageservice", "scope": "internal:groupservice",
+		"aud": routes.TOKEN_AUDIENCE, "exp": time.Now().Add(time.Hour).Unix()})
+
+	server := handlers.NewServer(nil, nil, nil, nil)
+	engine := routes.Setup(server, "*")
+
+	req, _ := http.NewRequest(http.MethodPost, "/internal/groups/exist", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	s.Equal(http.StatusUnauthorized, w.Code)
+	s.JSONEq(`{"err":"tokens can't be verified"}`, w.Body.String())
+
+	// verified service token doesn't need internal key, request reaches handler which refuses empty body
+	server.TokenKeys = staticKey{key: &s.key.PublicKey}
+	engine = routes.Setup(server, "*")
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	s.Equal(http.StatusBadRequest, w.Code)
+	s.JSONEq(`{"err":"invalid request"}`, w.Body.String())
+}
+
+func TestScopeSuite(t *testing.T) {
+	suite.Run(t, &ScopeTestSuite{})
+}
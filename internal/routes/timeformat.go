@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/Slimo300/chat-groupservice/internal/handlers"
+	"github.com/gin-gonic/gin"
+)
+
+// TimeFormatMiddleware rejects requests with unsupported "time_format" query parameter before they reach handlers
+func TimeFormatMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, err := handlers.ParseTimeFormat(c.Query("time_format")); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,65 @@
+package routes_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Slimo300/chat-groupservice/internal/routes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type TimeFormatTestSuite struct {
+	suite.Suite
+}
+
+func (s *TimeFormatTestSuite) TestTimeFormatMiddleware() {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		desc               string
+		query              string
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			desc:               "TimeFormatOmitted",
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `{"message":"success"}`,
+		},
+		{
+			desc:               "TimeFormatEpochMillis",
+			query:              "?time_format=epoch_ms",
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `{"message":"success"}`,
+		},
+		{
+			desc:               "TimeFormatInvalid",
+			query:              "?time_format=unix",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       `{"err":"invalid time_format value: unix"}`,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+			w := httptest.NewRecorder()
+			_, engine := gin.CreateTestContext(w)
+			engine.Use(routes.TimeFormatMiddleware())
+			engine.GET("/test", func(c *gin.Context) {
+				c.JSON(http.StatusOK, gin.H{"message": "success"})
+			})
+
+			req, _ := http.NewRequest(http.MethodGet, "/test"+tC.query, nil)
+			engine.ServeHTTP(w, req)
+
+			s.Equal(tC.expectedStatusCode, w.Code)
+			s.Equal(tC.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestTimeFormatSuite(t *testing.T) {
+	suite.Run(t, &TimeFormatTestSuite{})
+}
@@ -0,0 +1,115 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TimeoutMiddleware cancels request's context after timeout and responds with 503 when handler neither finished
+// nor started writing its response in time. Response written before deadline is kept, as handler may have already
+// applied changes it describes. Response is buffered until handler returns so it mustn't be used with streaming
+// endpoints
+func TimeoutMiddleware(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		original := c.Writer
+		writer := &timeoutWriter{ResponseWriter: original, header: make(http.Header), ctx: ctx}
+		c.Writer = writer
+
+		c.Next()
+
+		c.Writer = original
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && !writer.inTime {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"err": "request timed out"})
+			return
+		}
+		writer.flush()
+	}
+}
+
+// timeoutWriter holds response in memory so it can be discarded when request times out
+type timeoutWriter struct {
+	gin.ResponseWriter
+	header  http.Header
+	body    bytes.Buffer
+	status  int
+	written bool
+
+	ctx context.Context
+	// inTime is set when handler started writing response before deadline
+	inTime bool
+}
+
+func (w *timeoutWriter) markWritten() {
+	if !w.written && w.ctx.Err() == nil {
+		w.inTime = true
+	}
+	w.written = true
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	if code > 0 && !w.written {
+		w.status = code
+		if w.ctx.Err() == nil {
+			w.inTime = true
+		}
+	}
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	w.markWritten()
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	w.markWritten()
+	return w.body.Write(data)
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	w.markWritten()
+	return w.body.WriteString(s)
+}
+
+func (w *timeoutWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+func (w *timeoutWriter) Size() int {
+	if !w.written {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *timeoutWriter) Written() bool {
+	return w.written
+}
+
+// flush sends buffered response to the underlying writer
+func (w *timeoutWriter) flush() {
+	dst := w.ResponseWriter.Header()
+	for key, values := range w.header {
+		dst[key] = values
+	}
+	w.ResponseWriter.WriteHeader(w.Status())
+	if w.body.Len() > 0 {
+		_, _ = w.ResponseWriter.Write(w.body.Bytes())
+		return
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
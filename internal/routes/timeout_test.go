@@ -0,0 +1,92 @@
+package routes_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Slimo300/chat-groupservice/internal/routes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type TimeoutTestSuite struct {
+	suite.Suite
+}
+
+func (s *TimeoutTestSuite) TestTimeoutMiddleware() {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		desc               string
+		handler            gin.HandlerFunc
+		expectedStatusCode int
+		expectedBody       string
+		expectedHeader     string
+	}{
+		{
+			desc: "TimeoutHandlerFinished",
+			handler: func(c *gin.Context) {
+				c.Header("X-Test", "value")
+				c.JSON(http.StatusCreated, gin.H{"message": "success"})
+			},
+			expectedStatusCode: http.StatusCreated,
+			expectedBody:       `{"message":"success"}`,
+			expectedHeader:     "value",
+		},
+		{
+			desc: "TimeoutHandlerNoBody",
+			handler: func(c *gin.Context) {
+				c.Status(http.StatusNoContent)
+			},
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			desc: "TimeoutHandlerRespondedInTime",
+			handler: func(c *gin.Context) {
+				// change is applied and described before deadline, handler returns only after it
+				c.JSON(http.StatusCreated, gin.H{"message": "success"})
+				<-c.Request.Context().Done()
+			},
+			expectedStatusCode: http.StatusCreated,
+			expectedBody:       `{"message":"success"}`,
+		},
+		{
+			desc: "TimeoutHandlerTimedOut",
+			handler: func(c *gin.Context) {
+				c.Header("X-Test", "value")
+				<-c.Request.Context().Done()
+				c.JSON(http.StatusInternalServerError, gin.H{"err": c.Request.Context().Err().Error()})
+			},
+			expectedStatusCode: http.StatusServiceUnavailable,
+			expectedBody:       `{"err":"request timed out"}`,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+			req, _ := http.NewRequest(http.MethodGet, "/test", nil)
+
+			w := httptest.NewRecorder()
+			_, engine := gin.CreateTestContext(w)
+
+			engine.Use(routes.TimeoutMiddleware(50 * time.Millisecond))
+			engine.Handle(http.MethodGet, "/test", tC.handler)
+			engine.ServeHTTP(w, req)
+			response := w.Result()
+			defer response.Body.Close()
+
+			s.Equal(tC.expectedStatusCode, response.StatusCode)
+			s.Equal(tC.expectedHeader, response.Header.Get("X-Test"))
+
+			body, _ := io.ReadAll(response.Body)
+			s.Equal(tC.expectedBody, string(body))
+		})
+	}
+}
+
+func TestTimeoutSuite(t *testing.T) {
+	suite.Run(t, &TimeoutTestSuite{})
+}
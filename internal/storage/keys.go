@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+)
+
+// ErrKeyOutsidePrefix is returned when storage is asked for a key outside of its namespace
+var ErrKeyOutsidePrefix = errors.New("key outside of storage prefix")
+
+// ContentKey returns key under prefix derived from SHA-256 of content read to its end. Identical files get
+// identical keys so they share a single stored object, all keys of stored files should be created with it
+func ContentKey(prefix string, content io.Reader) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, content); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// CheckKey checks whether key lies under prefix and isn't the prefix itself
+func CheckKey(prefix, key string) error {
+	if !strings.HasPrefix(key, prefix) || len(key) == len(prefix) {
+		return ErrKeyOutsidePrefix
+	}
+	return nil
+}
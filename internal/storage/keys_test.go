@@ -0,0 +1,63 @@
+package storage_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Slimo300/chat-groupservice/internal/storage"
+	"github.com/stretchr/testify/suite"
+)
+
+type KeysTestSuite struct {
+	suite.Suite
+}
+
+func (s *KeysTestSuite) TestContentKey() {
+	for _, prefix := range []string{"", "groups/", "tenant/groups/"} {
+		key, err := storage.ContentKey(prefix, strings.NewReader("picture"))
+		s.NoError(err)
+		s.NoError(storage.CheckKey(prefix, key))
+
+		same, _ := storage.ContentKey(prefix, strings.NewReader("picture"))
+		s.Equal(key, same)
+		other, _ := storage.ContentKey(prefix, strings.NewReader("other picture"))
+		s.NotEqual(key, other)
+	}
+}
+
+func (s *KeysTestSuite) TestCheckKey() {
+	testCases := []struct {
+		desc        string
+		prefix      string
+		key         string
+		expectedErr error
+	}{
+		{desc: "checkKeyNoPrefix", prefix: "", key: "picture", expectedErr: nil},
+		{desc: "checkKeyPrefixed", prefix: "groups/", key: "groups/picture", expectedErr: nil},
+		{desc: "checkKeyNotPrefixed", prefix: "groups/", key: "picture", expectedErr: storage.ErrKeyOutsidePrefix},
+		{desc: "checkKeyOtherPrefix", prefix: "groups/", key: "users/groups/picture", expectedErr: storage.ErrKeyOutsidePrefix},
+		{desc: "checkKeyPrefixOnly", prefix: "groups/", key: "groups/", expectedErr: storage.ErrKeyOutsidePrefix},
+		{desc: "checkKeyEmpty", prefix: "", key: "", expectedErr: storage.ErrKeyOutsidePrefix},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+			s.Equal(tC.expectedErr, storage.CheckKey(tC.prefix, tC.key))
+		})
+	}
+}
+
+// S3Storage has to refuse keys outside of its prefix before sending any request
+func (s *KeysTestSuite) TestS3StorageRefusesKeyOutsidePrefix() {
+	st := &storage.S3Storage{Bucket: "bucket", Prefix: "groups/"}
+
+	s.ErrorIs(st.UploadFile(nil, "picture", "image/png"), storage.ErrKeyOutsidePrefix)
+	_, err := st.GetFile("picture", time.Time{})
+	s.ErrorIs(err, storage.ErrKeyOutsidePrefix)
+	s.ErrorIs(st.DeleteFile("picture"), storage.ErrKeyOutsidePrefix)
+}
+
+func TestKeysSuite(t *testing.T) {
+	suite.Run(t, new(KeysTestSuite))
+}
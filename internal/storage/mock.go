@@ -4,6 +4,7 @@ package storage
 
 import (
 	multipart "mime/multipart"
+	time "time"
 
 	mock "github.com/stretchr/testify/mock"
 )
@@ -27,13 +28,39 @@ func (_m *MockStorage) DeleteFile(key string) error {
 	return r0
 }
 
-// UploadFile provides a mock function with given fields: img, key
-func (_m *MockStorage) UploadFile(img multipart.File, key string) error {
-	ret := _m.Called(img, key)
+// GetFile provides a mock function with given fields: key, modifiedSince
+func (_m *MockStorage) GetFile(key string, modifiedSince time.Time) (*File, error) {
+	ret := _m.Called(key, modifiedSince)
+
+	var r0 *File
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, time.Time) (*File, error)); ok {
+		return rf(key, modifiedSince)
+	}
+	if rf, ok := ret.Get(0).(func(string, time.Time) *File); ok {
+		r0 = rf(key, modifiedSince)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*File)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, time.Time) error); ok {
+		r1 = rf(key, modifiedSince)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UploadFile provides a mock function with given fields: img, key, contentType
+func (_m *MockStorage) UploadFile(img multipart.File, key string, contentType string) error {
+	ret := _m.Called(img, key, contentType)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(multipart.File, string) error); ok {
-		r0 = rf(img, key)
+	if rf, ok := ret.Get(0).(func(multipart.File, string, string) error); ok {
+		r0 = rf(img, key, contentType)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -1,27 +1,58 @@
 package storage
 
 import (
+	"context"
+	"errors"
+	"io"
 	"mime/multipart"
+	"net/http"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 )
 
-// StorageLayer describes Storage functionality (uploading and deleting files)
+// StorageLayer describes Storage functionality (uploading, fetching and deleting files)
 type StorageLayer interface {
-	UploadFile(img multipart.File, key string) error
+	UploadFile(img multipart.File, key, contentType string) error
+	GetFile(key string, modifiedSince time.Time) (*File, error)
 	DeleteFile(key string) error
 }
 
+// ContextStorageLayer is implemented by storage layers able to bind their requests to a context
+type ContextStorageLayer interface {
+	WithContext(ctx context.Context) StorageLayer
+}
+
+var ErrFileNotFound = errors.New("file not found")
+var ErrNotModified = errors.New("file not modified")
+
+// File is a file fetched from storage, Body has to be closed by the caller
+type File struct {
+	Body          io.ReadCloser
+	ContentType   string
+	ContentLength int64
+	LastModified  time.Time
+}
+
 // S3Storage allows to interact with S3 to store files
 type S3Storage struct {
 	S3     *s3.S3
 	Bucket string
+	// Prefix is the namespace of the service in bucket, keys outside of it are refused
+	Prefix string
+	// SSE is server-side encryption requested for uploaded objects ("AES256" or "aws:kms"), none when empty.
+	// SSEKMSKeyID optionally selects KMS key used with "aws:kms"
+	SSE         string
+	SSEKMSKeyID string
+
+	ctx context.Context
 }
 
 // NewS3Storage creates new S3 session
-func NewS3Storage(bucket, origin string) (*S3Storage, error) {
+func NewS3Storage(bucket, origin, prefix string) (*S3Storage, error) {
 	session, err := session.NewSession(&aws.Config{
 		Region: aws.String("eu-central-1"),
 	})
@@ -51,22 +82,88 @@ func NewS3Storage(bucket, origin string) (*S3Storage, error) {
 	return &S3Storage{
 		S3:     client,
 		Bucket: bucket,
+		Prefix: prefix,
 	}, nil
 }
 
-// UploadFile uploads file with a given key
-func (s *S3Storage) UploadFile(file multipart.File, key string) error {
-	_, err := s.S3.PutObject(&s3.PutObjectInput{
+// WithContext returns S3Storage sending its requests with ctx
+func (s *S3Storage) WithContext(ctx context.Context) StorageLayer {
+	return &S3Storage{S3: s.S3, Bucket: s.Bucket, Prefix: s.Prefix, SSE: s.SSE, SSEKMSKeyID: s.SSEKMSKeyID, ctx: ctx}
+}
+
+func (s *S3Storage) context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
+// UploadFile uploads file with a given key, contentType is stored with the object and served with it
+func (s *S3Storage) UploadFile(file multipart.File, key, contentType string) error {
+	if err := CheckKey(s.Prefix, key); err != nil {
+		return err
+	}
+	input := &s3.PutObjectInput{
 		Body:   file,
 		Bucket: aws.String(s.Bucket),
 		Key:    aws.String(key),
-	})
+	}
+	if contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+	if s.SSE != "" {
+		input.ServerSideEncryption = aws.String(s.SSE)
+		if s.SSEKMSKeyID != "" {
+			input.SSEKMSKeyId = aws.String(s.SSEKMSKeyID)
+		}
+	}
+
+	_, err := s.S3.PutObjectWithContext(s.context(), input)
 	return err
 }
 
+// GetFile fetches a file with a given key. When modifiedSince is set and file hasn't changed since then
+// ErrNotModified is returned
+func (s *S3Storage) GetFile(key string, modifiedSince time.Time) (*File, error) {
+	if err := CheckKey(s.Prefix, key); err != nil {
+		return nil, err
+	}
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	}
+	if !modifiedSince.IsZero() {
+		input.IfModifiedSince = aws.Time(modifiedSince)
+	}
+
+	out, err := s.S3.GetObjectWithContext(s.context(), input)
+	if err != nil {
+		var reqErr awserr.RequestFailure
+		if errors.As(err, &reqErr) {
+			switch {
+			case reqErr.StatusCode() == http.StatusNotModified:
+				return nil, ErrNotModified
+			case reqErr.StatusCode() == http.StatusNotFound || reqErr.Code() == s3.ErrCodeNoSuchKey:
+				return nil, ErrFileNotFound
+			}
+		}
+		return nil, err
+	}
+
+	return &File{
+		Body:          out.Body,
+		ContentType:   aws.StringValue(out.ContentType),
+		ContentLength: aws.Int64Value(out.ContentLength),
+		LastModified:  aws.TimeValue(out.LastModified),
+	}, nil
+}
+
 // DeleteFile deletes a file with a given key
 func (s *S3Storage) DeleteFile(key string) error {
-	_, err := s.S3.DeleteObject(&s3.DeleteObjectInput{
+	if err := CheckKey(s.Prefix, key); err != nil {
+		return err
+	}
+	_, err := s.S3.DeleteObjectWithContext(s.context(), &s3.DeleteObjectInput{
 		Bucket: aws.String(s.Bucket),
 		Key:    aws.String(key),
 	})
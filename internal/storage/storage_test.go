@@ -0,0 +1,67 @@
+package storage_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Slimo300/chat-groupservice/internal/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/suite"
+)
+
+type file struct {
+	*bytes.Reader
+}
+
+func (file) Close() error { return nil }
+
+type S3StorageTestSuite struct {
+	suite.Suite
+}
+
+func (s *S3StorageTestSuite) TestUploadFileEncryption() {
+	testCases := []struct {
+		desc             string
+		sse              string
+		kmsKeyID         string
+		expectedSSE      string
+		expectedKMSKeyID string
+	}{
+		{desc: "uploadFileNoEncryption"},
+		{desc: "uploadFileAES256", sse: "AES256", expectedSSE: "AES256"},
+		{desc: "uploadFileKMS", sse: "aws:kms", expectedSSE: "aws:kms"},
+		{desc: "uploadFileKMSWithKey", sse: "aws:kms", kmsKeyID: "key-id", expectedSSE: "aws:kms", expectedKMSKeyID: "key-id"},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.desc, func() {
+			var received http.Header
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received = r.Header.Clone()
+			}))
+			defer server.Close()
+
+			sess := session.Must(session.NewSession(&aws.Config{
+				Region:           aws.String("eu-central-1"),
+				Endpoint:         aws.String(server.URL),
+				S3ForcePathStyle: aws.Bool(true),
+				Credentials:      credentials.NewStaticCredentials("id", "secret", ""),
+			}))
+			st := &storage.S3Storage{S3: s3.New(sess), Bucket: "bucket", SSE: tC.sse, SSEKMSKeyID: tC.kmsKeyID}
+
+			s.NoError(st.UploadFile(file{bytes.NewReader([]byte("picture"))}, "key", "image/png"))
+			s.Equal("image/png", received.Get("Content-Type"))
+			s.Equal(tC.expectedSSE, received.Get("X-Amz-Server-Side-Encryption"))
+			s.Equal(tC.expectedKMSKeyID, received.Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"))
+		})
+	}
+}
+
+func TestS3StorageSuite(t *testing.T) {
+	suite.Run(t, new(S3StorageTestSuite))
+}
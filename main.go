@@ -10,10 +10,14 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/Slimo300/MicroservicesChatApp/backend/lib/msgqueue"
 	"github.com/Slimo300/chat-groupservice/internal/config"
 	"github.com/Slimo300/chat-groupservice/internal/database/orm"
+	"github.com/Slimo300/chat-groupservice/internal/eventemitter"
+	"github.com/Slimo300/chat-groupservice/internal/eventlistener"
 	"github.com/Slimo300/chat-groupservice/internal/eventprocessor"
 	"github.com/Slimo300/chat-groupservice/internal/handlers"
+	"github.com/Slimo300/chat-groupservice/internal/moderation"
 	"github.com/Slimo300/chat-groupservice/internal/routes"
 	"github.com/Slimo300/chat-groupservice/internal/storage"
 	"github.com/Slimo300/chat-tokenservice/pkg/client"
@@ -25,29 +29,84 @@ func main() {
 	if err != nil {
 		log.Fatal("Couldn't read config")
 	}
+	logConfig(conf)
 
 	db, err := orm.Setup(conf.DBAddress)
 	if err != nil {
 		log.Fatal(err)
 	}
-	storage, err := storage.NewS3Storage(conf.S3Bucket, conf.Origin)
+	db.UniqueGroupNames = conf.UniqueGroupNamePerOwner
+	db.MaxGroupsPerUser = conf.MaxGroupsPerUser
+
+	storage, err := storage.NewS3Storage(conf.S3Bucket, conf.Origin, conf.S3KeyPrefix)
 	if err != nil {
 		log.Fatalf("Error connecting to AWS S3: %v", err)
 	}
+	storage.SSE = conf.S3SSE
+	storage.SSEKMSKeyID = conf.S3SSEKMSKeyID
 	tokenClient, err := client.NewGRPCTokenClient(conf.TokenServiceAddress)
 	if err != nil {
 		log.Fatalf("Couldn't connect to grpc auth server: %v", err)
 	}
 
-	emiter, listener, err := kafkaSetup([]string{conf.BrokerAddress})
-	if err != nil {
-		log.Fatalf("Error setting up kafka: %v", err)
+	var emiter msgqueue.EventEmiter
+	if conf.EmitterType == "webhook" {
+		emiter = eventemitter.NewWebhookEmitter(conf.WebhookURL, conf.WebhookSecret)
 	}
 
-	eventProcessor := eventprocessor.NewEventProcessor(db, listener)
-	go eventProcessor.ProcessEvents()
+	// kafka is needed only for consuming events when they're emitted to a webhook, deployments without it
+	// don't consume anything
+	var listener *eventlistener.KafkaListener
+	var eventProcessor *eventprocessor.EventProcessor
+	if conf.BrokerAddress != "" {
+		kafkaEmiter, kafkaListener, err := kafkaSetup([]string{conf.BrokerAddress}, conf.ConsumerStartOffset, conf.ProducerAcks, emiter == nil)
+		if err != nil {
+			log.Fatalf("Error setting up kafka: %v", err)
+		}
+		if emiter == nil {
+			emiter = kafkaEmiter
+		}
+		listener = kafkaListener
+
+		eventProcessor = eventprocessor.NewEventProcessor(db, listener)
+		eventProcessor.ActivityDebounce = conf.ActivityDebounce
+		eventProcessor.UserBatchSize = conf.UserBatchSize
+		eventProcessor.UserFlushInterval = conf.UserFlushInterval
+		eventProcessor.LogUnknownGroups = conf.LogUnknownGroups
+		go eventProcessor.ProcessEvents()
+	} else {
+		log.Println("BROKER_ADDRESS not set, events of other services won't be consumed")
+	}
 
 	server := handlers.NewServer(db, storage, tokenClient, emiter)
+	// service tokens are verified with the same key token service client checks user tokens with
+	server.TokenKeys = tokenClient
+	server.MaxImageDimension = conf.MaxImageDimension
+	server.SetMaxConcurrentImageOps(conf.MaxConcurrentImageOps)
+	server.RequestTimeout = conf.RequestTimeout
+	server.AvatarMaxAge = conf.AvatarMaxAge
+	server.SetPlatformMetricsTTL(conf.PlatformMetricsTTL)
+	if err := server.SetDefaultSorts(conf.DefaultSorts); err != nil {
+		log.Fatalf("Invalid default sorts: %v", err)
+	}
+	// nil listener would make non-nil interfaces
+	if listener != nil {
+		server.Replayer = listener
+		server.Pauser = listener
+	}
+	server.InternalAPIKey = conf.InternalAPIKey
+	server.ReadOnly = conf.ReadOnly
+	server.TrustedProxies = conf.TrustedProxies
+	if conf.RateLimitEnabled {
+		server.IPRateLimit = float64(conf.RateLimitPerSecond)
+		server.IPRateBurst = conf.RateLimitBurst
+		server.RateLimitExempt = conf.RateLimitExempt
+	}
+	server.KeyPrefix = conf.S3KeyPrefix
+	if conf.ModerationEndpoint != "" {
+		server.Moderator = moderation.NewHTTPModerator(conf.ModerationEndpoint, conf.ModerationTimeout)
+		server.ModerationFailOpen = conf.ModerationFailOpen
+	}
 	handler := routes.Setup(server, conf.Origin)
 
 	httpServer := &http.Server{
@@ -61,7 +120,7 @@ func main() {
 
 	errChan := make(chan error)
 
-	go startHTTPSServer(httpsServer, conf.CertDir, errChan)
+	go startHTTPSServer(httpsServer, conf.CertDir, conf.TLSConfig(), errChan)
 	go func() { errChan <- httpServer.ListenAndServe() }()
 
 	quit := make(chan os.Signal, 1)
@@ -77,6 +136,10 @@ func main() {
 		if err := httpsServer.Shutdown(ctx); err != nil {
 			log.Fatalf("Server forced to shutdown: %v\n", err)
 		}
+		server.WaitForEmits()
+		if eventProcessor != nil {
+			eventProcessor.Stop()
+		}
 	case err := <-errChan:
 		log.Fatal(err)
 	}